@@ -0,0 +1,481 @@
+package api
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+// addrDescAtHeight is the state of one address reconstructed from its transactions up to a block height
+type addrDescAtHeight struct {
+	txs      int
+	received big.Int
+	sent     big.Int
+	balance  big.Int
+	utxos    Utxos
+	tokens   Tokens
+}
+
+// blockInfoAtHeight returns the block for the snapshot at height, which must be already indexed
+func (w *Worker) blockInfoAtHeight(height uint32) (*db.BlockInfo, error) {
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if height > bestheight {
+		return nil, NewAPIError("Height "+strconv.FormatUint(uint64(height), 10)+" is above the best block", true)
+	}
+	bi, err := w.db.GetBlockInfo(height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockInfo %v", height)
+	}
+	if bi == nil {
+		return nil, NewAPIError("Block "+strconv.FormatUint(uint64(height), 10)+" not found", true)
+	}
+	bi.Height = height
+	return bi, nil
+}
+
+// maxAtHeightSpendingTxs is the limit of the spending transactions loaded from the backend
+// to find the outputs unspent at height, which is necessary only without the extended index
+const maxAtHeightSpendingTxs = 1000
+
+// getBitcoinTypeAddrDescAtHeight computes the balance and the unspent outputs of the address at the end of the block at height
+// from the address index and the txAddresses column. With the extended index, the height of the spend of an output is stored
+// in txAddresses, otherwise the currently spent outputs are checked against the inputs of the address in blocks up to height,
+// which requires the spending transactions to be loaded.
+// If txids is not nil, the transactions of the address up to height are added to it.
+func (w *Worker) getBitcoinTypeAddrDescAtHeight(addrDesc bchain.AddressDescriptor, height uint32, txids map[string]struct{}) (*addrDescAtHeight, error) {
+	type spendingTx struct {
+		txid   string
+		inputs []int32
+	}
+	r := &addrDescAtHeight{utxos: make(Utxos, 0)}
+	extendedIndex := w.db.HasExtendedIndex()
+	spentCandidates := make(map[string]int)
+	var spending []spendingTx
+	err := w.db.GetAddrDescTransactions(addrDesc, 0, height, func(txid string, h uint32, indexes []int32) error {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return err
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+			return nil
+		}
+		r.txs++
+		if txids != nil {
+			txids[txid] = struct{}{}
+		}
+		coinbase := len(ta.Inputs) == 1 && len(ta.Inputs[0].AddrDesc) == 0 && IsZeroBigInt(&ta.Inputs[0].ValueSat)
+		var inputs []int32
+		for _, index := range indexes {
+			if index < 0 {
+				index = ^index
+				if int(index) >= len(ta.Inputs) {
+					continue
+				}
+				r.sent.Add(&r.sent, &ta.Inputs[index].ValueSat)
+				inputs = append(inputs, index)
+				continue
+			}
+			if int(index) >= len(ta.Outputs) {
+				continue
+			}
+			tao := &ta.Outputs[index]
+			r.received.Add(&r.received, &tao.ValueSat)
+			if tao.Spent {
+				if extendedIndex {
+					if tao.SpentHeight <= height {
+						continue
+					}
+				} else {
+					spentCandidates[txid+":"+strconv.Itoa(int(index))] = len(r.utxos)
+				}
+			}
+			r.utxos = append(r.utxos, Utxo{
+				Txid:          txid,
+				Vout:          index,
+				AmountSat:     (*Amount)(new(big.Int).Set(&tao.ValueSat)),
+				Height:        int(h),
+				Confirmations: int(height-h) + 1,
+				Coinbase:      coinbase,
+			})
+		}
+		if len(inputs) > 0 && !extendedIndex {
+			spending = append(spending, spendingTx{txid: txid, inputs: inputs})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.balance.Sub(&r.received, &r.sent)
+	// the outputs spent now but not by the transactions up to height were unspent at height
+	spent := make(map[int]struct{})
+	for i := 0; i < len(spending) && len(spent) < len(spentCandidates); i++ {
+		if i == maxAtHeightSpendingTxs {
+			return nil, NewAPIError("Too many transactions of the address to compute the unspent outputs at height without the extended index", true)
+		}
+		bchainTx, _, err := w.txCache.GetTransaction(spending[i].txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTransaction %v", spending[i].txid)
+		}
+		for _, index := range spending[i].inputs {
+			if int(index) >= len(bchainTx.Vin) {
+				continue
+			}
+			vin := &bchainTx.Vin[index]
+			if u, found := spentCandidates[vin.Txid+":"+strconv.Itoa(int(vin.Vout))]; found {
+				spent[u] = struct{}{}
+			}
+		}
+	}
+	if len(spent) > 0 {
+		utxos := r.utxos[:0]
+		for i := range r.utxos {
+			if _, found := spent[i]; !found {
+				utxos = append(utxos, r.utxos[i])
+			}
+		}
+		r.utxos = utxos
+	}
+	var checksum big.Int
+	checksum.Set(&r.balance)
+	for i := range r.utxos {
+		checksum.Sub(&checksum, (*big.Int)(r.utxos[i].AmountSat))
+	}
+	if checksum.Sign() != 0 {
+		glog.Warning("DB inconsistency:  ", addrDesc, ": checksum of utxos at height ", height, " is not zero, checksum=", checksum.String())
+	}
+	sort.Stable(r.utxos)
+	return r, nil
+}
+
+// tokenFromAddrContract returns the token of the address with the balance, the ids or the values stored in the index
+func (w *Worker) tokenFromAddrContract(c *db.AddrContract) (*Token, error) {
+	standard := bchain.EthereumTokenStandardMap[c.Standard]
+	ci, _, err := w.getContractDescriptorInfo(c.Contract, standard)
+	if err != nil {
+		return nil, errors.Annotatef(err, "getContractDescriptorInfo %v", c.Contract)
+	}
+	t := &Token{
+		Type:      standard,
+		Standard:  standard,
+		Contract:  ci.Contract,
+		Name:      ci.Name,
+		Symbol:    ci.Symbol,
+		Decimals:  ci.Decimals,
+		Transfers: int(c.Txs),
+	}
+	switch c.Standard {
+	case bchain.FungibleToken:
+		t.BalanceSat = (*Amount)(new(big.Int).Set(&c.Value))
+	case bchain.NonFungibleToken:
+		t.Ids = make([]Amount, len(c.Ids))
+		for i := range c.Ids {
+			t.Ids[i] = (Amount)(*new(big.Int).Set(&c.Ids[i]))
+		}
+	case bchain.MultiToken:
+		t.MultiTokenValues = make([]MultiTokenValue, len(c.MultiTokenValues))
+		for i := range c.MultiTokenValues {
+			t.MultiTokenValues[i] = MultiTokenValue{
+				Id:    (*Amount)(new(big.Int).Set(&c.MultiTokenValues[i].Id)),
+				Value: (*Amount)(new(big.Int).Set(&c.MultiTokenValues[i].Value)),
+			}
+		}
+	}
+	return t, nil
+}
+
+// revertTokenTransferAtHeight removes the effect of one token transfer of the address from the token
+func revertTokenTransferAtHeight(token *Token, t *TokenTransfer, received bool) {
+	switch t.Standard {
+	case bchain.EthereumTokenStandardMap[bchain.NonFungibleToken]:
+		if t.Value == nil {
+			return
+		}
+		if !received {
+			token.Ids = append(token.Ids, *t.Value)
+			return
+		}
+		for i := range token.Ids {
+			if (*big.Int)(&token.Ids[i]).Cmp((*big.Int)(t.Value)) == 0 {
+				token.Ids = append(token.Ids[:i], token.Ids[i+1:]...)
+				break
+			}
+		}
+	case bchain.EthereumTokenStandardMap[bchain.MultiToken]:
+		for i := range t.MultiTokenValues {
+			mtv := &t.MultiTokenValues[i]
+			if mtv.Id == nil || mtv.Value == nil {
+				continue
+			}
+			j := 0
+			for ; j < len(token.MultiTokenValues); j++ {
+				if (*big.Int)(token.MultiTokenValues[j].Id).Cmp((*big.Int)(mtv.Id)) == 0 {
+					break
+				}
+			}
+			if j == len(token.MultiTokenValues) {
+				token.MultiTokenValues = append(token.MultiTokenValues, MultiTokenValue{Id: mtv.Id, Value: &Amount{}})
+			}
+			v := (*big.Int)(token.MultiTokenValues[j].Value)
+			if received {
+				v.Sub(v, (*big.Int)(mtv.Value))
+			} else {
+				v.Add(v, (*big.Int)(mtv.Value))
+			}
+		}
+	default:
+		if token.BalanceSat == nil {
+			token.BalanceSat = &Amount{}
+		}
+		if t.Value == nil {
+			return
+		}
+		b := (*big.Int)(token.BalanceSat)
+		if received {
+			b.Sub(b, (*big.Int)(t.Value))
+		} else {
+			b.Add(b, (*big.Int)(t.Value))
+		}
+	}
+}
+
+// getEthereumTypeAddrDescAtHeight computes the balance of the coin and of the tokens of the address at the end of the block at height.
+// The tokens are taken from the current state in the index, from which the transactions above height are walked back.
+// The balance of the coin is requested from the backend at height, which includes the validator withdrawals and the mining rewards
+// not present in the index. If the backend does not keep the state at height, the current balance is walked back instead,
+// the withdrawals and the rewards received above height are then not subtracted.
+func (w *Worker) getEthereumTypeAddrDescAtHeight(addrDesc bchain.AddressDescriptor, address string, height uint32) (*addrDescAtHeight, error) {
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	ca, err := w.db.GetAddrDescContracts(addrDesc)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescContracts %v", addrDesc)
+	}
+	r := &addrDescAtHeight{}
+	tokens := make(map[string]*Token)
+	order := make([]string, 0)
+	if ca != nil {
+		r.txs = int(ca.TotalTxs)
+		for i := range ca.Contracts {
+			c := &ca.Contracts[i]
+			t, err := w.tokenFromAddrContract(c)
+			if err != nil {
+				return nil, err
+			}
+			tokens[string(c.Contract)] = t
+			order = append(order, string(c.Contract))
+		}
+	}
+	var txids []string
+	var received, sent big.Int
+	if height < bestheight {
+		err = w.db.GetAddrDescTransactions(addrDesc, height+1, maxUint32, func(txid string, h uint32, indexes []int32) error {
+			txids = append(txids, txid)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// walk back from the newest transaction, the order matters for the ids of the non fungible tokens
+	own := map[string]struct{}{address: {}}
+	for _, txid := range txids {
+		tx, err := w.txFromTxid(txid, bestheight, AccountDetailsTxHistory, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if r.txs > 0 {
+			r.txs--
+		}
+		row := w.exportRowFromTx(tx, own)
+		received.Add(&received, (*big.Int)(row.ReceivedSat))
+		sent.Add(&sent, (*big.Int)(row.SentSat))
+		sent.Add(&sent, (*big.Int)(row.FeeSat))
+		// the index counts the transactions with the contract, not the transfers
+		reverted := make(map[*Token]struct{})
+		for j := range tx.TokenTransfers {
+			t := &tx.TokenTransfers[j]
+			// transfer to self does not change the balance
+			if t.From == t.To || (t.From != address && t.To != address) {
+				continue
+			}
+			cd, err := w.chainParser.GetAddrDescFromAddress(t.Contract)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetAddrDescFromAddress %v", t.Contract)
+			}
+			token, found := tokens[string(cd)]
+			if !found {
+				glog.Warning("DB inconsistency:  ", address, ": contract ", t.Contract, " of tx ", txid, " not found in addressContracts")
+				continue
+			}
+			revertTokenTransferAtHeight(token, t, t.To == address)
+			reverted[token] = struct{}{}
+		}
+		for token := range reverted {
+			if token.Transfers > 0 {
+				token.Transfers--
+			}
+		}
+	}
+	balance, err := w.chain.EthereumTypeGetBalanceAtBlock(addrDesc, new(big.Int).SetUint64(uint64(height)))
+	if err != nil {
+		glog.Warning("EthereumTypeGetBalanceAtBlock ", address, ", height ", height, ": ", err, ", walking back the current balance")
+		balance, err = w.chain.EthereumTypeGetBalance(addrDesc)
+		if err != nil {
+			return nil, errors.Annotatef(err, "EthereumTypeGetBalance %v", addrDesc)
+		}
+		balance.Sub(balance, &received)
+		balance.Add(balance, &sent)
+	}
+	r.balance.Set(balance)
+	r.tokens = make(Tokens, 0, len(order))
+	for _, contract := range order {
+		t := tokens[contract]
+		// the tokens first transferred above height did not exist at height
+		if t.Transfers == 0 {
+			continue
+		}
+		if len(t.MultiTokenValues) > 0 {
+			values := t.MultiTokenValues[:0]
+			for _, v := range t.MultiTokenValues {
+				if (*big.Int)(v.Value).Sign() != 0 {
+					values = append(values, v)
+				}
+			}
+			t.MultiTokenValues = values
+		}
+		r.tokens = append(r.tokens, *t)
+	}
+	sort.Sort(r.tokens)
+	return r, nil
+}
+
+// GetAddressAtHeight returns the balance, the unspent outputs (Bitcoin type coins) and the token balances (Ethereum type coins)
+// of the address as they were at the end of the block at given height
+func (w *Worker) GetAddressAtHeight(address string, height uint32) (*BalanceAtHeight, error) {
+	start := time.Now()
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	bi, err := w.blockInfoAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	var a *addrDescAtHeight
+	if w.chainType == bchain.ChainEthereumType {
+		a, err = w.getEthereumTypeAddrDescAtHeight(addrDesc, address, height)
+	} else {
+		a, err = w.getBitcoinTypeAddrDescAtHeight(addrDesc, height, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &BalanceAtHeight{
+		Address:    address,
+		Height:     height,
+		BlockHash:  bi.Hash,
+		BlockTime:  bi.Time,
+		Txs:        a.txs,
+		BalanceSat: (*Amount)(&a.balance),
+		Tokens:     a.tokens,
+	}
+	if w.chainType == bchain.ChainBitcoinType {
+		r.TotalReceivedSat = (*Amount)(&a.received)
+		r.TotalSentSat = (*Amount)(&a.sent)
+		r.Utxos = a.utxos
+	}
+	glog.Info("GetAddressAtHeight ", address, ", height ", height, ", ", a.txs, " txs, ", time.Since(start))
+	return r, nil
+}
+
+// GetXpubAtHeight returns the balance and the unspent outputs of the xpub and the balances of its addresses
+// as they were at the end of the block at given height
+func (w *Worker) GetXpubAtHeight(xpub string, height uint32, gap int) (*BalanceAtHeight, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, ErrUnsupportedXpub
+	}
+	start := time.Now()
+	xd, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	bi, err := w.blockInfoAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	// the addresses are discovered using the current state, which covers all addresses used up to height
+	data, _, inCache, err := w.getXpubData(xd, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout:          AddressFilterVoutOff,
+		OnlyConfirmed: true,
+	}, gap)
+	if err != nil {
+		return nil, err
+	}
+	var received, sent, balance big.Int
+	r := &BalanceAtHeight{
+		Address:   xpub,
+		Height:    height,
+		BlockHash: bi.Hash,
+		BlockTime: bi.Time,
+		Utxos:     make(Utxos, 0),
+		Tokens:    make(Tokens, 0),
+	}
+	txids := make(map[string]struct{})
+	for ci, da := range data.addresses {
+		for i := range da {
+			ad := &da[i]
+			if ad.balance == nil {
+				continue
+			}
+			a, err := w.getBitcoinTypeAddrDescAtHeight(ad.addrDesc, height, txids)
+			if err != nil {
+				return nil, err
+			}
+			if a.txs == 0 {
+				continue
+			}
+			received.Add(&received, &a.received)
+			sent.Add(&sent, &a.sent)
+			balance.Add(&balance, &a.balance)
+			t := w.tokenFromXpubAddress(data, ad, ci, i, AccountDetailsTokens)
+			for j := range a.utxos {
+				u := &a.utxos[j]
+				u.Address = t.Name
+				u.Path = t.Path
+			}
+			r.Utxos = append(r.Utxos, a.utxos...)
+			r.Tokens = append(r.Tokens, Token{
+				Type:             t.Type,
+				Standard:         t.Standard,
+				Name:             t.Name,
+				Path:             t.Path,
+				Transfers:        a.txs,
+				Decimals:         t.Decimals,
+				BalanceSat:       (*Amount)(new(big.Int).Set(&a.balance)),
+				TotalReceivedSat: (*Amount)(new(big.Int).Set(&a.received)),
+				TotalSentSat:     (*Amount)(new(big.Int).Set(&a.sent)),
+			})
+		}
+	}
+	sort.Stable(r.Utxos)
+	r.Txs = len(txids)
+	// the totals are summed over the addresses, the same way as in GetXpubAddress
+	r.BalanceSat = (*Amount)(&balance)
+	r.TotalReceivedSat = (*Amount)(&received)
+	r.TotalSentSat = (*Amount)(&sent)
+	glog.Info("GetXpubAtHeight ", xpub[:xpubLogPrefix], ", height ", height, ", cache ", inCache, ", ", r.Txs, " txs, ", time.Since(start))
+	return r, nil
+}
@@ -0,0 +1,45 @@
+//go:build unittest
+
+package api
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func TestRevertTokenTransferAtHeight(t *testing.T) {
+	amount := func(v int64) *Amount { return (*Amount)(big.NewInt(v)) }
+	erc20 := bchain.EthereumTokenStandardMap[bchain.FungibleToken]
+	erc721 := bchain.EthereumTokenStandardMap[bchain.NonFungibleToken]
+	erc1155 := bchain.EthereumTokenStandardMap[bchain.MultiToken]
+	tokens := map[string]*Token{
+		"c20":   {Standard: erc20, BalanceSat: amount(70)},
+		"c721":  {Standard: erc721, Ids: []Amount{*amount(8)}},
+		"c1155": {Standard: erc1155, MultiTokenValues: []MultiTokenValue{{Id: amount(1), Value: amount(3)}, {Id: amount(2), Value: amount(3)}}},
+	}
+	// the transfers above height, from the newest
+	transfers := []struct {
+		t        TokenTransfer
+		received bool
+	}{
+		{TokenTransfer{Standard: erc1155, Contract: "c1155", MultiTokenValues: []MultiTokenValue{{Id: amount(1), Value: amount(2)}}}, false},
+		{TokenTransfer{Standard: erc721, Contract: "c721", Value: amount(7)}, false},
+		{TokenTransfer{Standard: erc721, Contract: "c721", Value: amount(8)}, true},
+		{TokenTransfer{Standard: erc20, Contract: "c20", Value: amount(30)}, false},
+		{TokenTransfer{Standard: erc20, Contract: "c20", Value: amount(50)}, true},
+	}
+	for i := range transfers {
+		revertTokenTransferAtHeight(tokens[transfers[i].t.Contract], &transfers[i].t, transfers[i].received)
+	}
+	if c := tokens["c20"]; c.BalanceSat.String() != "50" {
+		t.Errorf("unexpected fungible token %+v", c)
+	}
+	if c := tokens["c721"]; len(c.Ids) != 1 || c.Ids[0].String() != "7" {
+		t.Errorf("unexpected non fungible token %+v", c)
+	}
+	if c := tokens["c1155"]; len(c.MultiTokenValues) != 2 || c.MultiTokenValues[0].Value.String() != "5" || c.MultiTokenValues[1].Value.String() != "3" {
+		t.Errorf("unexpected multi token %+v", c)
+	}
+}
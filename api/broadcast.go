@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+const (
+	// broadcastTxMaxAge is the time after which an unconfirmed transaction is no longer rebroadcast, it is the default mempool expiry of bitcoind
+	broadcastTxMaxAge = 14 * 24 * time.Hour
+	// broadcastTxRetention is the time for which the transactions in a final state are kept in the queue
+	broadcastTxRetention = 7 * 24 * time.Hour
+)
+
+// SendRawTransaction sends the transaction to the backends and records it to the broadcast queue, if the queue is enabled
+func (w *Worker) SendRawTransaction(txHex string, disableAlternativeRPC bool) (string, error) {
+	txid, err := w.chain.SendRawTransaction(txHex, disableAlternativeRPC)
+	if err != nil {
+		return "", err
+	}
+	if w.is.EnableBroadcastQueue {
+		if err := w.recordBroadcastTx(txid, txHex); err != nil {
+			glog.Error("recordBroadcastTx ", txid, ": ", err)
+		}
+	}
+	return txid, nil
+}
+
+func (w *Worker) recordBroadcastTx(txid string, txHex string) error {
+	bt, err := w.db.GetBroadcastTx(txid)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	if bt == nil {
+		b, err := hex.DecodeString(txHex)
+		if err != nil {
+			return err
+		}
+		bt = &db.BroadcastTx{
+			Tx:             b,
+			FirstBroadcast: now,
+			StatusTime:     now,
+		}
+	}
+	bt.LastBroadcast = now
+	bt.Broadcasts++
+	bt.LastError = ""
+	return w.db.StoreBroadcastTx(txid, bt)
+}
+
+func broadcastTxStatus(s db.BroadcastTxStatus) string {
+	switch s {
+	case db.BroadcastTxConfirmed:
+		return "confirmed"
+	case db.BroadcastTxConflicted:
+		return "conflicted"
+	case db.BroadcastTxReplaced:
+		return "replaced"
+	case db.BroadcastTxExpired:
+		return "expired"
+	}
+	return "pending"
+}
+
+// GetBroadcastTx returns the status of a transaction in the broadcast queue
+func (w *Worker) GetBroadcastTx(txid string) (*BroadcastTx, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Broadcast queue is not supported", true)
+	}
+	bt, err := w.db.GetBroadcastTx(txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBroadcastTx %v", txid)
+	}
+	if bt == nil {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found in the broadcast queue", txid), true)
+	}
+	return &BroadcastTx{
+		Txid:           txid,
+		Status:         broadcastTxStatus(bt.Status),
+		FirstBroadcast: bt.FirstBroadcast,
+		LastBroadcast:  bt.LastBroadcast,
+		Broadcasts:     bt.Broadcasts,
+		InMempool:      w.mempool.GetTransactionTime(txid) != 0,
+		BlockHeight:    bt.BlockHeight,
+		ReplacedBy:     bt.ReplacedBy,
+		LastError:      bt.LastError,
+	}, nil
+}
+
+// BroadcastQueue rebroadcasts the transactions sent through Blockbook until they are confirmed, conflicted, replaced or expired
+type BroadcastQueue struct {
+	w      *Worker
+	period time.Duration
+}
+
+// NewBroadcastQueue creates the queue, the pending transactions are rebroadcast with the given period
+func NewBroadcastQueue(w *Worker, period time.Duration) *BroadcastQueue {
+	return &BroadcastQueue{
+		w:      w,
+		period: period,
+	}
+}
+
+// Process updates the status of the queued transactions, rebroadcasts the pending ones and removes the old final ones
+func (q *BroadcastQueue) Process() {
+	type queuedTx struct {
+		txid string
+		bt   *db.BroadcastTx
+	}
+	var pending []queuedTx
+	var old []string
+	now := time.Now()
+	err := q.w.db.IterateBroadcastTxs(func(txid string, bt *db.BroadcastTx) error {
+		if bt.Status == db.BroadcastTxPending {
+			pending = append(pending, queuedTx{txid, bt})
+		} else if now.Sub(time.Unix(bt.StatusTime, 0)) > broadcastTxRetention {
+			old = append(old, txid)
+		}
+		return nil
+	})
+	if err != nil {
+		glog.Error("IterateBroadcastTxs: ", err)
+		return
+	}
+	for _, txid := range old {
+		if err := q.w.db.DeleteBroadcastTx(txid); err != nil {
+			glog.Error("DeleteBroadcastTx ", txid, ": ", err)
+		}
+	}
+	for i := range pending {
+		if err := q.processTx(pending[i].txid, pending[i].bt, now); err != nil {
+			glog.Error("BroadcastQueue ", pending[i].txid, ": ", err)
+		}
+	}
+}
+
+func (q *BroadcastQueue) processTx(txid string, bt *db.BroadcastTx, now time.Time) error {
+	if err := q.w.updateBroadcastTxStatus(txid, bt); err != nil {
+		return err
+	}
+	if bt.Status == db.BroadcastTxPending && now.Sub(time.Unix(bt.FirstBroadcast, 0)) > broadcastTxMaxAge {
+		bt.Status = db.BroadcastTxExpired
+	}
+	if bt.Status != db.BroadcastTxPending {
+		bt.StatusTime = now.Unix()
+		glog.Info("BroadcastQueue ", txid, " ", broadcastTxStatus(bt.Status), " after ", bt.Broadcasts, " broadcasts")
+		return q.w.db.StoreBroadcastTx(txid, bt)
+	}
+	if now.Sub(time.Unix(bt.LastBroadcast, 0)) < q.period {
+		return nil
+	}
+	// the transaction is sent to all the backends, also to the alternative ones which may have dropped it
+	_, err := q.w.chain.SendRawTransaction(hex.EncodeToString(bt.Tx), false)
+	bt.LastBroadcast = now.Unix()
+	bt.Broadcasts++
+	bt.LastError = ""
+	if err != nil {
+		bt.LastError = err.Error()
+	}
+	return q.w.db.StoreBroadcastTx(txid, bt)
+}
+
+// updateBroadcastTxStatus sets the status of a pending transaction to confirmed, replaced or conflicted
+func (w *Worker) updateBroadcastTxStatus(txid string, bt *db.BroadcastTx) error {
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return err
+	}
+	if ta != nil {
+		bt.Status, bt.BlockHeight = db.BroadcastTxConfirmed, ta.Height
+		return nil
+	}
+	if t, ok := w.mempool.(bchain.MempoolReplacementTracker); ok {
+		if by := t.GetReplacedBy(txid); by != "" {
+			bt.Status, bt.ReplacedBy = db.BroadcastTxReplaced, by
+			return nil
+		}
+	}
+	tx, err := w.chainParser.ParseTx(bt.Tx)
+	if err != nil {
+		return err
+	}
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if vin.Txid == "" {
+			continue
+		}
+		ta, err := w.db.GetTxAddresses(vin.Txid)
+		if err != nil {
+			return err
+		}
+		if ta != nil && int(vin.Vout) < len(ta.Outputs) && ta.Outputs[vin.Vout].Spent {
+			bt.Status = db.BroadcastTxConflicted
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	// bnbMaxTries limits the number of the steps of the branch and bound search, as in bitcoind
+	bnbMaxTries = 100000
+	// knapsackIterations is the number of the random subsets tried by the knapsack selection, as in bitcoind
+	knapsackIterations = 1000
+	// knapsackMinChange is the minimal change targeted by the knapsack selection, to avoid creating tiny change outputs
+	knapsackMinChange = 50000
+	// knapsackSeed makes the knapsack selection deterministic for the same utxos and outputs
+	knapsackSeed = 1
+
+	// txInBaseSize is the size of the outpoint and of the sequence of an input
+	txInBaseSize = 32 + 4 + 4
+	// the sizes of the pushes of a DER signature with the sighash type and of a compressed public key
+	sigPushSize    = 1 + 72
+	pubKeyPushSize = 1 + 33
+)
+
+// xpubInputWeight returns the weight of the signed input spending an output of the xpub of the given type
+// and whether the input has a witness
+func xpubInputWeight(xd *bchain.XpubDescriptor) (int64, bool) {
+	multisigScriptSize := 3 + len(xd.Keys)*pubKeyPushSize
+	multisigWitnessSize := 1 + 1 + xd.Threshold*sigPushSize + varIntSize(multisigScriptSize) + multisigScriptSize
+	var scriptSig, witness int
+	switch xd.Type {
+	case bchain.P2PK:
+		scriptSig = sigPushSize
+	case bchain.P2PKH:
+		scriptSig = sigPushSize + pubKeyPushSize
+	case bchain.P2SHWPKH:
+		scriptSig = 1 + 22
+		witness = 1 + sigPushSize + pubKeyPushSize
+	case bchain.P2WPKH:
+		witness = 1 + sigPushSize + pubKeyPushSize
+	case bchain.P2TR:
+		// key path spend with the default sighash type
+		witness = 1 + 1 + 64
+	case bchain.P2SHMULTISIG:
+		scriptSig = 1 + xd.Threshold*sigPushSize + pushSize(multisigScriptSize) + multisigScriptSize
+	case bchain.P2WSHMULTISIG:
+		witness = multisigWitnessSize
+	case bchain.P2SHWSHMULTISIG:
+		scriptSig = 1 + 34
+		witness = multisigWitnessSize
+	}
+	return int64(txInBaseSize+varIntSize(scriptSig)+scriptSig)*4 + int64(witness), witness > 0
+}
+
+// outputWeight returns the weight of an output with the script
+func outputWeight(script []byte) int64 {
+	return int64(8+varIntSize(len(script))+len(script)) * 4
+}
+
+func varIntSize(l int) int {
+	switch {
+	case l < 0xfd:
+		return 1
+	case l <= 0xffff:
+		return 3
+	}
+	return 5
+}
+
+func pushSize(l int) int {
+	switch {
+	case l < 0x4c:
+		return 1
+	case l <= 0xff:
+		return 2
+	}
+	return 3
+}
+
+// feeForWeight returns the fee for the weight at the fee rate in satoshi per kvB, rounded up
+func feeForWeight(weight int64, feePerKb int64) int64 {
+	vsize := (weight + 3) / 4
+	return (vsize*feePerKb + 999) / 1000
+}
+
+// selectBnB finds by the branch and bound search the selection with the sum of the effective values
+// between target and target+costOfChange with the minimal excess, values must be sorted in descending order,
+// it returns nil if there is no such selection
+func selectBnB(values []int64, target int64, costOfChange int64) []int {
+	var available int64
+	for _, v := range values {
+		available += v
+	}
+	if available < target {
+		return nil
+	}
+	var best []int
+	bestExcess := int64(-1)
+	// included[i] is the decision about values[i] on the current branch
+	included := make([]bool, 0, len(values))
+	var value int64
+	for tries := 0; tries < bnbMaxTries; tries++ {
+		backtrack := false
+		if value+available < target || value > target+costOfChange {
+			backtrack = true
+		} else if value >= target {
+			if excess := value - target; bestExcess < 0 || excess < bestExcess {
+				bestExcess = excess
+				best = best[:0]
+				for i, in := range included {
+					if in {
+						best = append(best, i)
+					}
+				}
+				if excess == 0 {
+					break
+				}
+			}
+			backtrack = true
+		} else if len(included) == len(values) {
+			backtrack = true
+		}
+		if backtrack {
+			// return to the last included value and try the branch without it
+			for len(included) > 0 && !included[len(included)-1] {
+				available += values[len(included)-1]
+				included = included[:len(included)-1]
+			}
+			if len(included) == 0 {
+				break
+			}
+			included[len(included)-1] = false
+			value -= values[len(included)-1]
+		} else {
+			available -= values[len(included)]
+			value += values[len(included)]
+			included = append(included, true)
+		}
+	}
+	return best
+}
+
+// selectKnapsack selects the values as the knapsack solver of bitcoind, it prefers the selections
+// with the sum equal to target or exceeding target at least by minChange, values must be sorted in descending order,
+// it returns nil if the values are not sufficient
+func selectKnapsack(values []int64, target int64, minChange int64) []int {
+	lowestLarger := -1
+	var lower []int
+	var totalLower int64
+	for i, v := range values {
+		if v == target {
+			return []int{i}
+		}
+		if v < target+minChange {
+			lower = append(lower, i)
+			totalLower += v
+		} else if lowestLarger < 0 || v < values[lowestLarger] {
+			lowestLarger = i
+		}
+	}
+	if totalLower == target {
+		return lower
+	}
+	if totalLower < target {
+		if lowestLarger < 0 {
+			return nil
+		}
+		return []int{lowestLarger}
+	}
+	rnd := rand.New(rand.NewSource(knapsackSeed))
+	best, bestValue := approximateBestSubset(values, lower, totalLower, target, rnd)
+	if bestValue != target && totalLower >= target+minChange {
+		best, bestValue = approximateBestSubset(values, lower, totalLower, target+minChange, rnd)
+	}
+	if lowestLarger >= 0 && ((bestValue != target && bestValue < target+minChange) || values[lowestLarger] <= bestValue) {
+		return []int{lowestLarger}
+	}
+	return best
+}
+
+// approximateBestSubset searches randomly for the subset of the values with the smallest sum reaching target
+func approximateBestSubset(values []int64, lower []int, totalLower int64, target int64, rnd *rand.Rand) ([]int, int64) {
+	bestIncluded := make([]bool, len(lower))
+	for i := range bestIncluded {
+		bestIncluded[i] = true
+	}
+	bestValue := totalLower
+	included := make([]bool, len(lower))
+	for rep := 0; rep < knapsackIterations && bestValue != target; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+		var total int64
+		reached := false
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for i, li := range lower {
+				// the first pass includes the values randomly, the second one all the values not included by the first pass
+				if (pass == 0 && rnd.Intn(2) == 1) || (pass == 1 && !included[i]) {
+					total += values[li]
+					included[i] = true
+					if total >= target {
+						reached = true
+						if total < bestValue {
+							bestValue = total
+							copy(bestIncluded, included)
+						}
+						total -= values[li]
+						included[i] = false
+					}
+				}
+			}
+		}
+	}
+	var best []int
+	for i, in := range bestIncluded {
+		if in {
+			best = append(best, lower[i])
+		}
+	}
+	return best, bestValue
+}
+
+// SelectCoins selects the confirmed utxos of the xpub to fund the outputs at the fee rate, the change is sent
+// to the first unused change address of the xpub. The selected utxos are locked for the client with the address clientAddr if LockTTL is set.
+func (w *Worker) SelectCoins(xpub string, req *CoinSelectionReq, gap int, clientAddr string) (*CoinSelection, error) {
+	start := time.Now()
+	if len(req.Outputs) == 0 {
+		return nil, NewAPIError("Missing outputs", true)
+	}
+	if req.FeePerKb <= 0 {
+		return nil, NewAPIError("Invalid fee rate", true)
+	}
+	if req.LockTTL != 0 {
+		if err := w.checkUtxoLocks(); err != nil {
+			return nil, err
+		}
+	}
+	if req.LockToken != "" {
+		if err := checkUtxoLockToken(req.LockToken); err != nil {
+			return nil, err
+		}
+	}
+	xd, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	data, _, inCache, err := w.getXpubData(xd, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout:          AddressFilterVoutOff,
+		OnlyConfirmed: true,
+	}, gap)
+	if err != nil {
+		return nil, err
+	}
+
+	inputWeight, witness := xpubInputWeight(xd)
+	inputFee := feeForWeight(inputWeight, req.FeePerKb)
+	// version, locktime and the numbers of the inputs and outputs
+	weight := int64(4+4+1+varIntSize(len(req.Outputs)+1)) * 4
+	if witness {
+		// segwit marker and flag
+		weight += 2
+	}
+	var outSat big.Int
+	outputs := make([]CoinSelectionOutput, 0, len(req.Outputs)+1)
+	for i := range req.Outputs {
+		o := &req.Outputs[i]
+		addrDesc, err := w.chainParser.GetAddrDescFromAddress(o.Address)
+		if err != nil {
+			return nil, NewAPIError(fmt.Sprintf("Invalid output address %v, %v", o.Address, err), true)
+		}
+		script, err := w.chainParser.GetScriptFromAddrDesc(addrDesc)
+		if err != nil {
+			return nil, NewAPIError(fmt.Sprintf("Invalid output address %v, %v", o.Address, err), true)
+		}
+		if o.AmountSat == nil || (*big.Int)(o.AmountSat).Cmp(big.NewInt(dustThreshold(script))) < 0 {
+			return nil, NewAPIError(fmt.Sprintf("Value of the output to %v is below the dust threshold %d", o.Address, dustThreshold(script)), true)
+		}
+		weight += outputWeight(script)
+		outSat.Add(&outSat, (*big.Int)(o.AmountSat))
+		outputs = append(outputs, CoinSelectionOutput{Address: o.Address, AmountSat: o.AmountSat})
+	}
+	if !outSat.IsInt64() {
+		return nil, NewAPIError("Value of the outputs is too large", true)
+	}
+
+	change, changeIndex, index, err := w.firstUnusedChangeAddress(xd, data)
+	if err != nil {
+		return nil, err
+	}
+	changeScript, err := w.chainParser.GetScriptFromAddrDesc(change.addrDesc)
+	if err != nil {
+		return nil, err
+	}
+	changeOutputFee := feeForWeight(outputWeight(changeScript), req.FeePerKb)
+
+	all, err := w.getXpubDataUtxo(data, true, UtxoLockFilter{Token: req.LockToken, ExcludeLocked: true})
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]struct{}, len(req.Exclude))
+	for _, o := range req.Exclude {
+		excluded[o] = struct{}{}
+	}
+	utxos := make(Utxos, 0, len(all))
+	for i := range all {
+		u := &all[i]
+		if u.Coinbase && u.Confirmations < w.chainParser.MinimumCoinbaseConfirmations() {
+			continue
+		}
+		if _, found := excluded[u.Txid+":"+strconv.Itoa(int(u.Vout))]; found {
+			continue
+		}
+		// the outputs of the xpub have the same type of the script as the change output
+		if req.ExcludeDust && (*big.Int)(u.AmountSat).Cmp(big.NewInt(dustThreshold(changeScript))) < 0 {
+			continue
+		}
+		// the utxos costing more to spend than their value are never selected
+		if (*big.Int)(u.AmountSat).Int64() <= inputFee {
+			continue
+		}
+		utxos = append(utxos, *u)
+	}
+	sort.SliceStable(utxos, func(i, j int) bool {
+		return (*big.Int)(utxos[i].AmountSat).Cmp((*big.Int)(utxos[j].AmountSat)) > 0
+	})
+	values := make([]int64, len(utxos))
+	for i := range utxos {
+		values[i] = (*big.Int)(utxos[i].AmountSat).Int64() - inputFee
+	}
+
+	target := outSat.Int64() + feeForWeight(weight, req.FeePerKb)
+	cs := &CoinSelection{Algorithm: "bnb"}
+	selected := selectBnB(values, target, changeOutputFee+inputFee)
+	if selected == nil {
+		cs.Algorithm = "knapsack"
+		selected = selectKnapsack(values, target+changeOutputFee, knapsackMinChange)
+	}
+	if selected == nil {
+		var available int64
+		for _, v := range values {
+			available += v
+		}
+		return nil, NewAPIError(fmt.Sprintf("Insufficient funds, the spendable utxos cover %d of the required %d", available, target), true)
+	}
+
+	var inSat int64
+	cs.Inputs = make(Utxos, len(selected))
+	for i, s := range selected {
+		cs.Inputs[i] = utxos[s]
+		inSat += (*big.Int)(utxos[s].AmountSat).Int64()
+	}
+	weight += int64(len(selected)) * inputWeight
+	weight += int64(varIntSize(len(selected))-1) * 4
+	fee := inSat - outSat.Int64()
+	if cs.Algorithm == "knapsack" {
+		changeSat := inSat - outSat.Int64() - feeForWeight(weight+outputWeight(changeScript), req.FeePerKb)
+		if changeSat >= dustThreshold(changeScript) {
+			weight += outputWeight(changeScript)
+			fee = inSat - outSat.Int64() - changeSat
+			t := w.tokenFromXpubAddress(data, change, int(changeIndex), index, AccountDetailsBasic)
+			outputs = append(outputs, CoinSelectionOutput{
+				Address:   t.Name,
+				AmountSat: (*Amount)(big.NewInt(changeSat)),
+				Change:    true,
+				Path:      t.Path,
+			})
+		}
+	}
+	if fee < feeForWeight(weight, req.FeePerKb) {
+		return nil, NewAPIError("Insufficient funds to pay the fee", true)
+	}
+	cs.Outputs = outputs
+	cs.FeesSat = (*Amount)(big.NewInt(fee))
+	cs.VSize = (weight + 3) / 4
+	cs.FeePerKb = fee * 1000 / cs.VSize
+	if req.LockTTL != 0 {
+		outpoints := make([]string, len(cs.Inputs))
+		for i := range cs.Inputs {
+			outpoints[i] = cs.Inputs[i].Txid + ":" + strconv.Itoa(int(cs.Inputs[i].Vout))
+		}
+		l, err := w.LockUtxos(req.LockToken, outpoints, req.LockTTL, clientAddr)
+		if err != nil {
+			return nil, err
+		}
+		// another client locked some of the utxos during the selection
+		if len(l.Conflicts) > 0 {
+			return nil, NewAPIError("The selected utxos were locked by another token, repeat the selection", true)
+		}
+		cs.LockToken = l.Token
+	}
+	glog.Info("SelectCoins ", xpub[:xpubLogPrefix], ", cache ", inCache, ", ", cs.Algorithm, " ", len(cs.Inputs), " of ", len(utxos), " utxos, ", time.Since(start))
+	return cs, nil
+}
+
+// firstUnusedChangeAddress returns the first address of the change chain of the xpub without any transaction,
+// the change chain is the chain with the index 1 or the only chain of the descriptor
+func (w *Worker) firstUnusedChangeAddress(xd *bchain.XpubDescriptor, data *xpubData) (*xpubAddress, uint32, int, error) {
+	ci := len(xd.ChangeIndexes) - 1
+	for i, change := range xd.ChangeIndexes {
+		if change == 1 {
+			ci = i
+		}
+	}
+	da := data.addresses[ci]
+	for i := range da {
+		ad := &da[i]
+		if ad.balance != nil {
+			continue
+		}
+		mtxs, err := w.mempool.GetAddrDescTransactions(ad.addrDesc)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if len(mtxs) == 0 {
+			return ad, xd.ChangeIndexes[ci], i, nil
+		}
+	}
+	return nil, 0, 0, NewAPIError("No unused change address", true)
+}
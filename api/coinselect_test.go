@@ -0,0 +1,81 @@
+//go:build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func TestSelectBnB(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       []int64
+		target       int64
+		costOfChange int64
+		want         []int
+	}{
+		{"exact single", []int64{5000, 3000, 2000, 1000}, 3000, 0, []int{1}},
+		{"exact pair", []int64{5000, 3000, 2000, 1000}, 4000, 0, []int{1, 3}},
+		{"within cost of change", []int64{5000, 3000, 2000}, 4900, 200, []int{0}},
+		{"minimal excess", []int64{5000, 3050, 2010}, 5050, 100, []int{1, 2}},
+		{"no changeless solution", []int64{5000, 3000}, 4000, 100, nil},
+		{"insufficient", []int64{1000, 500}, 2000, 1000, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectBnB(tt.values, tt.target, tt.costOfChange); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectBnB() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectKnapsack(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []int64
+		target    int64
+		minChange int64
+		want      []int
+	}{
+		{"exact match", []int64{9000, 4000, 1000}, 4000, 1000, []int{1}},
+		{"all lower values match", []int64{50000, 3000, 1000}, 4000, 1000, []int{1, 2}},
+		{"lowest larger", []int64{90000, 20000, 1000, 500}, 4000, 1000, []int{1}},
+		{"subset reaching target with change", []int64{90000, 6000, 3000, 2500, 2000}, 7000, 1000, []int{1, 4}},
+		{"insufficient", []int64{3000, 1000}, 5000, 1000, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectKnapsack(tt.values, tt.target, tt.minChange); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectKnapsack() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXpubInputWeight(t *testing.T) {
+	keys := make([]bchain.XpubDescriptorKey, 3)
+	tests := []struct {
+		name        string
+		xd          bchain.XpubDescriptor
+		wantWeight  int64
+		wantWitness bool
+	}{
+		{"P2PKH", bchain.XpubDescriptor{Type: bchain.P2PKH}, 592, false},
+		{"P2SHWPKH", bchain.XpubDescriptor{Type: bchain.P2SHWPKH}, 364, true},
+		{"P2WPKH", bchain.XpubDescriptor{Type: bchain.P2WPKH}, 272, true},
+		{"P2TR", bchain.XpubDescriptor{Type: bchain.P2TR}, 230, true},
+		{"P2WSH 2of3", bchain.XpubDescriptor{Type: bchain.P2WSHMULTISIG, Threshold: 2, Keys: keys}, 418, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weight, witness := xpubInputWeight(&tt.xd)
+			if weight != tt.wantWeight || witness != tt.wantWitness {
+				t.Errorf("xpubInputWeight() = %v, %v, want %v, %v", weight, witness, tt.wantWeight, tt.wantWitness)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// Lot matching methods of the cost basis report
+const (
+	CostBasisFIFO    = "fifo"
+	CostBasisLIFO    = "lifo"
+	CostBasisAverage = "average"
+)
+
+const defaultCostBasisGroupBy = 86400
+
+// costBasisLot is an acquired amount not yet disposed, with the fiat price of one unit at the time of the acquisition
+type costBasisLot struct {
+	amount big.Int
+	rate   float64
+}
+
+// costBasisAccumulator matches the disposals of one asset to the acquired lots in the order of the transactions
+type costBasisAccumulator struct {
+	asset    *CostBasisAsset
+	method   string
+	lots     []costBasisLot
+	fromUnix uint32
+	toUnix   uint32
+	groupBy  uint32
+}
+
+func (a *costBasisAccumulator) inRange(blockTime int64) bool {
+	return uint32(blockTime) >= a.fromUnix && uint32(blockTime) < a.toUnix
+}
+
+func (a *costBasisAccumulator) period(blockTime int64) *CostBasisPeriod {
+	t := uint32(blockTime) - uint32(blockTime)%a.groupBy
+	if n := len(a.asset.Periods); n > 0 && a.asset.Periods[n-1].Time == t {
+		return &a.asset.Periods[n-1]
+	}
+	a.asset.Periods = append(a.asset.Periods, CostBasisPeriod{
+		Time:        t,
+		AcquiredSat: &Amount{},
+		DisposedSat: &Amount{},
+	})
+	return &a.asset.Periods[len(a.asset.Periods)-1]
+}
+
+func (a *costBasisAccumulator) acquire(txid string, blockTime int64, amount *big.Int, rate float64) {
+	value := rate * amountToFloat((*Amount)(amount), a.asset.Decimals)
+	if a.method == CostBasisAverage && len(a.lots) > 0 {
+		lot := &a.lots[0]
+		held := amountToFloat((*Amount)(&lot.amount), a.asset.Decimals)
+		lot.amount.Add(&lot.amount, amount)
+		if total := amountToFloat((*Amount)(&lot.amount), a.asset.Decimals); total > 0 {
+			lot.rate = (lot.rate*held + value) / total
+		}
+	} else {
+		lot := costBasisLot{rate: rate}
+		lot.amount.Set(amount)
+		a.lots = append(a.lots, lot)
+	}
+	if !a.inRange(blockTime) {
+		return
+	}
+	a.asset.Transactions = append(a.asset.Transactions, CostBasisTx{
+		Txid:        txid,
+		BlockTime:   blockTime,
+		AcquiredSat: (*Amount)(new(big.Int).Set(amount)),
+		Rate:        rate,
+		Value:       value,
+	})
+	p := a.period(blockTime)
+	p.Txs++
+	(*big.Int)(p.AcquiredSat).Add((*big.Int)(p.AcquiredSat), amount)
+	p.Cost += value
+}
+
+func (a *costBasisAccumulator) dispose(txid string, blockTime int64, amount *big.Int, rate float64) {
+	var remaining, take big.Int
+	var costBasis float64
+	remaining.Set(amount)
+	for remaining.Sign() > 0 && len(a.lots) > 0 {
+		i := 0
+		if a.method == CostBasisLIFO {
+			i = len(a.lots) - 1
+		}
+		lot := &a.lots[i]
+		if lot.amount.Cmp(&remaining) <= 0 {
+			take.Set(&lot.amount)
+		} else {
+			take.Set(&remaining)
+		}
+		costBasis += lot.rate * amountToFloat((*Amount)(&take), a.asset.Decimals)
+		lot.amount.Sub(&lot.amount, &take)
+		remaining.Sub(&remaining, &take)
+		if lot.amount.Sign() == 0 {
+			a.lots = append(a.lots[:i], a.lots[i+1:]...)
+		}
+	}
+	// the amount not covered by the lots (e.g. acquired outside of the tracked history) has zero cost basis
+	proceeds := rate * amountToFloat((*Amount)(amount), a.asset.Decimals)
+	gain := proceeds - costBasis
+	if !a.inRange(blockTime) {
+		return
+	}
+	a.asset.Transactions = append(a.asset.Transactions, CostBasisTx{
+		Txid:         txid,
+		BlockTime:    blockTime,
+		DisposedSat:  (*Amount)(new(big.Int).Set(amount)),
+		Rate:         rate,
+		Value:        proceeds,
+		CostBasis:    costBasis,
+		RealizedGain: gain,
+	})
+	p := a.period(blockTime)
+	p.Txs++
+	(*big.Int)(p.DisposedSat).Add((*big.Int)(p.DisposedSat), amount)
+	p.Proceeds += proceeds
+	p.CostBasis += costBasis
+	p.RealizedGain += gain
+	a.asset.RealizedGain += gain
+}
+
+// add processes the net flow of the asset in one transaction, positive is an acquisition, negative a disposal
+func (a *costBasisAccumulator) add(txid string, blockTime int64, net *big.Int, rate float64, rateFound bool) {
+	if net.Sign() == 0 {
+		return
+	}
+	if !rateFound && a.inRange(blockTime) {
+		a.asset.MissingRates++
+	}
+	if net.Sign() > 0 {
+		a.acquire(txid, blockTime, net, rate)
+	} else {
+		a.dispose(txid, blockTime, new(big.Int).Neg(net), rate)
+	}
+}
+
+// finish computes the held amount and its cost basis from the open lots and the unrealized gain at the current rate
+func (a *costBasisAccumulator) finish(currentRate float64, rateFound bool) {
+	var held big.Int
+	a.asset.CostBasis = 0
+	for i := range a.lots {
+		held.Add(&held, &a.lots[i].amount)
+		a.asset.CostBasis += a.lots[i].rate * amountToFloat((*Amount)(&a.lots[i].amount), a.asset.Decimals)
+	}
+	a.asset.HeldSat = (*Amount)(&held)
+	if rateFound {
+		a.asset.CurrentRate = currentRate
+		a.asset.CurrentValue = currentRate * amountToFloat(a.asset.HeldSat, a.asset.Decimals)
+		a.asset.UnrealizedGain = a.asset.CurrentValue - a.asset.CostBasis
+	}
+}
+
+// GetCostBasis computes the cost basis lots of the base coin and of the fungible tokens of the address or xpub
+// from the whole confirmed transaction history valued at historical rates, and reports the realized gains
+// in the given time range and the unrealized gains at the current rates
+func (w *Worker) GetCostBasis(descriptor string, fromTimestamp, toTimestamp int64, currency, method string, groupBy uint32, gap int) (*CostBasisReport, error) {
+	start := time.Now()
+	currency = strings.ToLower(currency)
+	if currency == "" {
+		return nil, NewAPIError("Missing parameter 'currency'", true)
+	}
+	if w.fiatRates == nil || !w.fiatRates.Enabled {
+		return nil, NewAPIError("Fiat rates are not available", true)
+	}
+	method = strings.ToLower(method)
+	if method == "" {
+		method = CostBasisFIFO
+	} else if method != CostBasisFIFO && method != CostBasisLIFO && method != CostBasisAverage {
+		return nil, NewAPIError("Unsupported cost basis method "+method, true)
+	}
+	if groupBy == 0 {
+		groupBy = defaultCostBasisGroupBy
+	}
+	fromUnix, _, toUnix, _ := w.balanceHistoryHeightsFromTo(fromTimestamp, toTimestamp)
+	report := &CostBasisReport{
+		Descriptor: descriptor,
+		Currency:   currency,
+		Method:     method,
+		GroupBy:    groupBy,
+	}
+	newAccumulator := func(asset *CostBasisAsset) *costBasisAccumulator {
+		return &costBasisAccumulator{
+			asset:    asset,
+			method:   method,
+			fromUnix: fromUnix,
+			toUnix:   toUnix,
+			groupBy:  groupBy,
+		}
+	}
+	decimals := w.chainParser.AmountDecimals()
+	coin := newAccumulator(&CostBasisAsset{Decimals: decimals})
+	tokens := make(map[string]*costBasisAccumulator)
+	tokenOrder := make([]string, 0)
+	var net big.Int
+	type tokenFlow struct {
+		net       big.Int
+		gross     big.Int
+		value     float64
+		rateFound bool
+	}
+	rows := 0
+	// the lots must be built from the beginning of the history, the time range limits only the reported gains
+	err := w.exportTransactions(descriptor, 0, 0, currency, gap, true, func(r *ExportRow) error {
+		rows++
+		net.Sub((*big.Int)(r.ReceivedSat), (*big.Int)(r.SentSat))
+		if w.chainType == bchain.ChainEthereumType {
+			// in ethereum type coins the fee is not part of the sent amount
+			net.Sub(&net, (*big.Int)(r.FeeSat))
+		}
+		coin.add(r.Txid, r.BlockTime, &net, r.Rate, r.Rate != 0)
+		if len(r.TokenTransfers) == 0 {
+			return nil
+		}
+		flows := make(map[string]*tokenFlow)
+		flowOrder := make([]string, 0, len(r.TokenTransfers))
+		for j := range r.TokenTransfers {
+			t := &r.TokenTransfers[j]
+			if t.Value == nil || t.Standard != erc4626EvmFungibleStandard() {
+				continue
+			}
+			acc, found := tokens[t.Contract]
+			if !found {
+				acc = newAccumulator(&CostBasisAsset{Contract: t.Contract, Symbol: t.Symbol, Decimals: t.Decimals})
+				tokens[t.Contract] = acc
+				tokenOrder = append(tokenOrder, t.Contract)
+			}
+			f, found := flows[t.Contract]
+			if !found {
+				f = &tokenFlow{rateFound: true}
+				flows[t.Contract] = f
+				flowOrder = append(flowOrder, t.Contract)
+			}
+			f.gross.Add(&f.gross, (*big.Int)(t.Value))
+			if t.Received {
+				f.net.Add(&f.net, (*big.Int)(t.Value))
+			} else {
+				f.net.Sub(&f.net, (*big.Int)(t.Value))
+			}
+			if t.FiatValue == 0 && (*big.Int)(t.Value).Sign() != 0 {
+				f.rateFound = false
+			}
+			f.value += t.FiatValue
+		}
+		for _, contract := range flowOrder {
+			f := flows[contract]
+			acc := tokens[contract]
+			var rate float64
+			if f.rateFound {
+				// all transfers of the contract in the transaction are valued at the same price
+				if g := amountToFloat((*Amount)(&f.gross), acc.asset.Decimals); g > 0 {
+					rate = f.value / g
+				}
+			}
+			acc.add(r.Txid, r.BlockTime, &f.net, rate, f.rateFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ticker := w.fiatRates.GetCurrentTicker(currency, "")
+	var currentRate float64
+	var currentRateFound bool
+	if ticker != nil {
+		var rate float32
+		rate, currentRateFound = ticker.Rates[currency]
+		currentRate = float64(rate)
+	}
+	coin.finish(currentRate, currentRateFound)
+	report.Assets = append(report.Assets, *coin.asset)
+	for _, contract := range tokenOrder {
+		acc := tokens[contract]
+		var tokenRate float64
+		tokenRateFound := false
+		if currentRateFound {
+			var baseRate float64
+			if baseRate, tokenRateFound = w.GetContractBaseRate(ticker, contract, 0); tokenRateFound {
+				tokenRate = baseRate * currentRate
+			}
+		}
+		acc.finish(tokenRate, tokenRateFound)
+		report.Assets = append(report.Assets, *acc.asset)
+	}
+	glog.Info("GetCostBasis ", descriptor[:min(len(descriptor), xpubLogPrefix)], ", ", method, ", ", rows, " txs, ", time.Since(start))
+	return report, nil
+}
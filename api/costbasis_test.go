@@ -0,0 +1,98 @@
+//go:build unittest
+
+package api
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestCostBasisAccumulator(t *testing.T) {
+	// acquire 2 @ 100, acquire 2 @ 200, dispose 3 @ 300, dispose 2 @ 50 (one unit not covered by lots)
+	steps := []struct {
+		net  int64
+		rate float64
+	}{
+		{2, 100},
+		{2, 200},
+		{-3, 300},
+		{-2, 50},
+	}
+	tests := []struct {
+		method        string
+		wantGains     []float64
+		wantCostBasis []float64
+	}{
+		{
+			method:        CostBasisFIFO,
+			wantCostBasis: []float64{400, 200},
+			wantGains:     []float64{500, -100},
+		},
+		{
+			method:        CostBasisLIFO,
+			wantCostBasis: []float64{500, 100},
+			wantGains:     []float64{400, 0},
+		},
+		{
+			method:        CostBasisAverage,
+			wantCostBasis: []float64{450, 150},
+			wantGains:     []float64{450, -50},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			a := &costBasisAccumulator{
+				asset:   &CostBasisAsset{},
+				method:  tt.method,
+				toUnix:  maxUint32,
+				groupBy: 100,
+			}
+			for i, s := range steps {
+				a.add("tx", int64(1000+i*50), big.NewInt(s.net), s.rate, true)
+			}
+			a.add("zero", 1300, big.NewInt(0), 1, true)
+			a.finish(10, true)
+			if len(a.asset.Transactions) != 4 {
+				t.Fatalf("got %d transactions, want 4", len(a.asset.Transactions))
+			}
+			for i := range tt.wantGains {
+				tx := &a.asset.Transactions[i+2]
+				if math.Abs(tx.CostBasis-tt.wantCostBasis[i]) > 1e-9 || math.Abs(tx.RealizedGain-tt.wantGains[i]) > 1e-9 {
+					t.Errorf("disposal %d: costBasis %v, gain %v, want %v, %v", i, tx.CostBasis, tx.RealizedGain, tt.wantCostBasis[i], tt.wantGains[i])
+				}
+			}
+			if a.asset.HeldSat.String() != "0" || a.asset.CostBasis != 0 || a.asset.UnrealizedGain != 0 {
+				t.Errorf("unexpected held %v, costBasis %v, unrealized %v", a.asset.HeldSat, a.asset.CostBasis, a.asset.UnrealizedGain)
+			}
+			// steps at 1000, 1050 fall to period 1000, steps at 1100, 1150 to period 1100
+			if len(a.asset.Periods) != 2 || a.asset.Periods[0].Time != 1000 || a.asset.Periods[1].Time != 1100 ||
+				a.asset.Periods[0].AcquiredSat.String() != "4" || a.asset.Periods[1].DisposedSat.String() != "5" ||
+				math.Abs(a.asset.Periods[1].RealizedGain-a.asset.RealizedGain) > 1e-9 {
+				t.Errorf("unexpected periods %+v", a.asset.Periods)
+			}
+		})
+	}
+}
+
+func TestCostBasisAccumulator_Unrealized(t *testing.T) {
+	a := &costBasisAccumulator{
+		asset:    &CostBasisAsset{},
+		method:   CostBasisFIFO,
+		fromUnix: 2000,
+		toUnix:   maxUint32,
+		groupBy:  86400,
+	}
+	// the acquisitions before the range build the lots but are not reported
+	a.add("a", 1000, big.NewInt(3), 100, true)
+	a.add("b", 1500, big.NewInt(1), 0, false)
+	a.add("c", 2500, big.NewInt(-2), 150, true)
+	a.finish(400, true)
+	if a.asset.MissingRates != 0 || len(a.asset.Transactions) != 1 || a.asset.RealizedGain != 100 {
+		t.Errorf("unexpected report %+v", a.asset)
+	}
+	// one unit @ 100 and one unit with unknown (zero) price remain
+	if a.asset.HeldSat.String() != "2" || a.asset.CostBasis != 100 || a.asset.CurrentValue != 800 || a.asset.UnrealizedGain != 700 {
+		t.Errorf("unexpected held %v, costBasis %v, value %v, unrealized %v", a.asset.HeldSat, a.asset.CostBasis, a.asset.CurrentValue, a.asset.UnrealizedGain)
+	}
+}
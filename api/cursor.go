@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+// txCursor is a position in the confirmed transaction history of an address or xpub.
+// The history continues after the first index transactions in the block at height.
+// New blocks are added above the cursor, therefore the position stays valid as the chain grows.
+type txCursor struct {
+	height uint32
+	index  int
+}
+
+// startTxCursor points to the beginning (the newest transaction) of the history
+var startTxCursor = txCursor{height: maxUint32}
+
+// String encodes the cursor to the opaque form returned to the clients
+func (c txCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(c.height), 10) + "." + strconv.Itoa(c.index)))
+}
+
+func parseTxCursor(s string) (txCursor, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		if h, i, found := strings.Cut(string(b), "."); found {
+			height, errHeight := strconv.ParseUint(h, 10, 32)
+			index, errIndex := strconv.Atoi(i)
+			if errHeight == nil && errIndex == nil && index >= 0 {
+				return txCursor{height: uint32(height), index: index}, nil
+			}
+		}
+	}
+	return txCursor{}, NewAPIError("Invalid cursor", true)
+}
+
+// filterCursor returns the cursor requested by the filter, the start of the history if cursor is not set
+func filterCursor(filter *AddressFilter) (txCursor, bool, error) {
+	if filter.Cursor == "" {
+		return startTxCursor, false, nil
+	}
+	c, err := parseTxCursor(filter.Cursor)
+	return c, true, err
+}
+
+// addressFilterMatch checks if a transaction with given address indexes passes the vout filter
+func addressFilterMatch(filter *AddressFilter, indexes []int32) bool {
+	if filter.Vout == AddressFilterVoutOff {
+		return true
+	}
+	for _, index := range indexes {
+		vout := index
+		if vout < 0 {
+			vout = ^vout
+		}
+		if (filter.Vout == AddressFilterVoutInputs && index < 0) ||
+			(filter.Vout == AddressFilterVoutOutputs && index >= 0) ||
+			(vout == int32(filter.Vout)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAddressTxidsAfterCursor returns up to maxResults confirmed txids of the address following the cursor,
+// together with the cursors pointing after each of the returned txids. The index seeks directly to the height
+// of the cursor, the cost does not depend on the depth of the history. The returned bool is true if there are
+// more transactions after the returned ones.
+func (w *Worker) getAddressTxidsAfterCursor(addrDesc bchain.AddressDescriptor, filter *AddressFilter, cursor txCursor, maxResults int) ([]string, []txCursor, bool, error) {
+	txids := make([]string, 0, maxResults)
+	cursors := make([]txCursor, 0, maxResults)
+	more := false
+	to := filter.ToHeight
+	if to == 0 || to > cursor.height {
+		to = cursor.height
+	}
+	if to < filter.FromHeight || maxResults <= 0 {
+		return txids, cursors, false, nil
+	}
+	var height uint32
+	position := 0
+	err := w.db.GetAddrDescTransactions(addrDesc, filter.FromHeight, to, func(txid string, h uint32, indexes []int32) error {
+		// the position counts all transactions of the address in the block, regardless of the filter
+		if position == 0 || h != height {
+			height = h
+			position = 0
+		}
+		position++
+		if h == cursor.height && position <= cursor.index {
+			return nil
+		}
+		if !addressFilterMatch(filter, indexes) {
+			return nil
+		}
+		if len(txids) == maxResults {
+			more = true
+			return &db.StopIteration{}
+		}
+		txids = append(txids, txid)
+		cursors = append(cursors, txCursor{height: h, index: position})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return txids, cursors, more, nil
+}
+
+// xpubTxidsCursorStart returns the index in txids (sorted by height descending) at which the history following the cursor starts
+func xpubTxidsCursorStart(txids xpubTxids, cursor txCursor) int {
+	i := sort.Search(len(txids), func(i int) bool { return txids[i].height <= cursor.height })
+	for n := 0; n < cursor.index && i < len(txids) && txids[i].height == cursor.height; n++ {
+		i++
+	}
+	return i
+}
+
+// xpubTxidCursor returns the cursor pointing after the i-th of txids (sorted by height descending)
+func xpubTxidCursor(txids xpubTxids, i int) txCursor {
+	c := txCursor{height: txids[i].height, index: 1}
+	for j := i - 1; j >= 0 && txids[j].height == c.height; j-- {
+		c.index++
+	}
+	return c
+}
@@ -0,0 +1,53 @@
+//go:build unittest
+
+package api
+
+import (
+	"testing"
+)
+
+func TestTxCursor(t *testing.T) {
+	for _, c := range []txCursor{startTxCursor, {height: 0, index: 0}, {height: 123456, index: 7}} {
+		got, err := parseTxCursor(c.String())
+		if err != nil || got != c {
+			t.Errorf("parseTxCursor(%v) = %v, %v, want %v", c.String(), got, err, c)
+		}
+	}
+	for _, s := range []string{"", "!", "MTIz", "MTIzLi0x", "YS5i"} {
+		if _, err := parseTxCursor(s); err == nil {
+			t.Errorf("parseTxCursor(%q): expected error", s)
+		}
+	}
+}
+
+func TestXpubTxidsCursor(t *testing.T) {
+	txids := xpubTxids{
+		{txid: "a", height: 30},
+		{txid: "b", height: 20},
+		{txid: "c", height: 20},
+		{txid: "d", height: 20},
+		{txid: "e", height: 10},
+	}
+	tests := []struct {
+		cursor txCursor
+		want   int
+	}{
+		{startTxCursor, 0},
+		{txCursor{height: 30, index: 1}, 1},
+		{txCursor{height: 25, index: 0}, 1},
+		{txCursor{height: 20, index: 2}, 3},
+		{txCursor{height: 20, index: 5}, 4},
+		{txCursor{height: 10, index: 1}, 5},
+	}
+	for _, tt := range tests {
+		if got := xpubTxidsCursorStart(txids, tt.cursor); got != tt.want {
+			t.Errorf("xpubTxidsCursorStart(%v) = %v, want %v", tt.cursor, got, tt.want)
+		}
+	}
+	// walking the list by cursors visits every txid once
+	for i := range txids {
+		if got := xpubTxidsCursorStart(txids, xpubTxidCursor(txids, i)); got != i+1 {
+			t.Errorf("xpubTxidsCursorStart(xpubTxidCursor(%d)) = %v, want %v", i, got, i+1)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// number of transactions processed (and fiat rates looked up) at once during the export
+const exportBatchSize = 1000
+
+// ExportTokenTransfer is a token transfer of the exported account
+type ExportTokenTransfer struct {
+	Standard  bchain.TokenStandardName `json:"standard" ts_doc:"Token standard, e.g. ERC20."`
+	Contract  string                   `json:"contract" ts_doc:"Contract address of the token."`
+	Symbol    string                   `json:"symbol,omitempty" ts_doc:"Token symbol."`
+	Decimals  int                      `json:"decimals,omitempty" ts_doc:"Number of decimals of the token."`
+	Received  bool                     `json:"received" ts_doc:"True if the account received the tokens, false if it sent them."`
+	Value     *Amount                  `json:"value,omitempty" ts_doc:"Transferred amount in the token base units."`
+	FiatValue float64                  `json:"fiatValue,omitempty" ts_doc:"Value of the transfer in the requested fiat currency, if known."`
+}
+
+// ExportRow is one transaction of the exported address or xpub
+type ExportRow struct {
+	Txid           string                `json:"txid" ts_doc:"Transaction ID."`
+	BlockHeight    int                   `json:"blockHeight" ts_doc:"Height of the block containing the transaction."`
+	BlockTime      int64                 `json:"blockTime" ts_doc:"Unix timestamp of the block."`
+	ReceivedSat    *Amount               `json:"received" ts_doc:"Amount received by the account in satoshis."`
+	SentSat        *Amount               `json:"sent" ts_doc:"Amount sent by the account in satoshis, for Bitcoin-type coins including the fee."`
+	FeeSat         *Amount               `json:"fee" ts_doc:"Fee paid by the account in satoshis."`
+	TokenTransfers []ExportTokenTransfer `json:"tokenTransfers,omitempty" ts_doc:"Token transfers to or from the account."`
+	Rate           float64               `json:"rate,omitempty" ts_doc:"Fiat rate of the coin at the time of the block."`
+	ReceivedFiat   float64               `json:"receivedFiat,omitempty" ts_doc:"Fiat value of the received amount."`
+	SentFiat       float64               `json:"sentFiat,omitempty" ts_doc:"Fiat value of the sent amount."`
+	FeeFiat        float64               `json:"feeFiat,omitempty" ts_doc:"Fiat value of the fee."`
+}
+
+// ExportCSVHeader is the header line of the CSV export
+var ExportCSVHeader = []string{"txid", "blockHeight", "blockTime", "received", "sent", "fee", "rate", "receivedFiat", "sentFiat", "feeFiat", "tokenTransfers"}
+
+func formatExportFiat(v float64, hasRate bool) string {
+	if !hasRate {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// CSVRecord formats the row as a CSV record, the amounts are in coin units with given number of decimals,
+// the fiat columns are empty if the fiat rate is not known
+func (r *ExportRow) CSVRecord(decimals int) []string {
+	hasRate := r.Rate != 0
+	tokens := make([]string, len(r.TokenTransfers))
+	for i := range r.TokenTransfers {
+		t := &r.TokenTransfers[i]
+		sign := "-"
+		if t.Received {
+			sign = "+"
+		}
+		var value string
+		if t.Value != nil {
+			value = t.Value.DecimalString(t.Decimals)
+		}
+		tokens[i] = sign + value + " " + t.Symbol + " (" + t.Contract + ")"
+	}
+	return []string{
+		r.Txid,
+		strconv.Itoa(r.BlockHeight),
+		time.Unix(r.BlockTime, 0).UTC().Format(time.RFC3339),
+		r.ReceivedSat.DecimalString(decimals),
+		r.SentSat.DecimalString(decimals),
+		r.FeeSat.DecimalString(decimals),
+		formatExportFiat(r.Rate, hasRate),
+		formatExportFiat(r.ReceivedFiat, hasRate),
+		formatExportFiat(r.SentFiat, hasRate),
+		formatExportFiat(r.FeeFiat, hasRate),
+		strings.Join(tokens, ";"),
+	}
+}
+
+type exportContext struct {
+	own        map[string]struct{}
+	currency   string
+	fromUnix   uint32
+	toUnix     uint32
+	bestheight uint32
+	emit       func(*ExportRow) error
+	rows       int
+}
+
+func amountToFloat(a *Amount, decimals int) float64 {
+	if a == nil {
+		return 0
+	}
+	f, err := strconv.ParseFloat(a.DecimalString(decimals), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// exportRowFromTx computes amounts received and sent by the own addresses in the transaction
+func (w *Worker) exportRowFromTx(tx *Tx, own map[string]struct{}) *ExportRow {
+	var received, sent, fee big.Int
+	if w.chainType == bchain.ChainEthereumType {
+		fromOwn := len(tx.Vin) > 0 && isOwnAddresses(own, tx.Vin[0].Addresses)
+		success := tx.EthereumSpecific == nil || tx.EthereumSpecific.Status == bchain.TxStatusOK || tx.EthereumSpecific.Status == bchain.TxStatusUnknown
+		if success && len(tx.Vout) > 0 && tx.Vout[0].ValueSat != nil {
+			if fromOwn {
+				sent.Add(&sent, (*big.Int)(tx.Vout[0].ValueSat))
+			}
+			if isOwnAddresses(own, tx.Vout[0].Addresses) {
+				received.Add(&received, (*big.Int)(tx.Vout[0].ValueSat))
+			}
+		}
+		if success && tx.EthereumSpecific != nil {
+			for i := range tx.EthereumSpecific.InternalTransfers {
+				t := &tx.EthereumSpecific.InternalTransfers[i]
+				if _, found := own[t.From]; found {
+					sent.Add(&sent, (*big.Int)(t.Value))
+				}
+				if _, found := own[t.To]; found {
+					received.Add(&received, (*big.Int)(t.Value))
+				}
+			}
+		}
+		if fromOwn && tx.FeesSat != nil {
+			fee.Set((*big.Int)(tx.FeesSat))
+		}
+	} else {
+		ownInput := false
+		for i := range tx.Vin {
+			vin := &tx.Vin[i]
+			if vin.ValueSat != nil && isOwnAddresses(own, vin.Addresses) {
+				sent.Add(&sent, (*big.Int)(vin.ValueSat))
+				ownInput = true
+			}
+		}
+		for i := range tx.Vout {
+			vout := &tx.Vout[i]
+			if vout.ValueSat != nil && isOwnAddresses(own, vout.Addresses) {
+				received.Add(&received, (*big.Int)(vout.ValueSat))
+			}
+		}
+		// the fee is already part of the sent amount, report it only if paid by the account
+		if ownInput && tx.FeesSat != nil {
+			fee.Set((*big.Int)(tx.FeesSat))
+		}
+	}
+	r := &ExportRow{
+		Txid:        tx.Txid,
+		BlockHeight: tx.Blockheight,
+		BlockTime:   tx.Blocktime,
+		ReceivedSat: (*Amount)(&received),
+		SentSat:     (*Amount)(&sent),
+		FeeSat:      (*Amount)(&fee),
+	}
+	for i := range tx.TokenTransfers {
+		t := &tx.TokenTransfers[i]
+		_, from := own[t.From]
+		_, to := own[t.To]
+		if !from && !to {
+			continue
+		}
+		et := ExportTokenTransfer{
+			Standard: t.Standard,
+			Contract: t.Contract,
+			Symbol:   t.Symbol,
+			Decimals: t.Decimals,
+			Received: to,
+			Value:    t.Value,
+		}
+		// transfer to self is reported both as sent and received
+		if from && to {
+			r.TokenTransfers = append(r.TokenTransfers, et)
+			et.Received = false
+		}
+		r.TokenTransfers = append(r.TokenTransfers, et)
+	}
+	return r
+}
+
+// setFiatRatesToExportRows sets historical fiat values to the rows using one batch lookup of tickers
+func (w *Worker) setFiatRatesToExportRows(rows []*ExportRow, currency string) {
+	if currency == "" || len(rows) == 0 || w.fiatRates == nil || !w.fiatRates.Enabled {
+		return
+	}
+	timestamps := make([]int64, len(rows))
+	for i := range rows {
+		timestamps[i] = rows[i].BlockTime
+	}
+	tickers, err := getTickersForTimestamps(w.fiatRates, timestamps, currency, "")
+	if err != nil || tickers == nil || len(*tickers) != len(rows) {
+		glog.Errorf("Error finding tickers for %d export timestamps: %v", len(timestamps), err)
+		return
+	}
+	decimals := w.chainParser.AmountDecimals()
+	for i, r := range rows {
+		ticker := (*tickers)[i]
+		if ticker == nil {
+			continue
+		}
+		rate, found := ticker.Rates[currency]
+		if !found {
+			continue
+		}
+		r.Rate = float64(rate)
+		r.ReceivedFiat = r.Rate * amountToFloat(r.ReceivedSat, decimals)
+		r.SentFiat = r.Rate * amountToFloat(r.SentSat, decimals)
+		r.FeeFiat = r.Rate * amountToFloat(r.FeeSat, decimals)
+		for j := range r.TokenTransfers {
+			t := &r.TokenTransfers[j]
+			if t.Value == nil || t.Standard != erc4626EvmFungibleStandard() {
+				continue
+			}
+			if baseRate, found := w.GetContractBaseRate(ticker, t.Contract, r.BlockTime); found {
+				t.FiatValue = r.Rate * baseRate * amountToFloat(t.Value, t.Decimals)
+			}
+		}
+	}
+}
+
+// exportTxids loads the transactions, computes the rows and passes them to the emit function
+func (w *Worker) exportTxids(txids []xpubTxid, ctx *exportContext) error {
+	option := AccountDetailsTxHistoryLight
+	if w.chainType == bchain.ChainEthereumType {
+		option = AccountDetailsTxHistory
+	}
+	rows := make([]*ExportRow, 0, len(txids))
+	for i := range txids {
+		tx, err := w.txFromTxid(txids[i].txid, ctx.bestheight, option, nil, nil)
+		if err != nil {
+			return err
+		}
+		if uint32(tx.Blocktime) < ctx.fromUnix || uint32(tx.Blocktime) >= ctx.toUnix {
+			continue
+		}
+		rows = append(rows, w.exportRowFromTx(tx, ctx.own))
+	}
+	w.setFiatRatesToExportRows(rows, ctx.currency)
+	for _, r := range rows {
+		if err := ctx.emit(r); err != nil {
+			return err
+		}
+		ctx.rows++
+	}
+	return nil
+}
+
+// exportAddrDesc walks the whole history of the address in batches, without keeping all txids in memory
+func (w *Worker) exportAddrDesc(addrDesc bchain.AddressDescriptor, fromHeight, toHeight uint32, ctx *exportContext) error {
+	higher := toHeight
+	for {
+		txids, complete, err := w.xpubGetAddressTxids(addrDesc, false, fromHeight, higher, exportBatchSize)
+		if err != nil {
+			return errors.Annotatef(err, "xpubGetAddressTxids %v", addrDesc)
+		}
+		if err = w.exportTxids(txids, ctx); err != nil {
+			return err
+		}
+		// xpubGetAddressTxids returns all txs of the last returned block, continue below it
+		if complete || len(txids) == 0 {
+			return nil
+		}
+		last := txids[len(txids)-1].height
+		if last <= fromHeight {
+			return nil
+		}
+		higher = last - 1
+	}
+}
+
+// sortTxidsOldestFirst orders the txids from the oldest, keeping the order of the index for the transactions in the same block.
+// The index stores the transactions of a block in the block order, in the bitcoin type chains first the transactions
+// with outputs of the address and then the transactions only spending from it, the merged txids of xpub addresses are ordered the same way.
+func (w *Worker) sortTxidsOldestFirst(txids xpubTxids) {
+	bitcoinType := w.chainType == bchain.ChainBitcoinType
+	sort.SliceStable(txids, func(i, j int) bool {
+		if txids[i].height != txids[j].height {
+			return txids[i].height < txids[j].height
+		}
+		return bitcoinType && txids[i].inputOutput&txOutput != 0 && txids[j].inputOutput&txOutput == 0
+	})
+}
+
+// ExportTransactions passes the confirmed transaction history of the address or xpub, from the newest to the oldest
+// transaction, as rows with amounts and historical fiat values to the emit function.
+// Errors returned before the first call of emit are caused by invalid parameters.
+func (w *Worker) ExportTransactions(descriptor string, fromTimestamp, toTimestamp int64, currency string, gap int, emit func(*ExportRow) error) error {
+	return w.exportTransactions(descriptor, fromTimestamp, toTimestamp, currency, gap, false, emit)
+}
+
+// exportTransactions passes the rows from the newest or, if oldestFirst is set, from the oldest transaction.
+// The oldest first order requires the list of all txids of the account, the newest first order of an address is streamed from the index.
+func (w *Worker) exportTransactions(descriptor string, fromTimestamp, toTimestamp int64, currency string, gap int, oldestFirst bool, emit func(*ExportRow) error) error {
+	start := time.Now()
+	fromUnix, fromHeight, toUnix, toHeight := w.balanceHistoryHeightsFromTo(fromTimestamp, toTimestamp)
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return errors.Annotatef(err, "GetBestBlock")
+	}
+	ctx := &exportContext{
+		own:        make(map[string]struct{}),
+		currency:   strings.ToLower(currency),
+		fromUnix:   fromUnix,
+		toUnix:     toUnix,
+		bestheight: bestheight,
+		emit:       emit,
+	}
+	if fromHeight >= toHeight {
+		return nil
+	}
+	var xd *bchain.XpubDescriptor
+	if w.chainType == bchain.ChainBitcoinType {
+		xd, _ = w.chainParser.ParseXpub(descriptor)
+	}
+	if xd != nil {
+		data, _, _, err := w.getXpubData(xd, 0, 1, AccountDetailsTxidHistory, &AddressFilter{
+			Vout:          AddressFilterVoutOff,
+			OnlyConfirmed: true,
+			FromHeight:    fromHeight,
+			ToHeight:      toHeight,
+		}, gap)
+		if err != nil {
+			return err
+		}
+		// the txids of the xpub are already held in the xpub cache, merge them to one ordered list
+		txcMap := make(map[string]struct{})
+		txc := make(xpubTxids, 0, 32)
+		for _, da := range data.addresses {
+			for i := range da {
+				ad := &da[i]
+				if ad.balance == nil {
+					continue
+				}
+				a, _, err := w.chainParser.GetAddressesFromAddrDesc(ad.addrDesc)
+				if err == nil && len(a) == 1 {
+					ctx.own[a[0]] = struct{}{}
+				}
+				for _, txid := range ad.txids {
+					if txid.height < fromHeight || txid.height > toHeight {
+						continue
+					}
+					if _, found := txcMap[txid.txid]; !found {
+						txcMap[txid.txid] = struct{}{}
+						txc = append(txc, txid)
+					}
+				}
+			}
+		}
+		if oldestFirst {
+			w.sortTxidsOldestFirst(txc)
+		} else {
+			sort.Stable(txc)
+		}
+		for i := 0; i < len(txc); i += exportBatchSize {
+			if err = w.exportTxids(txc[i:min(i+exportBatchSize, len(txc))], ctx); err != nil {
+				return err
+			}
+		}
+		glog.Info("ExportTransactions ", descriptor[:xpubLogPrefix], ", blocks ", fromHeight, "-", toHeight, ", ", ctx.rows, " txs, ", time.Since(start))
+		return nil
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(descriptor)
+	if err != nil {
+		return err
+	}
+	ctx.own[address] = struct{}{}
+	if oldestFirst {
+		txids, _, err := w.xpubGetAddressTxids(addrDesc, false, fromHeight, toHeight, maxInt)
+		if err != nil {
+			return errors.Annotatef(err, "xpubGetAddressTxids %v", addrDesc)
+		}
+		w.sortTxidsOldestFirst(txids)
+		for i := 0; i < len(txids); i += exportBatchSize {
+			if err = w.exportTxids(txids[i:min(i+exportBatchSize, len(txids))], ctx); err != nil {
+				return err
+			}
+		}
+	} else if err = w.exportAddrDesc(addrDesc, fromHeight, toHeight, ctx); err != nil {
+		return err
+	}
+	glog.Info("ExportTransactions ", address, ", blocks ", fromHeight, "-", toHeight, ", ", ctx.rows, " txs, ", time.Since(start))
+	return nil
+}
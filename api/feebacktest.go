@@ -0,0 +1,285 @@
+package api
+
+import (
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+	"github.com/trezor/blockbook/common"
+)
+
+// feeBacktestTargets are the numbers of blocks for which the fee estimates are recorded
+var feeBacktestTargets = []int{1, 2, 3, 6, 12, 24}
+
+const (
+	// feeBacktestPercentile of the fee rates of the transactions of a block is considered the fee rate required for the inclusion in the block,
+	// the lowest fee rates are paid by the transactions prioritized by the miners and by the parents of the CPFP transactions
+	feeBacktestPercentile = 10
+	// feeBacktestWindow is the number of the recent evaluations of a provider and target from which the accuracy is computed
+	feeBacktestWindow = 1000
+	// feeBacktestMaxBlockAge limits the recording of the estimates to recent blocks, the estimates are not valid for the blocks connected during a catch-up sync
+	feeBacktestMaxBlockAge = 2 * time.Hour
+	feeBacktestQueueSize   = 16
+)
+
+type feeBacktestKey struct {
+	provider string
+	blocks   int
+}
+
+type feeBacktestEstimate struct {
+	height uint32
+	key    feeBacktestKey
+	fee    int64
+}
+
+// feeBacktestResult is the evaluation of an estimate, deviation is the relative difference of the estimate and the required fee rate
+type feeBacktestResult struct {
+	key       feeBacktestKey
+	hit       bool
+	deviation float64
+}
+
+// feeBacktest keeps the pending estimates and the required fee rates of the recent blocks
+type feeBacktest struct {
+	lastHeight uint32
+	estimates  []feeBacktestEstimate
+	// required fee rates of the recent blocks by height, 0 if the block does not contain any rated transaction
+	required map[uint32]int64
+	// recent deviations by provider and target, negative for the misses
+	deviations map[feeBacktestKey][]float64
+}
+
+func newFeeBacktest() *feeBacktest {
+	return &feeBacktest{
+		required:   make(map[uint32]int64),
+		deviations: make(map[feeBacktestKey][]float64),
+	}
+}
+
+// addBlock stores the required fee rate of a block and evaluates the estimates whose target ends at the block
+func (b *feeBacktest) addBlock(height uint32, required int64) []feeBacktestResult {
+	if height <= b.lastHeight {
+		// reorg, forget the disconnected blocks and the estimates made at them
+		b.estimates = slices.DeleteFunc(b.estimates, func(e feeBacktestEstimate) bool { return e.height >= height })
+		for h := range b.required {
+			if h >= height {
+				delete(b.required, h)
+			}
+		}
+	}
+	b.lastHeight = height
+	b.required[height] = required
+	var results []feeBacktestResult
+	b.estimates = slices.DeleteFunc(b.estimates, func(e feeBacktestEstimate) bool {
+		end := e.height + uint32(e.key.blocks)
+		if end > height {
+			return false
+		}
+		// the estimate is sufficient if it pays the required fee rate of at least one of the target blocks
+		lowest := int64(0)
+		for h := e.height + 1; h <= end; h++ {
+			if r := b.required[h]; r > 0 && (lowest == 0 || r < lowest) {
+				lowest = r
+			}
+		}
+		if lowest > 0 {
+			r := feeBacktestResult{
+				key:       e.key,
+				hit:       e.fee >= lowest,
+				deviation: float64(e.fee-lowest) / float64(lowest),
+			}
+			d := append(b.deviations[e.key], r.deviation)
+			if len(d) > feeBacktestWindow {
+				d = d[len(d)-feeBacktestWindow:]
+			}
+			b.deviations[e.key] = d
+			results = append(results, r)
+		}
+		return true
+	})
+	maxTarget := uint32(slices.Max(feeBacktestTargets))
+	for h := range b.required {
+		if h+maxTarget < height {
+			delete(b.required, h)
+		}
+	}
+	return results
+}
+
+// addEstimates stores the estimates made after the block at the given height
+func (b *feeBacktest) addEstimates(height uint32, estimates []bchain.FeeEstimate) {
+	for i := range estimates {
+		e := &estimates[i]
+		if e.Blocks <= 0 || e.FeePerUnit.Sign() <= 0 || !e.FeePerUnit.IsInt64() {
+			continue
+		}
+		b.estimates = append(b.estimates, feeBacktestEstimate{
+			height: height,
+			key:    feeBacktestKey{provider: e.Provider, blocks: e.Blocks},
+			fee:    e.FeePerUnit.Int64(),
+		})
+	}
+}
+
+// accuracy returns the hit rates and the average over and under payments ordered by provider and target
+func (b *feeBacktest) accuracy() []common.FeeEstimateAccuracy {
+	r := make([]common.FeeEstimateAccuracy, 0, len(b.deviations))
+	for k, d := range b.deviations {
+		a := common.FeeEstimateAccuracy{
+			Provider:  k.provider,
+			Blocks:    k.blocks,
+			Evaluated: len(d),
+		}
+		var over, under float64
+		for _, v := range d {
+			if v >= 0 {
+				a.Hits++
+				over += v
+			} else {
+				under -= v
+			}
+		}
+		a.HitRate = float64(a.Hits) / float64(a.Evaluated)
+		if a.Hits > 0 {
+			a.AvgOverpayment = over / float64(a.Hits)
+		}
+		if misses := a.Evaluated - a.Hits; misses > 0 {
+			a.AvgUnderpayment = under / float64(misses)
+		}
+		r = append(r, a)
+	}
+	slices.SortFunc(r, func(a, b common.FeeEstimateAccuracy) int {
+		if c := strings.Compare(a.Provider, b.Provider); c != 0 {
+			return c
+		}
+		return a.Blocks - b.Blocks
+	})
+	return r
+}
+
+// FeeEstimateBacktester records the fee estimates at each new block and compares them with the fee rates of the following mined blocks
+type FeeEstimateBacktester struct {
+	w         *Worker
+	provider  bchain.FeeEstimatesProvider
+	chanBlock chan *bchain.Block
+	backtest  *feeBacktest
+}
+
+// NewFeeEstimateBacktester creates the back-tester, it returns nil if the chain does not provide the fee estimates
+func NewFeeEstimateBacktester(w *Worker) *FeeEstimateBacktester {
+	p, ok := w.chain.(bchain.FeeEstimatesProvider)
+	if !ok || (w.chainType != bchain.ChainBitcoinType && w.chainType != bchain.ChainEthereumType) {
+		return nil
+	}
+	b := &FeeEstimateBacktester{
+		w:         w,
+		provider:  p,
+		chanBlock: make(chan *bchain.Block, feeBacktestQueueSize),
+		backtest:  newFeeBacktest(),
+	}
+	go b.run()
+	return b
+}
+
+// OnNewBlock queues the block for the evaluation, the evaluation reads the fees of all the transactions and is done asynchronously
+func (b *FeeEstimateBacktester) OnNewBlock(block *bchain.Block) {
+	select {
+	case b.chanBlock <- block:
+	default:
+		glog.Warning("FeeEstimateBacktester: queue full, skipping block ", block.Height)
+	}
+}
+
+func (b *FeeEstimateBacktester) run() {
+	for block := range b.chanBlock {
+		b.processBlock(block)
+	}
+}
+
+func (b *FeeEstimateBacktester) processBlock(block *bchain.Block) {
+	start := time.Now()
+	rates := b.w.blockFeeRates(block)
+	var required int64
+	if len(rates) > 0 {
+		required = rates[len(rates)*feeBacktestPercentile/100]
+	}
+	results := b.backtest.addBlock(block.Height, required)
+	for _, r := range results {
+		blocks := strconv.Itoa(r.key.blocks)
+		result := "miss"
+		if r.hit {
+			result = "hit"
+		}
+		b.w.metrics.FeeEstimateBacktests.With(common.Labels{"provider": r.key.provider, "blocks": blocks, "result": result}).Inc()
+		b.w.metrics.FeeEstimateDeviation.With(common.Labels{"provider": r.key.provider, "blocks": blocks}).Observe(r.deviation)
+	}
+	if time.Since(time.Unix(block.Time, 0)) < feeBacktestMaxBlockAge {
+		b.backtest.addEstimates(block.Height, b.provider.GetFeeEstimates(feeBacktestTargets))
+	}
+	if len(results) > 0 {
+		accuracy := b.backtest.accuracy()
+		for i := range accuracy {
+			a := &accuracy[i]
+			labels := common.Labels{"provider": a.Provider, "blocks": strconv.Itoa(a.Blocks)}
+			for _, s := range []struct {
+				stat  string
+				value float64
+			}{{"hit_rate", a.HitRate}, {"overpayment", a.AvgOverpayment}, {"underpayment", a.AvgUnderpayment}} {
+				labels["stat"] = s.stat
+				b.w.metrics.FeeEstimateAccuracy.With(labels).Set(s.value)
+			}
+		}
+		b.w.is.SetFeeEstimateAccuracy(accuracy)
+	}
+	glog.V(1).Info("FeeEstimateBacktester block ", block.Height, ", required fee rate ", required, ", evaluated ", len(results), " estimates, ", time.Since(start))
+}
+
+// blockFeeRates returns the sorted fee rates of the transactions of the block,
+// fees per kB for the bitcoin type chains and gas prices for the ethereum type chains
+func (w *Worker) blockFeeRates(block *bchain.Block) []int64 {
+	rates := make([]int64, 0, len(block.Txs))
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		if w.chainType == bchain.ChainBitcoinType {
+			// skip the coinbase transaction
+			if i == 0 {
+				continue
+			}
+			vsize := tx.VSize
+			if vsize == 0 {
+				vsize = int64(len(tx.Hex) / 2)
+			}
+			if vsize == 0 {
+				continue
+			}
+			ta, err := w.db.GetTxAddresses(tx.Txid)
+			if err != nil || ta == nil {
+				continue
+			}
+			var fee big.Int
+			for j := range ta.Inputs {
+				fee.Add(&fee, &ta.Inputs[j].ValueSat)
+			}
+			for j := range ta.Outputs {
+				fee.Sub(&fee, &ta.Outputs[j].ValueSat)
+			}
+			if fee.Sign() < 0 || !fee.IsInt64() {
+				continue
+			}
+			rates = append(rates, fee.Int64()*1000/vsize)
+		} else if w.chainType == bchain.ChainEthereumType {
+			etd := eth.GetEthereumTxDataFromSpecificData(tx.CoinSpecificData)
+			if etd.GasPrice != nil && etd.GasPrice.IsInt64() {
+				rates = append(rates, etd.GasPrice.Int64())
+			}
+		}
+	}
+	slices.Sort(rates)
+	return rates
+}
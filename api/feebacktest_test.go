@@ -0,0 +1,66 @@
+//go:build unittest
+
+package api
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+func feeEstimate(provider string, blocks int, fee int64) bchain.FeeEstimate {
+	return bchain.FeeEstimate{Provider: provider, Blocks: blocks, FeePerUnit: *big.NewInt(fee)}
+}
+
+func TestFeeBacktest(t *testing.T) {
+	b := newFeeBacktest()
+	if got := b.addBlock(100, 1000); len(got) != 0 {
+		t.Fatalf("addBlock(100) = %+v, want no results", got)
+	}
+	b.addEstimates(100, []bchain.FeeEstimate{
+		feeEstimate("p", 1, 1500),
+		feeEstimate("p", 2, 1200),
+		feeEstimate("q", 1, 2000),
+		feeEstimate("q", 2, 0),
+	})
+	got := b.addBlock(101, 2000)
+	want := []feeBacktestResult{
+		{key: feeBacktestKey{"p", 1}, hit: false, deviation: -0.25},
+		{key: feeBacktestKey{"q", 1}, hit: true, deviation: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("addBlock(101) = %+v, want %+v", got, want)
+	}
+	// the 2 blocks estimate is sufficient for the second block
+	got = b.addBlock(102, 1000)
+	want = []feeBacktestResult{{key: feeBacktestKey{"p", 2}, hit: true, deviation: 0.2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("addBlock(102) = %+v, want %+v", got, want)
+	}
+
+	// the estimates made at a disconnected block are dropped
+	b.addEstimates(102, []bchain.FeeEstimate{feeEstimate("p", 1, 500)})
+	if got = b.addBlock(102, 400); len(got) != 0 {
+		t.Errorf("addBlock(102) after reorg = %+v, want no results", got)
+	}
+	// a block without rated transactions does not count as a target block
+	b.addEstimates(102, []bchain.FeeEstimate{feeEstimate("p", 2, 500)})
+	b.addBlock(103, 0)
+	got = b.addBlock(104, 600)
+	want = []feeBacktestResult{{key: feeBacktestKey{"p", 2}, hit: false, deviation: float64(500-600) / 600}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("addBlock(104) = %+v, want %+v", got, want)
+	}
+
+	wantAccuracy := []common.FeeEstimateAccuracy{
+		{Provider: "p", Blocks: 1, Evaluated: 1, HitRate: 0, AvgUnderpayment: 0.25},
+		{Provider: "p", Blocks: 2, Evaluated: 2, Hits: 1, HitRate: 0.5, AvgOverpayment: 0.2, AvgUnderpayment: float64(600-500) / 600},
+		{Provider: "q", Blocks: 1, Evaluated: 1, Hits: 1, HitRate: 1},
+	}
+	if got := b.accuracy(); !reflect.DeepEqual(got, wantAccuracy) {
+		t.Errorf("accuracy() = %+v, want %+v", got, wantAccuracy)
+	}
+}
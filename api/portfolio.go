@@ -0,0 +1,453 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+const maxPortfolioAccounts = 100
+const maxPortfolioNameLength = 128
+const portfolioTokenBytes = 20
+
+// maxPortfolios is the limit of the stored portfolios, the portfolios are created by anonymous clients
+const maxPortfolios = 10000
+
+// portfolioExpiration is the period after which a portfolio that was not read or updated is removed
+const portfolioExpiration = 180 * 24 * time.Hour
+
+// portfolioAccessPeriod is the minimal period between the updates of the last access time of a portfolio
+const portfolioAccessPeriod = 24 * time.Hour
+
+// newPortfolioToken returns a random hex encoded token, it is the only credential protecting the portfolio
+func newPortfolioToken() (string, error) {
+	b := make([]byte, portfolioTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func isValidPortfolioToken(token string) bool {
+	if len(token) != 2*portfolioTokenBytes {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// portfolioLogID returns an identification of the portfolio for the logs, derived by hashing so that it does not reveal the token
+func portfolioLogID(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:4])
+}
+
+// portfolioXpub returns parsed xpub descriptor if the account descriptor is an xpub, otherwise nil
+func (w *Worker) portfolioXpub(descriptor string) *bchain.XpubDescriptor {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil
+	}
+	xd, err := w.chainParser.ParseXpub(descriptor)
+	if err != nil {
+		return nil
+	}
+	return xd
+}
+
+// normalizePortfolio validates the accounts of the portfolio and converts it to the stored form
+func (w *Worker) normalizePortfolio(p *Portfolio) (*db.Portfolio, error) {
+	if p == nil {
+		return nil, NewAPIError("Missing portfolio", true)
+	}
+	if len(p.Name) > maxPortfolioNameLength {
+		return nil, NewAPIError(fmt.Sprintf("Portfolio name is longer than %d characters", maxPortfolioNameLength), true)
+	}
+	if len(p.Accounts) == 0 {
+		return nil, NewAPIError("Portfolio has no accounts", true)
+	}
+	if len(p.Accounts) > maxPortfolioAccounts {
+		return nil, NewAPIError(fmt.Sprintf("Portfolio has more than %d accounts", maxPortfolioAccounts), true)
+	}
+	dp := &db.Portfolio{
+		Name:     p.Name,
+		Accounts: make([]db.PortfolioAccount, 0, len(p.Accounts)),
+	}
+	unique := make(map[string]struct{}, len(p.Accounts))
+	for i := range p.Accounts {
+		pa := &p.Accounts[i]
+		descriptor := strings.TrimSpace(pa.Descriptor)
+		if len(pa.Label) > maxPortfolioNameLength {
+			return nil, NewAPIError(fmt.Sprintf("Label of account %v is longer than %d characters", descriptor, maxPortfolioNameLength), true)
+		}
+		if w.portfolioXpub(descriptor) == nil {
+			_, address, err := w.getAddrDescAndNormalizeAddress(descriptor)
+			if err != nil {
+				return nil, NewAPIError(fmt.Sprintf("Invalid portfolio account %v", descriptor), true)
+			}
+			descriptor = address
+		}
+		if _, found := unique[descriptor]; found {
+			return nil, NewAPIError(fmt.Sprintf("Duplicate portfolio account %v", descriptor), true)
+		}
+		unique[descriptor] = struct{}{}
+		dp.Accounts = append(dp.Accounts, db.PortfolioAccount{Descriptor: descriptor, Label: pa.Label})
+	}
+	return dp, nil
+}
+
+func portfolioFromDB(token string, dp *db.Portfolio) *Portfolio {
+	p := &Portfolio{
+		Token:    token,
+		Name:     dp.Name,
+		Accounts: make([]PortfolioAccount, len(dp.Accounts)),
+		Created:  dp.Created,
+		Updated:  dp.Updated,
+	}
+	for i := range dp.Accounts {
+		p.Accounts[i] = PortfolioAccount{Descriptor: dp.Accounts[i].Descriptor, Label: dp.Accounts[i].Label}
+	}
+	return p
+}
+
+func (w *Worker) getStoredPortfolio(token string) (*db.Portfolio, error) {
+	if !isValidPortfolioToken(token) {
+		return nil, NewAPIError("Invalid portfolio token", true)
+	}
+	dp, err := w.db.GetPortfolio(token)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetPortfolio")
+	}
+	// the expired portfolio is removed by the next prune
+	if dp == nil || dp.LastUse() < time.Now().Add(-portfolioExpiration).Unix() {
+		return nil, NewAPIError("Portfolio not found", true)
+	}
+	return dp, nil
+}
+
+// touchPortfolio records the read of the portfolio, which postpones its expiration
+func (w *Worker) touchPortfolio(token string, dp *db.Portfolio) {
+	if time.Since(time.Unix(dp.LastUse(), 0)) < portfolioAccessPeriod {
+		return
+	}
+	dp.Accessed = time.Now().Unix()
+	if err := w.db.StorePortfolio(token, dp); err != nil {
+		glog.Error("StorePortfolio ", portfolioLogID(token), ": ", err)
+	}
+}
+
+// CreatePortfolio validates and stores a new portfolio, returns it with newly assigned token
+func (w *Worker) CreatePortfolio(p *Portfolio) (*Portfolio, error) {
+	dp, err := w.normalizePortfolio(p)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newPortfolioToken()
+	if err != nil {
+		return nil, errors.Annotatef(err, "newPortfolioToken")
+	}
+	now := time.Now()
+	dp.Created = now.Unix()
+	dp.Updated = dp.Created
+	added, err := w.db.AddPortfolio(token, dp, maxPortfolios, now.Add(-portfolioExpiration).Unix())
+	if err != nil {
+		return nil, errors.Annotatef(err, "AddPortfolio")
+	}
+	if !added {
+		glog.Warning("CreatePortfolio: the limit of ", maxPortfolios, " portfolios is reached")
+		return nil, NewAPIError("Too many portfolios, try again later", true)
+	}
+	return portfolioFromDB(token, dp), nil
+}
+
+// UpdatePortfolio replaces the name and accounts of an existing portfolio
+func (w *Worker) UpdatePortfolio(token string, p *Portfolio) (*Portfolio, error) {
+	stored, err := w.getStoredPortfolio(token)
+	if err != nil {
+		return nil, err
+	}
+	dp, err := w.normalizePortfolio(p)
+	if err != nil {
+		return nil, err
+	}
+	dp.Created = stored.Created
+	dp.Updated = time.Now().Unix()
+	if err = w.db.StorePortfolio(token, dp); err != nil {
+		return nil, errors.Annotatef(err, "StorePortfolio")
+	}
+	return portfolioFromDB(token, dp), nil
+}
+
+// GetPortfolio returns the stored definition of the portfolio
+func (w *Worker) GetPortfolio(token string) (*Portfolio, error) {
+	dp, err := w.getStoredPortfolio(token)
+	if err != nil {
+		return nil, err
+	}
+	w.touchPortfolio(token, dp)
+	return portfolioFromDB(token, dp), nil
+}
+
+// DeletePortfolio removes the portfolio, returns its last stored definition
+func (w *Worker) DeletePortfolio(token string) (*Portfolio, error) {
+	dp, err := w.getStoredPortfolio(token)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.db.DeletePortfolio(token); err != nil {
+		return nil, errors.Annotatef(err, "DeletePortfolio")
+	}
+	return portfolioFromDB(token, dp), nil
+}
+
+// mergePortfolioToken adds the token holding to the tokens merged by contract
+func mergePortfolioToken(tokens Tokens, tokenIndex map[string]int, t *Token) Tokens {
+	key := string(t.Standard) + ":" + t.Contract
+	i, found := tokenIndex[key]
+	if !found {
+		m := *t
+		if t.BalanceSat != nil {
+			var b big.Int
+			b.Set((*big.Int)(t.BalanceSat))
+			m.BalanceSat = (*Amount)(&b)
+		}
+		m.Ids = append([]Amount(nil), t.Ids...)
+		m.MultiTokenValues = append([]MultiTokenValue(nil), t.MultiTokenValues...)
+		// per account values do not make sense in the merged holding
+		m.TotalReceivedSat = nil
+		m.TotalSentSat = nil
+		m.Path = ""
+		tokenIndex[key] = len(tokens)
+		return append(tokens, m)
+	}
+	m := &tokens[i]
+	if t.BalanceSat != nil {
+		if m.BalanceSat == nil {
+			m.BalanceSat = (*Amount)(new(big.Int))
+		}
+		(*big.Int)(m.BalanceSat).Add((*big.Int)(m.BalanceSat), (*big.Int)(t.BalanceSat))
+	}
+	m.Transfers += t.Transfers
+	m.BaseValue += t.BaseValue
+	m.SecondaryValue += t.SecondaryValue
+	m.Ids = append(m.Ids, t.Ids...)
+	m.MultiTokenValues = append(m.MultiTokenValues, t.MultiTokenValues...)
+	return tokens
+}
+
+func addAmount(sum *big.Int, a *Amount) bool {
+	if a == nil {
+		return false
+	}
+	sum.Add(sum, (*big.Int)(a))
+	return true
+}
+
+// GetPortfolioBalance computes aggregated balance, token holdings and merged, deduplicated transaction history of the portfolio
+func (w *Worker) GetPortfolioBalance(token string, page int, txsOnPage int, option AccountDetails, secondaryCoin string, gap int) (*PortfolioBalance, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	dp, err := w.getStoredPortfolio(token)
+	if err != nil {
+		return nil, err
+	}
+	w.touchPortfolio(token, dp)
+	var (
+		balanceSat, totalReceived, totalSent, uBalSat big.Int
+		hasTotals                                     bool
+		txc                                           xpubTxids
+		txs                                           []*Tx
+		txids                                         []string
+		pg                                            Paging
+		tokens                                        Tokens
+	)
+	r := &PortfolioBalance{
+		Token:    token,
+		Name:     dp.Name,
+		Accounts: make([]PortfolioAccountBalance, 0, len(dp.Accounts)),
+	}
+	filter := &AddressFilter{Vout: AddressFilterVoutOff, TokensToReturn: TokensToReturnNonzeroBalance}
+	tokenIndex := make(map[string]int)
+	ownAddresses := make(map[string]struct{})
+	// all address descriptors of the portfolio, used to find mempool transactions
+	var addrDescs []bchain.AddressDescriptor
+	// confirmed txids of the individual accounts, complete is false if some list is truncated
+	complete := true
+	accountTxids := make([][]xpubTxid, 0, len(dp.Accounts))
+	maxResults := (page + 1) * txsOnPage
+	for i := range dp.Accounts {
+		pa := &dp.Accounts[i]
+		var a *Address
+		if xd := w.portfolioXpub(pa.Descriptor); xd != nil {
+			a, err = w.GetXpubAddress(pa.Descriptor, 1, 1, AccountDetailsTokens, filter, gap, secondaryCoin)
+			if err != nil {
+				return nil, err
+			}
+			for addr := range a.XPubAddresses {
+				ownAddresses[addr] = struct{}{}
+			}
+			// the xpub data is already cached by GetXpubAddress, load txids if history is requested
+			data, _, _, err := w.getXpubData(xd, page, txsOnPage, option, filter, gap)
+			if err != nil {
+				return nil, err
+			}
+			for _, da := range data.addresses {
+				for j := range da {
+					ad := &da[j]
+					addrDescs = append(addrDescs, ad.addrDesc)
+					if option >= AccountDetailsTxidHistory && len(ad.txids) > 0 {
+						accountTxids = append(accountTxids, ad.txids)
+					}
+				}
+			}
+		} else {
+			a, err = w.GetAddress(pa.Descriptor, 1, 1, AccountDetailsTokenBalances, filter, secondaryCoin)
+			if err != nil {
+				return nil, err
+			}
+			ownAddresses[a.AddrStr] = struct{}{}
+			addrDesc, _, err := w.getAddrDescAndNormalizeAddress(a.AddrStr)
+			if err != nil {
+				return nil, err
+			}
+			addrDescs = append(addrDescs, addrDesc)
+			if option >= AccountDetailsTxidHistory {
+				t, c, err := w.xpubGetAddressTxids(addrDesc, false, 0, maxUint32, maxResults)
+				if err != nil {
+					return nil, errors.Annotatef(err, "xpubGetAddressTxids %v", addrDesc)
+				}
+				accountTxids = append(accountTxids, t)
+				complete = complete && c
+			}
+		}
+		addAmount(&balanceSat, a.BalanceSat)
+		addAmount(&uBalSat, a.UnconfirmedBalanceSat)
+		if addAmount(&totalReceived, a.TotalReceivedSat) {
+			hasTotals = true
+		}
+		addAmount(&totalSent, a.TotalSentSat)
+		r.SecondaryValue += a.SecondaryValue
+		r.TokensSecondaryValue += a.TokensSecondaryValue
+		for j := range a.Tokens {
+			if a.Tokens[j].Standard != bchain.XPUBAddressStandard {
+				tokens = mergePortfolioToken(tokens, tokenIndex, &a.Tokens[j])
+			}
+		}
+		r.Accounts = append(r.Accounts, PortfolioAccountBalance{
+			Descriptor:            pa.Descriptor,
+			Label:                 pa.Label,
+			BalanceSat:            a.BalanceSat,
+			UnconfirmedBalanceSat: a.UnconfirmedBalanceSat,
+			Txs:                   a.Txs,
+			SecondaryValue:        a.SecondaryValue,
+		})
+	}
+	addresses := w.newAddressesMapForAliases()
+	// find unique mempool transactions of the portfolio, they are returned only on the first page
+	txmMap := make(map[string]*Tx)
+	mempoolEntries := make(bchain.MempoolTxidEntries, 0)
+	for _, addrDesc := range addrDescs {
+		newTxids, _, err := w.xpubGetAddressTxids(addrDesc, true, 0, 0, maxInt)
+		if err != nil {
+			return nil, err
+		}
+		for _, txid := range newTxids {
+			if _, found := txmMap[txid.txid]; found {
+				continue
+			}
+			tx, err := w.getTransaction(txid.txid, false, true, addresses)
+			// mempool transaction may fail
+			if err != nil || tx == nil {
+				glog.Warning("GetTransaction in mempool: ", err)
+				continue
+			}
+			txmMap[txid.txid] = tx
+			// skip already confirmed txs, mempool may be out of sync
+			if tx.Confirmations == 0 {
+				r.UnconfirmedTxs++
+				mempoolEntries = append(mempoolEntries, bchain.MempoolTxidEntry{Txid: txid.txid, Time: uint32(tx.Blocktime)})
+			}
+		}
+	}
+	if option >= AccountDetailsTxidHistory {
+		if page == 0 {
+			sort.Sort(mempoolEntries)
+			for _, entry := range mempoolEntries {
+				if option == AccountDetailsTxidHistory {
+					txids = append(txids, entry.Txid)
+				} else {
+					txs = append(txs, txmMap[entry.Txid])
+				}
+			}
+		}
+		// merge the histories of the accounts, the same tx can belong to more accounts
+		txcMap := make(map[string]int)
+		for _, at := range accountTxids {
+			for _, txid := range at {
+				if j, found := txcMap[txid.txid]; found {
+					txc[j].inputOutput |= txid.inputOutput
+				} else {
+					txcMap[txid.txid] = len(txc)
+					txc = append(txc, txid)
+				}
+			}
+		}
+		sort.Stable(txc)
+		bestheight, _, err := w.db.GetBestBlock()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBestBlock")
+		}
+		var from, to int
+		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+		if len(txc) >= txsOnPage && !complete {
+			pg.TotalPages = -1
+		}
+		for i := from; i < to; i++ {
+			if option == AccountDetailsTxidHistory {
+				txids = append(txids, txc[i].txid)
+			} else {
+				tx, err := w.txFromTxid(txc[i].txid, bestheight, option, nil, addresses)
+				if err != nil {
+					return nil, err
+				}
+				txs = append(txs, tx)
+			}
+		}
+		// keep the first page bounded by the page size after the mempool txs were prepended
+		if page == 0 && txsOnPage > 0 {
+			if len(txids) > txsOnPage {
+				txids = txids[:txsOnPage]
+			} else if len(txs) > txsOnPage {
+				txs = txs[:txsOnPage]
+			}
+		}
+		setIsOwnAddresses(txs, ownAddresses)
+	}
+	sort.Sort(tokens)
+	r.Paging = pg
+	r.BalanceSat = (*Amount)(&balanceSat)
+	r.UnconfirmedBalanceSat = (*Amount)(&uBalSat)
+	if hasTotals {
+		r.TotalReceivedSat = (*Amount)(&totalReceived)
+		r.TotalSentSat = (*Amount)(&totalSent)
+	}
+	r.Tokens = tokens
+	r.TotalSecondaryValue = r.SecondaryValue + r.TokensSecondaryValue
+	r.Transactions = txs
+	r.Txids = txids
+	r.AddressAliases = w.getAddressAliases(addresses)
+	glog.Info("GetPortfolioBalance ", portfolioLogID(token), ", ", len(dp.Accounts), " accounts, ", len(txc), " txs, ", time.Since(start))
+	return r, nil
+}
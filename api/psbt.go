@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// psbtHexPrefix is the magic of the serialized PSBT in hex
+const psbtHexPrefix = "70736274ff"
+
+const (
+	psbtInputConfirmed   = "confirmed"
+	psbtInputUnconfirmed = "unconfirmed"
+	psbtInputSpent       = "spent"
+	psbtInputUnknown     = "unknown"
+)
+
+// decodePsbt decodes the PSBT encoded in base64 or in hex
+func decodePsbt(data string) ([]byte, error) {
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(strings.ToLower(data), psbtHexPrefix) {
+		return hex.DecodeString(data)
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// AnalyzePsbt decodes the partially signed transaction, adds the missing utxos of its inputs from the index,
+// reports the state of the spent outputs and computes the fee and the estimated fee rate of the signed transaction
+func (w *Worker) AnalyzePsbt(data string) (*PsbtAnalysis, error) {
+	pp, ok := w.chainParser.(bchain.PsbtParser)
+	if w.chainType != bchain.ChainBitcoinType || !ok {
+		return nil, NewAPIError("PSBT is not supported", true)
+	}
+	b, err := decodePsbt(data)
+	if err != nil {
+		return nil, NewAPIError("Invalid PSBT: "+err.Error(), true)
+	}
+	p, err := pp.ParsePsbt(b)
+	if err != nil {
+		return nil, NewAPIError("Invalid PSBT: "+err.Error(), true)
+	}
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	inputs := make([]PsbtInput, len(p.Inputs))
+	utxos := make([]bchain.PsbtUtxo, len(p.Inputs))
+	for i := range p.Tx.Vin {
+		if err = w.psbtInputUtxo(&p.Tx.Vin[i], &p.Inputs[i], bestHeight, &inputs[i], &utxos[i]); err != nil {
+			return nil, err
+		}
+		inputs[i].N = i
+	}
+	if b, err = pp.UpdatePsbt(b, utxos); err != nil {
+		return nil, err
+	}
+	if p, err = pp.ParsePsbt(b); err != nil {
+		return nil, err
+	}
+	a := &PsbtAnalysis{
+		Psbt:     base64.StdEncoding.EncodeToString(b),
+		Inputs:   inputs,
+		Complete: true,
+		VSize:    p.VSize,
+	}
+	for i := range p.Inputs {
+		pi := &p.Inputs[i]
+		in := &inputs[i]
+		in.AddedWitnessUtxo = pi.HasWitnessUtxo && !in.WitnessUtxo
+		in.AddedNonWitnessUtxo = pi.HasNonWitnessUtxo && !in.NonWitnessUtxo
+		in.WitnessUtxo = pi.HasWitnessUtxo
+		in.NonWitnessUtxo = pi.HasNonWitnessUtxo
+		in.PartialSigs = pi.PartialSigs
+		in.Finalized = pi.Finalized
+		if !pi.Finalized {
+			a.Complete = false
+		}
+	}
+	if a.Tx, err = w.GetTransactionFromBchainTx(p.Tx, 0, false, false, nil); err != nil {
+		return nil, err
+	}
+	// the inputs not known to the index and the backend take the values from the utxos in the psbt
+	var valInSat big.Int
+	valInKnown := true
+	for i := range a.Tx.Vin {
+		vin := &a.Tx.Vin[i]
+		if vin.ValueSat == nil {
+			u := p.Inputs[i].Utxo
+			if u == nil {
+				valInKnown = false
+				continue
+			}
+			vin.ValueSat = (*Amount)(&u.ValueSat)
+			if vin.AddrDesc, vin.Addresses, vin.IsAddress, err = w.getAddressesFromVout(u); err != nil {
+				glog.Errorf("getAddressesFromVout error %v, vout %+v", err, u)
+			}
+		}
+		valInSat.Add(&valInSat, (*big.Int)(vin.ValueSat))
+	}
+	a.Tx.FeesSat = nil
+	if !valInKnown {
+		a.Tx.ValueInSat = nil
+		return a, nil
+	}
+	a.Tx.ValueInSat = (*Amount)(&valInSat)
+	fee := new(big.Int).Sub(&valInSat, (*big.Int)(a.Tx.ValueOutSat))
+	if fee.Sign() >= 0 {
+		a.FeesSat = (*Amount)(fee)
+		a.Tx.FeesSat = a.FeesSat
+		if a.VSize > 0 {
+			a.FeePerKb = fee.Int64() * 1000 / a.VSize
+		}
+	}
+	return a, nil
+}
+
+// psbtInputUtxo sets the state of the output spent by the input and finds its utxo data missing in the psbt
+func (w *Worker) psbtInputUtxo(vin *bchain.Vin, pi *bchain.PsbtInput, bestHeight uint32, in *PsbtInput, u *bchain.PsbtUtxo) error {
+	in.Status = psbtInputUnknown
+	in.WitnessUtxo = pi.HasWitnessUtxo
+	in.NonWitnessUtxo = pi.HasNonWitnessUtxo
+	if vin.Txid == "" {
+		return nil
+	}
+	ta, err := w.db.GetTxAddresses(vin.Txid)
+	if err != nil {
+		return errors.Annotatef(err, "GetTxAddresses %v", vin.Txid)
+	}
+	if ta != nil {
+		if int(vin.Vout) < len(ta.Outputs) {
+			out := &ta.Outputs[vin.Vout]
+			in.Status = psbtInputConfirmed
+			if out.Spent {
+				in.Status = psbtInputSpent
+				in.SpentTxid = out.SpentTxid
+			}
+			if bestHeight >= ta.Height {
+				in.Confirmations = bestHeight - ta.Height + 1
+			}
+			if !pi.HasWitnessUtxo {
+				script, err := w.chainParser.GetScriptFromAddrDesc(out.AddrDesc)
+				if err == nil {
+					u.WitnessUtxo = &bchain.Vout{
+						ValueSat:     out.ValueSat,
+						N:            vin.Vout,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(script)},
+					}
+				}
+			}
+		}
+	} else if w.mempool.GetTransactionTime(vin.Txid) != 0 {
+		in.Status = psbtInputUnconfirmed
+	}
+	if pi.HasNonWitnessUtxo && (pi.HasWitnessUtxo || u.WitnessUtxo != nil) {
+		return nil
+	}
+	prevTx, _, err := w.txCache.GetTransaction(vin.Txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return nil
+		}
+		return errors.Annotatef(err, "txCache.GetTransaction %v", vin.Txid)
+	}
+	if !pi.HasNonWitnessUtxo && prevTx.Hex != "" {
+		if u.Tx, err = hex.DecodeString(prevTx.Hex); err != nil {
+			return errors.Annotatef(err, "transaction %v", vin.Txid)
+		}
+	}
+	if !pi.HasWitnessUtxo && u.WitnessUtxo == nil && int(vin.Vout) < len(prevTx.Vout) {
+		u.WitnessUtxo = &prevTx.Vout[vin.Vout]
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+//go:build unittest
+
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodePsbt(t *testing.T) {
+	want := []byte{0x70, 0x73, 0x62, 0x74, 0xff, 0x01, 0x00}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"base64", "cHNidP8BAA==", false},
+		{"hex", "70736274ff0100", false},
+		{"hex upper case with whitespace", " 70736274FF0100\n", false},
+		{"invalid base64", "cHNidP8BAA", true},
+		{"invalid hex", "70736274ff01z0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePsbt(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodePsbt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, want) {
+				t.Errorf("decodePsbt() = %x, want %x", got, want)
+			}
+		})
+	}
+}
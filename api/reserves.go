@@ -0,0 +1,192 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+const maxReservesDescriptors = 1000
+
+// prefixes of the hashed data separate the leaves from the inner nodes of the tree
+const (
+	merkleSumLeafPrefix = 0
+	merkleSumNodePrefix = 1
+)
+
+// merkleSumNode is a node of the Merkle-sum tree, each node commits to the sum of the values of the leaves below it
+type merkleSumNode struct {
+	hash [sha256.Size]byte
+	sum  uint64
+}
+
+// merkleSumLeaf returns the leaf of an unspent output, sha256(0x00 || txid || vout || value),
+// txid is in the usual (reversed) byte order, vout is 4 bytes and value 8 bytes big endian
+func merkleSumLeaf(txid string, vout int32, value uint64) (merkleSumNode, error) {
+	t, err := hex.DecodeString(txid)
+	if err != nil {
+		return merkleSumNode{}, errors.Annotatef(err, "txid %v", txid)
+	}
+	buf := make([]byte, 0, 1+len(t)+4+8)
+	buf = append(buf, merkleSumLeafPrefix)
+	buf = append(buf, t...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(vout))
+	buf = binary.BigEndian.AppendUint64(buf, value)
+	return merkleSumNode{hash: sha256.Sum256(buf), sum: value}, nil
+}
+
+// merkleSumParent returns sha256(0x01 || left hash || left sum || right hash || right sum) with the sum of both children
+func merkleSumParent(left, right *merkleSumNode) merkleSumNode {
+	buf := make([]byte, 0, 1+2*(sha256.Size+8))
+	buf = append(buf, merkleSumNodePrefix)
+	buf = append(buf, left.hash[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, left.sum)
+	buf = append(buf, right.hash[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, right.sum)
+	return merkleSumNode{hash: sha256.Sum256(buf), sum: left.sum + right.sum}
+}
+
+// merkleSumRoot computes the root of the tree over the leaves. An odd node at the end of a level is promoted
+// to the next level unchanged, duplicating it would count its value twice. The root of no leaves is zero.
+func merkleSumRoot(leaves []merkleSumNode) merkleSumNode {
+	if len(leaves) == 0 {
+		return merkleSumNode{}
+	}
+	level := append([]merkleSumNode(nil), leaves...)
+	for len(level) > 1 {
+		next := level[:0]
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, merkleSumParent(&level[i], &level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// reservesBlockHeight returns the height of the block with given hash, the block must be in the indexed chain
+func (w *Worker) reservesBlockHeight(blockHash string) (uint32, error) {
+	if blockHash == "" {
+		return 0, NewAPIError("Missing blockHash", true)
+	}
+	bh, err := w.chain.GetBlockHeader(blockHash)
+	if err != nil {
+		if err == bchain.ErrBlockNotFound {
+			return 0, NewAPIError("Block not found", true)
+		}
+		return 0, NewAPIError("Block not found, "+err.Error(), true)
+	}
+	hash, err := w.db.GetBlockHash(bh.Height)
+	if err != nil {
+		return 0, errors.Annotatef(err, "GetBlockHash %v", bh.Height)
+	}
+	if hash != blockHash {
+		return 0, NewAPIError("Block "+blockHash+" is not in the indexed chain", true)
+	}
+	return bh.Height, nil
+}
+
+// GetReserves returns the confirmed balance and the unspent outputs of a set of addresses and xpubs at the given block
+// together with a Merkle-sum tree commitment over the unspent outputs. Outputs belonging to more descriptors are counted once.
+func (w *Worker) GetReserves(req *ReservesRequest) (*ReservesReport, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	if len(req.Descriptors) == 0 {
+		return nil, NewAPIError("Missing descriptors", true)
+	}
+	if len(req.Descriptors) > maxReservesDescriptors {
+		return nil, NewAPIError("Too many descriptors, maximum is "+strconv.Itoa(maxReservesDescriptors), true)
+	}
+	height, err := w.reservesBlockHeight(req.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	r := &ReservesReport{
+		BlockHash: req.BlockHash,
+		Height:    height,
+		Accounts:  make([]ReservesAccount, 0, len(req.Descriptors)),
+		Utxos:     make([]ReservesUtxo, 0),
+	}
+	seen := make(map[string]struct{})
+	for _, descriptor := range req.Descriptors {
+		var b *BalanceAtHeight
+		if xd, _ := w.chainParser.ParseXpub(descriptor); xd != nil {
+			b, err = w.GetXpubAtHeight(descriptor, height, req.Gap)
+		} else {
+			b, err = w.GetAddressAtHeight(descriptor, height)
+			if b != nil {
+				for i := range b.Utxos {
+					b.Utxos[i].Address = b.Address
+				}
+			}
+		}
+		if err != nil {
+			if apiErr, ok := err.(*APIError); ok && apiErr.Public {
+				return nil, NewAPIError("Descriptor "+descriptor+": "+apiErr.Text, true)
+			}
+			return nil, err
+		}
+		r.BlockTime = b.BlockTime
+		r.Accounts = append(r.Accounts, ReservesAccount{
+			Descriptor: descriptor,
+			BalanceSat: b.BalanceSat,
+			Utxos:      len(b.Utxos),
+		})
+		for i := range b.Utxos {
+			u := &b.Utxos[i]
+			outpoint := u.Txid + ":" + strconv.Itoa(int(u.Vout))
+			if _, found := seen[outpoint]; found {
+				continue
+			}
+			seen[outpoint] = struct{}{}
+			r.Utxos = append(r.Utxos, ReservesUtxo{
+				Txid:      u.Txid,
+				Vout:      u.Vout,
+				AmountSat: u.AmountSat,
+				Height:    u.Height,
+				Address:   u.Address,
+				Path:      u.Path,
+			})
+		}
+	}
+	// the leaves are ordered by the outpoint so that the tree does not depend on the order of the descriptors
+	sort.Slice(r.Utxos, func(i, j int) bool {
+		if r.Utxos[i].Txid != r.Utxos[j].Txid {
+			return r.Utxos[i].Txid < r.Utxos[j].Txid
+		}
+		return r.Utxos[i].Vout < r.Utxos[j].Vout
+	})
+	var balance big.Int
+	leaves := make([]merkleSumNode, len(r.Utxos))
+	for i := range r.Utxos {
+		u := &r.Utxos[i]
+		value := (*big.Int)(u.AmountSat)
+		if !value.IsUint64() {
+			return nil, errors.Errorf("Value %v of output %v:%v out of range", value, u.Txid, u.Vout)
+		}
+		if leaves[i], err = merkleSumLeaf(u.Txid, u.Vout, value.Uint64()); err != nil {
+			return nil, err
+		}
+		u.LeafHash = hex.EncodeToString(leaves[i].hash[:])
+		balance.Add(&balance, value)
+	}
+	root := merkleSumRoot(leaves)
+	r.BalanceSat = (*Amount)(&balance)
+	r.MerkleRoot = hex.EncodeToString(root.hash[:])
+	r.MerkleSumSat = (*Amount)(new(big.Int).SetUint64(root.sum))
+	glog.Info("GetReserves ", len(req.Descriptors), " descriptors, height ", height, ", ", len(r.Utxos), " utxos, ", time.Since(start))
+	return r, nil
+}
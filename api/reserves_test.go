@@ -0,0 +1,54 @@
+//go:build unittest
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMerkleSumRoot(t *testing.T) {
+	txids := []string{
+		"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840",
+		"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71",
+		"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25",
+	}
+	values := []uint64{100, 200, 300}
+	leaves := make([]merkleSumNode, len(txids))
+	for i := range txids {
+		var err error
+		if leaves[i], err = merkleSumLeaf(txids[i], int32(i), values[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// leaf = sha256(0x00 || txid || vout || value)
+	b, _ := hex.DecodeString(txids[0])
+	b = append([]byte{0}, b...)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = binary.BigEndian.AppendUint64(b, 100)
+	if leaves[0].hash != sha256.Sum256(b) || leaves[0].sum != 100 {
+		t.Errorf("unexpected leaf %x %v", leaves[0].hash, leaves[0].sum)
+	}
+	// the odd third leaf is promoted to the next level unchanged
+	left := merkleSumParent(&leaves[0], &leaves[1])
+	want := merkleSumParent(&left, &leaves[2])
+	got := merkleSumRoot(leaves)
+	if got != want || got.sum != 600 {
+		t.Errorf("merkleSumRoot = %x %v, want %x 600", got.hash, got.sum, want.hash)
+	}
+	// the computation must not modify the leaves
+	if leaves[0].sum != 100 || leaves[1].sum != 200 {
+		t.Errorf("leaves modified %+v", leaves)
+	}
+	if root := merkleSumRoot([]merkleSumNode{leaves[2]}); root != leaves[2] {
+		t.Errorf("root of single leaf %x, want %x", root.hash, leaves[2].hash)
+	}
+	if root := merkleSumRoot(nil); root != (merkleSumNode{}) {
+		t.Errorf("root of no leaves %x, want zero", root.hash)
+	}
+	if _, err := merkleSumLeaf("xyz", 0, 1); err == nil {
+		t.Error("expected error for invalid txid")
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+// txLifecyclePrunePeriod is the minimal period between the removals of the expired lifecycle records
+const txLifecyclePrunePeriod = 6 * time.Hour
+
+// TxLifecycleTracker records the first-seen time, the mining and the eviction of the mempool transactions
+type TxLifecycleTracker struct {
+	w         *Worker
+	retention time.Duration
+	pruneMux  sync.Mutex
+	lastPrune time.Time
+}
+
+// NewTxLifecycleTracker creates the tracker, the records older than retention are removed
+func NewTxLifecycleTracker(w *Worker, retention time.Duration) *TxLifecycleTracker {
+	return &TxLifecycleTracker{
+		w:         w,
+		retention: retention,
+		lastPrune: time.Now(),
+	}
+}
+
+// OnNewTx records the first-seen time, the fee rate and the predicted confirmation ETA of a new mempool transaction
+func (t *TxLifecycleTracker) OnNewTx(mtx *bchain.MempoolTx) {
+	// the transactions found in the mempool at the startup were seen at an unknown time
+	if t.w.is.InitialSync {
+		return
+	}
+	l, err := t.w.db.GetTxLifecycle(mtx.Txid)
+	if err != nil {
+		glog.Error("GetTxLifecycle ", mtx.Txid, ": ", err)
+		return
+	}
+	if l != nil {
+		return
+	}
+	_, height, _, _ := t.w.is.GetSyncState()
+	l = &db.TxLifecycle{
+		FirstSeen:       mtx.Blocktime,
+		FirstSeenHeight: height,
+	}
+	vsize := mtx.VSize
+	if vsize == 0 {
+		vsize = int64(len(mtx.Hex) / 2)
+	}
+	var fee big.Int
+	for i := range mtx.Vin {
+		fee.Add(&fee, &mtx.Vin[i].ValueSat)
+	}
+	for i := range mtx.Vout {
+		fee.Sub(&fee, &mtx.Vout[i].ValueSat)
+	}
+	if vsize > 0 && fee.Sign() > 0 && fee.IsInt64() {
+		l.FeePerKb = fee.Int64() * 1000 / vsize
+		_, l.ETABlocks = t.w.getConfirmationETA(&Tx{FeesSat: (*Amount)(&fee), VSize: int(vsize)})
+	}
+	if err = t.w.db.StoreTxLifecycle(mtx.Txid, l); err != nil {
+		glog.Error("StoreTxLifecycle ", mtx.Txid, ": ", err)
+	}
+}
+
+// OnTxRemoved records the reason of the removal of a transaction from the mempool
+func (t *TxLifecycleTracker) OnTxRemoved(removal *bchain.MempoolTxRemoval) {
+	l, err := t.w.db.GetTxLifecycle(removal.Txid)
+	if err != nil {
+		glog.Error("GetTxLifecycle ", removal.Txid, ": ", err)
+		return
+	}
+	if l == nil || l.BlockHeight != 0 {
+		return
+	}
+	if removal.ReplacedBy != "" {
+		l.Eviction = db.TxEvictionReplaced
+		l.ReplacedBy = removal.ReplacedBy
+	} else {
+		ta, err := t.w.db.GetTxAddresses(removal.Txid)
+		if err != nil {
+			glog.Error("GetTxAddresses ", removal.Txid, ": ", err)
+			return
+		}
+		if ta != nil {
+			// the transaction was mined, the block is normally recorded by OnNewBlock before the mempool resync
+			bi, err := t.w.db.GetBlockInfo(ta.Height)
+			if err != nil || bi == nil {
+				glog.Error("GetBlockInfo ", ta.Height, ": ", err)
+				return
+			}
+			l.BlockHeight, l.BlockTime = bi.Height, bi.Time
+		} else if t.inputSpentInBlock(removal.Inputs) {
+			l.Eviction = db.TxEvictionConflicted
+		} else {
+			l.Eviction = db.TxEvictionExpired
+		}
+	}
+	if l.Eviction != db.TxNotEvicted {
+		l.EvictionTime = time.Now().Unix()
+	}
+	if err = t.w.db.StoreTxLifecycle(removal.Txid, l); err != nil {
+		glog.Error("StoreTxLifecycle ", removal.Txid, ": ", err)
+	}
+}
+
+// inputSpentInBlock checks if any of the outpoints is spent by a confirmed transaction
+func (t *TxLifecycleTracker) inputSpentInBlock(inputs []bchain.Outpoint) bool {
+	for _, o := range inputs {
+		ta, err := t.w.db.GetTxAddresses(o.Txid)
+		if err != nil || ta == nil {
+			continue
+		}
+		if int(o.Vout) < len(ta.Outputs) && ta.Outputs[o.Vout].Spent {
+			return true
+		}
+	}
+	return false
+}
+
+// OnNewBlock records the mining of the tracked transactions and removes the expired records
+func (t *TxLifecycleTracker) OnNewBlock(block *bchain.Block) {
+	for i := range block.Txs {
+		txid := block.Txs[i].Txid
+		l, err := t.w.db.GetTxLifecycle(txid)
+		if err != nil {
+			glog.Error("GetTxLifecycle ", txid, ": ", err)
+			continue
+		}
+		if l == nil {
+			continue
+		}
+		// a transaction evicted from the mempool of the backend can still be mined
+		l.BlockHeight, l.BlockTime = block.Height, block.Time
+		l.Eviction, l.EvictionTime, l.ReplacedBy = db.TxNotEvicted, 0, ""
+		if err = t.w.db.StoreTxLifecycle(txid, l); err != nil {
+			glog.Error("StoreTxLifecycle ", txid, ": ", err)
+		}
+	}
+	if t.pruneMux.TryLock() {
+		if time.Since(t.lastPrune) < txLifecyclePrunePeriod {
+			t.pruneMux.Unlock()
+			return
+		}
+		go func() {
+			defer t.pruneMux.Unlock()
+			start := time.Now()
+			count, err := t.w.db.PruneTxLifecycles(start.Add(-t.retention).Unix())
+			if err != nil {
+				glog.Error("PruneTxLifecycles: ", err)
+				return
+			}
+			t.lastPrune = start
+			glog.Info("PruneTxLifecycles removed ", count, " records, ", time.Since(start))
+		}()
+	}
+}
+
+func txLifecycleEviction(e db.TxEviction) string {
+	switch e {
+	case db.TxEvictionReplaced:
+		return "replaced"
+	case db.TxEvictionExpired:
+		return "expired"
+	case db.TxEvictionConflicted:
+		return "conflicted"
+	}
+	return ""
+}
+
+func (w *Worker) getTxLifecycle(txid string) *TxLifecycle {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil
+	}
+	l, err := w.db.GetTxLifecycle(txid)
+	if err != nil {
+		glog.Error("GetTxLifecycle ", txid, ": ", err)
+		return nil
+	}
+	if l == nil {
+		return nil
+	}
+	r := &TxLifecycle{
+		FirstSeen:          l.FirstSeen,
+		FirstSeenHeight:    l.FirstSeenHeight,
+		FeePerKb:           l.FeePerKb,
+		PredictedETABlocks: l.ETABlocks,
+		BlockHeight:        l.BlockHeight,
+		BlockTime:          l.BlockTime,
+		Eviction:           txLifecycleEviction(l.Eviction),
+		EvictionTime:       l.EvictionTime,
+		ReplacedBy:         l.ReplacedBy,
+	}
+	if l.BlockHeight > 0 {
+		r.ConfirmationSeconds = l.BlockTime - l.FirstSeen
+		if r.ConfirmationSeconds < 0 {
+			r.ConfirmationSeconds = 0
+		}
+		if l.BlockHeight > l.FirstSeenHeight {
+			r.ConfirmationBlocks = l.BlockHeight - l.FirstSeenHeight
+		}
+	}
+	return r
+}
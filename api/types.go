@@ -315,6 +315,9 @@ type Tx struct {
 	FeesSat                *Amount           `json:"fees,omitempty" ts_doc:"Transaction fee (inputs - outputs)."`
 	Hex                    string            `json:"hex,omitempty" ts_doc:"Raw hex-encoded transaction data."`
 	Rbf                    bool              `json:"rbf,omitempty" ts_doc:"Indicates if this transaction is replace-by-fee (RBF) enabled."`
+	Replaces               []string          `json:"replaces,omitempty" ts_doc:"Txids of the transactions replaced by this unconfirmed transaction (RBF)."`
+	EffectiveFeePerKb      int64             `json:"effectiveFeePerKb,omitempty" ts_doc:"Fee rate of this unconfirmed transaction including its unconfirmed ancestors and descendants (CPFP) in satoshi per kvB."`
+	Lifecycle              *TxLifecycle      `json:"lifecycle,omitempty" ts_doc:"Recorded mempool history of the transaction, if it was seen in the mempool."`
 	CoinSpecificData       json.RawMessage   `json:"coinSpecificData,omitempty" ts_type:"any" ts_doc:"Blockchain-specific extended data."`
 	ChainExtraData         *TxChainExtraData `json:"chainExtraData,omitempty" ts_type:"{ payloadType: 'tron'; payload?: TronChainExtraData } | { payloadType: string; payload?: any }" ts_doc:"Additional normalized chain-specific transaction data. Use payloadType as discriminator for payload."`
 	TokenTransfers         []TokenTransfer   `json:"tokenTransfers,omitempty" ts_doc:"List of token transfers that occurred in this transaction."`
@@ -322,19 +325,126 @@ type Tx struct {
 	AddressAliases         AddressAliasesMap `json:"addressAliases,omitempty" ts_doc:"Aliases for addresses involved in this transaction."`
 }
 
+// TxLifecycle contains the recorded mempool history of a transaction
+type TxLifecycle struct {
+	FirstSeen           int64  `json:"firstSeen" ts_doc:"Unix timestamp when the transaction was first seen in the mempool."`
+	FirstSeenHeight     uint32 `json:"firstSeenHeight" ts_doc:"Best block height when the transaction was first seen."`
+	FeePerKb            int64  `json:"feePerKb,omitempty" ts_doc:"Fee rate of the transaction in satoshi per kvB."`
+	PredictedETABlocks  uint32 `json:"predictedETABlocks,omitempty" ts_doc:"Confirmation ETA in blocks predicted when the transaction was first seen."`
+	BlockHeight         uint32 `json:"blockHeight,omitempty" ts_doc:"Height of the block in which the transaction was mined."`
+	BlockTime           int64  `json:"blockTime,omitempty" ts_doc:"Unix timestamp of the block in which the transaction was mined."`
+	ConfirmationSeconds int64  `json:"confirmationSeconds,omitempty" ts_doc:"Seconds from the first seen time to the block time."`
+	ConfirmationBlocks  uint32 `json:"confirmationBlocks,omitempty" ts_doc:"Number of blocks from the first seen height to the block of the transaction."`
+	Eviction            string `json:"eviction,omitempty" ts_type:"'replaced' | 'expired' | 'conflicted'" ts_doc:"Reason of the eviction from the mempool, if the transaction was not mined."`
+	EvictionTime        int64  `json:"evictionTime,omitempty" ts_doc:"Unix timestamp of the eviction from the mempool."`
+	ReplacedBy          string `json:"replacedBy,omitempty" ts_doc:"Txid of the transaction which replaced the evicted transaction."`
+}
+
+// BroadcastTx is the status of a transaction sent through Blockbook and rebroadcast until it is confirmed
+type BroadcastTx struct {
+	Txid           string `json:"txid" ts_doc:"Transaction ID (hash)."`
+	Status         string `json:"status" ts_type:"'pending' | 'confirmed' | 'conflicted' | 'replaced' | 'expired'" ts_doc:"State of the transaction in the broadcast queue, only pending transactions are rebroadcast."`
+	FirstBroadcast int64  `json:"firstBroadcast" ts_doc:"Unix timestamp of the first broadcast."`
+	LastBroadcast  int64  `json:"lastBroadcast" ts_doc:"Unix timestamp of the last broadcast."`
+	Broadcasts     uint32 `json:"broadcasts" ts_doc:"Number of broadcasts, including the rebroadcasts."`
+	InMempool      bool   `json:"inMempool" ts_doc:"True if the transaction is currently in the mempool."`
+	BlockHeight    uint32 `json:"blockHeight,omitempty" ts_doc:"Height of the block in which the transaction was mined."`
+	ReplacedBy     string `json:"replacedBy,omitempty" ts_doc:"Txid of the transaction which replaced it in the mempool."`
+	LastError      string `json:"lastError,omitempty" ts_doc:"Error returned by the backends in the last broadcast."`
+}
+
+// TxRejection is a machine readable reason of the rejection of a transaction by the pre-flight validation
+type TxRejection struct {
+	Code    string `json:"code" ts_type:"'invalid-tx' | 'invalid-signature' | 'already-known' | 'missing-input' | 'spent-input' | 'mempool-conflict' | 'insufficient-funds' | 'fee-too-low' | 'fee-too-high' | 'dust' | 'non-final' | 'non-standard' | 'nonce-too-low' | 'nonce-too-high' | 'gas-limit-too-low' | 'execution-reverted' | 'rejected'" ts_doc:"Code of the reason of the rejection."`
+	Message string `json:"message" ts_doc:"Human readable description of the reason, for the backend rejections the reason returned by the backend."`
+	Vin     *int   `json:"vin,omitempty" ts_doc:"Index of the input the reason relates to."`
+	Vout    *int   `json:"vout,omitempty" ts_doc:"Index of the output the reason relates to."`
+}
+
+// TxValidation is the result of the pre-flight validation of a transaction
+type TxValidation struct {
+	Txid       string        `json:"txid,omitempty" ts_doc:"Transaction ID (hash), empty if the transaction cannot be decoded."`
+	Valid      bool          `json:"valid" ts_doc:"True if the transaction would be accepted by the backend."`
+	Rejections []TxRejection `json:"rejections,omitempty" ts_doc:"Reasons of the rejection of the transaction."`
+	VSize      int64         `json:"vsize,omitempty" ts_doc:"Virtual size of the transaction (Bitcoin-type coins)."`
+	FeesSat    *Amount       `json:"fees,omitempty" ts_doc:"Fee of the transaction in satoshi (Bitcoin-type coins), missing if some inputs are not known."`
+	FeePerKb   int64         `json:"feePerKb,omitempty" ts_doc:"Fee rate of the transaction in satoshi per kvB (Bitcoin-type coins)."`
+	GasUsed    uint64        `json:"gasUsed,omitempty" ts_doc:"Gas estimated for the execution of the transaction (Ethereum-type coins)."`
+}
+
+// PsbtInput describes the state of an input of an analyzed partially signed transaction
+type PsbtInput struct {
+	N                   int    `json:"n" ts_doc:"Index of the input."`
+	Status              string `json:"status" ts_type:"'confirmed' | 'unconfirmed' | 'spent' | 'unknown'" ts_doc:"State of the spent output: confirmed, in the mempool, already spent in a block or not known to the index and the mempool."`
+	Confirmations       uint32 `json:"confirmations,omitempty" ts_doc:"Number of confirmations of the spent output."`
+	SpentTxid           string `json:"spentTxId,omitempty" ts_doc:"Transaction which already spent the output, only with the extended index."`
+	WitnessUtxo         bool   `json:"witnessUtxo" ts_doc:"True if the PSBT contains the witness utxo of the input."`
+	NonWitnessUtxo      bool   `json:"nonWitnessUtxo" ts_doc:"True if the PSBT contains the non-witness utxo (the whole previous transaction) of the input."`
+	AddedWitnessUtxo    bool   `json:"addedWitnessUtxo,omitempty" ts_doc:"True if the witness utxo was added from the index."`
+	AddedNonWitnessUtxo bool   `json:"addedNonWitnessUtxo,omitempty" ts_doc:"True if the non-witness utxo was added from the index."`
+	PartialSigs         int    `json:"partialSigs,omitempty" ts_doc:"Number of partial signatures of the input."`
+	Finalized           bool   `json:"finalized,omitempty" ts_doc:"True if the input has the final script sig or witness."`
+}
+
+// PsbtAnalysis is the result of the analysis of a partially signed transaction
+type PsbtAnalysis struct {
+	Psbt     string      `json:"psbt" ts_doc:"Base64 encoded PSBT with the utxo data added from the index."`
+	Tx       *Tx         `json:"tx" ts_doc:"Decoded unsigned transaction."`
+	Inputs   []PsbtInput `json:"inputs" ts_doc:"State of the inputs of the transaction."`
+	Complete bool        `json:"complete" ts_doc:"True if all the inputs are finalized."`
+	FeesSat  *Amount     `json:"fees,omitempty" ts_doc:"Fee of the transaction in satoshi, missing if the values of some inputs are not known or the outputs exceed the inputs."`
+	VSize    int64       `json:"vsize,omitempty" ts_doc:"Estimated virtual size of the signed transaction, missing if it cannot be estimated for some input."`
+	FeePerKb int64       `json:"feePerKb,omitempty" ts_doc:"Estimated fee rate of the signed transaction in satoshi per kvB."`
+}
+
+// CoinSelectionOutput is an output of the transaction for which the coins are selected
+type CoinSelectionOutput struct {
+	Address   string  `json:"address" ts_doc:"Address of the output."`
+	AmountSat *Amount `json:"value" ts_doc:"Value of the output in satoshi."`
+	Change    bool    `json:"change,omitempty" ts_doc:"True for the change output added by the selection."`
+	Path      string  `json:"path,omitempty" ts_doc:"Derivation path of the change address."`
+}
+
+// CoinSelectionReq describes the transaction for which the coins are selected
+type CoinSelectionReq struct {
+	Outputs     []CoinSelectionOutput `json:"outputs" ts_doc:"Target outputs of the transaction."`
+	FeePerKb    int64                 `json:"feePerKb" ts_doc:"Fee rate of the transaction in satoshi per kvB."`
+	ExcludeDust bool                  `json:"excludeDust,omitempty" ts_doc:"Do not select the utxos with value below the dust threshold."`
+	Exclude     []string              `json:"exclude,omitempty" ts_doc:"Outpoints in the format txid:vout which must not be selected."`
+	LockToken   string                `json:"lockToken,omitempty" ts_doc:"Token of the UTXO locks, the UTXOs locked by other tokens are not selected."`
+	LockTTL     int64                 `json:"lockTtl,omitempty" ts_doc:"If set, the selected UTXOs are locked for the number of seconds, by lockToken or by a newly issued token."`
+}
+
+// CoinSelection is the result of the selection of the utxos of an xpub for a transaction
+type CoinSelection struct {
+	Algorithm string                `json:"algorithm" ts_type:"'bnb' | 'knapsack'" ts_doc:"Algorithm which found the selection, branch and bound finds the selections without change."`
+	Inputs    Utxos                 `json:"inputs" ts_doc:"Selected utxos."`
+	Outputs   []CoinSelectionOutput `json:"outputs" ts_doc:"Target outputs followed by the change output, if there is any."`
+	FeesSat   *Amount               `json:"fees" ts_doc:"Fee of the transaction in satoshi."`
+	VSize     int64                 `json:"vsize" ts_doc:"Estimated virtual size of the signed transaction."`
+	FeePerKb  int64                 `json:"feePerKb" ts_doc:"Resulting fee rate of the transaction in satoshi per kvB."`
+	LockToken string                `json:"lockToken,omitempty" ts_doc:"Token of the locks of the selected UTXOs, if they were locked."`
+}
+
 // FeeStats contains detailed block fee statistics
 type FeeStats struct {
-	TxCount         int       `json:"txCount" ts_doc:"Number of transactions in the given block."`
-	TotalFeesSat    *Amount   `json:"totalFeesSat" ts_doc:"Sum of all fees in satoshi or base units."`
-	AverageFeePerKb int64     `json:"averageFeePerKb" ts_doc:"Average fee per kilobyte in satoshi or base units."`
-	DecilesFeePerKb [11]int64 `json:"decilesFeePerKb" ts_doc:"Fee distribution deciles (0%..100%) in satoshi or base units per kB."`
+	TxCount                   int       `json:"txCount" ts_doc:"Number of transactions in the given block."`
+	TotalFeesSat              *Amount   `json:"totalFeesSat" ts_doc:"Sum of all fees in satoshi or base units."`
+	AverageFeePerKb           int64     `json:"averageFeePerKb" ts_doc:"Average fee per kilobyte in satoshi or base units."`
+	DecilesFeePerKb           [11]int64 `json:"decilesFeePerKb" ts_doc:"Fee distribution deciles (0%..100%) in satoshi or base units per kB."`
+	FirstSeenTxCount          int       `json:"firstSeenTxCount,omitempty" ts_doc:"Number of transactions of the block with a recorded mempool first seen time."`
+	MedianConfirmationSeconds int64     `json:"medianConfirmationSeconds,omitempty" ts_doc:"Median time from the first seen time to the block time of the transactions with a recorded first seen time."`
+	MedianConfirmationBlocks  uint32    `json:"medianConfirmationBlocks,omitempty" ts_doc:"Median number of blocks from the first seen height to the block of the transactions with a recorded first seen time."`
+	PredictedETATxCount       int       `json:"predictedETATxCount,omitempty" ts_doc:"Number of transactions of the block with a confirmation ETA predicted when they were first seen."`
+	WithinETATxCount          int       `json:"withinETATxCount,omitempty" ts_doc:"Number of transactions of the block mined within their predicted confirmation ETA."`
 }
 
 // Paging contains information about paging for address, blocks and block
 type Paging struct {
-	Page        int `json:"page,omitempty" ts_doc:"Current page index."`
-	TotalPages  int `json:"totalPages,omitempty" ts_doc:"Total number of pages available."`
-	ItemsOnPage int `json:"itemsOnPage,omitempty" ts_doc:"Number of items returned on this page."`
+	Page        int    `json:"page,omitempty" ts_doc:"Current page index."`
+	TotalPages  int    `json:"totalPages,omitempty" ts_doc:"Total number of pages available."`
+	ItemsOnPage int    `json:"itemsOnPage,omitempty" ts_doc:"Number of items returned on this page."`
+	NextCursor  string `json:"nextCursor,omitempty" ts_doc:"Opaque cursor of the following page of the confirmed history, if there is one. It is stable when new blocks arrive."`
 }
 
 // TokensToReturn specifies what tokens are returned by GetAddress and GetXpubAddress
@@ -368,6 +478,8 @@ type AddressFilter struct {
 	IncludeErc4626 bool           `ts_doc:"If true, enriches fungible EVM tokens with ERC4626 vault data when available."`
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool `ts_doc:"If true, ignores mempool (unconfirmed) transactions."`
+	// Cursor, if set, selects the page of the confirmed history following the cursor instead of the page number
+	Cursor string `ts_doc:"Opaque cursor returned as nextCursor by the previous page."`
 }
 
 // StakingPool holds data about address participation in a staking pool contract
@@ -401,6 +513,10 @@ type Address struct {
 	Transactions          []*Tx                `json:"transactions,omitempty" ts_doc:"List of transaction details (if requested)."`
 	Txids                 []string             `json:"txids,omitempty" ts_doc:"List of transaction IDs (if detailed data is not requested)."`
 	Nonce                 string               `json:"nonce,omitempty" ts_doc:"Current transaction nonce for Ethereum-like addresses."`
+	FirstSeenHeight       uint32               `json:"firstSeenHeight,omitempty" ts_doc:"Height of the first block with a transaction of this address (Ethereum-like addresses)."`
+	FirstSeenTime         int64                `json:"firstSeenTime,omitempty" ts_doc:"Time of the first block with a transaction of this address (Ethereum-like addresses)."`
+	LastSeenHeight        uint32               `json:"lastSeenHeight,omitempty" ts_doc:"Height of the last block with a transaction of this address (Ethereum-like addresses)."`
+	LastSeenTime          int64                `json:"lastSeenTime,omitempty" ts_doc:"Time of the last block with a transaction of this address (Ethereum-like addresses)."`
 	UsedTokens            int                  `json:"usedTokens,omitempty" ts_doc:"Number of tokens with any historical usage at this address."`
 	Tokens                Tokens               `json:"tokens,omitempty" ts_doc:"List of tokens associated with this address."`
 	SecondaryValue        float64              `json:"secondaryValue,omitempty" ts_doc:"Total value of the address in secondary currency (e.g. fiat)."`
@@ -419,6 +535,151 @@ type Address struct {
 	XPubAddresses map[string]struct{} `json:"-" ts_doc:"Set of derived XPUB addresses (internal usage)."`
 }
 
+// PortfolioAccount is an address, xpub/output descriptor or EVM account which is a member of a portfolio
+type PortfolioAccount struct {
+	Descriptor string `json:"descriptor" ts_doc:"Address, XPUB or output descriptor of the account."`
+	Label      string `json:"label,omitempty" ts_doc:"Optional user defined label of the account."`
+}
+
+// Portfolio is a server side stored group of watch-only accounts, identified by an opaque token
+type Portfolio struct {
+	Token    string             `json:"token,omitempty" ts_doc:"Opaque token identifying the portfolio, assigned by the server."`
+	Name     string             `json:"name,omitempty" ts_doc:"Optional name of the portfolio."`
+	Accounts []PortfolioAccount `json:"accounts" ts_doc:"Accounts grouped in the portfolio."`
+	Created  int64              `json:"created,omitempty" ts_doc:"Unix timestamp of the creation of the portfolio."`
+	Updated  int64              `json:"updated,omitempty" ts_doc:"Unix timestamp of the last update of the portfolio."`
+}
+
+// PortfolioAccountBalance is the balance summary of one account of a portfolio
+type PortfolioAccountBalance struct {
+	Descriptor            string  `json:"descriptor" ts_doc:"Address, XPUB or output descriptor of the account."`
+	Label                 string  `json:"label,omitempty" ts_doc:"Optional user defined label of the account."`
+	BalanceSat            *Amount `json:"balance" ts_doc:"Current confirmed balance of the account."`
+	UnconfirmedBalanceSat *Amount `json:"unconfirmedBalance" ts_doc:"Unconfirmed balance of the account."`
+	Txs                   int     `json:"txs" ts_doc:"Number of transactions of the account."`
+	SecondaryValue        float64 `json:"secondaryValue,omitempty" ts_doc:"Value of the account in secondary currency (e.g. fiat)."`
+}
+
+// PortfolioBalance is the aggregated balance, token holdings and merged transaction history of a portfolio
+type PortfolioBalance struct {
+	Paging
+	Token                 string                    `json:"token" ts_doc:"Opaque token identifying the portfolio."`
+	Name                  string                    `json:"name,omitempty" ts_doc:"Optional name of the portfolio."`
+	BalanceSat            *Amount                   `json:"balance" ts_doc:"Sum of confirmed balances of all accounts."`
+	TotalReceivedSat      *Amount                   `json:"totalReceived,omitempty" ts_doc:"Sum of amounts ever received by all accounts."`
+	TotalSentSat          *Amount                   `json:"totalSent,omitempty" ts_doc:"Sum of amounts ever sent by all accounts."`
+	UnconfirmedBalanceSat *Amount                   `json:"unconfirmedBalance" ts_doc:"Sum of unconfirmed balances of all accounts."`
+	UnconfirmedTxs        int                       `json:"unconfirmedTxs" ts_doc:"Number of unique unconfirmed transactions of the portfolio."`
+	Accounts              []PortfolioAccountBalance `json:"accounts" ts_doc:"Balance summary of the individual accounts."`
+	Tokens                Tokens                    `json:"tokens,omitempty" ts_doc:"Token holdings merged over all accounts."`
+	SecondaryValue        float64                   `json:"secondaryValue,omitempty" ts_doc:"Value of the coin balance in secondary currency (e.g. fiat)."`
+	TokensSecondaryValue  float64                   `json:"tokensSecondaryValue,omitempty" ts_doc:"Value of the token holdings in secondary currency."`
+	TotalSecondaryValue   float64                   `json:"totalSecondaryValue,omitempty" ts_doc:"Value of the whole portfolio in secondary currency, including tokens."`
+	Transactions          []*Tx                     `json:"transactions,omitempty" ts_doc:"Merged and deduplicated transaction history (if requested)."`
+	Txids                 []string                  `json:"txids,omitempty" ts_doc:"Merged and deduplicated transaction IDs (if detailed data is not requested)."`
+	AddressAliases        AddressAliasesMap         `json:"addressAliases,omitempty" ts_doc:"Aliases of the addresses in the returned transactions."`
+}
+
+// CostBasisTx is an acquisition or disposal of an asset by a single transaction
+type CostBasisTx struct {
+	Txid         string  `json:"txid" ts_doc:"Transaction ID."`
+	BlockTime    int64   `json:"blockTime" ts_doc:"Unix timestamp of the block containing the transaction."`
+	AcquiredSat  *Amount `json:"acquired,omitempty" ts_doc:"Net amount acquired by the transaction (in satoshi or base units)."`
+	DisposedSat  *Amount `json:"disposed,omitempty" ts_doc:"Net amount disposed by the transaction, including the fee paid by the account."`
+	Rate         float64 `json:"rate,omitempty" ts_doc:"Fiat price of one unit of the asset at the time of the block, 0 if unknown."`
+	Value        float64 `json:"value" ts_doc:"Fiat value of the acquired or disposed amount (the cost or the proceeds)."`
+	CostBasis    float64 `json:"costBasis,omitempty" ts_doc:"Cost basis of the disposed amount computed from the matched lots."`
+	RealizedGain float64 `json:"realizedGain,omitempty" ts_doc:"Realized gain (or loss if negative) of the disposal."`
+}
+
+// CostBasisPeriod is the summary of acquisitions and disposals of an asset in one period
+type CostBasisPeriod struct {
+	Time         uint32  `json:"time" ts_doc:"Unix timestamp of the beginning of the period."`
+	Txs          int     `json:"txs" ts_doc:"Number of transactions in the period."`
+	AcquiredSat  *Amount `json:"acquired" ts_doc:"Amount acquired in the period."`
+	DisposedSat  *Amount `json:"disposed" ts_doc:"Amount disposed in the period."`
+	Cost         float64 `json:"cost" ts_doc:"Fiat value of the acquisitions in the period."`
+	Proceeds     float64 `json:"proceeds" ts_doc:"Fiat value of the disposals in the period."`
+	CostBasis    float64 `json:"costBasis" ts_doc:"Cost basis of the disposals in the period."`
+	RealizedGain float64 `json:"realizedGain" ts_doc:"Realized gain (or loss if negative) in the period."`
+}
+
+// CostBasisAsset is the cost basis report of the base coin or of one token
+type CostBasisAsset struct {
+	Contract       string            `json:"contract,omitempty" ts_doc:"Contract address of the token, empty for the base coin."`
+	Symbol         string            `json:"symbol,omitempty" ts_doc:"Symbol of the token."`
+	Decimals       int               `json:"decimals" ts_doc:"Number of decimals of the amounts."`
+	HeldSat        *Amount           `json:"held" ts_doc:"Amount held in the open lots at the end of the history."`
+	CostBasis      float64           `json:"costBasis" ts_doc:"Cost basis of the held amount."`
+	CurrentRate    float64           `json:"currentRate,omitempty" ts_doc:"Current fiat price of one unit of the asset, 0 if unknown."`
+	CurrentValue   float64           `json:"currentValue,omitempty" ts_doc:"Current fiat value of the held amount."`
+	UnrealizedGain float64           `json:"unrealizedGain,omitempty" ts_doc:"Unrealized gain (or loss if negative) of the held amount at the current price."`
+	RealizedGain   float64           `json:"realizedGain" ts_doc:"Sum of realized gains in the requested time range."`
+	MissingRates   int               `json:"missingRates,omitempty" ts_doc:"Number of transactions without a known historical price, valued at zero."`
+	Periods        []CostBasisPeriod `json:"periods,omitempty" ts_doc:"Summary per period in the requested time range."`
+	Transactions   []CostBasisTx     `json:"transactions,omitempty" ts_doc:"Acquisitions and disposals in the requested time range."`
+}
+
+// CostBasisReport is the cost basis and profit and loss report of an address or xpub
+type CostBasisReport struct {
+	Descriptor string           `json:"descriptor" ts_doc:"Address or XPUB of the report."`
+	Currency   string           `json:"currency" ts_doc:"Fiat currency of the values."`
+	Method     string           `json:"method" ts_doc:"Lot matching method: fifo, lifo or average." ts_type:"'fifo' | 'lifo' | 'average'"`
+	GroupBy    uint32           `json:"groupBy" ts_doc:"Length of the reported periods in seconds."`
+	Assets     []CostBasisAsset `json:"assets" ts_doc:"Report of the base coin followed by the reports of the tokens."`
+}
+
+// BalanceAtHeight is the state of an address or xpub at the end of the block at given height
+type BalanceAtHeight struct {
+	Address          string  `json:"address" ts_doc:"The address or xpub/descriptor."`
+	Height           uint32  `json:"height" ts_doc:"Block height of the snapshot."`
+	BlockHash        string  `json:"blockHash" ts_doc:"Hash of the block at the snapshot height."`
+	BlockTime        int64   `json:"blockTime" ts_doc:"Time of the block at the snapshot height (Unix timestamp)."`
+	Txs              int     `json:"txs" ts_doc:"Number of transactions up to and including the snapshot block."`
+	BalanceSat       *Amount `json:"balance" ts_doc:"Balance at the snapshot height."`
+	TotalReceivedSat *Amount `json:"totalReceived,omitempty" ts_doc:"Total amount received up to the snapshot height (Bitcoin-type coins)."`
+	TotalSentSat     *Amount `json:"totalSent,omitempty" ts_doc:"Total amount sent up to the snapshot height (Bitcoin-type coins)."`
+	Utxos            Utxos   `json:"utxos,omitempty" ts_doc:"Unspent outputs at the snapshot height, confirmations are counted to the snapshot block (Bitcoin-type coins)."`
+	Tokens           Tokens  `json:"tokens,omitempty" ts_doc:"Token balances (Ethereum-type coins) or addresses of the xpub with their balances at the snapshot height."`
+}
+
+// ReservesRequest is the set of addresses and xpubs for which the proof of reserves is requested
+type ReservesRequest struct {
+	Descriptors []string `json:"descriptors" ts_doc:"Addresses, xpubs or output descriptors holding the reserves."`
+	BlockHash   string   `json:"blockHash" ts_doc:"Hash of the block at which the reserves are computed."`
+	Gap         int      `json:"gap,omitempty" ts_doc:"Gap limit for xpubs."`
+}
+
+// ReservesAccount is the balance of one descriptor of the reserves
+type ReservesAccount struct {
+	Descriptor string  `json:"descriptor" ts_doc:"Address, xpub or output descriptor."`
+	BalanceSat *Amount `json:"balance" ts_doc:"Confirmed balance of the descriptor at the block."`
+	Utxos      int     `json:"utxos" ts_doc:"Number of unspent outputs of the descriptor at the block."`
+}
+
+// ReservesUtxo is an unspent output of the reserves, a leaf of the Merkle-sum tree
+type ReservesUtxo struct {
+	Txid      string  `json:"txid" ts_doc:"Transaction ID in which this UTXO was created."`
+	Vout      int32   `json:"vout" ts_doc:"Index of the output in that transaction."`
+	AmountSat *Amount `json:"value" ts_doc:"Value of this UTXO."`
+	Height    int     `json:"height,omitempty" ts_doc:"Block height in which the UTXO was confirmed."`
+	Address   string  `json:"address,omitempty" ts_doc:"Address to which this UTXO belongs."`
+	Path      string  `json:"path,omitempty" ts_doc:"Derivation path for xpub addresses."`
+	LeafHash  string  `json:"leafHash" ts_doc:"Hash of the Merkle-sum tree leaf of this UTXO."`
+}
+
+// ReservesReport is the proof of reserves of a set of addresses and xpubs at a block
+type ReservesReport struct {
+	BlockHash    string            `json:"blockHash" ts_doc:"Hash of the block at which the reserves are computed."`
+	Height       uint32            `json:"height" ts_doc:"Height of the block."`
+	BlockTime    int64             `json:"blockTime" ts_doc:"Time of the block (Unix timestamp)."`
+	BalanceSat   *Amount           `json:"balance" ts_doc:"Total confirmed balance, outputs belonging to more descriptors are counted once."`
+	Accounts     []ReservesAccount `json:"accounts" ts_doc:"Balances of the individual descriptors."`
+	Utxos        []ReservesUtxo    `json:"utxos" ts_doc:"Unspent outputs ordered by txid and vout, the leaves of the Merkle-sum tree."`
+	MerkleRoot   string            `json:"merkleRoot" ts_doc:"Hash of the root of the Merkle-sum tree over the unspent outputs."`
+	MerkleSumSat *Amount           `json:"merkleSum" ts_doc:"Sum committed by the root of the Merkle-sum tree."`
+}
+
 // Utxo is one unspent transaction output
 type Utxo struct {
 	Txid          string  `json:"txid" ts_doc:"Transaction ID in which this UTXO was created."`
@@ -430,11 +691,31 @@ type Utxo struct {
 	Path          string  `json:"path,omitempty" ts_doc:"Derivation path for XPUB-based wallets, if applicable."`
 	Locktime      uint32  `json:"lockTime,omitempty" ts_doc:"If non-zero, locktime required before spending this UTXO."`
 	Coinbase      bool    `json:"coinbase,omitempty" ts_doc:"Indicates if this UTXO originated from a coinbase transaction."`
+	Locked        bool    `json:"locked,omitempty" ts_doc:"True if the UTXO is reserved by a lock of another token."`
 }
 
 // Utxos is array of Utxo
 type Utxos []Utxo
 
+// UtxoLock is the reservation of an outpoint
+type UtxoLock struct {
+	Outpoint string `json:"outpoint" ts_doc:"Locked outpoint in the format txid:vout."`
+	Expires  int64  `json:"expires" ts_doc:"Unix time when the lock expires."`
+}
+
+// UtxoLocks contains the locks of a lock token
+type UtxoLocks struct {
+	Token     string     `json:"token,omitempty" ts_doc:"Lock token issued by Blockbook, the only credential to extend, list and release the locks."`
+	Locks     []UtxoLock `json:"locks" ts_doc:"Current locks of the token."`
+	Conflicts []string   `json:"conflicts,omitempty" ts_doc:"Requested outpoints locked by another token, if there are any, none of the requested outpoints was locked."`
+}
+
+// UtxoLockReq requests the lock of the outpoints
+type UtxoLockReq struct {
+	Outpoints []string `json:"outpoints" ts_doc:"Outpoints in the format txid:vout."`
+	TTL       int64    `json:"ttl,omitempty" ts_doc:"Duration of the lock in seconds, 60 seconds by default, at most one hour."`
+}
+
 func (a Utxos) Len() int      { return len(a) }
 func (a Utxos) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a Utxos) Less(i, j int) bool {
@@ -602,6 +883,61 @@ type MempoolTxids struct {
 	MempoolSize int           `json:"mempoolSize" ts_doc:"Number of unconfirmed transactions in the mempool."`
 }
 
+// MempoolFeeHistogramBucket is a fee rate range of the mempool fee histogram
+type MempoolFeeHistogramBucket struct {
+	FeePerKb  int64   `json:"feePerKb" ts_doc:"Lower bound of the fee rate of the bucket in satoshi per kvB."`
+	TxCount   int     `json:"txCount" ts_doc:"Number of transactions in the bucket."`
+	VSize     int64   `json:"vsize" ts_doc:"Total virtual size of the transactions in the bucket."`
+	TotalFees *Amount `json:"totalFees" ts_doc:"Sum of the fees of the transactions in the bucket in satoshi."`
+}
+
+// MempoolProjectedBlock is a block simulated from the mempool transactions ordered by fee rate
+type MempoolProjectedBlock struct {
+	TxCount         int     `json:"txCount" ts_doc:"Number of transactions in the projected block."`
+	VSize           int64   `json:"vsize" ts_doc:"Virtual size of the projected block."`
+	TotalFees       *Amount `json:"totalFees" ts_doc:"Sum of the fees of the projected block in satoshi."`
+	MinFeePerKb     int64   `json:"minFeePerKb" ts_doc:"Lowest fee rate in the projected block in satoshi per kvB."`
+	MedianFeePerKb  int64   `json:"medianFeePerKb" ts_doc:"Median fee rate weighted by vsize in satoshi per kvB."`
+	MaxFeePerKb     int64   `json:"maxFeePerKb" ts_doc:"Highest fee rate in the projected block in satoshi per kvB."`
+	FeeRangePerKb   []int64 `json:"feeRangePerKb" ts_doc:"2nd, 10th, 25th, 50th, 75th, 90th and 98th percentile of the fee rate weighted by vsize in satoshi per kvB."`
+	ReachesMaxVSize bool    `json:"reachesMaxVSize" ts_doc:"True if the projected block is full, false for the last block taking the rest of the mempool."`
+}
+
+// MempoolFeeHistogram contains the fee rate histogram and the projected blocks of the mempool
+type MempoolFeeHistogram struct {
+	Time            int64                       `json:"time" ts_doc:"Unix timestamp of the computation, it is done after each mempool resync."`
+	TxCount         int                         `json:"txCount" ts_doc:"Number of the mempool transactions with known fee."`
+	VSize           int64                       `json:"vsize" ts_doc:"Total virtual size of the mempool transactions with known fee."`
+	TotalFees       *Amount                     `json:"totalFees" ts_doc:"Sum of the fees of the mempool transactions in satoshi."`
+	Histogram       []MempoolFeeHistogramBucket `json:"histogram" ts_doc:"Fee rate buckets in ascending order of their fee rate."`
+	ProjectedBlocks []MempoolProjectedBlock     `json:"projectedBlocks" ts_doc:"Next blocks simulated from the mempool, ordered by the effective fee rate of the transactions including their unconfirmed ancestors and descendants."`
+}
+
+// MempoolPackageTx is a transaction of the in-mempool package of an unconfirmed transaction
+type MempoolPackageTx struct {
+	Txid              string   `json:"txid" ts_doc:"Transaction ID (hash)."`
+	VSize             int64    `json:"vsize" ts_doc:"Virtual size of the transaction, 0 if its fee is not known."`
+	FeesSat           *Amount  `json:"fees" ts_doc:"Fee of the transaction in satoshi."`
+	EffectiveFeePerKb int64    `json:"effectiveFeePerKb,omitempty" ts_doc:"Fee rate including the unconfirmed ancestors and descendants (CPFP) in satoshi per kvB."`
+	Depends           []string `json:"depends,omitempty" ts_doc:"Txids of the unconfirmed transactions spent by the transaction."`
+	SpentBy           []string `json:"spentBy,omitempty" ts_doc:"Txids of the unconfirmed transactions spending the transaction."`
+}
+
+// MempoolPackage contains the in-mempool ancestors and descendants of an unconfirmed transaction with their aggregates
+type MempoolPackage struct {
+	Tx                 MempoolPackageTx   `json:"tx" ts_doc:"The requested transaction."`
+	Ancestors          []MempoolPackageTx `json:"ancestors" ts_doc:"Unconfirmed ancestors of the transaction, the nearest first."`
+	Descendants        []MempoolPackageTx `json:"descendants" ts_doc:"Unconfirmed descendants of the transaction, the nearest first."`
+	AncestorCount      int                `json:"ancestorCount" ts_doc:"Number of the transactions of the ancestor package, including the transaction itself."`
+	AncestorVSize      int64              `json:"ancestorVSize" ts_doc:"Virtual size of the ancestor package."`
+	AncestorFeesSat    *Amount            `json:"ancestorFees" ts_doc:"Fees of the ancestor package in satoshi."`
+	AncestorFeePerKb   int64              `json:"ancestorFeePerKb" ts_doc:"Fee rate of the ancestor package in satoshi per kvB."`
+	DescendantCount    int                `json:"descendantCount" ts_doc:"Number of the transactions of the descendant package, including the transaction itself."`
+	DescendantVSize    int64              `json:"descendantVSize" ts_doc:"Virtual size of the descendant package."`
+	DescendantFeesSat  *Amount            `json:"descendantFees" ts_doc:"Fees of the descendant package in satoshi."`
+	DescendantFeePerKb int64              `json:"descendantFeePerKb" ts_doc:"Fee rate of the descendant package in satoshi per kvB."`
+}
+
 // FiatTicker contains formatted CurrencyRatesTicker data
 type FiatTicker struct {
 	Timestamp int64              `json:"ts,omitempty" ts_doc:"Unix timestamp for these fiat rates."`
@@ -648,3 +984,42 @@ type LongTermFeeRate struct {
 	FeePerUnit string `json:"feePerUnit" ts_doc:"Long term fee rate (in sat/kByte)."`
 	Blocks     uint64 `json:"blocks" ts_doc:"Amount of blocks used for the long term fee rate estimation."`
 }
+
+// Webhook is the registration of an outbound webhook managed through the internal server
+type Webhook struct {
+	ID            string   `json:"id,omitempty" ts_doc:"Identifier of the webhook, assigned by the server."`
+	URL           string   `json:"url" ts_doc:"URL receiving the events in POST requests."`
+	Secret        string   `json:"secret,omitempty" ts_doc:"Key of the HMAC-SHA256 signature of the payloads, generated if not specified, returned only on creation."`
+	Events        []string `json:"events" ts_doc:"Delivered events - address, confirmations, reorg."`
+	Addresses     []string `json:"addresses,omitempty" ts_doc:"Watched addresses of the address and confirmations events."`
+	Confirmations []uint32 `json:"confirmations,omitempty" ts_doc:"Numbers of confirmations at which the confirmations events are delivered."`
+	Disabled      bool     `json:"disabled,omitempty" ts_doc:"If true, no events are delivered to the webhook."`
+	Created       int64    `json:"created,omitempty" ts_doc:"Unix timestamp of the creation of the webhook."`
+	Updated       int64    `json:"updated,omitempty" ts_doc:"Unix timestamp of the last update of the webhook."`
+}
+
+// WebhookPayload is the signed JSON body of a webhook delivery
+type WebhookPayload struct {
+	ID            string        `json:"id" ts_doc:"Identifier of the delivery, the same in all attempts."`
+	Webhook       string        `json:"webhook" ts_doc:"Identifier of the webhook."`
+	Event         string        `json:"event" ts_doc:"Kind of the event - address, confirmations, reorg."`
+	Created       int64         `json:"created" ts_doc:"Unix timestamp of the event."`
+	Address       string        `json:"address,omitempty" ts_doc:"Watched address involved in the transaction."`
+	Txid          string        `json:"txid,omitempty" ts_doc:"Transaction of the confirmations event."`
+	BlockHeight   uint32        `json:"blockHeight,omitempty" ts_doc:"Height of the block containing the transaction, zero for a mempool transaction."`
+	Confirmations uint32        `json:"confirmations,omitempty" ts_doc:"Reached number of confirmations of the transaction."`
+	Tx            *Tx           `json:"tx,omitempty" ts_doc:"Transaction of the address event."`
+	Reorg         *bchain.Reorg `json:"reorg,omitempty" ts_doc:"Blocks disconnected by the chain reorganization."`
+}
+
+// WebhookDeadLetter is an event which could not be delivered to a webhook
+type WebhookDeadLetter struct {
+	ID          string          `json:"id" ts_doc:"Identifier of the delivery."`
+	Webhook     string          `json:"webhook" ts_doc:"Identifier of the webhook."`
+	Event       string          `json:"event" ts_doc:"Kind of the event."`
+	Payload     json.RawMessage `json:"payload" ts_doc:"The undelivered payload."`
+	Attempts    int             `json:"attempts" ts_doc:"Number of the delivery attempts."`
+	LastError   string          `json:"lastError" ts_doc:"Error of the last delivery attempt."`
+	Created     int64           `json:"created" ts_doc:"Unix timestamp of the event."`
+	LastAttempt int64           `json:"lastAttempt" ts_doc:"Unix timestamp of the last delivery attempt."`
+}
@@ -0,0 +1,416 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	defaultUtxoLockTTL   = 60
+	maxUtxoLockTTL       = 3600
+	utxoLockTokenBytes   = 20
+	maxUtxoLockOutpoints = 1000
+	// the locks are created by anonymous clients, a client identified by its IP address can hold only a small part of all locks
+	maxUtxoLocks          = 100000
+	maxUtxoLocksPerClient = 1000
+)
+
+// utxoLock is the reservation of an outpoint by the holder of a lock token
+type utxoLock struct {
+	token   string
+	expires int64
+}
+
+// utxoLockHolder is the client which was issued the lock token and the outpoints locked by the token
+type utxoLockHolder struct {
+	client    string
+	outpoints map[string]struct{}
+}
+
+// UtxoLocker keeps the reservations of the outpoints. The locks are kept only in memory and are shared
+// by the workers of the public interfaces, they are lost when blockbook restarts, which is fine given their short TTL.
+type UtxoLocker struct {
+	mux    sync.Mutex
+	locks  map[string]utxoLock
+	tokens map[string]*utxoLockHolder
+	// number of locks held by each client
+	clients map[string]int
+}
+
+// NewUtxoLocker creates an empty store of the utxo locks
+func NewUtxoLocker() *UtxoLocker {
+	return &UtxoLocker{
+		locks:   make(map[string]utxoLock),
+		tokens:  make(map[string]*utxoLockHolder),
+		clients: make(map[string]int),
+	}
+}
+
+// utxoLockClient returns the identification of the client to which the locks are counted,
+// the IPv6 addresses are grouped by their /64 prefix, which is usually assigned to a single client
+func utxoLockClient(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	if ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(64, 128)).String()
+	}
+	return ip.String()
+}
+
+// SetUtxoLocker sets the store of the utxo locks, the worker does not support the locks without it
+func (w *Worker) SetUtxoLocker(l *UtxoLocker) {
+	w.utxoLocker = l
+}
+
+// UtxoLockFilter specifies how the locked utxos are reported to a caller,
+// the utxos locked by the token of the filter are reported as unlocked
+type UtxoLockFilter struct {
+	Token         string
+	ExcludeLocked bool
+}
+
+// filter flags the utxos locked by other tokens than the token of the filter or removes them
+func (l *UtxoLocker) filter(utxos Utxos, lf UtxoLockFilter) Utxos {
+	if l == nil || len(utxos) == 0 {
+		return utxos
+	}
+	now := time.Now().Unix()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if len(l.locks) == 0 {
+		return utxos
+	}
+	r := utxos[:0]
+	for i := range utxos {
+		u := &utxos[i]
+		lock, found := l.locks[u.Txid+":"+strconv.Itoa(int(u.Vout))]
+		if found && lock.expires > now && lock.token != lf.Token {
+			if lf.ExcludeLocked {
+				continue
+			}
+			u.Locked = true
+		}
+		r = append(r, *u)
+	}
+	return r
+}
+
+// locksOfToken returns the current locks of the token, must be called with mux locked
+func (l *UtxoLocker) locksOfToken(token string, now int64) []UtxoLock {
+	locks := make([]UtxoLock, 0)
+	h := l.tokens[token]
+	if h == nil {
+		return locks
+	}
+	for o := range h.outpoints {
+		if lock := l.locks[o]; lock.expires > now {
+			locks = append(locks, UtxoLock{Outpoint: o, Expires: lock.expires})
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Outpoint < locks[j].Outpoint })
+	return locks
+}
+
+// remove deletes the lock of the outpoint, must be called with mux locked
+func (l *UtxoLocker) remove(outpoint string) {
+	lock, found := l.locks[outpoint]
+	if !found {
+		return
+	}
+	delete(l.locks, outpoint)
+	if h := l.tokens[lock.token]; h != nil {
+		delete(h.outpoints, outpoint)
+		if len(h.outpoints) == 0 {
+			delete(l.tokens, lock.token)
+		}
+		if l.clients[h.client] <= 1 {
+			delete(l.clients, h.client)
+		} else {
+			l.clients[h.client]--
+		}
+	}
+}
+
+// prune removes the expired locks, must be called with mux locked
+func (l *UtxoLocker) prune(now int64) {
+	for o, lock := range l.locks {
+		if lock.expires <= now {
+			l.remove(o)
+		}
+	}
+}
+
+// lock reserves the normalized outpoints for the token, a new token is issued to the client if the token is empty.
+// The locks are counted to the client which was issued the token.
+// Either all outpoints are locked or, if some of them are locked by another token, none of them.
+func (l *UtxoLocker) lock(token string, outpoints []string, ttl int64, client string) (*UtxoLocks, error) {
+	now := time.Now().Unix()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.prune(now)
+	if token != "" {
+		h, found := l.tokens[token]
+		if !found {
+			return nil, NewAPIError("Unknown lock token", true)
+		}
+		client = h.client
+	}
+	r := &UtxoLocks{Token: token}
+	held := 0
+	for _, o := range outpoints {
+		if lock, found := l.locks[o]; found {
+			if lock.token != token {
+				r.Conflicts = append(r.Conflicts, o)
+			} else {
+				held++
+			}
+		}
+	}
+	if len(r.Conflicts) > 0 {
+		r.Locks = l.locksOfToken(token, now)
+		return r, nil
+	}
+	added := len(outpoints) - held
+	if l.clients[client]+added > maxUtxoLocksPerClient {
+		return nil, NewAPIError(fmt.Sprintf("More than %d UTXO locks of the client", maxUtxoLocksPerClient), true)
+	}
+	if len(l.locks)+added > maxUtxoLocks {
+		glog.Warning("The limit of ", maxUtxoLocks, " UTXO locks is reached")
+		return nil, NewAPIError("Too many UTXO locks", true)
+	}
+	if token == "" {
+		var err error
+		if token, err = randomHex(utxoLockTokenBytes); err != nil {
+			return nil, err
+		}
+		r.Token = token
+		l.tokens[token] = &utxoLockHolder{client: client, outpoints: make(map[string]struct{}, len(outpoints))}
+	}
+	h := l.tokens[token]
+	for _, o := range outpoints {
+		l.locks[o] = utxoLock{token: token, expires: now + ttl}
+		if _, found := h.outpoints[o]; !found {
+			h.outpoints[o] = struct{}{}
+			l.clients[client]++
+		}
+	}
+	r.Locks = l.locksOfToken(token, now)
+	return r, nil
+}
+
+// unlock releases the normalized outpoints locked by the token, all of them if no outpoints are specified
+func (l *UtxoLocker) unlock(token string, outpoints []string) (*UtxoLocks, error) {
+	now := time.Now().Unix()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.prune(now)
+	h, found := l.tokens[token]
+	if !found {
+		return nil, NewAPIError("Unknown lock token", true)
+	}
+	if len(outpoints) == 0 {
+		for o := range h.outpoints {
+			l.remove(o)
+		}
+	}
+	for _, o := range outpoints {
+		if _, found := h.outpoints[o]; found {
+			l.remove(o)
+		}
+	}
+	return &UtxoLocks{Token: token, Locks: l.locksOfToken(token, now)}, nil
+}
+
+// get returns the current locks of the token
+func (l *UtxoLocker) get(token string) (*UtxoLocks, error) {
+	now := time.Now().Unix()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.prune(now)
+	if _, found := l.tokens[token]; !found {
+		return nil, NewAPIError("Unknown lock token", true)
+	}
+	return &UtxoLocks{Token: token, Locks: l.locksOfToken(token, now)}, nil
+}
+
+// release removes the lock of the outpoint spent by the input, must be called with mux locked
+func (l *UtxoLocker) release(vin *bchain.Vin) {
+	o := vin.Txid + ":" + strconv.FormatUint(uint64(vin.Vout), 10)
+	if _, found := l.locks[o]; found {
+		l.remove(o)
+		glog.V(1).Info("Released lock of spent outpoint ", o)
+	}
+}
+
+// OnNewTx releases the locks of the outpoints spent by a new mempool transaction
+func (l *UtxoLocker) OnNewTx(mtx *bchain.MempoolTx) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if len(l.locks) == 0 {
+		return
+	}
+	for i := range mtx.Vin {
+		l.release(&mtx.Vin[i].Vin)
+	}
+}
+
+// OnNewBlock releases the locks of the outpoints spent by the transactions of a new block,
+// which were not seen in the mempool, and removes the expired locks
+func (l *UtxoLocker) OnNewBlock(block *bchain.Block) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if len(l.locks) == 0 {
+		return
+	}
+	for i := range block.Txs {
+		for j := range block.Txs[i].Vin {
+			l.release(&block.Txs[i].Vin[j])
+		}
+	}
+	l.prune(time.Now().Unix())
+}
+
+// parseOutpoint checks that the outpoint is in the format txid:vout and returns it normalized
+func parseOutpoint(outpoint string) (string, error) {
+	outpoint = strings.TrimSpace(outpoint)
+	i := strings.LastIndexByte(outpoint, ':')
+	if i <= 0 {
+		return "", NewAPIError(fmt.Sprintf("Invalid outpoint %v", outpoint), true)
+	}
+	vout, err := strconv.ParseUint(outpoint[i+1:], 10, 32)
+	if err != nil {
+		return "", NewAPIError(fmt.Sprintf("Invalid outpoint %v", outpoint), true)
+	}
+	return strings.ToLower(outpoint[:i]) + ":" + strconv.FormatUint(vout, 10), nil
+}
+
+func (w *Worker) checkUtxoLocks() error {
+	if w.chainType != bchain.ChainBitcoinType || w.utxoLocker == nil {
+		return NewAPIError("UTXO locks are not supported", true)
+	}
+	return nil
+}
+
+func checkUtxoLockToken(token string) error {
+	if len(token) == 0 {
+		return NewAPIError("Missing lock token", true)
+	}
+	if len(token) != 2*utxoLockTokenBytes {
+		return NewAPIError("Unknown lock token", true)
+	}
+	return nil
+}
+
+// checkUnspentOutpoint returns error if the normalized outpoint is not an unspent output of a confirmed or a mempool transaction.
+// The number of outputs of the transactions is cached in vouts.
+func (w *Worker) checkUnspentOutpoint(outpoint string, vouts map[string]int) error {
+	i := strings.LastIndexByte(outpoint, ':')
+	txid := outpoint[:i]
+	vout, _ := strconv.Atoi(outpoint[i+1:])
+	n, found := vouts[txid]
+	if !found {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return err
+		}
+		if ta != nil {
+			if vout < len(ta.Outputs) && ta.Outputs[vout].Spent {
+				return NewAPIError(fmt.Sprintf("Outpoint %v is spent", outpoint), true)
+			}
+			n = len(ta.Outputs)
+		} else if w.mempool != nil && w.mempool.GetTransactionTime(txid) != 0 {
+			tx, _, err := w.txCache.GetTransaction(txid)
+			if err != nil {
+				return NewAPIError(fmt.Sprintf("Outpoint %v not found", outpoint), true)
+			}
+			n = len(tx.Vout)
+		}
+		vouts[txid] = n
+	}
+	if vout >= n {
+		return NewAPIError(fmt.Sprintf("Outpoint %v not found", outpoint), true)
+	}
+	return nil
+}
+
+// LockUtxos reserves the outpoints for ttl seconds. If token is empty, a new lock token is issued to the client
+// with the address clientAddr and returned, otherwise the outpoints are added to the locks of the token and the locks of the token are extended.
+// Either all outpoints are locked or, if some of them are locked by another token, none of them.
+func (w *Worker) LockUtxos(token string, outpoints []string, ttl int64, clientAddr string) (*UtxoLocks, error) {
+	if err := w.checkUtxoLocks(); err != nil {
+		return nil, err
+	}
+	if token != "" {
+		if err := checkUtxoLockToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if len(outpoints) == 0 {
+		return nil, NewAPIError("Missing outpoints", true)
+	}
+	if len(outpoints) > maxUtxoLockOutpoints {
+		return nil, NewAPIError(fmt.Sprintf("More than %d outpoints", maxUtxoLockOutpoints), true)
+	}
+	if ttl == 0 {
+		ttl = defaultUtxoLockTTL
+	}
+	if ttl < 0 || ttl > maxUtxoLockTTL {
+		return nil, NewAPIError(fmt.Sprintf("Lock TTL must be between 1 and %d seconds", maxUtxoLockTTL), true)
+	}
+	normalized := make([]string, len(outpoints))
+	for i, o := range outpoints {
+		var err error
+		if normalized[i], err = parseOutpoint(o); err != nil {
+			return nil, err
+		}
+	}
+	vouts := make(map[string]int)
+	for _, o := range normalized {
+		if err := w.checkUnspentOutpoint(o, vouts); err != nil {
+			return nil, err
+		}
+	}
+	return w.utxoLocker.lock(token, normalized, ttl, utxoLockClient(clientAddr))
+}
+
+// UnlockUtxos releases the locks of the token, all of them if no outpoints are specified
+func (w *Worker) UnlockUtxos(token string, outpoints []string) (*UtxoLocks, error) {
+	if err := w.checkUtxoLocks(); err != nil {
+		return nil, err
+	}
+	if err := checkUtxoLockToken(token); err != nil {
+		return nil, err
+	}
+	normalized := make([]string, len(outpoints))
+	for i, o := range outpoints {
+		var err error
+		if normalized[i], err = parseOutpoint(o); err != nil {
+			return nil, err
+		}
+	}
+	return w.utxoLocker.unlock(token, normalized)
+}
+
+// GetUtxoLocks returns the current locks of the token
+func (w *Worker) GetUtxoLocks(token string) (*UtxoLocks, error) {
+	if err := w.checkUtxoLocks(); err != nil {
+		return nil, err
+	}
+	if err := checkUtxoLockToken(token); err != nil {
+		return nil, err
+	}
+	return w.utxoLocker.get(token)
+}
@@ -0,0 +1,207 @@
+//go:build unittest
+
+package api
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	testLockTxid1 = "13d26cd939bf5d155b1c60054e02d9c9b832a85e6ec4f2411be44b6b5a2842e9"
+	testLockTxid2 = "a79e396a32e10856c97b95f43da7e9d2b9a11d446f7638dbd75e5e7603128cac"
+)
+
+func testLockUtxos() Utxos {
+	return Utxos{
+		{Txid: testLockTxid1, Vout: 0, AmountSat: (*Amount)(big.NewInt(1000))},
+		{Txid: testLockTxid1, Vout: 1, AmountSat: (*Amount)(big.NewInt(2000))},
+		{Txid: testLockTxid2, Vout: 0, AmountSat: (*Amount)(big.NewInt(3000))},
+	}
+}
+
+func outpointsOfLocks(l *UtxoLocks) []string {
+	r := make([]string, len(l.Locks))
+	for i := range l.Locks {
+		r[i] = l.Locks[i].Outpoint
+	}
+	return r
+}
+
+func TestUtxoLocker(t *testing.T) {
+	l := NewUtxoLocker()
+
+	a, err := l.lock("", []string{testLockTxid1 + ":0", testLockTxid1 + ":1"}, 60, "client1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{testLockTxid1 + ":0", testLockTxid1 + ":1"}
+	if got := outpointsOfLocks(a); len(a.Token) != 2*utxoLockTokenBytes || !reflect.DeepEqual(got, want) || len(a.Conflicts) != 0 {
+		t.Errorf("lock() = %+v, want a new token and locks %v", a, want)
+	}
+	tokenA := a.Token
+
+	// none of the outpoints is locked if some of them are locked by another token, no token is issued
+	b, err := l.lock("", []string{testLockTxid1 + ":1", testLockTxid2 + ":0"}, 10, "client2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Token != "" || !reflect.DeepEqual(b.Conflicts, []string{testLockTxid1 + ":1"}) || len(b.Locks) != 0 {
+		t.Errorf("lock() = %+v, want conflict and no locks", b)
+	}
+	b, err = l.lock("", []string{testLockTxid2 + ":0"}, 10, "client2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenB := b.Token
+	if tokenB == "" || tokenB == tokenA {
+		t.Errorf("lock() = %+v, want another token", b)
+	}
+
+	// the locks can be added only to an issued token
+	if _, err = l.lock(strings.Repeat("0", 2*utxoLockTokenBytes), []string{testLockTxid2 + ":1"}, 10, "client2"); err == nil || err.Error() != "Unknown lock token" {
+		t.Errorf("lock() error = %v, want Unknown lock token", err)
+	}
+
+	utxos := l.filter(testLockUtxos(), UtxoLockFilter{Token: tokenA})
+	if utxos[0].Locked || utxos[1].Locked || !utxos[2].Locked {
+		t.Errorf("filter() = %+v, want only the last utxo locked", utxos)
+	}
+	utxos = l.filter(testLockUtxos(), UtxoLockFilter{Token: tokenB})
+	if !utxos[0].Locked || !utxos[1].Locked || utxos[2].Locked {
+		t.Errorf("filter() = %+v, want the first two utxos locked", utxos)
+	}
+	utxos = l.filter(testLockUtxos(), UtxoLockFilter{ExcludeLocked: true})
+	if len(utxos) != 0 {
+		t.Errorf("filter() = %+v, want no utxos", utxos)
+	}
+
+	// the lock is released when a mempool transaction spends the outpoint
+	l.OnNewTx(&bchain.MempoolTx{Vin: []bchain.MempoolVin{{Vin: bchain.Vin{Txid: testLockTxid1, Vout: 0}}}})
+	if a, err = l.get(tokenA); err != nil {
+		t.Fatal(err)
+	}
+	if got := outpointsOfLocks(a); !reflect.DeepEqual(got, []string{testLockTxid1 + ":1"}) {
+		t.Errorf("get() = %v after spend in mempool", got)
+	}
+
+	// the token releases only its own locks
+	if a, err = l.unlock(tokenA, []string{testLockTxid2 + ":0"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := outpointsOfLocks(a); !reflect.DeepEqual(got, []string{testLockTxid1 + ":1"}) {
+		t.Errorf("unlock() = %v, want the lock kept", got)
+	}
+	if a, err = l.unlock(tokenA, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Locks) != 0 {
+		t.Errorf("unlock() = %+v, want all locks released", a)
+	}
+	// the token without locks is forgotten
+	if _, err = l.get(tokenA); err == nil || err.Error() != "Unknown lock token" {
+		t.Errorf("get() error = %v, want Unknown lock token", err)
+	}
+
+	// the lock is released when a block transaction spends the outpoint
+	l.OnNewBlock(&bchain.Block{Txs: []bchain.Tx{{Vin: []bchain.Vin{{Txid: testLockTxid2, Vout: 0}}}}})
+	if len(l.locks) != 0 || len(l.tokens) != 0 || len(l.clients) != 0 {
+		t.Errorf("OnNewBlock() left locks %+v, tokens %+v, clients %+v", l.locks, l.tokens, l.clients)
+	}
+}
+
+func TestUtxoLockerQuota(t *testing.T) {
+	l := NewUtxoLocker()
+	outpoints := make([]string, maxUtxoLocksPerClient)
+	for i := range outpoints {
+		outpoints[i] = testLockTxid1 + ":" + strconv.Itoa(i)
+	}
+	r, err := l.lock("", outpoints[:maxUtxoLocksPerClient-10], 60, "client1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another token of the same client shares the quota
+	if _, err = l.lock("", outpoints[maxUtxoLocksPerClient-10:], 60, "client1"); err != nil {
+		t.Fatal(err)
+	}
+	// extending the held locks does not count against the quota
+	if _, err = l.lock(r.Token, outpoints[:10], 60, "client1"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.lock("", []string{testLockTxid2 + ":0"}, 60, "client1")
+	if err == nil || err.Error() != "More than 1000 UTXO locks of the client" {
+		t.Errorf("lock() error = %v, want quota error", err)
+	}
+	// the locks added to the token from another address are counted to the client which was issued the token
+	_, err = l.lock(r.Token, []string{testLockTxid2 + ":0"}, 60, "client2")
+	if err == nil || err.Error() != "More than 1000 UTXO locks of the client" {
+		t.Errorf("lock() error = %v, want quota error", err)
+	}
+	// other clients are not affected
+	if _, err = l.lock("", []string{testLockTxid2 + ":0"}, 60, "client2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.unlock(r.Token, outpoints[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.lock("", []string{testLockTxid2 + ":1"}, 60, "client1"); err != nil {
+		t.Errorf("lock() error = %v after unlock, want nil", err)
+	}
+}
+
+func TestUtxoLockClient(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"192.0.2.1", "192.0.2.1"},
+		{"192.0.2.1:45678", "192.0.2.1"},
+		{"[2001:db8:1:2:3:4:5:6]:45678", "2001:db8:1:2::"},
+		{"2001:db8:1:2:ffff::1", "2001:db8:1:2::"},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := utxoLockClient(tt.addr); got != tt.want {
+			t.Errorf("utxoLockClient(%v) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestLockUtxosErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		chainType bchain.ChainType
+		token     string
+		outpoints []string
+		ttl       int64
+		wantErr   string
+	}{
+		{"ethereum", bchain.ChainEthereumType, "", []string{testLockTxid1 + ":0"}, 0, "UTXO locks are not supported"},
+		{"invalid token", bchain.ChainBitcoinType, "worker1", []string{testLockTxid1 + ":0"}, 0, "Unknown lock token"},
+		{"missing outpoints", bchain.ChainBitcoinType, "", nil, 0, "Missing outpoints"},
+		{"invalid outpoint", bchain.ChainBitcoinType, "", []string{testLockTxid1}, 0, "Invalid outpoint " + testLockTxid1},
+		{"invalid vout", bchain.ChainBitcoinType, "", []string{testLockTxid1 + ":x"}, 0, "Invalid outpoint " + testLockTxid1 + ":x"},
+		{"ttl too long", bchain.ChainBitcoinType, "", []string{testLockTxid1 + ":0"}, maxUtxoLockTTL + 1, "Lock TTL must be between 1 and 3600 seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Worker{chainType: tt.chainType}
+			if tt.chainType == bchain.ChainBitcoinType {
+				w.SetUtxoLocker(NewUtxoLocker())
+			}
+			_, err := w.LockUtxos(tt.token, tt.outpoints, tt.ttl, "192.0.2.1")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("LockUtxos() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	w := &Worker{chainType: bchain.ChainBitcoinType, utxoLocker: NewUtxoLocker()}
+	if _, err := w.GetUtxoLocks(""); err == nil || err.Error() != "Missing lock token" {
+		t.Errorf("GetUtxoLocks() error = %v, want Missing lock token", err)
+	}
+}
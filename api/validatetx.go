@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	// minRelayFeePerKb is the default minimal relay fee rate of bitcoind in satoshi per kvB
+	minRelayFeePerKb = 1000
+	// dustRelayFeePerKb is the default fee rate of bitcoind used to compute the dust threshold in satoshi per kvB
+	dustRelayFeePerKb = 3000
+	opReturn          = 0x6a
+)
+
+// ValidateTransaction checks whether the backend would accept the transaction, without broadcasting it
+func (w *Worker) ValidateTransaction(txHex string) (*TxValidation, error) {
+	if w.chainType != bchain.ChainBitcoinType && w.chainType != bchain.ChainEthereumType {
+		return nil, NewAPIError("Transaction validation is not supported", true)
+	}
+	v := &TxValidation{}
+	var rejections []bchain.TxRejection
+	if w.chainType == bchain.ChainBitcoinType {
+		b, err := hex.DecodeString(txHex)
+		if err != nil {
+			return txValidation(v, []bchain.TxRejection{{Code: bchain.TxRejectInvalidTx, Message: err.Error(), Vin: -1, Vout: -1}}), nil
+		}
+		tx, err := w.chainParser.ParseTx(b)
+		if err != nil {
+			return txValidation(v, []bchain.TxRejection{{Code: bchain.TxRejectInvalidTx, Message: err.Error(), Vin: -1, Vout: -1}}), nil
+		}
+		v.Txid = tx.Txid
+		ta, err := w.db.GetTxAddresses(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta != nil {
+			return txValidation(v, []bchain.TxRejection{{Code: bchain.TxRejectAlreadyKnown, Message: fmt.Sprintf("transaction is already mined in block %d", ta.Height), Vin: -1, Vout: -1}}), nil
+		}
+		if rejections, err = w.checkBitcoinTypeTx(tx, v); err != nil {
+			return nil, err
+		}
+	}
+	var r *bchain.TxValidationResult
+	if tv, ok := w.chain.(bchain.TransactionValidator); ok {
+		var err error
+		if r, err = tv.ValidateRawTransaction(txHex); err != nil {
+			return nil, err
+		}
+	}
+	if r == nil {
+		if w.chainType != bchain.ChainBitcoinType {
+			return nil, NewAPIError("Transaction validation is not supported", true)
+		}
+	} else {
+		if r.Txid != "" {
+			v.Txid = r.Txid
+		}
+		if r.VSize > 0 {
+			v.VSize = r.VSize
+		}
+		if r.Fees != nil {
+			v.FeesSat = (*Amount)(r.Fees)
+			if v.VSize > 0 {
+				v.FeePerKb = r.Fees.Int64() * 1000 / v.VSize
+			}
+		}
+		v.GasUsed = r.GasUsed
+		rejections = mergeTxRejections(rejections, r.Rejections)
+	}
+	if v.Txid != "" && w.mempool.GetTransactionTime(v.Txid) != 0 {
+		rejections = []bchain.TxRejection{{Code: bchain.TxRejectAlreadyKnown, Message: "transaction is already in the mempool", Vin: -1, Vout: -1}}
+	}
+	return txValidation(v, rejections), nil
+}
+
+func txValidation(v *TxValidation, rejections []bchain.TxRejection) *TxValidation {
+	v.Valid = len(rejections) == 0
+	for i := range rejections {
+		r := &rejections[i]
+		tr := TxRejection{Code: r.Code, Message: r.Message}
+		if r.Vin >= 0 {
+			vin := r.Vin
+			tr.Vin = &vin
+		}
+		if r.Vout >= 0 {
+			vout := r.Vout
+			tr.Vout = &vout
+		}
+		v.Rejections = append(v.Rejections, tr)
+	}
+	return v
+}
+
+// mergeTxRejections adds the rejections of the backend to the rejections found by the checks against the index,
+// omitting the backend rejections already described more specifically by the checks
+func mergeTxRejections(checked []bchain.TxRejection, backend []bchain.TxRejection) []bchain.TxRejection {
+	found := make(map[string]struct{}, len(checked))
+	for i := range checked {
+		found[checked[i].Code] = struct{}{}
+		if checked[i].Code == bchain.TxRejectSpentInput {
+			found[bchain.TxRejectMissingInput] = struct{}{}
+		}
+	}
+	r := checked
+	for i := range backend {
+		if _, ok := found[backend[i].Code]; !ok {
+			r = append(r, backend[i])
+		}
+	}
+	return r
+}
+
+// checkBitcoinTypeTx checks the inputs of the transaction against the index and the mempool
+// and its fee rate and outputs against the default relay policy of bitcoind
+func (w *Worker) checkBitcoinTypeTx(tx *bchain.Tx, v *TxValidation) ([]bchain.TxRejection, error) {
+	var rejections []bchain.TxRejection
+	reject := func(code string, vin int, vout int, format string, a ...interface{}) {
+		rejections = append(rejections, bchain.TxRejection{Code: code, Message: fmt.Sprintf(format, a...), Vin: vin, Vout: vout})
+	}
+	var fee big.Int
+	feeKnown := true
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if vin.Txid == "" {
+			feeKnown = false
+			continue
+		}
+		ta, err := w.db.GetTxAddresses(vin.Txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta != nil {
+			if int(vin.Vout) >= len(ta.Outputs) {
+				reject(bchain.TxRejectMissingInput, i, -1, "output %v:%d does not exist", vin.Txid, vin.Vout)
+				feeKnown = false
+			} else if ta.Outputs[vin.Vout].Spent {
+				reject(bchain.TxRejectSpentInput, i, -1, "output %v:%d is already spent", vin.Txid, vin.Vout)
+				feeKnown = false
+			} else {
+				fee.Add(&fee, &ta.Outputs[vin.Vout].ValueSat)
+			}
+			continue
+		}
+		// the input can spend an output of an unconfirmed transaction
+		if w.mempool.GetTransactionTime(vin.Txid) != 0 {
+			if ptx, err := w.chain.GetTransactionForMempool(vin.Txid); err == nil && int(vin.Vout) < len(ptx.Vout) {
+				fee.Add(&fee, &ptx.Vout[vin.Vout].ValueSat)
+				continue
+			}
+		}
+		reject(bchain.TxRejectMissingInput, i, -1, "output %v:%d is not known", vin.Txid, vin.Vout)
+		feeKnown = false
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		fee.Sub(&fee, &vout.ValueSat)
+		script, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+		if err != nil {
+			continue
+		}
+		if dust := dustThreshold(script); vout.ValueSat.Cmp(big.NewInt(dust)) < 0 {
+			reject(bchain.TxRejectDust, -1, i, "value %v is below the dust threshold %d", vout.ValueSat.String(), dust)
+		}
+	}
+	v.VSize = tx.VSize
+	if v.VSize == 0 {
+		v.VSize = int64(len(tx.Hex) / 2)
+	}
+	if feeKnown && v.VSize > 0 {
+		if fee.Sign() < 0 {
+			reject(bchain.TxRejectInsufficientFunds, -1, -1, "outputs exceed inputs by %v", new(big.Int).Neg(&fee).String())
+		} else {
+			v.FeesSat = (*Amount)(&fee)
+			v.FeePerKb = fee.Int64() * 1000 / v.VSize
+			if v.FeePerKb < minRelayFeePerKb {
+				reject(bchain.TxRejectFeeTooLow, -1, -1, "fee rate %d is below the minimal relay fee rate %d per kvB", v.FeePerKb, minRelayFeePerKb)
+			}
+		}
+	}
+	return rejections, nil
+}
+
+// dustThreshold returns the minimal value of a standard output with the script, computed as by bitcoind from
+// the size of the output and of the input spending it, the unspendable OP_RETURN outputs have no threshold
+func dustThreshold(script []byte) int64 {
+	if len(script) > 0 && script[0] == opReturn {
+		return 0
+	}
+	size := 8 + len(script) + 1
+	if len(script) >= 0xfd {
+		size += 2
+	}
+	if isWitnessProgram(script) {
+		// outpoint, sequence and the witness discounted to a quarter
+		size += 32 + 4 + 1 + 107/4 + 4
+	} else {
+		size += 32 + 4 + 1 + 107 + 4
+	}
+	return int64(size) * dustRelayFeePerKb / 1000
+}
+
+// isWitnessProgram returns true for the scripts consisting of the witness version and a single push of 2 to 40 bytes
+func isWitnessProgram(script []byte) bool {
+	if len(script) < 4 || len(script) > 42 {
+		return false
+	}
+	if script[0] != 0 && (script[0] < 0x51 || script[0] > 0x60) {
+		return false
+	}
+	return int(script[1])+2 == len(script)
+}
@@ -0,0 +1,68 @@
+//go:build unittest
+
+package api
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func TestDustThreshold(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int64
+	}{
+		{"P2PKH", "76a914" + "0000000000000000000000000000000000000000" + "88ac", 546},
+		{"P2SH", "a914" + "0000000000000000000000000000000000000000" + "87", 540},
+		{"P2WPKH", "0014" + "0000000000000000000000000000000000000000", 294},
+		{"P2WSH", "0020" + "0000000000000000000000000000000000000000000000000000000000000000", 330},
+		{"P2TR", "5120" + "0000000000000000000000000000000000000000000000000000000000000000", 330},
+		{"OP_RETURN", "6a0401020304", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := hex.DecodeString(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dustThreshold(script); got != tt.want {
+				t.Errorf("dustThreshold() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeTxRejections(t *testing.T) {
+	checked := []bchain.TxRejection{
+		{Code: bchain.TxRejectSpentInput, Message: "spent", Vin: 1, Vout: -1},
+		{Code: bchain.TxRejectDust, Message: "dust", Vin: -1, Vout: 0},
+	}
+	backend := []bchain.TxRejection{
+		{Code: bchain.TxRejectMissingInput, Message: "missing-inputs", Vin: -1, Vout: -1},
+		{Code: bchain.TxRejectDust, Message: "dust", Vin: -1, Vout: -1},
+		{Code: bchain.TxRejectNonStandard, Message: "scriptpubkey", Vin: -1, Vout: -1},
+	}
+	want := []bchain.TxRejection{checked[0], checked[1], backend[2]}
+	if got := mergeTxRejections(checked, backend); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeTxRejections() = %+v, want %+v", got, want)
+	}
+
+	vin := 1
+	vout := 0
+	wantValidation := &TxValidation{
+		Rejections: []TxRejection{
+			{Code: bchain.TxRejectSpentInput, Message: "spent", Vin: &vin},
+			{Code: bchain.TxRejectDust, Message: "dust", Vout: &vout},
+		},
+	}
+	if got := txValidation(&TxValidation{}, checked); !reflect.DeepEqual(got, wantValidation) {
+		t.Errorf("txValidation() = %+v, want %+v", got, wantValidation)
+	}
+	if got := txValidation(&TxValidation{}, nil); !got.Valid || got.Rejections != nil {
+		t.Errorf("txValidation(nil) = %+v, want valid", got)
+	}
+}
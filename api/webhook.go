@@ -0,0 +1,848 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/db"
+)
+
+const (
+	// WebhookEventAddress is delivered for each mempool and confirmed transaction involving a watched address
+	WebhookEventAddress = "address"
+	// WebhookEventConfirmations is delivered when a transaction involving a watched address reaches a number of confirmations
+	WebhookEventConfirmations = "confirmations"
+	// WebhookEventReorg is delivered when blocks are disconnected from the index by a chain reorganization
+	WebhookEventReorg = "reorg"
+)
+
+const (
+	maxWebhooks             = 1000
+	maxWebhookAddresses     = 1000
+	maxWebhookConfirmations = 1000
+	maxWebhookURLLength     = 2048
+	maxWebhookDeadLetters   = 1000
+	maxWebhookTrackedTxs    = 100000
+	webhookIDBytes          = 16
+	webhookSecretBytes      = 32
+	webhookQueueSize        = 10000
+	webhookEventsQueueSize  = 1000
+	webhookMaxAttempts      = 8
+	webhookRetryDelay       = 5 * time.Second
+	webhookTimeout          = 10 * time.Second
+)
+
+// webhooksVersion is incremented on each change of the registrations, the dispatcher then reloads them from the db
+var webhooksVersion int64
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newWebhookDeliveryID returns a random id prefixed by the time, the dead letters stored under it are ordered by time
+func newWebhookDeliveryID() string {
+	r, err := randomHex(4)
+	if err != nil {
+		glog.Error("newWebhookDeliveryID ", err)
+	}
+	return fmt.Sprintf("%016x%s", time.Now().UnixNano(), r)
+}
+
+func hasWebhookEvent(wh *db.Webhook, event string) bool {
+	for _, e := range wh.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhookPayload returns the hex encoded HMAC-SHA256 of the timestamp and the body joined by a dot, keyed by the secret of the webhook
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook makes one attempt to deliver the signed payload, any other response than 2xx is an error
+func postWebhook(client *http.Client, wh *db.Webhook, id string, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Blockbook")
+	req.Header.Set("X-Blockbook-Event", event)
+	req.Header.Set("X-Blockbook-Delivery", id)
+	req.Header.Set("X-Blockbook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Blockbook-Signature", "sha256="+SignWebhookPayload(wh.Secret, timestamp, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// read the response so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("Status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// normalizeWebhook validates the registration and converts it to the stored form, the secret is not set
+func (w *Worker) normalizeWebhook(wh *Webhook) (*db.Webhook, error) {
+	if wh == nil {
+		return nil, NewAPIError("Missing webhook", true)
+	}
+	dw := &db.Webhook{
+		URL:      strings.TrimSpace(wh.URL),
+		Disabled: wh.Disabled,
+	}
+	if len(dw.URL) > maxWebhookURLLength {
+		return nil, NewAPIError(fmt.Sprintf("Webhook URL is longer than %d characters", maxWebhookURLLength), true)
+	}
+	u, err := url.Parse(dw.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, NewAPIError("Invalid webhook URL", true)
+	}
+	for _, e := range wh.Events {
+		if e != WebhookEventAddress && e != WebhookEventConfirmations && e != WebhookEventReorg {
+			return nil, NewAPIError(fmt.Sprintf("Invalid webhook event %v", e), true)
+		}
+		if !hasWebhookEvent(dw, e) {
+			dw.Events = append(dw.Events, e)
+		}
+	}
+	if len(dw.Events) == 0 {
+		return nil, NewAPIError("Missing webhook events", true)
+	}
+	if hasWebhookEvent(dw, WebhookEventAddress) || hasWebhookEvent(dw, WebhookEventConfirmations) {
+		if len(wh.Addresses) == 0 {
+			return nil, NewAPIError("Missing webhook addresses", true)
+		}
+		if len(wh.Addresses) > maxWebhookAddresses {
+			return nil, NewAPIError(fmt.Sprintf("Webhook has more than %d addresses", maxWebhookAddresses), true)
+		}
+		unique := make(map[string]struct{}, len(wh.Addresses))
+		for _, a := range wh.Addresses {
+			_, address, err := w.getAddrDescAndNormalizeAddress(strings.TrimSpace(a))
+			if err != nil {
+				return nil, NewAPIError(fmt.Sprintf("Invalid webhook address %v", a), true)
+			}
+			if _, found := unique[address]; !found {
+				unique[address] = struct{}{}
+				dw.Addresses = append(dw.Addresses, address)
+			}
+		}
+	}
+	if hasWebhookEvent(dw, WebhookEventConfirmations) {
+		if len(wh.Confirmations) == 0 {
+			return nil, NewAPIError("Missing webhook confirmations", true)
+		}
+		for _, c := range wh.Confirmations {
+			if c == 0 || c > maxWebhookConfirmations {
+				return nil, NewAPIError(fmt.Sprintf("Webhook confirmations must be between 1 and %d", maxWebhookConfirmations), true)
+			}
+			dw.Confirmations = append(dw.Confirmations, c)
+		}
+		sort.Slice(dw.Confirmations, func(i, j int) bool { return dw.Confirmations[i] < dw.Confirmations[j] })
+		n := 1
+		for i := 1; i < len(dw.Confirmations); i++ {
+			if dw.Confirmations[i] != dw.Confirmations[n-1] {
+				dw.Confirmations[n] = dw.Confirmations[i]
+				n++
+			}
+		}
+		dw.Confirmations = dw.Confirmations[:n]
+	}
+	return dw, nil
+}
+
+// webhookFromDB converts the stored webhook, the secret is returned only if withSecret is set
+func webhookFromDB(id string, dw *db.Webhook, withSecret bool) *Webhook {
+	wh := &Webhook{
+		ID:            id,
+		URL:           dw.URL,
+		Events:        dw.Events,
+		Addresses:     dw.Addresses,
+		Confirmations: dw.Confirmations,
+		Disabled:      dw.Disabled,
+		Created:       dw.Created,
+		Updated:       dw.Updated,
+	}
+	if withSecret {
+		wh.Secret = dw.Secret
+	}
+	return wh
+}
+
+func (w *Worker) getStoredWebhook(id string) (*db.Webhook, error) {
+	if len(id) == 0 {
+		return nil, NewAPIError("Missing webhook id", true)
+	}
+	dw, err := w.db.GetWebhook(id)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetWebhook")
+	}
+	if dw == nil {
+		return nil, NewAPIError("Webhook not found", true)
+	}
+	return dw, nil
+}
+
+// CreateWebhook validates and stores a new webhook registration, returns it with the assigned id and the secret
+func (w *Worker) CreateWebhook(wh *Webhook) (*Webhook, error) {
+	dw, err := w.normalizeWebhook(wh)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	if err = w.db.IterateWebhooks(func(id string, wh *db.Webhook) error {
+		count++
+		return nil
+	}); err != nil {
+		return nil, errors.Annotatef(err, "IterateWebhooks")
+	}
+	if count >= maxWebhooks {
+		return nil, NewAPIError(fmt.Sprintf("There are more than %d webhooks", maxWebhooks), true)
+	}
+	dw.Secret = wh.Secret
+	if len(dw.Secret) == 0 {
+		if dw.Secret, err = randomHex(webhookSecretBytes); err != nil {
+			return nil, errors.Annotatef(err, "randomHex")
+		}
+	}
+	id, err := randomHex(webhookIDBytes)
+	if err != nil {
+		return nil, errors.Annotatef(err, "randomHex")
+	}
+	dw.Created = time.Now().Unix()
+	dw.Updated = dw.Created
+	if err = w.db.StoreWebhook(id, dw); err != nil {
+		return nil, errors.Annotatef(err, "StoreWebhook")
+	}
+	atomic.AddInt64(&webhooksVersion, 1)
+	return webhookFromDB(id, dw, true), nil
+}
+
+// UpdateWebhook replaces the registration of an existing webhook, the secret is kept if it is not specified
+func (w *Worker) UpdateWebhook(id string, wh *Webhook) (*Webhook, error) {
+	stored, err := w.getStoredWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	dw, err := w.normalizeWebhook(wh)
+	if err != nil {
+		return nil, err
+	}
+	dw.Secret = wh.Secret
+	if len(dw.Secret) == 0 {
+		dw.Secret = stored.Secret
+	}
+	dw.Created = stored.Created
+	dw.Updated = time.Now().Unix()
+	if err = w.db.StoreWebhook(id, dw); err != nil {
+		return nil, errors.Annotatef(err, "StoreWebhook")
+	}
+	atomic.AddInt64(&webhooksVersion, 1)
+	return webhookFromDB(id, dw, false), nil
+}
+
+// GetWebhook returns the registration of the webhook without the secret
+func (w *Worker) GetWebhook(id string) (*Webhook, error) {
+	dw, err := w.getStoredWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	return webhookFromDB(id, dw, false), nil
+}
+
+// GetWebhooks returns all webhook registrations without the secrets, ordered by the time of creation
+func (w *Worker) GetWebhooks() ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	if err := w.db.IterateWebhooks(func(id string, dw *db.Webhook) error {
+		webhooks = append(webhooks, *webhookFromDB(id, dw, false))
+		return nil
+	}); err != nil {
+		return nil, errors.Annotatef(err, "IterateWebhooks")
+	}
+	sort.SliceStable(webhooks, func(i, j int) bool { return webhooks[i].Created < webhooks[j].Created })
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook, returns its last registration
+func (w *Worker) DeleteWebhook(id string) (*Webhook, error) {
+	dw, err := w.getStoredWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.db.DeleteWebhook(id); err != nil {
+		return nil, errors.Annotatef(err, "DeleteWebhook")
+	}
+	atomic.AddInt64(&webhooksVersion, 1)
+	return webhookFromDB(id, dw, false), nil
+}
+
+func webhookDeadLetterFromDB(id string, dl *db.WebhookDeadLetter) *WebhookDeadLetter {
+	return &WebhookDeadLetter{
+		ID:          id,
+		Webhook:     dl.Webhook,
+		Event:       dl.Event,
+		Payload:     dl.Payload,
+		Attempts:    dl.Attempts,
+		LastError:   dl.LastError,
+		Created:     dl.Created,
+		LastAttempt: dl.LastAttempt,
+	}
+}
+
+func (w *Worker) getStoredWebhookDeadLetter(id string) (*db.WebhookDeadLetter, error) {
+	if len(id) == 0 {
+		return nil, NewAPIError("Missing dead letter id", true)
+	}
+	dl, err := w.db.GetWebhookDeadLetter(id)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetWebhookDeadLetter")
+	}
+	if dl == nil {
+		return nil, NewAPIError("Dead letter not found", true)
+	}
+	return dl, nil
+}
+
+// GetWebhookDeadLetters returns the oldest undelivered events, of all webhooks if webhook is empty
+func (w *Worker) GetWebhookDeadLetters(webhook string) ([]WebhookDeadLetter, error) {
+	dls := make([]WebhookDeadLetter, 0)
+	if err := w.db.IterateWebhookDeadLetters(func(id string, dl *db.WebhookDeadLetter) error {
+		if len(webhook) == 0 || dl.Webhook == webhook {
+			dls = append(dls, *webhookDeadLetterFromDB(id, dl))
+			if len(dls) >= maxWebhookDeadLetters {
+				return errStopIteration
+			}
+		}
+		return nil
+	}); err != nil && err != errStopIteration {
+		return nil, errors.Annotatef(err, "IterateWebhookDeadLetters")
+	}
+	return dls, nil
+}
+
+// DeleteWebhookDeadLetter removes the dead letter, returns its last stored state
+func (w *Worker) DeleteWebhookDeadLetter(id string) (*WebhookDeadLetter, error) {
+	dl, err := w.getStoredWebhookDeadLetter(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.db.DeleteWebhookDeadLetter(id); err != nil {
+		return nil, errors.Annotatef(err, "DeleteWebhookDeadLetter")
+	}
+	return webhookDeadLetterFromDB(id, dl), nil
+}
+
+// RedeliverWebhookDeadLetter makes one more attempt to deliver the dead letter, it is removed if the delivery succeeds
+func (w *Worker) RedeliverWebhookDeadLetter(id string) (*WebhookDeadLetter, error) {
+	dl, err := w.getStoredWebhookDeadLetter(id)
+	if err != nil {
+		return nil, err
+	}
+	dw, err := w.getStoredWebhook(dl.Webhook)
+	if err != nil {
+		return nil, err
+	}
+	dl.Attempts++
+	dl.LastAttempt = time.Now().Unix()
+	if err = postWebhook(webhookClient, dw, id, dl.Event, dl.Payload); err != nil {
+		dl.LastError = err.Error()
+		if err := w.db.StoreWebhookDeadLetter(id, dl); err != nil {
+			return nil, errors.Annotatef(err, "StoreWebhookDeadLetter")
+		}
+		return nil, NewAPIError("Delivery failed: "+dl.LastError, true)
+	}
+	dl.LastError = ""
+	if err = w.db.DeleteWebhookDeadLetter(id); err != nil {
+		return nil, errors.Annotatef(err, "DeleteWebhookDeadLetter")
+	}
+	return webhookDeadLetterFromDB(id, dl), nil
+}
+
+var errStopIteration = errors.New("stop iteration")
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookDelivery is an event waiting for the delivery to a webhook
+type webhookDelivery struct {
+	id          string
+	webhook     string
+	event       string
+	payload     []byte
+	attempts    int
+	created     int64
+	lastAttempt int64
+	lastError   string
+}
+
+// webhookSender delivers the events with retries and exponential backoff,
+// the events which cannot be delivered are stored as dead letters
+type webhookSender struct {
+	client          *http.Client
+	maxAttempts     int
+	retryDelay      time.Duration
+	queue           chan *webhookDelivery
+	metrics         *common.Metrics
+	getWebhook      func(id string) (*db.Webhook, error)
+	storeDeadLetter func(id string, dl *db.WebhookDeadLetter) error
+}
+
+func (s *webhookSender) run() {
+	for d := range s.queue {
+		s.deliver(d)
+	}
+}
+
+// schedule adds the delivery to the queue, if the queue is full, the delivery becomes a dead letter
+func (s *webhookSender) schedule(d *webhookDelivery) {
+	select {
+	case s.queue <- d:
+	default:
+		d.lastError = "Delivery queue is full"
+		s.deadLetter(d)
+	}
+}
+
+func (s *webhookSender) deliver(d *webhookDelivery) {
+	wh, err := s.getWebhook(d.webhook)
+	if err != nil {
+		glog.Error("webhook ", d.webhook, " delivery ", d.id, ": ", err)
+		d.lastError = err.Error()
+		s.deadLetter(d)
+		return
+	}
+	if wh == nil || wh.Disabled {
+		glog.V(1).Info("webhook ", d.webhook, " removed or disabled, delivery ", d.id, " dropped")
+		return
+	}
+	d.attempts++
+	d.lastAttempt = time.Now().Unix()
+	if err = postWebhook(s.client, wh, d.id, d.event, d.payload); err == nil {
+		s.observe(d.event, "delivered")
+		return
+	}
+	d.lastError = err.Error()
+	if d.attempts >= s.maxAttempts {
+		s.deadLetter(d)
+		return
+	}
+	s.observe(d.event, "retry")
+	delay := s.retryDelay << uint(d.attempts-1)
+	glog.V(1).Info("webhook ", d.webhook, " delivery ", d.id, " failed: ", d.lastError, ", retry in ", delay)
+	time.AfterFunc(delay, func() { s.schedule(d) })
+}
+
+func (s *webhookSender) deadLetter(d *webhookDelivery) {
+	s.observe(d.event, "deadletter")
+	glog.Warning("webhook ", d.webhook, " delivery ", d.id, " failed after ", d.attempts, " attempts: ", d.lastError)
+	if err := s.storeDeadLetter(d.id, &db.WebhookDeadLetter{
+		Webhook:     d.webhook,
+		Event:       d.event,
+		Payload:     d.payload,
+		Attempts:    d.attempts,
+		LastError:   d.lastError,
+		Created:     d.created,
+		LastAttempt: d.lastAttempt,
+	}); err != nil {
+		glog.Error("StoreWebhookDeadLetter ", d.id, ": ", err)
+	}
+}
+
+func (s *webhookSender) observe(event, result string) {
+	if s.metrics != nil {
+		s.metrics.WebhookDeliveries.With(common.Labels{"event": event, "result": result}).Inc()
+	}
+}
+
+// webhookTxWatch is a confirmed transaction involving an address watched by a webhook with the confirmations event
+type webhookTxWatch struct {
+	webhook string
+	address string
+	txid    string
+	height  uint32
+	// last is the last delivered number of confirmations
+	last uint32
+}
+
+// webhookMilestone is a number of confirmations reached by a watched transaction
+type webhookMilestone struct {
+	webhookTxWatch
+	confirmations uint32
+}
+
+// webhookConfirmations tracks the confirmations of the watched transactions until they reach the highest requested milestone
+// the watches are kept only in memory, the milestones not reached before a restart are not delivered (see docs/webhooks.md)
+type webhookConfirmations struct {
+	watches []webhookTxWatch
+}
+
+func (c *webhookConfirmations) track(webhook, address, txid string, height uint32) {
+	if len(c.watches) >= maxWebhookTrackedTxs {
+		glog.Warning("webhook confirmations: too many tracked transactions, ", txid, " not tracked")
+		return
+	}
+	c.watches = append(c.watches, webhookTxWatch{webhook: webhook, address: address, txid: txid, height: height})
+}
+
+// advance returns the milestones reached at the best height, the watches which reached all their milestones are removed
+func (c *webhookConfirmations) advance(bestHeight uint32, milestones func(webhook string) []uint32) []webhookMilestone {
+	var r []webhookMilestone
+	watches := c.watches[:0]
+	for _, tw := range c.watches {
+		if bestHeight < tw.height {
+			watches = append(watches, tw)
+			continue
+		}
+		confirmations := bestHeight - tw.height + 1
+		ms := milestones(tw.webhook)
+		for _, m := range ms {
+			if m > tw.last && m <= confirmations {
+				r = append(r, webhookMilestone{webhookTxWatch: tw, confirmations: m})
+				tw.last = m
+			}
+		}
+		if len(ms) > 0 && tw.last < ms[len(ms)-1] {
+			watches = append(watches, tw)
+		}
+	}
+	c.watches = watches
+	return r
+}
+
+// disconnect removes the watches of the transactions in the blocks above the fork height,
+// they are tracked again when the transactions are mined in the new chain
+func (c *webhookConfirmations) disconnect(forkHeight uint32) {
+	watches := c.watches[:0]
+	for _, tw := range c.watches {
+		if tw.height <= forkHeight {
+			watches = append(watches, tw)
+		}
+	}
+	c.watches = watches
+}
+
+// WebhookDispatcher matches the new transactions, blocks and reorgs to the registered webhooks and delivers the events
+type WebhookDispatcher struct {
+	w             *Worker
+	sender        *webhookSender
+	workers       int
+	started       bool
+	events        chan interface{}
+	confirmations webhookConfirmations
+	// the registrations, accessed only by the event loop
+	version   int64
+	webhooks  map[string]*db.Webhook
+	addrHooks map[string][]string
+}
+
+// NewWebhookDispatcher creates the dispatcher and starts the event loop,
+// the given number of delivery workers is started when the first webhook is registered
+func NewWebhookDispatcher(w *Worker, workers int) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		w: w,
+		sender: &webhookSender{
+			client:          webhookClient,
+			maxAttempts:     webhookMaxAttempts,
+			retryDelay:      webhookRetryDelay,
+			queue:           make(chan *webhookDelivery, webhookQueueSize),
+			metrics:         w.metrics,
+			getWebhook:      w.db.GetWebhook,
+			storeDeadLetter: w.db.StoreWebhookDeadLetter,
+		},
+		workers: workers,
+		events:  make(chan interface{}, webhookEventsQueueSize),
+		version: -1,
+	}
+	go d.eventLoop()
+	return d
+}
+
+// OnNewBlock is a callback delivering the address events of the transactions in the block and the confirmations events
+func (d *WebhookDispatcher) OnNewBlock(block *bchain.Block) {
+	d.queueEvent(block, "block")
+}
+
+// OnNewTx is a callback delivering the address events of the new mempool transaction
+func (d *WebhookDispatcher) OnNewTx(tx *bchain.MempoolTx) {
+	d.queueEvent(tx, "tx")
+}
+
+// OnReorg is a callback delivering the reorg events
+func (d *WebhookDispatcher) OnReorg(reorg *bchain.Reorg) {
+	d.queueEvent(reorg, "reorg")
+}
+
+// queueEvent passes the event to the event loop without blocking the caller, the event is dropped if the queue is full
+func (d *WebhookDispatcher) queueEvent(e interface{}, kind string) {
+	select {
+	case d.events <- e:
+	default:
+		glog.Warning("webhook events queue is full, ", kind, " event dropped")
+		if d.w.metrics != nil {
+			d.w.metrics.WebhookDroppedEvents.With(common.Labels{"event": kind}).Inc()
+		}
+	}
+}
+
+func (d *WebhookDispatcher) eventLoop() {
+	for e := range d.events {
+		d.processEvent(e)
+	}
+}
+
+func (d *WebhookDispatcher) processEvent(e interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("webhook dispatcher recovered from panic: ", r)
+		}
+	}()
+	d.reload()
+	if len(d.webhooks) == 0 {
+		return
+	}
+	switch e := e.(type) {
+	case *bchain.Block:
+		d.processBlock(e)
+	case *bchain.MempoolTx:
+		d.processMempoolTx(e)
+	case *bchain.Reorg:
+		d.processReorg(e)
+	}
+}
+
+// reload loads the registrations from the db if they were changed
+func (d *WebhookDispatcher) reload() {
+	version := atomic.LoadInt64(&webhooksVersion)
+	if version == d.version {
+		return
+	}
+	webhooks := make(map[string]*db.Webhook)
+	addrHooks := make(map[string][]string)
+	if err := d.w.db.IterateWebhooks(func(id string, wh *db.Webhook) error {
+		if wh.Disabled {
+			return nil
+		}
+		webhooks[id] = wh
+		for _, a := range wh.Addresses {
+			addrDesc, err := d.w.chainParser.GetAddrDescFromAddress(a)
+			if err != nil {
+				glog.Error("webhook ", id, " address ", a, ": ", err)
+				continue
+			}
+			addrHooks[string(addrDesc)] = append(addrHooks[string(addrDesc)], id)
+		}
+		return nil
+	}); err != nil {
+		glog.Error("IterateWebhooks: ", err)
+		return
+	}
+	d.version = version
+	d.webhooks = webhooks
+	d.addrHooks = addrHooks
+	glog.Info("webhook dispatcher: loaded ", len(webhooks), " webhooks watching ", len(addrHooks), " addresses")
+	if len(webhooks) > 0 && !d.started {
+		for i := 0; i < d.workers; i++ {
+			go d.sender.run()
+		}
+		d.started = true
+	}
+}
+
+func (d *WebhookDispatcher) enqueue(webhook string, p *WebhookPayload) {
+	p.ID = newWebhookDeliveryID()
+	p.Webhook = webhook
+	p.Created = time.Now().Unix()
+	payload, err := json.Marshal(p)
+	if err != nil {
+		glog.Error("webhook ", webhook, " payload: ", err)
+		return
+	}
+	d.sender.schedule(&webhookDelivery{
+		id:      p.ID,
+		webhook: webhook,
+		event:   p.Event,
+		payload: payload,
+		created: p.Created,
+	})
+}
+
+// getVinAddrDesc returns the address descriptor of the output spent by a bitcoin type input from the index
+func (d *WebhookDispatcher) getVinAddrDesc(txid string, vout uint32) bchain.AddressDescriptor {
+	ta, err := d.w.db.GetTxAddresses(txid)
+	if err != nil || ta == nil || int(vout) >= len(ta.Outputs) {
+		return nil
+	}
+	return ta.Outputs[vout].AddrDesc
+}
+
+// matchTx returns the watched address descriptors involved in the transaction
+func (d *WebhookDispatcher) matchTx(vins []bchain.MempoolVin, vouts []bchain.Vout, tokenTransfers bchain.TokenTransfers) map[string]struct{} {
+	matched := make(map[string]struct{})
+	matchAddrDesc := func(addrDesc bchain.AddressDescriptor) {
+		if _, found := d.addrHooks[string(addrDesc)]; found && len(addrDesc) > 0 {
+			matched[string(addrDesc)] = struct{}{}
+		}
+	}
+	matchAddress := func(address string) {
+		if addrDesc, err := d.w.chainParser.GetAddrDescFromAddress(address); err == nil {
+			matchAddrDesc(addrDesc)
+		}
+	}
+	for i := range vins {
+		vin := &vins[i]
+		if len(vin.AddrDesc) > 0 {
+			matchAddrDesc(vin.AddrDesc)
+		} else if d.w.chainType == bchain.ChainBitcoinType {
+			if vin.Txid != "" {
+				matchAddrDesc(d.getVinAddrDesc(vin.Txid, vin.Vout))
+			}
+		} else if len(vin.Addresses) > 0 {
+			matchAddress(vin.Addresses[0])
+		}
+	}
+	for i := range vouts {
+		if addrDesc, err := d.w.chainParser.GetAddrDescFromVout(&vouts[i]); err == nil {
+			matchAddrDesc(addrDesc)
+		}
+	}
+	for i := range tokenTransfers {
+		matchAddress(tokenTransfers[i].From)
+		matchAddress(tokenTransfers[i].To)
+	}
+	return matched
+}
+
+// addressOfAddrDesc returns the address of a watched address descriptor
+func (d *WebhookDispatcher) addressOfAddrDesc(addrDesc string) string {
+	addresses, _, err := d.w.chainParser.GetAddressesFromAddrDesc(bchain.AddressDescriptor(addrDesc))
+	if err != nil || len(addresses) == 0 {
+		return ""
+	}
+	return addresses[0]
+}
+
+func (d *WebhookDispatcher) processMempoolTx(mtx *bchain.MempoolTx) {
+	if len(d.addrHooks) == 0 {
+		return
+	}
+	matched := d.matchTx(mtx.Vin, mtx.Vout, mtx.TokenTransfers)
+	var tx *Tx
+	for addrDesc := range matched {
+		address := d.addressOfAddrDesc(addrDesc)
+		for _, id := range d.addrHooks[addrDesc] {
+			if !hasWebhookEvent(d.webhooks[id], WebhookEventAddress) {
+				continue
+			}
+			if tx == nil {
+				var err error
+				if tx, err = d.w.GetTransactionFromMempoolTx(mtx); err != nil {
+					glog.Error("GetTransactionFromMempoolTx error ", err, " for ", mtx.Txid)
+					return
+				}
+			}
+			d.enqueue(id, &WebhookPayload{Event: WebhookEventAddress, Address: address, Tx: tx})
+		}
+	}
+}
+
+func (d *WebhookDispatcher) processBlock(block *bchain.Block) {
+	if len(d.addrHooks) > 0 {
+		for _, tx := range block.Txs {
+			// the copy of the transaction is modified, the block is shared by all callbacks
+			d.processBlockTx(&tx, block)
+		}
+	}
+	milestones := d.confirmations.advance(block.Height, func(webhook string) []uint32 {
+		if wh := d.webhooks[webhook]; wh != nil {
+			return wh.Confirmations
+		}
+		return nil
+	})
+	for i := range milestones {
+		m := &milestones[i]
+		d.enqueue(m.webhook, &WebhookPayload{
+			Event:         WebhookEventConfirmations,
+			Address:       m.address,
+			Txid:          m.txid,
+			BlockHeight:   m.height,
+			Confirmations: m.confirmations,
+		})
+	}
+}
+
+func (d *WebhookDispatcher) processBlockTx(btx *bchain.Tx, block *bchain.Block) {
+	var tokenTransfers bchain.TokenTransfers
+	if d.w.chainType == bchain.ChainEthereumType {
+		tokenTransfers, _ = d.w.chainParser.EthereumTypeGetTokenTransfersFromTx(btx)
+	}
+	vins := make([]bchain.MempoolVin, len(btx.Vin))
+	for i := range btx.Vin {
+		vins[i] = bchain.MempoolVin{Vin: btx.Vin[i]}
+	}
+	matched := d.matchTx(vins, btx.Vout, tokenTransfers)
+	var tx *Tx
+	for addrDesc := range matched {
+		address := d.addressOfAddrDesc(addrDesc)
+		for _, id := range d.addrHooks[addrDesc] {
+			wh := d.webhooks[id]
+			if hasWebhookEvent(wh, WebhookEventConfirmations) {
+				d.confirmations.track(id, address, btx.Txid, block.Height)
+			}
+			if !hasWebhookEvent(wh, WebhookEventAddress) {
+				continue
+			}
+			if tx == nil {
+				if btx.Confirmations == 0 {
+					btx.Confirmations = 1
+					btx.Blocktime = block.Time
+					btx.Time = block.Time
+				}
+				var err error
+				if tx, err = d.w.GetTransactionFromBchainTx(btx, int(block.Height), false, false, nil); err != nil {
+					glog.Error("GetTransactionFromBchainTx error ", err, " for ", btx.Txid)
+					return
+				}
+			}
+			d.enqueue(id, &WebhookPayload{Event: WebhookEventAddress, Address: address, BlockHeight: block.Height, Tx: tx})
+		}
+	}
+}
+
+func (d *WebhookDispatcher) processReorg(reorg *bchain.Reorg) {
+	d.confirmations.disconnect(reorg.ForkHeight)
+	for id, wh := range d.webhooks {
+		if hasWebhookEvent(wh, WebhookEventReorg) {
+			d.enqueue(id, &WebhookPayload{Event: WebhookEventReorg, BlockHeight: reorg.ForkHeight, Reorg: reorg})
+		}
+	}
+}
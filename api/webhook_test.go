@@ -0,0 +1,152 @@
+//go:build unittest
+
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trezor/blockbook/db"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// HMAC-SHA256 of "1700000000.{}" keyed by "secret"
+	got := SignWebhookPayload("secret", 1700000000, []byte("{}"))
+	if want := "b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"; got != want {
+		t.Fatalf("SignWebhookPayload() = %v, want %v", got, want)
+	}
+	if SignWebhookPayload("other", 1700000000, []byte("{}")) == got ||
+		SignWebhookPayload("secret", 1700000001, []byte("{}")) == got ||
+		SignWebhookPayload("secret", 1700000000, []byte("[]")) == got {
+		t.Error("SignWebhookPayload() does not depend on all of secret, timestamp and body")
+	}
+}
+
+// webhookStandIn is a local receiver of the webhooks, failing the first requests
+type webhookStandIn struct {
+	mux       sync.Mutex
+	fail      int
+	requests  int
+	delivered chan []byte
+	t         *testing.T
+}
+
+func (s *webhookStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	timestamp, err := strconv.ParseInt(r.Header.Get("X-Blockbook-Timestamp"), 10, 64)
+	if err != nil {
+		s.t.Errorf("invalid timestamp header %v", r.Header.Get("X-Blockbook-Timestamp"))
+	}
+	if got, want := r.Header.Get("X-Blockbook-Signature"), "sha256="+SignWebhookPayload("secret", timestamp, body); got != want {
+		s.t.Errorf("signature = %v, want %v", got, want)
+	}
+	if got := r.Header.Get("X-Blockbook-Delivery"); got != "d1" {
+		s.t.Errorf("delivery = %v, want d1", got)
+	}
+	s.mux.Lock()
+	s.requests++
+	fail := s.requests <= s.fail
+	s.mux.Unlock()
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	s.delivered <- body
+}
+
+func testWebhookSender(url string, maxAttempts int, deadLetters chan *db.WebhookDeadLetter) *webhookSender {
+	return &webhookSender{
+		client:      &http.Client{Timeout: time.Second},
+		maxAttempts: maxAttempts,
+		retryDelay:  time.Millisecond,
+		queue:       make(chan *webhookDelivery, 10),
+		getWebhook: func(id string) (*db.Webhook, error) {
+			return &db.Webhook{URL: url, Secret: "secret", Events: []string{WebhookEventReorg}}, nil
+		},
+		storeDeadLetter: func(id string, dl *db.WebhookDeadLetter) error {
+			deadLetters <- dl
+			return nil
+		},
+	}
+}
+
+func TestWebhookSender(t *testing.T) {
+	standIn := &webhookStandIn{fail: 2, delivered: make(chan []byte, 1), t: t}
+	srv := httptest.NewServer(standIn)
+	defer srv.Close()
+	deadLetters := make(chan *db.WebhookDeadLetter, 1)
+
+	// the delivery succeeds after two failed attempts
+	s := testWebhookSender(srv.URL, 3, deadLetters)
+	go s.run()
+	s.schedule(&webhookDelivery{id: "d1", webhook: "w1", event: WebhookEventReorg, payload: []byte(`{"event":"reorg"}`)})
+	select {
+	case body := <-standIn.delivered:
+		if string(body) != `{"event":"reorg"}` {
+			t.Errorf("delivered %s", body)
+		}
+	case dl := <-deadLetters:
+		t.Fatalf("unexpected dead letter %+v", dl)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+	close(s.queue)
+
+	// the delivery becomes a dead letter after the maximal number of attempts
+	standIn.requests = 0
+	standIn.fail = 10
+	s = testWebhookSender(srv.URL, 2, deadLetters)
+	go s.run()
+	s.schedule(&webhookDelivery{id: "d1", webhook: "w1", event: WebhookEventReorg, payload: []byte(`{}`), created: 1})
+	select {
+	case dl := <-deadLetters:
+		want := &db.WebhookDeadLetter{Webhook: "w1", Event: WebhookEventReorg, Payload: []byte(`{}`), Attempts: 2, LastError: "Status 503", Created: 1, LastAttempt: dl.LastAttempt}
+		if !reflect.DeepEqual(dl, want) {
+			t.Errorf("dead letter %+v, want %+v", dl, want)
+		}
+	case <-standIn.delivered:
+		t.Fatal("unexpected delivery")
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead letter not stored")
+	}
+	close(s.queue)
+}
+
+func TestWebhookConfirmations(t *testing.T) {
+	milestones := func(webhook string) []uint32 {
+		if webhook == "w1" {
+			return []uint32{1, 3}
+		}
+		return []uint32{2}
+	}
+	var c webhookConfirmations
+	c.track("w1", "addr1", "tx1", 100)
+	c.track("w2", "addr2", "tx2", 101)
+	got := c.advance(101, milestones)
+	want := []webhookMilestone{{webhookTxWatch{webhook: "w1", address: "addr1", txid: "tx1", height: 100}, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("advance(101) = %+v, want %+v", got, want)
+	}
+	// the block 101 is disconnected, tx2 is tracked again when it is mined in the new chain
+	c.disconnect(100)
+	c.track("w2", "addr2", "tx2", 102)
+	got = c.advance(102, milestones)
+	want = []webhookMilestone{{webhookTxWatch{webhook: "w1", address: "addr1", txid: "tx1", height: 100, last: 1}, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("advance(102) = %+v, want %+v", got, want)
+	}
+	got = c.advance(103, milestones)
+	want = []webhookMilestone{{webhookTxWatch{webhook: "w2", address: "addr2", txid: "tx2", height: 102}, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("advance(103) = %+v, want %+v", got, want)
+	}
+	if len(c.watches) != 0 {
+		t.Errorf("watches %+v, want none after all milestones were reached", c.watches)
+	}
+}
@@ -34,6 +34,7 @@ type Worker struct {
 	is                *common.InternalState
 	fiatRates         *fiat.FiatRates
 	metrics           *common.Metrics
+	utxoLocker        *UtxoLocker
 }
 
 var getTickersForTimestamps = func(fr *fiat.FiatRates, timestamps []int64, vsCurrency string, token string) (*[]*common.CurrencyRatesTicker, error) {
@@ -242,6 +243,7 @@ func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool
 		return nil, err
 	}
 	tx.AddressAliases = w.getAddressAliases(addresses)
+	tx.Lifecycle = w.getTxLifecycle(txid)
 	return tx, nil
 }
 
@@ -255,6 +257,14 @@ func (w *Worker) getTransaction(txid string, spendingTxs bool, specificJSON bool
 	bchainTx, height, err := w.txCache.GetTransaction(txid)
 	if err != nil {
 		if err == bchain.ErrTxNotFound {
+			if t, ok := w.mempool.(bchain.MempoolReplacementTracker); ok {
+				if by := t.GetReplacedBy(txid); by != "" {
+					return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found, it was replaced by '%v'", txid, by), true)
+				}
+			}
+			if l := w.getTxLifecycle(txid); l != nil && l.Eviction != "" {
+				return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found, it was evicted from the mempool (%v)", txid, l.Eviction), true)
+			}
 			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
 		}
 		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
@@ -291,7 +301,10 @@ func (w *Worker) getConfirmationETA(tx *Tx) (int64, uint32) {
 			etaBlocks = 1
 		} else {
 			var txFeePerKB int64
-			if tx.VSize > 0 {
+			// the effective fee rate takes into account the unconfirmed ancestors and descendants (CPFP)
+			if tx.EffectiveFeePerKb > 0 {
+				txFeePerKB = tx.EffectiveFeePerKb
+			} else if tx.VSize > 0 {
 				txFeePerKB = 1000 * tx.FeesSat.AsInt64() / int64(tx.VSize)
 			} else if tx.Size > 0 {
 				txFeePerKB = 1000 * tx.FeesSat.AsInt64() / int64(tx.Size)
@@ -559,6 +572,12 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 	}
 	if bchainTx.Confirmations == 0 {
 		r.Blocktime = int64(w.mempool.GetTransactionTime(bchainTx.Txid))
+		if t, ok := w.mempool.(bchain.MempoolReplacementTracker); ok {
+			if rel := t.GetMempoolTxRelations(bchainTx.Txid); rel != nil {
+				r.Replaces = rel.Replaces
+				r.EffectiveFeePerKb = rel.EffectiveFeePerKb
+			}
+		}
 		r.ConfirmationETASeconds, r.ConfirmationETABlocks = w.getConfirmationETA(r)
 	}
 	return r, nil
@@ -841,19 +860,10 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 		}
 	} else {
 		callback = func(txid string, height uint32, indexes []int32) error {
-			for _, index := range indexes {
-				vout := index
-				if vout < 0 {
-					vout = ^vout
-				}
-				if (filter.Vout == AddressFilterVoutInputs && index < 0) ||
-					(filter.Vout == AddressFilterVoutOutputs && index >= 0) ||
-					(vout == int32(filter.Vout)) {
-					txids = append(txids, txid)
-					if len(txids) >= maxResults {
-						return &db.StopIteration{}
-					}
-					break
+			if addressFilterMatch(filter, indexes) {
+				txids = append(txids, txid)
+				if len(txids) >= maxResults {
+					return &db.StopIteration{}
 				}
 			}
 			return nil
@@ -1189,6 +1199,7 @@ type ethereumTypeAddressData struct {
 	tokens               Tokens
 	contractInfo         *bchain.ContractInfo
 	nonce                string
+	activity             *db.AddrActivity
 	nonContractTxs       int
 	internalTxs          int
 	totalResults         int
@@ -1335,6 +1346,14 @@ func (w *Worker) getEthereumTypeAddressBalances(addrDesc bchain.AddressDescripto
 		}
 		d.nonContractTxs = int(ca.NonContractTxs)
 		d.internalTxs = int(ca.InternalTxs)
+		d.activity, err = w.db.GetAddrDescActivity(addrDesc)
+		if err != nil {
+			return nil, nil, err
+		}
+		// skip the scan of the transactions if the requested confirmed range is outside of the activity of the address
+		if d.activity != nil && ((filter.ToHeight != 0 && filter.ToHeight < d.activity.FirstHeight) || filter.FromHeight > d.activity.LastHeight) {
+			filter.Vout = AddressFilterVoutQueryNotNecessary
+		}
 	} else {
 		// addresses without any normal transactions can have internal transactions that were not processed and therefore balance
 		if b != nil {
@@ -1516,6 +1535,10 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	if page < 0 {
 		page = 0
 	}
+	cursor, useCursor, err := filterCursor(filter)
+	if err != nil {
+		return nil, err
+	}
 	var (
 		ba                       *db.AddrBalance
 		txm                      []string
@@ -1588,7 +1611,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 					} else {
 						uBalSending.Add(&uBalSending, tx.getAddrVinValue(addrDesc))
 					}
-					if page == 0 {
+					if page == 0 && !useCursor {
 						if option == AccountDetailsTxidHistory {
 							txids = append(txids, tx.Txid)
 						} else if option >= AccountDetailsTxHistoryLight {
@@ -1602,7 +1625,17 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	}
 	// get tx history if requested by option or check mempool if there are some transactions for a new address
 	if option >= AccountDetailsTxidHistory && filter.Vout != AddressFilterVoutQueryNotNecessary {
-		txc, err := w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
+		var (
+			txc     []string
+			cursors []txCursor
+			more    bool
+		)
+		// the first page and the cursor pages are read after the cursor, the other pages by page number
+		if page == 0 || useCursor {
+			txc, cursors, more, err = w.getAddressTxidsAfterCursor(addrDesc, filter, cursor, txsOnPage)
+		} else {
+			txc, err = w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
+		}
 		if err != nil {
 			return nil, errors.Annotatef(err, "getAddressTxids %v false", addrDesc)
 		}
@@ -1611,12 +1644,28 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			return nil, errors.Annotatef(err, "GetBestBlock")
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if useCursor {
+			pg = Paging{ItemsOnPage: txsOnPage}
+			from, to = 0, len(txc)
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
+			}
+		}
+		if cursors != nil {
+			// the unconfirmed txs listed on the first page take place of the confirmed ones
+			unconfirmedListed := len(txids) + len(txs)
+			if listed := min(len(txc), max(txsOnPage-unconfirmedListed, 0)); listed < len(txc) || more {
+				if listed > 0 {
+					pg.NextCursor = cursors[listed-1].String()
+				} else {
+					pg.NextCursor = cursor.String()
+				}
 			}
 		}
 		for i := from; i < to; i++ {
@@ -1690,6 +1739,12 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		StakingPools:          ed.stakingPools,
 		ChainExtraData:        accountChainExtraData,
 	}
+	if ed.activity != nil {
+		r.FirstSeenHeight = ed.activity.FirstHeight
+		r.FirstSeenTime = ed.activity.FirstTime
+		r.LastSeenHeight = ed.activity.LastHeight
+		r.LastSeenTime = ed.activity.LastTime
+	}
 	// keep address backward compatible, set deprecated Erc20Contract value if ERC20 token
 	if ed.contractInfo != nil && ed.contractInfo.Standard == bchain.ERC20TokenStandard {
 		r.Erc20Contract = ed.contractInfo
@@ -1934,7 +1989,7 @@ func (w *Worker) waitForBackendSync() {
 	}
 }
 
-func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrBalance, onlyConfirmed bool, onlyMempool bool) (Utxos, error) {
+func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrBalance, onlyConfirmed bool, onlyMempool bool, lf UtxoLockFilter) (Utxos, error) {
 	w.waitForBackendSync()
 	var err error
 	utxos := make(Utxos, 0, 8)
@@ -2051,11 +2106,11 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 			}
 		}
 	}
-	return utxos, nil
+	return w.utxoLocker.filter(utxos, lf), nil
 }
 
-// GetAddressUtxo returns unspent outputs for given address
-func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, error) {
+// GetAddressUtxo returns unspent outputs for given address, the outputs locked by other owners than the owner of the filter are flagged or excluded
+func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool, lf UtxoLockFilter) (Utxos, error) {
 	if w.chainType != bchain.ChainBitcoinType {
 		return nil, NewAPIError("Not supported", true)
 	}
@@ -2064,7 +2119,7 @@ func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, erro
 	if err != nil {
 		return nil, NewAPIError(fmt.Sprintf("Invalid address '%v', %v", address, err), true)
 	}
-	r, err := w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false)
+	r, err := w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false, lf)
 	if err != nil {
 		return nil, err
 	}
@@ -2150,6 +2205,10 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 	feesPerKb := make([]int64, 0, len(bi.Txids))
 	totalFeesSat := big.NewInt(0)
 	averageFeePerKb := int64(0)
+	// the confirmation delays of the transactions with a recorded lifecycle
+	var delaysSeconds []int64
+	var delaysBlocks []int64
+	predictedETA, withinETA := 0, 0
 
 	for _, txid := range bi.Txids {
 		// Get a raw JSON with transaction details, including size, vsize, hex
@@ -2204,6 +2263,25 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 		feePerKb := int64(float64(feeSat.Int64()) / float64(txSize) * 1000)
 		averageFeePerKb += feePerKb
 		feesPerKb = append(feesPerKb, feePerKb)
+
+		l, err := w.db.GetTxLifecycle(txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTxLifecycle")
+		}
+		if l != nil {
+			var blocks int64
+			if bi.Height > l.FirstSeenHeight {
+				blocks = int64(bi.Height - l.FirstSeenHeight)
+			}
+			delaysSeconds = append(delaysSeconds, max(bi.Time-l.FirstSeen, 0))
+			delaysBlocks = append(delaysBlocks, blocks)
+			if l.ETABlocks > 0 {
+				predictedETA++
+				if blocks <= int64(l.ETABlocks) {
+					withinETA++
+				}
+			}
+		}
 	}
 
 	var deciles [11]int64
@@ -2228,13 +2306,27 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 	glog.Info("GetFeeStats ", bid, " (", len(feesPerKb), " txs), ", time.Since(start))
 
 	return &FeeStats{
-		TxCount:         len(feesPerKb),
-		AverageFeePerKb: averageFeePerKb,
-		TotalFeesSat:    (*Amount)(totalFeesSat),
-		DecilesFeePerKb: deciles,
+		TxCount:                   len(feesPerKb),
+		AverageFeePerKb:           averageFeePerKb,
+		TotalFeesSat:              (*Amount)(totalFeesSat),
+		DecilesFeePerKb:           deciles,
+		FirstSeenTxCount:          len(delaysSeconds),
+		MedianConfirmationSeconds: medianInt64(delaysSeconds),
+		MedianConfirmationBlocks:  uint32(medianInt64(delaysBlocks)),
+		PredictedETATxCount:       predictedETA,
+		WithinETATxCount:          withinETA,
 	}, nil
 }
 
+// medianInt64 returns the median of the values, 0 for no values, the values are sorted in place
+func medianInt64(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values[len(values)/2]
+}
+
 // GetBlock returns paged data about block
 func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 	start := time.Now()
@@ -2539,6 +2631,112 @@ func (w *Worker) GetMempool(page int, itemsOnPage int) (*MempoolTxids, error) {
 	return r, nil
 }
 
+// GetMempoolFeeHistogram returns the fee rate histogram and the projected blocks of the mempool
+func (w *Worker) GetMempoolFeeHistogram() (*MempoolFeeHistogram, error) {
+	p, ok := w.mempool.(bchain.MempoolFeeStatsProvider)
+	if !ok {
+		return nil, NewAPIError("Mempool fee histogram is not supported", true)
+	}
+	stats, err := p.GetMempoolFeeStats()
+	if err == bchain.ErrNotSupportedByMempool {
+		return nil, NewAPIError("Mempool fee histogram is not supported", true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, NewAPIError("Mempool fee histogram is not available yet", true)
+	}
+	r := &MempoolFeeHistogram{
+		Time:            stats.Time,
+		TxCount:         stats.TxCount,
+		VSize:           stats.VSize,
+		TotalFees:       (*Amount)(big.NewInt(stats.TotalFees)),
+		Histogram:       make([]MempoolFeeHistogramBucket, len(stats.Histogram)),
+		ProjectedBlocks: make([]MempoolProjectedBlock, len(stats.ProjectedBlocks)),
+	}
+	for i := range stats.Histogram {
+		h := &stats.Histogram[i]
+		r.Histogram[i] = MempoolFeeHistogramBucket{
+			FeePerKb:  h.FeePerKb,
+			TxCount:   h.TxCount,
+			VSize:     h.VSize,
+			TotalFees: (*Amount)(big.NewInt(h.TotalFees)),
+		}
+	}
+	for i := range stats.ProjectedBlocks {
+		b := &stats.ProjectedBlocks[i]
+		r.ProjectedBlocks[i] = MempoolProjectedBlock{
+			TxCount:         b.TxCount,
+			VSize:           b.VSize,
+			TotalFees:       (*Amount)(big.NewInt(b.TotalFees)),
+			MinFeePerKb:     b.MinFeePerKb,
+			MedianFeePerKb:  b.MedianFeePerKb,
+			MaxFeePerKb:     b.MaxFeePerKb,
+			FeeRangePerKb:   b.FeeRangePerKb,
+			ReachesMaxVSize: b.ReachesMaxVSize,
+		}
+	}
+	return r, nil
+}
+
+// GetMempoolPackage returns the in-mempool ancestors and descendants of an unconfirmed transaction
+func (w *Worker) GetMempoolPackage(txid string) (*MempoolPackage, error) {
+	p, ok := w.mempool.(bchain.MempoolPackageProvider)
+	if !ok {
+		return nil, NewAPIError("Mempool package is not supported", true)
+	}
+	mp, err := p.GetMempoolPackage(txid)
+	if err == bchain.ErrNotSupportedByMempool {
+		return nil, NewAPIError("Mempool package is not supported", true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mp == nil {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found in mempool", txid), true)
+	}
+	r := &MempoolPackage{
+		Tx:          mempoolPackageTx(&mp.Tx),
+		Ancestors:   make([]MempoolPackageTx, len(mp.Ancestors)),
+		Descendants: make([]MempoolPackageTx, len(mp.Descendants)),
+	}
+	for i := range mp.Ancestors {
+		r.Ancestors[i] = mempoolPackageTx(&mp.Ancestors[i])
+	}
+	for i := range mp.Descendants {
+		r.Descendants[i] = mempoolPackageTx(&mp.Descendants[i])
+	}
+	r.AncestorCount, r.AncestorVSize, r.AncestorFeesSat, r.AncestorFeePerKb = mempoolPackageAggregate(&mp.Tx, mp.Ancestors)
+	r.DescendantCount, r.DescendantVSize, r.DescendantFeesSat, r.DescendantFeePerKb = mempoolPackageAggregate(&mp.Tx, mp.Descendants)
+	return r, nil
+}
+
+func mempoolPackageTx(t *bchain.MempoolPackageTx) MempoolPackageTx {
+	return MempoolPackageTx{
+		Txid:              t.Txid,
+		VSize:             t.VSize,
+		FeesSat:           (*Amount)(big.NewInt(t.Fee)),
+		EffectiveFeePerKb: t.EffectiveFeePerKb,
+		Depends:           t.Depends,
+		SpentBy:           t.SpentBy,
+	}
+}
+
+// mempoolPackageAggregate returns the count, vsize, fees and fee rate of the transaction together with its relatives
+func mempoolPackageAggregate(tx *bchain.MempoolPackageTx, relatives []bchain.MempoolPackageTx) (int, int64, *Amount, int64) {
+	vsize, fees := tx.VSize, tx.Fee
+	for i := range relatives {
+		vsize += relatives[i].VSize
+		fees += relatives[i].Fee
+	}
+	var feePerKb int64
+	if vsize > 0 {
+		feePerKb = fees * 1000 / vsize
+	}
+	return len(relatives) + 1, vsize, (*Amount)(big.NewInt(fees)), feePerKb
+}
+
 type bitcoinTypeEstimatedFee struct {
 	timestamp int64
 	fee       big.Int
@@ -397,6 +397,10 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	if page < 0 {
 		page = 0
 	}
+	cursor, useCursor, err := filterCursor(filter)
+	if err != nil {
+		return nil, err
+	}
 	type mempoolMap struct {
 		tx          *Tx
 		inputOutput byte
@@ -473,7 +477,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 						uBalSat.Add(&uBalSat, tx.getAddrVoutValue(ad.addrDesc))
 						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(ad.addrDesc))
 						// mempool txs are returned only on the first page, uniquely and filtered
-						if page == 0 && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
+						if page == 0 && !useCursor && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
 							mempoolEntries = append(mempoolEntries, bchain.MempoolTxidEntry{Txid: txid.txid, Time: uint32(tx.Blocktime)})
 						}
 					}
@@ -520,14 +524,23 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			totalResults = -1
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if useCursor {
+			pg = Paging{ItemsOnPage: txsOnPage}
+			from = xpubTxidsCursorStart(txc, cursor)
+			to = min(from+txsOnPage, len(txc))
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
 			}
 		}
+		if (page == 0 || useCursor) && to > from && to < len(txc) {
+			pg.NextCursor = xpubTxidCursor(txc, to-1).String()
+		}
 		// get confirmed transactions
 		for i := from; i < to; i++ {
 			xpubTxid := &txc[i]
@@ -608,8 +621,8 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	return &addr, nil
 }
 
-// GetXpubUtxo returns unspent outputs for given xpub
-func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, error) {
+// GetXpubUtxo returns unspent outputs for given xpub, the outputs locked by other owners than the owner of the filter are flagged or excluded
+func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int, lf UtxoLockFilter) (Utxos, error) {
 	start := time.Now()
 	xd, err := w.chainParser.ParseXpub(xpub)
 	if err != nil {
@@ -622,6 +635,15 @@ func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, e
 	if err != nil {
 		return nil, err
 	}
+	r, err := w.getXpubDataUtxo(data, onlyConfirmed, lf)
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("GetXpubUtxo ", xpub[:xpubLogPrefix], ", cache ", inCache, ", ", len(r), " utxos,  ", time.Since(start))
+	return r, nil
+}
+
+func (w *Worker) getXpubDataUtxo(data *xpubData, onlyConfirmed bool, lf UtxoLockFilter) (Utxos, error) {
 	r := make(Utxos, 0, 8)
 	for ci, da := range data.addresses {
 		for i := range da {
@@ -633,7 +655,7 @@ func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, e
 				}
 				onlyMempool = true
 			}
-			utxos, err := w.getAddrDescUtxo(ad.addrDesc, ad.balance, onlyConfirmed, onlyMempool)
+			utxos, err := w.getAddrDescUtxo(ad.addrDesc, ad.balance, onlyConfirmed, onlyMempool, lf)
 			if err != nil {
 				return nil, err
 			}
@@ -649,7 +671,6 @@ func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, e
 		}
 	}
 	sort.Stable(r)
-	glog.Info("GetXpubUtxo ", xpub[:xpubLogPrefix], ", cache ", inCache, ", ", len(r), " utxos,  ", time.Since(start))
 	return r, nil
 }
 
@@ -55,6 +55,11 @@ func (b *BaseChain) EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int
 	return nil, errors.New("not supported")
 }
 
+// EthereumTypeGetBalanceAtBlock is not supported
+func (b *BaseChain) EthereumTypeGetBalanceAtBlock(addrDesc AddressDescriptor, blockNumber *big.Int) (*big.Int, error) {
+	return nil, errors.New("not supported")
+}
+
 // EthereumTypeGetNonce is not supported
 func (b *BaseChain) EthereumTypeGetNonce(addrDesc AddressDescriptor) (uint64, error) {
 	return 0, errors.New("not supported")
@@ -15,12 +15,25 @@ type txEntry struct {
 	addrIndexes []addrIndex
 	time        uint32
 	filter      string
+	// vsize and fee of the transaction, vsize is zero if the fee is not known
+	vsize int64
+	fee   int64
+	// effective fee rate including the unconfirmed ancestors and descendants, computed after each resync
+	effectiveFeePerKb int64
+	// outpoints spent by the transaction, the number of its outputs and the transactions it replaced
+	inputs   []Outpoint
+	outputs  int32
+	replaces []string
 }
 
 type txidio struct {
-	txid   string
-	io     []addrIndex
-	filter string
+	txid    string
+	io      []addrIndex
+	filter  string
+	vsize   int64
+	fee     int64
+	inputs  []Outpoint
+	outputs int32
 }
 
 // BaseMempool is mempool base handle
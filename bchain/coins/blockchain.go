@@ -314,6 +314,21 @@ func (c *blockChainWithMetrics) EstimateFee(blocks int) (v big.Int, err error) {
 	return c.b.EstimateFee(blocks)
 }
 
+func (c *blockChainWithMetrics) GetFeeEstimates(blocks []int) []bchain.FeeEstimate {
+	if p, ok := c.b.(bchain.FeeEstimatesProvider); ok {
+		return p.GetFeeEstimates(blocks)
+	}
+	return nil
+}
+
+func (c *blockChainWithMetrics) ValidateRawTransaction(tx string) (v *bchain.TxValidationResult, err error) {
+	if p, ok := c.b.(bchain.TransactionValidator); ok {
+		defer func(s time.Time) { c.observeRPCLatency("ValidateRawTransaction", s, err) }(time.Now())
+		return p.ValidateRawTransaction(tx)
+	}
+	return nil, nil
+}
+
 func (c *blockChainWithMetrics) LongTermFeeRate() (v *bchain.LongTermFeeRate, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("LongTermFeeRate", s, err) }(time.Now())
 	return c.b.LongTermFeeRate()
@@ -338,6 +353,11 @@ func (c *blockChainWithMetrics) EthereumTypeGetBalance(addrDesc bchain.AddressDe
 	return c.b.EthereumTypeGetBalance(addrDesc)
 }
 
+func (c *blockChainWithMetrics) EthereumTypeGetBalanceAtBlock(addrDesc bchain.AddressDescriptor, blockNumber *big.Int) (v *big.Int, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetBalanceAtBlock", s, err) }(time.Now())
+	return c.b.EthereumTypeGetBalanceAtBlock(addrDesc, blockNumber)
+}
+
 func (c *blockChainWithMetrics) EthereumTypeGetNonce(addrDesc bchain.AddressDescriptor) (v uint64, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetNonce", s, err) }(time.Now())
 	return c.b.EthereumTypeGetNonce(addrDesc)
@@ -484,6 +504,46 @@ func (c *mempoolWithMetrics) GetTxidFilterEntries(filterScripts string, fromTime
 	return c.mempool.GetTxidFilterEntries(filterScripts, fromTimestamp)
 }
 
+func (c *mempoolWithMetrics) GetMempoolFeeStats() (*bchain.MempoolFeeStats, error) {
+	if p, ok := c.mempool.(bchain.MempoolFeeStatsProvider); ok {
+		return p.GetMempoolFeeStats()
+	}
+	return nil, bchain.ErrNotSupportedByMempool
+}
+
+func (c *mempoolWithMetrics) GetMempoolTxRelations(txid string) *bchain.MempoolTxRelations {
+	if t, ok := c.mempool.(bchain.MempoolReplacementTracker); ok {
+		return t.GetMempoolTxRelations(txid)
+	}
+	return nil
+}
+
+func (c *mempoolWithMetrics) GetReplacedBy(txid string) string {
+	if t, ok := c.mempool.(bchain.MempoolReplacementTracker); ok {
+		return t.GetReplacedBy(txid)
+	}
+	return ""
+}
+
+func (c *mempoolWithMetrics) SetOnTxRemoved(f bchain.OnTxRemovedFunc) {
+	if t, ok := c.mempool.(bchain.MempoolReplacementTracker); ok {
+		t.SetOnTxRemoved(f)
+	}
+}
+
+func (c *mempoolWithMetrics) SetOnDoubleSpend(f bchain.OnDoubleSpendFunc) {
+	if t, ok := c.mempool.(bchain.MempoolReplacementTracker); ok {
+		t.SetOnDoubleSpend(f)
+	}
+}
+
+func (c *mempoolWithMetrics) GetMempoolPackage(txid string) (*bchain.MempoolPackage, error) {
+	if p, ok := c.mempool.(bchain.MempoolPackageProvider); ok {
+		return p.GetMempoolPackage(txid)
+	}
+	return nil, bchain.ErrNotSupportedByMempool
+}
+
 func (c *blockChainWithMetrics) ResolveENS(name string) (*bchain.ENSResolution, error) {
 	if ensResolver, ok := c.b.(interface {
 		ResolveENS(string) (*bchain.ENSResolution, error)
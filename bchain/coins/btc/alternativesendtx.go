@@ -0,0 +1,97 @@
+package btc
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/common"
+)
+
+// alternativeSendTxProvider sends the transactions also to other backends of the same network,
+// so that they are propagated even if the own backend drops them
+type alternativeSendTxProvider struct {
+	urls   []string
+	client http.Client
+	chain  *BitcoinRPC
+}
+
+// newAlternativeSendTxProvider creates the provider if the backends are configured by the environment variable
+// <coin shortcut>_ALTERNATIVE_SENDTX_URLS, a comma separated list of the JSON-RPC urls with the credentials
+func newAlternativeSendTxProvider(chain *BitcoinRPC, coinShortcut string, rpcTimeout int) *alternativeSendTxProvider {
+	var urls []string
+	for _, u := range strings.Split(os.Getenv(strings.ToUpper(coinShortcut)+"_ALTERNATIVE_SENDTX_URLS"), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	glog.Infof("Using %d alternative send transaction backends", len(urls))
+	return &alternativeSendTxProvider{
+		urls:   urls,
+		client: http.Client{Timeout: time.Duration(rpcTimeout) * time.Second},
+		chain:  chain,
+	}
+}
+
+// sendRawTransaction sends the transaction to all the backends in parallel,
+// it returns the txid if at least one of the backends accepted it, otherwise the first error
+func (p *alternativeSendTxProvider) sendRawTransaction(tx string) (string, error) {
+	var wg sync.WaitGroup
+	txids := make([]string, len(p.urls))
+	errs := make([]error, len(p.urls))
+	for i := range p.urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			txids[i], errs[i] = p.call(p.urls[i], tx)
+			if errs[i] != nil {
+				glog.Warning("alternative sendrawtransaction to backend ", i, ": ", errs[i])
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := range txids {
+		if errs[i] == nil {
+			return txids[i], nil
+		}
+	}
+	return "", errs[0]
+}
+
+func (p *alternativeSendTxProvider) call(url string, tx string) (string, error) {
+	req := CmdSendRawTransaction{Method: "sendrawtransaction"}
+	req.Params = []string{tx}
+	// the marshaler can be replaced by the coins derived from bitcoin after the creation of the provider
+	httpData, err := p.chain.RPCMarshaler.Marshal(&req)
+	if err != nil {
+		return "", err
+	}
+	// the credentials in the url are used for the basic authentication
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(httpData))
+	if err != nil {
+		return "", err
+	}
+	httpRes, err := p.client.Do(httpReq)
+	if httpRes != nil {
+		defer httpRes.Body.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+	res := ResSendRawTransaction{}
+	if err = common.SafeDecodeResponseFromReader(httpRes.Body, &res); err != nil {
+		return "", errors.Errorf("%v %v", httpRes.Status, err)
+	}
+	if res.Error != nil {
+		return "", res.Error
+	}
+	return res.Result, nil
+}
@@ -12,6 +12,7 @@ import (
 	"unicode/utf8"
 
 	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
 	"github.com/juju/errors"
 	"github.com/martinboehm/btcd/blockchain"
 	"github.com/martinboehm/btcd/btcec"
@@ -455,7 +456,18 @@ func init() {
 
 // ParseXpub parses xpub (or xpub descriptor) and returns XpubDescriptor
 func (p *BitcoinLikeParser) ParseXpub(xpub string) (*bchain.XpubDescriptor, error) {
-	match := xpubDesriptorRegex.FindStringSubmatch(xpub)
+	desc, err := stripDescriptorChecksum(xpub)
+	if isMultisigDescriptor(desc) {
+		if err != nil {
+			return nil, err
+		}
+		return p.parseMultisigDescriptor(xpub, desc)
+	}
+	// the checksum of the single key descriptors was never verified, the clients may have stored descriptors with a wrong checksum
+	if err != nil {
+		glog.V(1).Info("ParseXpub ", desc, ": ", err)
+	}
+	match := xpubDesriptorRegex.FindStringSubmatch(desc)
 	if len(match) > changeSubexpIndex {
 		var descriptor bchain.XpubDescriptor
 		descriptor.XpubDescriptor = xpub
@@ -525,6 +537,9 @@ func (p *BitcoinLikeParser) ParseXpub(xpub string) (*bchain.XpubDescriptor, erro
 
 // DeriveAddressDescriptors derives address descriptors from given xpub for listed indexes
 func (p *BitcoinLikeParser) DeriveAddressDescriptors(descriptor *bchain.XpubDescriptor, change uint32, indexes []uint32) ([]bchain.AddressDescriptor, error) {
+	if len(descriptor.Keys) > 0 {
+		return p.deriveMultisigAddressDescriptors(descriptor, change, indexes)
+	}
 	ad := make([]bchain.AddressDescriptor, len(indexes))
 	changeExtKey, err := descriptor.ExtKey.(*hdkeychain.ExtendedKey).Derive(change)
 	if err != nil {
@@ -548,6 +563,13 @@ func (p *BitcoinLikeParser) DeriveAddressDescriptorsFromTo(descriptor *bchain.Xp
 	if toIndex <= fromIndex {
 		return nil, errors.New("toIndex<=fromIndex")
 	}
+	if len(descriptor.Keys) > 0 {
+		indexes := make([]uint32, toIndex-fromIndex)
+		for i := range indexes {
+			indexes[i] = fromIndex + uint32(i)
+		}
+		return p.deriveMultisigAddressDescriptors(descriptor, change, indexes)
+	}
 	changeExtKey, err := descriptor.ExtKey.(*hdkeychain.ExtendedKey).Derive(change)
 	if err != nil {
 		return nil, err
@@ -568,6 +590,9 @@ func (p *BitcoinLikeParser) DeriveAddressDescriptorsFromTo(descriptor *bchain.Xp
 
 // DerivationBasePath returns base path of xpub
 func (p *BitcoinLikeParser) DerivationBasePath(descriptor *bchain.XpubDescriptor) (string, error) {
+	if len(descriptor.Keys) > 0 {
+		return p.multisigDerivationBasePath(descriptor)
+	}
 	var c string
 	extKey := descriptor.ExtKey.(*hdkeychain.ExtendedKey)
 	cn := extKey.ChildNum()
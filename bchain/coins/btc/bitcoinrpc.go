@@ -34,6 +34,7 @@ type BitcoinRPC struct {
 	mempoolFilterScripts   string
 	mempoolUseZeroedKey    bool
 	alternativeFeeProvider alternativeFeeProviderInterface
+	alternativeSendTx      *alternativeSendTxProvider
 }
 
 // Configuration represents json config file
@@ -118,6 +119,7 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 		mempoolFilterScripts: c.MempoolFilterScripts,
 		mempoolUseZeroedKey:  c.MempoolFilterUseZeroedKey,
 	}
+	s.alternativeSendTx = newAlternativeSendTxProvider(s, c.CoinShortcut, c.RPCTimeout)
 
 	return s, nil
 }
@@ -169,6 +171,13 @@ func (b *BitcoinRPC) Initialize() error {
 			// disable AlternativeEstimateFee logic
 			b.alternativeFeeProvider = nil
 		}
+	} else if b.ChainConfig.AlternativeEstimateFee == "mempool" {
+		glog.Info("Using MempoolFee")
+		if b.alternativeFeeProvider, err = NewMempoolFee(b, b.ChainConfig.AlternativeEstimateFeeParams); err != nil {
+			glog.Error("MempoolFee error ", err, " Reverting to default estimateFee functionality")
+			// disable AlternativeEstimateFee logic
+			b.alternativeFeeProvider = nil
+		}
 	} else if len(b.ChainConfig.AlternativeEstimateFee) > 0 {
 		glog.Error("AlternativeEstimateFee ", b.ChainConfig.AlternativeEstimateFee, " not supported")
 	} else {
@@ -956,6 +965,33 @@ func (b *BitcoinRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int, e
 	return b.blockchainEstimateSmartFee(blocks, conservative)
 }
 
+// GetFeeEstimates returns the estimates of the backend in both modes and of the alternative provider, if configured
+func (b *BitcoinRPC) GetFeeEstimates(blocks []int) []bchain.FeeEstimate {
+	r := make([]bchain.FeeEstimate, 0, 3*len(blocks))
+	for _, n := range blocks {
+		if b.ChainConfig.SupportsEstimateSmartFee {
+			for _, conservative := range []bool{true, false} {
+				fee, err := b.blockchainEstimateSmartFee(n, conservative)
+				if err != nil {
+					glog.Error("GetFeeEstimates ", n, ": ", err)
+					continue
+				}
+				provider := "economical"
+				if conservative {
+					provider = "conservative"
+				}
+				r = append(r, bchain.FeeEstimate{Provider: provider, Blocks: n, FeePerUnit: fee})
+			}
+		}
+		if b.alternativeFeeProvider != nil {
+			if fee, err := b.alternativeFeeProvider.estimateFee(n); err == nil {
+				r = append(r, bchain.FeeEstimate{Provider: b.ChainConfig.AlternativeEstimateFee, Blocks: n, FeePerUnit: fee})
+			}
+		}
+	}
+	return r
+}
+
 // EstimateFee returns fee estimation.
 func (b *BitcoinRPC) EstimateFee(blocks int) (big.Int, error) {
 	var r big.Int
@@ -1012,17 +1048,35 @@ func (b *BitcoinRPC) LongTermFeeRate() (*bchain.LongTermFeeRate, error) {
 func (b *BitcoinRPC) SendRawTransaction(tx string, disableAlternativeRPC bool) (string, error) {
 	glog.V(1).Info("rpc: sendrawtransaction")
 
+	// fan out the transaction to the alternative backends in parallel with the own backend
+	var altTxid string
+	var altErr error
+	altDone := make(chan struct{})
+	if !disableAlternativeRPC && b.alternativeSendTx != nil {
+		go func() {
+			defer close(altDone)
+			altTxid, altErr = b.alternativeSendTx.sendRawTransaction(tx)
+		}()
+	} else {
+		close(altDone)
+	}
+
 	res := ResSendRawTransaction{}
 	req := CmdSendRawTransaction{Method: "sendrawtransaction"}
 	req.Params = []string{tx}
 	err := b.Call(&req, &res)
-
+	if err == nil && res.Error != nil {
+		err = res.Error
+	}
+	<-altDone
 	if err != nil {
+		// the transaction is propagated if any of the alternative backends accepted it
+		if altTxid != "" && altErr == nil {
+			glog.Warning("rpc: sendrawtransaction rejected by the backend but accepted by an alternative backend: ", err)
+			return altTxid, nil
+		}
 		return "", err
 	}
-	if res.Error != nil {
-		return "", res.Error
-	}
 	return res.Result, nil
 }
 
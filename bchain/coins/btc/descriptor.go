@@ -0,0 +1,368 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil"
+	"github.com/martinboehm/btcutil/hdkeychain"
+	"github.com/martinboehm/btcutil/txscript"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// maximum number of keys in multisig scripts, limited by the script size (p2sh) and by the standardness rules (p2wsh)
+const (
+	maxMultisigKeysP2SH  = 15
+	maxMultisigKeysP2WSH = 20
+)
+
+// descriptor checksum according to https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki#checksum
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+var descriptorChecksumGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+func descriptorPolymod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	for i := 0; i < 5; i++ {
+		if (c0>>uint(i))&1 != 0 {
+			c ^= descriptorChecksumGenerator[i]
+		}
+	}
+	return c
+}
+
+// descriptorChecksum computes the 8 character checksum of a descriptor (without the #checksum part)
+func descriptorChecksum(desc string) (string, error) {
+	c := uint64(1)
+	cls := 0
+	clsCount := 0
+	for _, ch := range desc {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos < 0 {
+			return "", errors.Errorf("Invalid character '%c' in descriptor", ch)
+		}
+		c = descriptorPolymod(c, pos&31)
+		cls = cls*3 + (pos >> 5)
+		clsCount++
+		if clsCount == 3 {
+			c = descriptorPolymod(c, cls)
+			cls = 0
+			clsCount = 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolymod(c, cls)
+	}
+	for i := 0; i < 8; i++ {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+	var sb strings.Builder
+	for i := 0; i < 8; i++ {
+		sb.WriteByte(descriptorChecksumCharset[(c>>(5*(7-uint(i))))&31])
+	}
+	return sb.String(), nil
+}
+
+// stripDescriptorChecksum returns the descriptor without the optional #checksum suffix
+// and an error if the checksum does not match the descriptor
+func stripDescriptorChecksum(desc string) (string, error) {
+	i := strings.IndexByte(desc, '#')
+	if i < 0 {
+		return desc, nil
+	}
+	checksum, err := descriptorChecksum(desc[:i])
+	if err != nil {
+		return desc[:i], err
+	}
+	if desc[i+1:] != checksum {
+		return desc[:i], errors.Errorf("Invalid descriptor checksum %s, expected %s", desc[i+1:], checksum)
+	}
+	return desc[:i], nil
+}
+
+// descriptorScriptFunctions returns the names of the nested script expressions at the start of the descriptor,
+// for example [sh wsh sortedmulti] for sh(wsh(sortedmulti(2,...)))
+func descriptorScriptFunctions(desc string) []string {
+	var names []string
+	for {
+		i := strings.IndexByte(desc, '(')
+		if i <= 0 || strings.TrimLeft(desc[:i], "abcdefghijklmnopqrstuvwxyz") != "" {
+			return names
+		}
+		names = append(names, desc[:i])
+		desc = desc[i+1:]
+	}
+}
+
+// isMultisigDescriptor returns true if the innermost script expression of the descriptor is multi or sortedmulti
+func isMultisigDescriptor(desc string) bool {
+	names := descriptorScriptFunctions(desc)
+	if len(names) == 0 {
+		return false
+	}
+	last := names[len(names)-1]
+	return last == "multi" || last == "sortedmulti"
+}
+
+// splitDescriptorArgs splits the arguments of a descriptor expression by commas which are not nested in brackets
+func splitDescriptorArgs(s string) []string {
+	var args []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, s[start:])
+}
+
+// parseMultisigDescriptor parses descriptors in the form sh(multi(...)), wsh(multi(...)) or sh(wsh(multi(...))),
+// with multi replaceable by sortedmulti, for example
+// wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub.../<0;1>/*,[d34db33f/48'/0'/0'/2']xpub.../<0;1>/*))#checksum
+// the descriptor d is passed without the checksum, which is verified by the caller
+func (p *BitcoinLikeParser) parseMultisigDescriptor(desc string, d string) (*bchain.XpubDescriptor, error) {
+	descriptor := bchain.XpubDescriptor{XpubDescriptor: desc}
+	maxKeys := maxMultisigKeysP2WSH
+	switch {
+	case strings.HasPrefix(d, "sh(wsh(") && strings.HasSuffix(d, "))"):
+		descriptor.Type = bchain.P2SHWSHMULTISIG
+		d = d[7 : len(d)-2]
+	case strings.HasPrefix(d, "wsh(") && strings.HasSuffix(d, ")"):
+		descriptor.Type = bchain.P2WSHMULTISIG
+		d = d[4 : len(d)-1]
+	case strings.HasPrefix(d, "sh(") && strings.HasSuffix(d, ")"):
+		descriptor.Type = bchain.P2SHMULTISIG
+		maxKeys = maxMultisigKeysP2SH
+		d = d[3 : len(d)-1]
+	default:
+		return nil, errors.New("Unsupported multisig descriptor, expecting sh(multi()), wsh(multi()) or sh(wsh(multi()))")
+	}
+	switch {
+	case strings.HasPrefix(d, "sortedmulti(") && strings.HasSuffix(d, ")"):
+		descriptor.SortedKeys = true
+		d = d[12 : len(d)-1]
+	case strings.HasPrefix(d, "multi(") && strings.HasSuffix(d, ")"):
+		d = d[6 : len(d)-1]
+	default:
+		return nil, errors.New("Invalid multisig descriptor, expecting multi() or sortedmulti()")
+	}
+	args := splitDescriptorArgs(d)
+	if len(args) < 2 {
+		return nil, errors.New("Invalid multisig descriptor, missing keys")
+	}
+	threshold, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, errors.Annotatef(err, "Invalid multisig threshold")
+	}
+	keys := args[1:]
+	if len(keys) > maxKeys {
+		return nil, errors.Errorf("Too many keys in multisig descriptor, maximum is %d", maxKeys)
+	}
+	if threshold < 1 || threshold > len(keys) {
+		return nil, errors.Errorf("Invalid multisig threshold %d for %d keys", threshold, len(keys))
+	}
+	descriptor.Threshold = threshold
+	descriptor.Keys = make([]bchain.XpubDescriptorKey, len(keys))
+	for i, k := range keys {
+		key, err := p.parseDescriptorKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && len(key.ChangeIndexes) != len(descriptor.Keys[0].ChangeIndexes) {
+			return nil, errors.New("Invalid multisig descriptor, all keys must have the same number of change indexes")
+		}
+		descriptor.Keys[i] = *key
+	}
+	first := &descriptor.Keys[0]
+	descriptor.Xpub = first.Xpub
+	descriptor.ExtKey = first.ExtKey
+	descriptor.ChangeIndexes = first.ChangeIndexes
+	descriptor.Bip = "48"
+	if first.OriginPath != "" {
+		descriptor.Bip = strings.TrimRight(strings.SplitN(first.OriginPath, "/", 2)[0], "'h")
+	}
+	return &descriptor, nil
+}
+
+// parseDescriptorKey parses key expression [fingerprint/origin/path]xpub[/path/<change>/*]
+func (p *BitcoinLikeParser) parseDescriptorKey(k string) (*bchain.XpubDescriptorKey, error) {
+	var key bchain.XpubDescriptorKey
+	if strings.HasPrefix(k, "[") {
+		i := strings.IndexByte(k, ']')
+		if i < 0 {
+			return nil, errors.Errorf("Invalid key origin in %s", k)
+		}
+		origin := strings.SplitN(k[1:i], "/", 2)
+		if len(origin[0]) != 8 {
+			return nil, errors.Errorf("Invalid key origin fingerprint %s", origin[0])
+		}
+		key.Fingerprint = origin[0]
+		if len(origin) > 1 {
+			key.OriginPath = origin[1]
+		}
+		k = k[i+1:]
+	}
+	parts := strings.Split(k, "/")
+	key.Xpub = parts[0]
+	extKey, err := hdkeychain.NewKeyFromString(key.Xpub, p.Params.Base58CksumHasher)
+	if err != nil {
+		return nil, err
+	}
+	if extKey.IsPrivate() {
+		return nil, errors.New("Private keys are not allowed in descriptors")
+	}
+	key.ExtKey = extKey
+	if len(parts) == 1 {
+		// default to <0;1>
+		key.ChangeIndexes = []uint32{0, 1}
+		return &key, nil
+	}
+	if len(parts) < 3 || parts[len(parts)-1] != "*" {
+		return nil, errors.Errorf("Invalid key path in %s, expecting /<change>/*", k)
+	}
+	for _, step := range parts[1 : len(parts)-2] {
+		s, err := strconv.ParseUint(step, 10, 31)
+		if err != nil {
+			return nil, errors.Annotatef(err, "Invalid derivation step in %s, only unhardened steps are possible", k)
+		}
+		key.Path = append(key.Path, uint32(s))
+	}
+	change := parts[len(parts)-2]
+	var changes []string
+	if strings.HasPrefix(change, "<") && strings.HasSuffix(change, ">") {
+		changes = strings.Split(change[1:len(change)-1], ";")
+	} else if strings.HasPrefix(change, "{") && strings.HasSuffix(change, "}") {
+		changes = strings.Split(change[1:len(change)-1], ",")
+	} else {
+		changes = []string{change}
+	}
+	key.ChangeIndexes = make([]uint32, len(changes))
+	for i, ch := range changes {
+		c, err := strconv.ParseUint(ch, 10, 31)
+		if err != nil {
+			return nil, errors.Annotatef(err, "Invalid change index in %s", k)
+		}
+		key.ChangeIndexes[i] = uint32(c)
+	}
+	return &key, nil
+}
+
+// deriveMultisigAddressDescriptors derives multisig address descriptors for given change and address indexes
+func (p *BitcoinLikeParser) deriveMultisigAddressDescriptors(descriptor *bchain.XpubDescriptor, change uint32, indexes []uint32) ([]bchain.AddressDescriptor, error) {
+	// the change is specified as the change index of the first key, other keys use the change index at the same position
+	changePos := -1
+	for i, c := range descriptor.ChangeIndexes {
+		if c == change {
+			changePos = i
+			break
+		}
+	}
+	if changePos < 0 {
+		return nil, errors.Errorf("Change index %d is not part of the descriptor", change)
+	}
+	changeExtKeys := make([]*hdkeychain.ExtendedKey, len(descriptor.Keys))
+	for i := range descriptor.Keys {
+		key := &descriptor.Keys[i]
+		extKey := key.ExtKey.(*hdkeychain.ExtendedKey)
+		var err error
+		for _, step := range key.Path {
+			if extKey, err = extKey.Derive(step); err != nil {
+				return nil, err
+			}
+		}
+		changeExtKeys[i], err = extKey.Derive(key.ChangeIndexes[changePos])
+		if err != nil {
+			return nil, err
+		}
+	}
+	ad := make([]bchain.AddressDescriptor, len(indexes))
+	pubKeys := make([][]byte, len(changeExtKeys))
+	for i, index := range indexes {
+		for j, changeExtKey := range changeExtKeys {
+			indexExtKey, err := changeExtKey.Derive(index)
+			if err != nil {
+				return nil, err
+			}
+			pubKeys[j] = indexExtKey.PubKeyBytes()
+		}
+		var err error
+		ad[i], err = p.multisigAddrDesc(pubKeys, descriptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ad, nil
+}
+
+func (p *BitcoinLikeParser) multisigAddrDesc(pubKeys [][]byte, descriptor *bchain.XpubDescriptor) (bchain.AddressDescriptor, error) {
+	if descriptor.SortedKeys {
+		// BIP67 - sort the public keys lexicographically, do not modify the caller's slice
+		sorted := make([][]byte, len(pubKeys))
+		copy(sorted, pubKeys)
+		sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+		pubKeys = sorted
+	}
+	builder := txscript.NewScriptBuilder().AddInt64(int64(descriptor.Threshold))
+	for _, pk := range pubKeys {
+		builder.AddData(pk)
+	}
+	script, err := builder.AddInt64(int64(len(pubKeys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		return nil, err
+	}
+	var a btcutil.Address
+	switch descriptor.Type {
+	case bchain.P2SHMULTISIG:
+		a, err = btcutil.NewAddressScriptHash(script, p.Params)
+	case bchain.P2WSHMULTISIG:
+		witnessProgram := sha256.Sum256(script)
+		a, err = btcutil.NewAddressWitnessScriptHash(witnessProgram[:], p.Params)
+	case bchain.P2SHWSHMULTISIG:
+		// redeemScript <witness version: OP_0><len scriptHash: 32><32-byte-scriptHash>
+		witnessProgram := sha256.Sum256(script)
+		redeemScript := make([]byte, len(witnessProgram)+2)
+		redeemScript[0] = 0
+		redeemScript[1] = byte(len(witnessProgram))
+		copy(redeemScript[2:], witnessProgram[:])
+		a, err = btcutil.NewAddressScriptHash(redeemScript, p.Params)
+	default:
+		return nil, errors.New("Unsupported multisig descriptor type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(a)
+}
+
+// multisigDerivationBasePath returns the key origin path of the first key of the descriptor
+func (p *BitcoinLikeParser) multisigDerivationBasePath(descriptor *bchain.XpubDescriptor) (string, error) {
+	key := &descriptor.Keys[0]
+	if key.OriginPath != "" {
+		return "m/" + strings.ReplaceAll(key.OriginPath, "h", "'"), nil
+	}
+	var c string
+	cn := key.ExtKey.(*hdkeychain.ExtendedKey).ChildNum()
+	if cn >= 0x80000000 {
+		cn -= 0x80000000
+		c = "'"
+	}
+	return "unknown/" + strconv.Itoa(int(cn)) + c, nil
+}
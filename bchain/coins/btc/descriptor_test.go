@@ -0,0 +1,305 @@
+//go:build unittest
+
+package btc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	testMultisigKey1 = "[5c9e228d/48'/0'/0'/2']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/<0;1>/*"
+	testMultisigKey2 = "[d34db33f/48'/0'/0'/2']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*"
+)
+
+func TestDescriptorChecksum(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "BIP380 test vector",
+			desc: "raw(deadbeef)",
+			want: "89f8spxm",
+		},
+		{
+			name: "wsh(sortedmulti)",
+			desc: "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))",
+			want: "y7empmzx",
+		},
+		{
+			name:    "invalid character",
+			desc:    "wsh(multi(2,€))",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := descriptorChecksum(tt.desc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("descriptorChecksum() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("descriptorChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMultisigDescriptor(t *testing.T) {
+	tests := []struct {
+		desc string
+		want bool
+	}{
+		{desc: "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))", want: true},
+		{desc: "sh(wsh(multi(1," + testMultisigKey1 + ")))", want: true},
+		{desc: "tr(multi(1," + testMultisigKey1 + "))", want: true},
+		{desc: "wpkh(" + testMultisigKey1 + ")", want: false},
+		{desc: "sh(wpkh(" + testMultisigKey1 + "))", want: false},
+		{desc: "pkh(xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/0/*)#multi(", want: false},
+		{desc: "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj", want: false},
+	}
+	for _, tt := range tests {
+		if got := isMultisigDescriptor(tt.desc); got != tt.want {
+			t.Errorf("isMultisigDescriptor(%s) = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestParseMultisigDescriptors(t *testing.T) {
+	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
+	tests := []struct {
+		name    string
+		xpub    string
+		want    *bchain.XpubDescriptor
+		wantErr bool
+	}{
+		{
+			name: "wsh(sortedmulti(2,key1,key2))#checksum",
+			xpub: "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))#y7empmzx",
+			want: &bchain.XpubDescriptor{
+				XpubDescriptor: "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))#y7empmzx",
+				Xpub:           "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+				Type:           bchain.P2WSHMULTISIG,
+				Bip:            "48",
+				ChangeIndexes:  []uint32{0, 1},
+				Threshold:      2,
+				SortedKeys:     true,
+				Keys: []bchain.XpubDescriptorKey{
+					{
+						Xpub:          "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+						Fingerprint:   "5c9e228d",
+						OriginPath:    "48'/0'/0'/2'",
+						ChangeIndexes: []uint32{0, 1},
+					},
+					{
+						Xpub:          "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+						Fingerprint:   "d34db33f",
+						OriginPath:    "48'/0'/0'/2'",
+						ChangeIndexes: []uint32{0, 1},
+					},
+				},
+			},
+		},
+		{
+			name: "sh(multi(1,xpub/1/{3,4}/*,xpub))",
+			xpub: "sh(multi(1,xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/1/{3,4}/*,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj))",
+			want: &bchain.XpubDescriptor{
+				XpubDescriptor: "sh(multi(1,xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/1/{3,4}/*,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj))",
+				Xpub:           "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+				Type:           bchain.P2SHMULTISIG,
+				Bip:            "48",
+				ChangeIndexes:  []uint32{3, 4},
+				Threshold:      1,
+				Keys: []bchain.XpubDescriptorKey{
+					{
+						Xpub:          "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+						Path:          []uint32{1},
+						ChangeIndexes: []uint32{3, 4},
+					},
+					{
+						Xpub:          "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+						ChangeIndexes: []uint32{0, 1},
+					},
+				},
+			},
+		},
+		{
+			name:    "invalid checksum",
+			xpub:    "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))#y7empmzz",
+			wantErr: true,
+		},
+		{
+			name:    "threshold greater than number of keys",
+			xpub:    "wsh(multi(3," + testMultisigKey1 + "," + testMultisigKey2 + "))",
+			wantErr: true,
+		},
+		{
+			name:    "different number of change indexes",
+			xpub:    "wsh(multi(1," + testMultisigKey1 + ",xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/0/*))",
+			wantErr: true,
+		},
+		{
+			name:    "hardened step after xpub",
+			xpub:    "wsh(multi(1,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/1'/0/*))",
+			wantErr: true,
+		},
+		{
+			name:    "multi not in sh or wsh",
+			xpub:    "tr(multi(1," + testMultisigKey1 + "))",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := btcMainParser.ParseXpub(tt.xpub)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseXpub() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil {
+				if got.ExtKey == nil {
+					t.Errorf("ParseXpub() got nil ExtKey")
+					return
+				}
+				got.ExtKey = nil
+				for i := range got.Keys {
+					if got.Keys[i].ExtKey == nil {
+						t.Errorf("ParseXpub() got nil ExtKey of key %d", i)
+						return
+					}
+					got.Keys[i].ExtKey = nil
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ParseXpub() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveMultisigAddressDescriptors(t *testing.T) {
+	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
+	type args struct {
+		xpub      string
+		change    uint32
+		fromIndex uint32
+		toIndex   uint32
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         []string
+		wantBasePath string
+		wantErr      bool
+	}{
+		{
+			name: "wsh(sortedmulti(2,key1,key2))",
+			args: args{
+				xpub:      "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))",
+				change:    1,
+				fromIndex: 0,
+				toIndex:   2,
+			},
+			want:         []string{"bc1q4huf8qstfsw8equr9wuel77x8mxjejkdyy4vszy4hehmphlsgv3sam5uxd", "bc1q9y7xms77yqdvc986mggmkl9ws42ayauafwl7ay2nkzcvmslyfdjq8xuyr7"},
+			wantBasePath: "m/48'/0'/0'/2'",
+		},
+		{
+			name: "wsh(multi(2,key2,key1)) is different from sortedmulti",
+			args: args{
+				xpub:      "wsh(multi(2," + testMultisigKey2 + "," + testMultisigKey1 + "))",
+				change:    1,
+				fromIndex: 0,
+				toIndex:   2,
+			},
+			want:         []string{"bc1qj52x9pugedvrs6g7fxj0r0ztdwsfw7l468qg0neqfx0pken7z7as5exgyp", "bc1qevy8hq3sqqlu2fkynr8824ljj5flja8rdegdz68n8403fxd6p2fskvhpt8"},
+			wantBasePath: "m/48'/0'/0'/2'",
+		},
+		{
+			name: "sh(multi(1,key1,key2))",
+			args: args{
+				xpub:      "sh(multi(1," + testMultisigKey1 + "," + testMultisigKey2 + "))",
+				change:    1,
+				fromIndex: 0,
+				toIndex:   2,
+			},
+			want:         []string{"3B38tKZDNQ5NTodCtHQFLd1xomruoZqJoF", "34rBukPt2bNrh3rYEVHHuBBnNJQqgViFPb"},
+			wantBasePath: "m/48'/0'/0'/2'",
+		},
+		{
+			name: "sh(wsh(sortedmulti(2,key2,key1)))",
+			args: args{
+				xpub:      "sh(wsh(sortedmulti(2," + testMultisigKey2 + "," + testMultisigKey1 + ")))",
+				change:    1,
+				fromIndex: 0,
+				toIndex:   2,
+			},
+			want:         []string{"36q7F94hSaL6KDzMdDsKjkG1eMDbYhZDji", "3FNZBr37ySkYWyHG8YgfpT3W7wK6xgTLap"},
+			wantBasePath: "m/48'/0'/0'/2'",
+		},
+		{
+			name: "change index not in descriptor",
+			args: args{
+				xpub:      "wsh(sortedmulti(2," + testMultisigKey1 + "," + testMultisigKey2 + "))",
+				change:    2,
+				fromIndex: 0,
+				toIndex:   2,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descriptor, err := btcMainParser.ParseXpub(tt.args.xpub)
+			if err != nil {
+				t.Errorf("ParseXpub() error = %v", err)
+				return
+			}
+			got, err := btcMainParser.DeriveAddressDescriptorsFromTo(descriptor, tt.args.change, tt.args.fromIndex, tt.args.toIndex)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeriveAddressDescriptorsFromTo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			gotAddresses := make([]string, len(got))
+			for i, ad := range got {
+				aa, _, err := btcMainParser.GetAddressesFromAddrDesc(ad)
+				if err != nil || len(aa) != 1 {
+					t.Errorf("DeriveAddressDescriptorsFromTo() got incorrect address descriptor %v, error %v", ad, err)
+					return
+				}
+				gotAddresses[i] = aa[0]
+			}
+			if !reflect.DeepEqual(gotAddresses, tt.want) {
+				t.Errorf("DeriveAddressDescriptorsFromTo() = %v, want %v", gotAddresses, tt.want)
+			}
+			indexes := make([]uint32, 0, tt.args.toIndex-tt.args.fromIndex)
+			for i := tt.args.fromIndex; i < tt.args.toIndex; i++ {
+				indexes = append(indexes, i)
+			}
+			gotIndexes, err := btcMainParser.DeriveAddressDescriptors(descriptor, tt.args.change, indexes)
+			if err != nil {
+				t.Errorf("DeriveAddressDescriptors() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(gotIndexes, got) {
+				t.Errorf("DeriveAddressDescriptors() = %v, want %v", gotIndexes, got)
+			}
+			basePath, err := btcMainParser.DerivationBasePath(descriptor)
+			if err != nil {
+				t.Errorf("DerivationBasePath() error = %v", err)
+				return
+			}
+			if basePath != tt.wantBasePath {
+				t.Errorf("DerivationBasePath() = %v, want %v", basePath, tt.wantBasePath)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package btc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// mempoolFeeProvider estimates the fees from the projected blocks computed by blockbook from its own mempool,
+// without any dependency on an external service
+
+type mempoolFeeParams struct {
+	PeriodSeconds int `json:"periodSeconds"`
+	// Either number, then take the specified index of the fee range of the projected block. If null or missing, take the median fee
+	FeeRangeIndex *int `json:"feeRangeIndex,omitempty"`
+	// MinFeePerKB is the fee returned for the blocks which are not filled by the mempool transactions, default 1000
+	MinFeePerKB      int `json:"minFeePerKB,omitempty"`
+	FallbackFeePerKB int `json:"fallbackFeePerKB,omitempty"`
+}
+
+type mempoolFeeProvider struct {
+	*alternativeFeeProvider
+	params mempoolFeeParams
+}
+
+// NewMempoolFee initializes the provider completely.
+func NewMempoolFee(chain bchain.BlockChain, params string) (alternativeFeeProviderInterface, error) {
+	var paramsParsed mempoolFeeParams
+	err := json.Unmarshal([]byte(params), &paramsParsed)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := NewMempoolFeeProviderFromParamsWithoutChain(paramsParsed)
+	if err != nil {
+		return nil, err
+	}
+
+	p.chain = chain
+	go p.updater()
+	return p, nil
+}
+
+// NewMempoolFeeProviderFromParamsWithoutChain initializes the provider from already parsed parameters and without chain.
+func NewMempoolFeeProviderFromParamsWithoutChain(params mempoolFeeParams) (*mempoolFeeProvider, error) {
+	if params.PeriodSeconds == 0 {
+		return nil, errors.New("NewMempoolFee: Missing periodSeconds")
+	}
+	if params.FeeRangeIndex == nil {
+		glog.Info("NewMempoolFee: Using median fee")
+	} else {
+		index := *params.FeeRangeIndex
+		if index < 0 || index > 6 {
+			return nil, errors.New("NewMempoolFee: feeRangeIndex must be between 0 and 6")
+		}
+		glog.Infof("NewMempoolFee: Using feeRangeIndex %d", index)
+	}
+	if params.MinFeePerKB <= 0 {
+		params.MinFeePerKB = 1000
+	}
+	// the fallback fee is handled by processData, the fees of the not full projected blocks take precedence
+	return &mempoolFeeProvider{
+		alternativeFeeProvider: &alternativeFeeProvider{},
+		params:                 params,
+	}, nil
+}
+
+func (p *mempoolFeeProvider) updater() {
+	period := time.Duration(p.params.PeriodSeconds) * time.Second
+	timer := time.NewTimer(period)
+	counter := 0
+	for {
+		// the mempool is created after the initialization of the chain
+		if m := p.chain.(*BitcoinRPC).Mempool; m != nil {
+			if stats, err := m.GetMempoolFeeStats(); err == nil && stats != nil && p.processData(stats) {
+				if counter%60 == 0 {
+					p.compareToDefault()
+				}
+				counter++
+			}
+		}
+		<-timer.C
+		timer.Reset(period)
+	}
+}
+
+func (p *mempoolFeeProvider) processData(stats *bchain.MempoolFeeStats) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.fees = make([]alternativeFeeProviderFee, 0, len(stats.ProjectedBlocks)+1)
+	full := true
+	for i := range stats.ProjectedBlocks {
+		block := &stats.ProjectedBlocks[i]
+		fee := block.MedianFeePerKb
+		if p.params.FeeRangeIndex != nil && len(block.FeeRangePerKb) > *p.params.FeeRangeIndex {
+			fee = block.FeeRangePerKb[*p.params.FeeRangeIndex]
+		}
+		// a transaction paying the minimal fee gets to the block which is not filled by the mempool
+		if !block.ReachesMaxVSize || fee < int64(p.params.MinFeePerKB) {
+			fee = int64(p.params.MinFeePerKB)
+		}
+		p.fees = append(p.fees, alternativeFeeProviderFee{
+			blocks:   i + 1,
+			feePerKB: int(fee),
+		})
+		if !block.ReachesMaxVSize {
+			full = false
+			break
+		}
+	}
+	if len(p.fees) == 0 {
+		p.fees = append(p.fees, alternativeFeeProviderFee{blocks: 1, feePerKB: p.params.MinFeePerKB})
+	} else if full && p.params.FallbackFeePerKB > 0 {
+		// the mempool fills all projected blocks, the fee of the later blocks is not known
+		p.fees = append(p.fees, alternativeFeeProviderFee{
+			blocks:   len(p.fees) + 1,
+			feePerKB: p.params.FallbackFeePerKB,
+		})
+	}
+
+	p.lastSync = time.Unix(stats.Time, 0)
+	return true
+}
@@ -0,0 +1,116 @@
+//go:build unittest
+
+package btc
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func testMempoolFeeStats(full bool) *bchain.MempoolFeeStats {
+	return &bchain.MempoolFeeStats{
+		Time: time.Now().Unix(),
+		ProjectedBlocks: []bchain.MempoolProjectedBlock{
+			{MedianFeePerKb: 25100, FeeRangePerKb: []int64{1000, 5000, 10000, 25100, 30000, 50000, 300000}, ReachesMaxVSize: true},
+			{MedianFeePerKb: 7310, FeeRangePerKb: []int64{800, 2000, 5000, 7310, 15000, 20000, 150000}, ReachesMaxVSize: true},
+			{MedianFeePerKb: 3140, FeeRangePerKb: []int64{1000, 1500, 2000, 3140, 7000, 10000, 100000}, ReachesMaxVSize: full},
+		},
+	}
+}
+
+func Test_mempoolFeeProvider(t *testing.T) {
+	index := 5
+	tests := []struct {
+		name   string
+		params mempoolFeeParams
+		stats  *bchain.MempoolFeeStats
+		want   []int64
+	}{
+		{
+			name:   "median, not full",
+			params: mempoolFeeParams{PeriodSeconds: 20},
+			stats:  testMempoolFeeStats(false),
+			want:   []int64{25100, 25100, 7310, 1000, 1000, 1000},
+		},
+		{
+			name:   "median, full with fallback",
+			params: mempoolFeeParams{PeriodSeconds: 20, FallbackFeePerKB: 500},
+			stats:  testMempoolFeeStats(true),
+			want:   []int64{25100, 25100, 7310, 3140, 500, 500},
+		},
+		{
+			name:   "feeRangeIndex, full without fallback",
+			params: mempoolFeeParams{PeriodSeconds: 20, FeeRangeIndex: &index},
+			stats:  testMempoolFeeStats(true),
+			want:   []int64{50000, 50000, 20000, 10000, 10000, 10000},
+		},
+		{
+			name:   "minimal fee",
+			params: mempoolFeeParams{PeriodSeconds: 20, MinFeePerKB: 4000},
+			stats:  testMempoolFeeStats(true),
+			want:   []int64{25100, 25100, 7310, 4000, 4000, 4000},
+		},
+		{
+			name:   "empty mempool",
+			params: mempoolFeeParams{PeriodSeconds: 20, FallbackFeePerKB: 500},
+			stats:  &bchain.MempoolFeeStats{Time: time.Now().Unix()},
+			want:   []int64{1000, 1000, 1000, 1000, 1000, 1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMempoolFeeProviderFromParamsWithoutChain(tt.params)
+			if err != nil {
+				t.Fatalf("NewMempoolFeeProviderFromParamsWithoutChain returned error: %v", err)
+			}
+			if !m.processData(tt.stats) {
+				t.Fatal("Expected data to be processed successfully")
+			}
+			for i, want := range tt.want {
+				blocks := i
+				if i == len(tt.want)-1 {
+					blocks = 100
+				}
+				got, err := m.estimateFee(blocks)
+				if err != nil {
+					t.Errorf("estimateFee(%d) returned error: %v", blocks, err)
+				}
+				if got.Cmp(big.NewInt(want)) != 0 {
+					t.Errorf("estimateFee(%d) = %v, want %v", blocks, got.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func Test_mempoolFeeProviderStale(t *testing.T) {
+	m, err := NewMempoolFeeProviderFromParamsWithoutChain(mempoolFeeParams{PeriodSeconds: 20})
+	if err != nil {
+		t.Fatalf("NewMempoolFeeProviderFromParamsWithoutChain returned error: %v", err)
+	}
+	stats := testMempoolFeeStats(true)
+	stats.Time = time.Now().Add(-time.Hour).Unix()
+	m.processData(stats)
+	if _, err := m.estimateFee(1); err == nil || !strings.Contains(err.Error(), "Missing recent value") {
+		t.Errorf("estimateFee error = %v, want Missing recent value", err)
+	}
+}
+
+func Test_mempoolFeeProviderInvalidParams(t *testing.T) {
+	index := 7
+	for _, tt := range []struct {
+		params mempoolFeeParams
+		want   string
+	}{
+		{mempoolFeeParams{}, "Missing periodSeconds"},
+		{mempoolFeeParams{PeriodSeconds: 20, FeeRangeIndex: &index}, "feeRangeIndex must be between 0 and 6"},
+	} {
+		if _, err := NewMempoolFeeProviderFromParamsWithoutChain(tt.params); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewMempoolFeeProviderFromParamsWithoutChain error = %v, want %v", err, tt.want)
+		}
+	}
+}
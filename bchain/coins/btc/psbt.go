@@ -0,0 +1,227 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/blockchain"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/martinboehm/btcutil/psbt"
+	"github.com/martinboehm/btcutil/txscript"
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	// the sizes of the pushes in the scripts spending the outputs, a DER signature with the sighash type has at most 72 bytes
+	psbtSigPushSize    = 1 + 72
+	psbtPubKeyPushSize = 1 + 33
+	// schnorr signature with the default sighash type
+	psbtSchnorrSigPushSize = 1 + 64
+)
+
+// ParsePsbt parses the serialized partially signed transaction
+func (p *BitcoinLikeParser) ParsePsbt(b []byte) (*bchain.Psbt, error) {
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(b), false)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = packet.UnsignedTx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	tx := p.TxFromMsgTx(packet.UnsignedTx, true)
+	tx.Hex = hex.EncodeToString(buf.Bytes())
+	r := &bchain.Psbt{
+		Tx:     &tx,
+		Inputs: make([]bchain.PsbtInput, len(packet.Inputs)),
+		VSize:  estimatePsbtVSize(packet),
+	}
+	for i := range packet.Inputs {
+		in := &packet.Inputs[i]
+		pi := &r.Inputs[i]
+		pi.HasWitnessUtxo = in.WitnessUtxo != nil
+		pi.HasNonWitnessUtxo = in.NonWitnessUtxo != nil
+		pi.PartialSigs = len(in.PartialSigs)
+		pi.Finalized = len(in.FinalScriptSig) > 0 || len(in.FinalScriptWitness) > 0
+		if out := psbtSpentOutput(packet, i); out != nil {
+			addrs, _, _ := p.OutputScriptToAddressesFunc(out.PkScript)
+			pi.Utxo = &bchain.Vout{
+				ValueSat: *big.NewInt(out.Value),
+				N:        packet.UnsignedTx.TxIn[i].PreviousOutPoint.Index,
+				ScriptPubKey: bchain.ScriptPubKey{
+					Hex:       hex.EncodeToString(out.PkScript),
+					Addresses: addrs,
+				},
+			}
+		}
+	}
+	return r, nil
+}
+
+// UpdatePsbt adds the utxos missing in the inputs of the partially signed transaction,
+// the witness utxo is added only to the inputs spending a witness program
+func (p *BitcoinLikeParser) UpdatePsbt(b []byte, utxos []bchain.PsbtUtxo) ([]byte, error) {
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(b), false)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) != len(packet.Inputs) {
+		return nil, errors.Errorf("UpdatePsbt: %d utxos for %d inputs", len(utxos), len(packet.Inputs))
+	}
+	for i := range utxos {
+		in := &packet.Inputs[i]
+		u := &utxos[i]
+		if in.WitnessUtxo == nil && u.WitnessUtxo != nil {
+			script, err := hex.DecodeString(u.WitnessUtxo.ScriptPubKey.Hex)
+			if err != nil {
+				return nil, err
+			}
+			if txscript.IsWitnessProgram(script) || (txscript.IsPayToScriptHash(script) && txscript.IsWitnessProgram(in.RedeemScript)) {
+				in.WitnessUtxo = wire.NewTxOut(u.WitnessUtxo.ValueSat.Int64(), script)
+			}
+		}
+		if in.NonWitnessUtxo == nil && u.Tx != nil {
+			t := wire.MsgTx{}
+			if err := t.Deserialize(bytes.NewReader(u.Tx)); err != nil {
+				return nil, err
+			}
+			if t.TxHash() != packet.UnsignedTx.TxIn[i].PreviousOutPoint.Hash {
+				return nil, errors.Errorf("UpdatePsbt: transaction %v is not spent by input %d", t.TxHash(), i)
+			}
+			in.NonWitnessUtxo = &t
+		}
+	}
+	var buf bytes.Buffer
+	if err = packet.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// psbtSpentOutput returns the output spent by the input, nil if the psbt does not contain it
+func psbtSpentOutput(packet *psbt.Packet, i int) *wire.TxOut {
+	in := &packet.Inputs[i]
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo
+	}
+	if in.NonWitnessUtxo != nil {
+		prev := &packet.UnsignedTx.TxIn[i].PreviousOutPoint
+		if in.NonWitnessUtxo.TxHash() == prev.Hash && int(prev.Index) < len(in.NonWitnessUtxo.TxOut) {
+			return in.NonWitnessUtxo.TxOut[prev.Index]
+		}
+	}
+	return nil
+}
+
+// estimatePsbtVSize estimates the virtual size of the signed transaction from the final scripts of the finalized inputs
+// and from the types of the spent outputs of the other inputs, it returns 0 if the size of some input cannot be estimated
+func estimatePsbtVSize(packet *psbt.Packet) int64 {
+	weight := int64(packet.UnsignedTx.SerializeSizeStripped()) * blockchain.WitnessScaleFactor
+	var witness int64
+	hasWitness := false
+	for i := range packet.Inputs {
+		scriptSig, wit, ok := estimatePsbtInputSize(packet, i)
+		if !ok {
+			return 0
+		}
+		// the unsigned transaction contains the empty script sigs
+		weight += int64(wire.VarIntSerializeSize(uint64(scriptSig))-1+scriptSig) * blockchain.WitnessScaleFactor
+		if wit > 0 {
+			hasWitness = true
+			witness += int64(wit)
+		} else {
+			// the empty witness of the input is serialized as zero number of items
+			witness++
+		}
+	}
+	if hasWitness {
+		// marker and flag
+		weight += 2 + witness
+	}
+	return (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor
+}
+
+// estimatePsbtInputSize returns the size of the script sig and of the serialized witness of the signed input
+func estimatePsbtInputSize(packet *psbt.Packet, i int) (int, int, bool) {
+	in := &packet.Inputs[i]
+	if len(in.FinalScriptSig) > 0 || len(in.FinalScriptWitness) > 0 {
+		return len(in.FinalScriptSig), len(in.FinalScriptWitness), true
+	}
+	out := psbtSpentOutput(packet, i)
+	if out == nil {
+		return 0, 0, false
+	}
+	switch txscript.GetScriptClass(out.PkScript) {
+	case txscript.PubKeyHashTy:
+		return psbtSigPushSize + psbtPubKeyPushSize, 0, true
+	case txscript.PubKeyTy:
+		return psbtSigPushSize, 0, true
+	case txscript.MultiSigTy:
+		if sigs, ok := multisigSigsSize(out.PkScript); ok {
+			return sigs, 0, true
+		}
+	case txscript.WitnessV0PubKeyHashTy:
+		return 0, 1 + psbtSigPushSize + psbtPubKeyPushSize, true
+	case txscript.WitnessV1TaprootTy:
+		// key path spend
+		return 0, 1 + psbtSchnorrSigPushSize, true
+	case txscript.WitnessV0ScriptHashTy:
+		if wit, ok := p2wshWitnessSize(in.WitnessScript); ok {
+			return 0, wit, true
+		}
+	case txscript.ScriptHashTy:
+		rs := in.RedeemScript
+		scriptSig := pushSize(len(rs)) + len(rs)
+		switch txscript.GetScriptClass(rs) {
+		case txscript.WitnessV0PubKeyHashTy:
+			return scriptSig, 1 + psbtSigPushSize + psbtPubKeyPushSize, true
+		case txscript.WitnessV0ScriptHashTy:
+			if wit, ok := p2wshWitnessSize(in.WitnessScript); ok {
+				return scriptSig, wit, true
+			}
+		case txscript.MultiSigTy:
+			if sigs, ok := multisigSigsSize(rs); ok {
+				return sigs + scriptSig, 0, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// multisigSigsSize returns the size of the pushes of the signatures satisfying the multisig script,
+// including the dummy element consumed by OP_CHECKMULTISIG
+func multisigSigsSize(script []byte) (int, bool) {
+	_, sigs, err := txscript.CalcMultiSigStats(script)
+	if err != nil {
+		return 0, false
+	}
+	return 1 + sigs*psbtSigPushSize, true
+}
+
+// p2wshWitnessSize returns the size of the serialized witness spending the multisig witness script
+func p2wshWitnessSize(ws []byte) (int, bool) {
+	if txscript.GetScriptClass(ws) != txscript.MultiSigTy {
+		return 0, false
+	}
+	_, sigs, err := txscript.CalcMultiSigStats(ws)
+	if err != nil {
+		return 0, false
+	}
+	// number of items, the empty dummy item, the signatures and the witness script
+	return 1 + 1 + sigs*psbtSigPushSize + wire.VarIntSerializeSize(uint64(len(ws))) + len(ws), true
+}
+
+// pushSize returns the size of the opcode pushing data of the length l
+func pushSize(l int) int {
+	switch {
+	case l < txscript.OP_PUSHDATA1:
+		return 1
+	case l <= 0xff:
+		return 2
+	case l <= 0xffff:
+		return 3
+	}
+	return 5
+}
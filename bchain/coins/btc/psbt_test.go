@@ -0,0 +1,129 @@
+//go:build unittest
+
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/martinboehm/btcd/wire"
+	"github.com/martinboehm/btcutil/psbt"
+	"github.com/trezor/blockbook/bchain"
+)
+
+func testPsbt(t *testing.T, prevTx *wire.MsgTx, outScript []byte) []byte {
+	tx := wire.NewMsgTx(2)
+	prevHash := prevTx.TxHash()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(90000, outScript))
+	p, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPsbt(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	tests := []struct {
+		name            string
+		script          string
+		wantWitnessUtxo bool
+		wantVSize       int64
+		wantAddress     string
+	}{
+		{
+			name:            "P2PKH",
+			script:          "76a914d0c59903c5bac2868760e90fd521a4665aa7652088ac",
+			wantWitnessUtxo: false,
+			wantVSize:       192,
+			wantAddress:     "1L2tGENeoh4mSoiUZrSbs1J3jazSdJH9QS",
+		},
+		{
+			name:            "P2WPKH",
+			script:          "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			wantWitnessUtxo: true,
+			wantVSize:       110,
+			wantAddress:     "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		},
+		{
+			name:            "P2TR",
+			script:          "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+			wantWitnessUtxo: true,
+			wantVSize:       111,
+			wantAddress:     "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(tt.script)
+			prevTx := wire.NewMsgTx(2)
+			prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+			prevTx.AddTxOut(wire.NewTxOut(100000, script))
+			var prevBuf bytes.Buffer
+			if err := prevTx.Serialize(&prevBuf); err != nil {
+				t.Fatal(err)
+			}
+			b := testPsbt(t, prevTx, script)
+
+			p, err := parser.ParsePsbt(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Inputs) != 1 || p.Inputs[0].Utxo != nil || p.VSize != 0 {
+				t.Fatalf("ParsePsbt() = %+v, want one input without utxo", p)
+			}
+			if p.Tx.Vin[0].Txid != prevTx.TxHash().String() || p.Tx.Vout[0].ValueSat.Int64() != 90000 {
+				t.Errorf("ParsePsbt() tx = %+v", p.Tx)
+			}
+
+			utxo := &bchain.Vout{ValueSat: *big.NewInt(100000), ScriptPubKey: bchain.ScriptPubKey{Hex: tt.script}}
+			b, err = parser.UpdatePsbt(b, []bchain.PsbtUtxo{{WitnessUtxo: utxo, Tx: prevBuf.Bytes()}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err = parser.ParsePsbt(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in := &p.Inputs[0]
+			if in.HasWitnessUtxo != tt.wantWitnessUtxo || !in.HasNonWitnessUtxo {
+				t.Errorf("UpdatePsbt() input = %+v, want witness utxo %v and non-witness utxo", in, tt.wantWitnessUtxo)
+			}
+			if in.Utxo == nil || in.Utxo.ValueSat.Int64() != 100000 || in.Utxo.ScriptPubKey.Hex != tt.script ||
+				len(in.Utxo.ScriptPubKey.Addresses) != 1 || in.Utxo.ScriptPubKey.Addresses[0] != tt.wantAddress {
+				t.Errorf("UpdatePsbt() utxo = %+v", in.Utxo)
+			}
+			if p.VSize != tt.wantVSize {
+				t.Errorf("ParsePsbt() vsize = %v, want %v", p.VSize, tt.wantVSize)
+			}
+		})
+	}
+}
+
+func TestUpdatePsbtWrongTx(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	prevTx := wire.NewMsgTx(2)
+	prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	prevTx.AddTxOut(wire.NewTxOut(100000, []byte{0x51}))
+	b := testPsbt(t, prevTx, []byte{0x51})
+	otherTx := wire.NewMsgTx(1)
+	otherTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 2}, nil, nil))
+	otherTx.AddTxOut(wire.NewTxOut(100000, []byte{0x51}))
+	var buf bytes.Buffer
+	if err := otherTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.UpdatePsbt(b, []bchain.PsbtUtxo{{Tx: buf.Bytes()}}); err == nil {
+		t.Error("UpdatePsbt() with a transaction not spent by the input, want error")
+	}
+	if _, err := parser.UpdatePsbt(b, nil); err == nil {
+		t.Error("UpdatePsbt() with wrong number of utxos, want error")
+	}
+}
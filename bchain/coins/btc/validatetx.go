@@ -0,0 +1,123 @@
+package btc
+
+import (
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// testmempoolaccept
+
+type CmdTestMempoolAccept struct {
+	Method string     `json:"method"`
+	Params [][]string `json:"params"`
+}
+
+type ResTestMempoolAccept struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result []struct {
+		Txid    string `json:"txid"`
+		Allowed bool   `json:"allowed"`
+		VSize   int64  `json:"vsize"`
+		Fees    struct {
+			Base common.JSONNumber `json:"base"`
+		} `json:"fees"`
+		RejectReason string `json:"reject-reason"`
+	} `json:"result"`
+}
+
+const (
+	// rpcMethodNotFound is the error code returned by the backends which do not implement the method
+	rpcMethodNotFound = -32601
+	// rpcDeserializationError is the error code returned for the transactions which cannot be decoded
+	rpcDeserializationError = -22
+)
+
+// txRejectionCodes maps the reject reasons of bitcoind to the codes of the rejection, the prefixes are matched in the order
+var txRejectionCodes = []struct {
+	prefix string
+	code   string
+}{
+	{"txn-already-in-mempool", bchain.TxRejectAlreadyKnown},
+	{"txn-already-known", bchain.TxRejectAlreadyKnown},
+	{"txn-same-nonwitness-data-in-mempool", bchain.TxRejectAlreadyKnown},
+	{"missing-inputs", bchain.TxRejectMissingInput},
+	{"bad-txns-inputs-missingorspent", bchain.TxRejectMissingInput},
+	{"bad-txns-in-belowout", bchain.TxRejectInsufficientFunds},
+	{"txn-mempool-conflict", bchain.TxRejectMempoolConflict},
+	{"insufficient fee", bchain.TxRejectMempoolConflict},
+	{"replacement-adds-unconfirmed", bchain.TxRejectMempoolConflict},
+	{"too many potential replacements", bchain.TxRejectMempoolConflict},
+	{"min relay fee not met", bchain.TxRejectFeeTooLow},
+	{"mempool min fee not met", bchain.TxRejectFeeTooLow},
+	{"max-fee-exceeded", bchain.TxRejectFeeTooHigh},
+	{"absurdly-high-fee", bchain.TxRejectFeeTooHigh},
+	{"dust", bchain.TxRejectDust},
+	{"non-final", bchain.TxRejectNonFinal},
+	{"non-BIP68-final", bchain.TxRejectNonFinal},
+	{"bad-", bchain.TxRejectInvalidTx},
+	{"mandatory-script-verify-flag-failed", bchain.TxRejectInvalidSignature},
+	{"non-mandatory-script-verify-flag", bchain.TxRejectNonStandard},
+	{"scriptpubkey", bchain.TxRejectNonStandard},
+	{"scriptsig-", bchain.TxRejectNonStandard},
+	{"tx-size", bchain.TxRejectNonStandard},
+	{"bare-multisig", bchain.TxRejectNonStandard},
+	{"multi-op-return", bchain.TxRejectNonStandard},
+	{"version", bchain.TxRejectNonStandard},
+}
+
+// txRejectionCode returns the code of the rejection for the reject reason returned by the backend
+func txRejectionCode(reason string) string {
+	for _, c := range txRejectionCodes {
+		if strings.HasPrefix(reason, c.prefix) {
+			return c.code
+		}
+	}
+	return bchain.TxRejectRejected
+}
+
+// ValidateRawTransaction checks by testmempoolaccept whether the backend would accept the transaction to its mempool
+func (b *BitcoinRPC) ValidateRawTransaction(tx string) (*bchain.TxValidationResult, error) {
+	glog.V(1).Info("rpc: testmempoolaccept")
+
+	res := ResTestMempoolAccept{}
+	req := CmdTestMempoolAccept{Method: "testmempoolaccept"}
+	req.Params = [][]string{{tx}}
+	err := b.Call(&req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		if res.Error.Code == rpcMethodNotFound {
+			return nil, nil
+		}
+		if res.Error.Code == rpcDeserializationError {
+			return &bchain.TxValidationResult{
+				Rejections: []bchain.TxRejection{{Code: bchain.TxRejectInvalidTx, Message: res.Error.Message, Vin: -1, Vout: -1}},
+			}, nil
+		}
+		return nil, res.Error
+	}
+	if len(res.Result) != 1 {
+		return nil, errors.Errorf("testmempoolaccept: unexpected number of results %d", len(res.Result))
+	}
+	r := &res.Result[0]
+	v := &bchain.TxValidationResult{
+		Txid:  r.Txid,
+		VSize: r.VSize,
+	}
+	if r.Fees.Base != "" {
+		fees, err := b.Parser.AmountToBigInt(r.Fees.Base)
+		if err != nil {
+			return nil, err
+		}
+		v.Fees = &fees
+	}
+	if !r.Allowed {
+		v.Rejections = []bchain.TxRejection{{Code: txRejectionCode(r.RejectReason), Message: r.RejectReason, Vin: -1, Vout: -1}}
+	}
+	return v, nil
+}
@@ -277,6 +277,35 @@ func contractGetTransfersFromTx(tx *bchain.RpcTransaction) (bchain.TokenTransfer
 	return r, nil
 }
 
+// GetTransferLogsFromTokenTransfers returns the Transfer event logs of the ERC20 and ERC721 token transfers,
+// the logs of the mempool transactions are not known before they are mined and must be derived from the transfers
+func GetTransferLogsFromTokenTransfers(transfers bchain.TokenTransfers) []*bchain.RpcLog {
+	var r []*bchain.RpcLog
+	for _, t := range transfers {
+		if !ethcommon.IsHexAddress(t.Contract) || !ethcommon.IsHexAddress(t.From) || !ethcommon.IsHexAddress(t.To) {
+			continue
+		}
+		from := ethcommon.BytesToHash(ethcommon.HexToAddress(t.From).Bytes()).Hex()
+		to := ethcommon.BytesToHash(ethcommon.HexToAddress(t.To).Bytes()).Hex()
+		value := ethcommon.BigToHash(&t.Value).Hex()
+		switch t.Standard {
+		case bchain.FungibleToken:
+			r = append(r, &bchain.RpcLog{
+				Address: t.Contract,
+				Topics:  []string{tokenTransferEventSignature, from, to},
+				Data:    value,
+			})
+		case bchain.NonFungibleToken:
+			r = append(r, &bchain.RpcLog{
+				Address: t.Contract,
+				Topics:  []string{tokenTransferEventSignature, from, to, value},
+				Data:    "0x",
+			})
+		}
+	}
+	return r
+}
+
 // EthereumTypeRpcCall calls eth_call with given data and to address
 func (b *EthereumRPC) EthereumTypeRpcCall(data, to, from string) (string, error) {
 	return b.EthereumTypeRpcCallAtBlock(data, to, from, nil)
@@ -289,3 +289,50 @@ func Test_contractGetTransfersFromTx(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTransferLogsFromTokenTransfers(t *testing.T) {
+	bn, _ := new(big.Int).SetString("21e19e0c9bab2400000", 16)
+	transfers := bchain.TokenTransfers{
+		{
+			Standard: bchain.FungibleToken,
+			Contract: "0x4af4114F73d1c1C903aC9E0361b379D1291808A2",
+			From:     "0x20cD153de35D469BA46127A0C8F18626b59a256A",
+			To:       "0x555Ee11FBDDc0E49A9bAB358A8941AD95fFDB48f",
+			Value:    *bn,
+		},
+		{
+			Standard: bchain.NonFungibleToken,
+			Contract: "0xcdA9FC258358EcaA88845f19Af595e908bb7EfE9",
+			From:     "0x837E3f699d85a4b0B99894567e9233dFB1DcB081",
+			To:       "0x7B62EB7fe80350DC7EC945C0B73242cb9877FB1b",
+			Value:    *big.NewInt(1),
+		},
+		{
+			Standard:         bchain.MultiToken,
+			Contract:         "0x6Fd712E3A5B556654044608F9129040A4839E36c",
+			From:             "0x837E3f699d85a4b0B99894567e9233dFB1DcB081",
+			To:               "0x7B62EB7fe80350DC7EC945C0B73242cb9877FB1b",
+			MultiTokenValues: []bchain.MultiTokenValue{{Id: *big.NewInt(1), Value: *big.NewInt(2)}},
+		},
+	}
+	logs := GetTransferLogsFromTokenTransfers(transfers)
+	if len(logs) != 2 {
+		t.Fatalf("GetTransferLogsFromTokenTransfers() = %d logs, want 2", len(logs))
+	}
+	if want := "0x00000000000000000000000020cd153de35d469ba46127a0c8f18626b59a256a"; logs[0].Topics[1] != want {
+		t.Errorf("GetTransferLogsFromTokenTransfers() from topic = %v, want %v", logs[0].Topics[1], want)
+	}
+	// the logs are parsed back to the same transfers
+	got, err := contractGetTransfersFromLog(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("contractGetTransfersFromLog() = %+v, want 2 transfers", got)
+	}
+	for i := range got {
+		if strings.ToLower(fmt.Sprint(got[i])) != strings.ToLower(fmt.Sprint(transfers[i])) {
+			t.Errorf("transfer %d = %+v, want %+v", i, got[i], transfers[i])
+		}
+	}
+}
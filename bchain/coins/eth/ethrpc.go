@@ -1317,6 +1317,46 @@ func (b *EthereumRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int,
 	return r, err
 }
 
+// GetFeeEstimates returns the gas price suggested by the backend and the effective gas prices of the eip1559 fee levels,
+// all the estimates are for the next block, the gas price does not depend on the number of blocks
+func (b *EthereumRPC) GetFeeEstimates(blocks []int) []bchain.FeeEstimate {
+	var r []bchain.FeeEstimate
+	if gp, err := b.EstimateSmartFee(1, true); err == nil {
+		r = append(r, bchain.FeeEstimate{Provider: "gasprice", Blocks: 1, FeePerUnit: gp})
+	} else {
+		glog.Error("GetFeeEstimates: ", err)
+	}
+	fees, err := b.EthereumTypeGetEip1559Fees()
+	if err != nil {
+		glog.Error("GetFeeEstimates: ", err)
+		return r
+	}
+	if fees == nil {
+		return r
+	}
+	provider := "feehistory"
+	if b.alternativeFeeProvider != nil {
+		provider = b.ChainConfig.AlternativeEstimateFee
+	}
+	for _, level := range []struct {
+		name string
+		fee  *bchain.Eip1559Fee
+	}{{"low", fees.Low}, {"medium", fees.Medium}, {"high", fees.High}, {"instant", fees.Instant}} {
+		if level.fee == nil || level.fee.MaxFeePerGas == nil {
+			continue
+		}
+		// the effective gas price is capped by the max fee, the priority fee is paid over the base fee
+		fee := new(big.Int).Set(level.fee.MaxFeePerGas)
+		if fees.BaseFeePerGas != nil && level.fee.MaxPriorityFeePerGas != nil {
+			if effective := new(big.Int).Add(fees.BaseFeePerGas, level.fee.MaxPriorityFeePerGas); effective.Cmp(fee) < 0 {
+				fee = effective
+			}
+		}
+		r = append(r, bchain.FeeEstimate{Provider: provider + "-" + level.name, Blocks: 1, FeePerUnit: *fee})
+	}
+	return r
+}
+
 // GetStringFromMap attempts to return the value for a specific key in a map as a string if valid,
 // otherwise returns an empty string with false indicating there was no key found, or the value was not a string
 func GetStringFromMap(p string, params map[string]interface{}) (string, bool) {
@@ -1503,9 +1543,14 @@ func (b *EthereumRPC) EthereumTypeGetTransactionReceipt(txid string) (*bchain.Rp
 
 // EthereumTypeGetBalance returns current balance of an address
 func (b *EthereumRPC) EthereumTypeGetBalance(addrDesc bchain.AddressDescriptor) (*big.Int, error) {
+	return b.EthereumTypeGetBalanceAtBlock(addrDesc, nil)
+}
+
+// EthereumTypeGetBalanceAtBlock returns balance of an address at a specific block, the backend must keep the state of the block
+func (b *EthereumRPC) EthereumTypeGetBalanceAtBlock(addrDesc bchain.AddressDescriptor, blockNumber *big.Int) (*big.Int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
 	defer cancel()
-	return b.Client.BalanceAt(ctx, addrDesc, nil)
+	return b.Client.BalanceAt(ctx, addrDesc, blockNumber)
 }
 
 // EthereumTypeGetNonce returns current balance of an address
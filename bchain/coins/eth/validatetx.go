@@ -0,0 +1,186 @@
+package eth
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// panicOutputSignature is the selector of Panic(uint256) returned by the failed solidity assertions
+const panicOutputSignature = "4e487b71"
+
+var panicReasons = map[uint64]string{
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call of zero-initialized function",
+}
+
+// decodeRevertReason returns the readable reason of the revert from the data returned by the reverted call
+func decodeRevertReason(data string) string {
+	if has0xPrefix(data) {
+		data = data[2:]
+	}
+	if len(data) < 8 {
+		return ""
+	}
+	switch data[:8] {
+	case errorOutputSignature:
+		return ParseErrorFromOutput(data)
+	case panicOutputSignature:
+		if len(data) < 8+64 {
+			return ""
+		}
+		code, ok := new(big.Int).SetString(data[8:8+64], 16)
+		if !ok {
+			return ""
+		}
+		if code.IsUint64() {
+			if r, found := panicReasons[code.Uint64()]; found {
+				return "panic: " + r
+			}
+		}
+		return fmt.Sprintf("panic: code 0x%x", code)
+	}
+	return "custom error 0x" + data[:8]
+}
+
+// callRejection returns the rejection for the error of the simulation of the transaction,
+// nil if the error is not a rejection of the transaction by the backend
+func callRejection(err error) *bchain.TxRejection {
+	if _, ok := err.(rpc.Error); !ok {
+		return nil
+	}
+	msg := err.Error()
+	r := &bchain.TxRejection{Code: bchain.TxRejectRejected, Message: msg, Vin: -1, Vout: -1}
+	switch {
+	case strings.Contains(msg, "execution reverted"):
+		r.Code = bchain.TxRejectExecutionReverted
+		if de, ok := err.(rpc.DataError); ok {
+			if data, ok := de.ErrorData().(string); ok {
+				if reason := decodeRevertReason(data); reason != "" {
+					r.Message = "execution reverted: " + reason
+				}
+			}
+		}
+	case strings.Contains(msg, "insufficient funds"):
+		r.Code = bchain.TxRejectInsufficientFunds
+	case strings.Contains(msg, "less than block base fee"):
+		r.Code = bchain.TxRejectFeeTooLow
+	case strings.Contains(msg, "intrinsic gas too low"), strings.Contains(msg, "out of gas"), strings.Contains(msg, "gas required exceeds"):
+		r.Code = bchain.TxRejectGasLimitTooLow
+	}
+	return r
+}
+
+// ValidateRawTransaction checks the nonce and the balance of the sender and simulates the transaction by eth_call and eth_estimateGas
+func (b *EthereumRPC) ValidateRawTransaction(hexTx string) (*bchain.TxValidationResult, error) {
+	v := &bchain.TxValidationResult{}
+	reject := func(code string, msg string) {
+		v.Rejections = append(v.Rejections, bchain.TxRejection{Code: code, Message: msg, Vin: -1, Vout: -1})
+	}
+	if has0xPrefix(hexTx) {
+		hexTx = hexTx[2:]
+	}
+	raw, err := hex.DecodeString(hexTx)
+	if err != nil {
+		reject(bchain.TxRejectInvalidTx, err.Error())
+		return v, nil
+	}
+	var tx types.Transaction
+	if err = tx.UnmarshalBinary(raw); err != nil {
+		reject(bchain.TxRejectInvalidTx, err.Error())
+		return v, nil
+	}
+	v.Txid = tx.Hash().Hex()
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	var chainID hexutil.Big
+	if err = b.RPC.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	if tx.Protected() && tx.ChainId().Cmp(chainID.ToInt()) != 0 {
+		reject(bchain.TxRejectInvalidSignature, fmt.Sprintf("chain id %v of the transaction does not match the chain id %v of the network", tx.ChainId(), chainID.ToInt()))
+		return v, nil
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID.ToInt()), &tx)
+	if err != nil {
+		reject(bchain.TxRejectInvalidSignature, err.Error())
+		return v, nil
+	}
+
+	nonce, err := b.EthereumTypeGetNonce(from.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if tx.Nonce() < nonce {
+		reject(bchain.TxRejectNonceTooLow, fmt.Sprintf("nonce %d is lower than the next nonce %d of the sender", tx.Nonce(), nonce))
+	} else if tx.Nonce() > nonce {
+		reject(bchain.TxRejectNonceTooHigh, fmt.Sprintf("nonce %d leaves a gap after the next nonce %d of the sender", tx.Nonce(), nonce))
+	}
+	balance, err := b.EthereumTypeGetBalance(from.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if cost := tx.Cost(); balance.Cmp(cost) < 0 {
+		reject(bchain.TxRejectInsufficientFunds, fmt.Sprintf("balance %v of the sender is lower than the maximal cost %v of the transaction", balance, cost))
+		// the simulation would fail on the balance check
+		return v, nil
+	}
+
+	args := map[string]interface{}{
+		"from":  from,
+		"value": (*hexutil.Big)(tx.Value()),
+		"input": hexutil.Bytes(tx.Data()),
+	}
+	if tx.To() != nil {
+		args["to"] = tx.To()
+	}
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		args["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		args["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		args["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+	}
+	if al := tx.AccessList(); len(al) > 0 {
+		args["accessList"] = al
+	}
+	// the gas is estimated without the limit of the transaction to report the gas needed even if the limit is too low
+	var gas hexutil.Uint64
+	if err = b.RPC.CallContext(ctx, &gas, "eth_estimateGas", args, "pending"); err != nil {
+		r := callRejection(err)
+		if r == nil {
+			return nil, err
+		}
+		v.Rejections = append(v.Rejections, *r)
+		return v, nil
+	}
+	v.GasUsed = uint64(gas)
+	if v.GasUsed > tx.Gas() {
+		reject(bchain.TxRejectGasLimitTooLow, fmt.Sprintf("gas limit %d is lower than the estimated gas %d", tx.Gas(), v.GasUsed))
+		return v, nil
+	}
+	args["gas"] = hexutil.Uint64(tx.Gas())
+	var out hexutil.Bytes
+	if err = b.RPC.CallContext(ctx, &out, "eth_call", args, "pending"); err != nil {
+		r := callRejection(err)
+		if r == nil {
+			return nil, err
+		}
+		v.Rejections = append(v.Rejections, *r)
+	}
+	return v, nil
+}
@@ -90,6 +90,14 @@ type MempoolBitcoinType struct {
 	resyncBatchWorkers int
 	// resyncOutpoints caches mempool outputs during resync to avoid extra RPC lookups for parents.
 	resyncOutpoints atomic.Value
+	// feeStats holds the *MempoolFeeStats computed at the end of the last resync
+	feeStats atomic.Value
+	// spenders maps the outpoints spent by the mempool transactions to the spending txid
+	spenders map[Outpoint]string
+	// replaced holds the recently replaced transactions
+	replaced      map[string]replacedTx
+	OnTxRemoved   OnTxRemovedFunc
+	OnDoubleSpend OnDoubleSpendFunc
 }
 
 // NewMempoolBitcoinType creates new mempool handler.
@@ -107,6 +115,8 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int, golomb
 			txEntries:    make(map[string]txEntry),
 			addrDescToTx: make(map[string][]Outpoint),
 		},
+		spenders:           make(map[Outpoint]string),
+		replaced:           make(map[string]replacedTx),
 		chanTx:             make(chan txPayload, 1),
 		chanAddrIndex:      make(chan txidio, 1),
 		golombFilterP:      golombFilterP,
@@ -129,11 +139,11 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int, golomb
 				}(j)
 			}
 			for payload := range m.chanTx {
-				io, golombFilter, ok := m.getTxAddrs(payload.txid, payload.tx, chanInput, chanResult)
+				tio, ok := m.getTxAddrs(payload.txid, payload.tx, chanInput, chanResult)
 				if !ok {
-					io = []addrIndex{}
+					tio = txidio{txid: payload.txid, io: []addrIndex{}}
 				}
-				m.chanAddrIndex <- txidio{payload.txid, io, golombFilter}
+				m.chanAddrIndex <- tio
 			}
 		}(i)
 	}
@@ -242,13 +252,112 @@ func (m *MempoolBitcoinType) computeGolombFilter(mtx *MempoolTx, tx *Tx) string
 	return hex.EncodeToString(fb)
 }
 
-func (m *MempoolBitcoinType) getTxAddrs(txid string, tx *Tx, chanInput chan chanInputPayload, chanResult chan *addrIndex) ([]addrIndex, string, bool) {
+// txVSizeFee returns the vsize and the fee of the transaction, the vsize is zero if the fee cannot be computed
+func txVSizeFee(tx *Tx, mtx *MempoolTx) (int64, int64) {
+	vsize := tx.VSize
+	if vsize == 0 {
+		vsize = txVSizeFromHex(tx.Hex)
+	}
+	if vsize <= 0 {
+		return 0, 0
+	}
+	var fee big.Int
+	for i := range mtx.Vin {
+		fee.Add(&fee, &mtx.Vin[i].ValueSat)
+	}
+	for i := range tx.Vout {
+		fee.Sub(&fee, &tx.Vout[i].ValueSat)
+	}
+	if fee.Sign() < 0 || !fee.IsInt64() {
+		return 0, 0
+	}
+	return vsize, fee.Int64()
+}
+
+// txVSizeFromHex computes the vsize of a serialized transaction as its weight divided by 4, rounded up,
+// it returns zero if the transaction cannot be parsed
+func txVSizeFromHex(txHex string) int64 {
+	b, err := hex.DecodeString(txHex)
+	if err != nil || len(b) < 10 {
+		return 0
+	}
+	size := int64(len(b))
+	// without the segwit marker and flag the weight is 4 times the size
+	if b[4] != 0 || b[5] != 1 {
+		return size
+	}
+	// skip the inputs and outputs to find the start of the witness data, which is followed only by the locktime
+	off := 6
+	readVarint := func() int {
+		if off >= len(b) {
+			return -1
+		}
+		n := 1
+		switch b[off] {
+		case 0xfd:
+			n = 3
+		case 0xfe:
+			n = 5
+		case 0xff:
+			n = 9
+		}
+		if off+n > len(b) {
+			return -1
+		}
+		var v uint64
+		if n == 1 {
+			v = uint64(b[off])
+		} else {
+			for i := n - 1; i > 0; i-- {
+				v = v<<8 | uint64(b[off+i])
+			}
+		}
+		off += n
+		if v > uint64(len(b)) {
+			return -1
+		}
+		return int(v)
+	}
+	skip := func(n int) bool {
+		if n < 0 || off+n > len(b) {
+			return false
+		}
+		off += n
+		return true
+	}
+	inputs := readVarint()
+	if inputs <= 0 {
+		return 0
+	}
+	for i := 0; i < inputs; i++ {
+		if !skip(36) || !skip(readVarint()) || !skip(4) {
+			return 0
+		}
+	}
+	outputs := readVarint()
+	if outputs < 0 {
+		return 0
+	}
+	for i := 0; i < outputs; i++ {
+		if !skip(8) || !skip(readVarint()) {
+			return 0
+		}
+	}
+	witness := size - 4 - int64(off)
+	if witness < 0 {
+		return 0
+	}
+	stripped := size - 2 - witness
+	return (3*stripped + size + 3) / 4
+}
+
+func (m *MempoolBitcoinType) getTxAddrs(txid string, tx *Tx, chanInput chan chanInputPayload, chanResult chan *addrIndex) (txidio, bool) {
 	if tx == nil {
 		var err error
 		tx, err = m.chain.GetTransactionForMempool(txid)
 		if err != nil {
 			glog.Error("cannot get transaction ", txid, ": ", err)
-			return nil, "", false
+			return txidio{}, false
 		}
 	}
 	glog.V(2).Info("mempool: gettxaddrs ", txid, ", ", len(tx.Vin), " inputs")
@@ -271,12 +380,18 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, tx *Tx, chanInput chan chan
 			m.OnNewTxAddr(tx, addrDesc)
 		}
 	}
-	dispatched := 0
+	// the fee is known only if the values of all inputs are resolved
+	dispatched, inputs, resolved := 0, 0, 0
+	spent := make([]Outpoint, 0, len(tx.Vin))
 	for i := range tx.Vin {
 		input := &tx.Vin[i]
 		if input.Coinbase != "" {
 			continue
 		}
+		inputs++
+		if input.Txid != "" {
+			spent = append(spent, Outpoint{input.Txid, int32(input.Vout)})
+		}
 		payload := chanInputPayload{mtx, i}
 	loop:
 		for {
@@ -285,6 +400,7 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, tx *Tx, chanInput chan chan
 			case ai := <-chanResult:
 				if ai != nil {
 					io = append(io, *ai)
+					resolved++
 				}
 				dispatched--
 			// send input to be processed
@@ -298,16 +414,20 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, tx *Tx, chanInput chan chan
 		ai := <-chanResult
 		if ai != nil {
 			io = append(io, *ai)
+			resolved++
 		}
 	}
-	var golombFilter string
+	tio := txidio{txid: txid, io: io, inputs: spent, outputs: int32(len(tx.Vout))}
 	if m.golombFilterP > 0 {
-		golombFilter = m.computeGolombFilter(mtx, tx)
+		tio.filter = m.computeGolombFilter(mtx, tx)
+	}
+	if inputs > 0 && inputs == resolved {
+		tio.vsize, tio.fee = txVSizeFee(tx, mtx)
 	}
 	if m.OnNewTx != nil {
 		m.OnNewTx(mtx)
 	}
-	return io, golombFilter, true
+	return tio, true
 }
 
 func (m *MempoolBitcoinType) dispatchResyncPayloads(txids []string, cache map[string]*Tx, txTime uint32, onNewEntry func(txid string, entry txEntry)) {
@@ -322,7 +442,7 @@ func (m *MempoolBitcoinType) dispatchResyncPayloads(txids []string, cache map[st
 			select {
 			// store as many processed transactions as possible
 			case tio := <-m.chanAddrIndex:
-				onNewEntry(tio.txid, txEntry{tio.io, txTime, tio.filter})
+				onNewEntry(tio.txid, txEntry{addrIndexes: tio.io, time: txTime, filter: tio.filter, vsize: tio.vsize, fee: tio.fee, inputs: tio.inputs, outputs: tio.outputs})
 				dispatched--
 			// send transaction to be processed
 			case m.chanTx <- txPayload{txid: txid, tx: tx}:
@@ -333,7 +453,7 @@ func (m *MempoolBitcoinType) dispatchResyncPayloads(txids []string, cache map[st
 	}
 	for i := 0; i < dispatched; i++ {
 		tio := <-m.chanAddrIndex
-		onNewEntry(tio.txid, txEntry{tio.io, txTime, tio.filter})
+		onNewEntry(tio.txid, txEntry{addrIndexes: tio.io, time: txTime, filter: tio.filter, vsize: tio.vsize, fee: tio.fee, inputs: tio.inputs, outputs: tio.outputs})
 	}
 }
 
@@ -495,9 +615,14 @@ func (m *MempoolBitcoinType) Resync() (count int, err error) {
 	mempoolSize = len(txs)
 	m.resyncOutpoints.Store(newResyncOutpointCache(mempoolSize))
 	glog.V(2).Info("mempool: resync ", len(txs), " txs")
+	var doubleSpends []*MempoolDoubleSpend
 	onNewEntry := func(txid string, entry txEntry) {
 		if len(entry.addrIndexes) > 0 {
 			m.mux.Lock()
+			ds := m.addTxSpends(txid, &entry)
+			if m.OnDoubleSpend != nil {
+				doubleSpends = append(doubleSpends, ds...)
+			}
 			m.txEntries[txid] = entry
 			for _, si := range entry.addrIndexes {
 				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
@@ -543,13 +668,28 @@ func (m *MempoolBitcoinType) Resync() (count int, err error) {
 		}
 	}
 
+	var removals []*MempoolTxRemoval
 	for txid, entry := range m.txEntries {
 		if _, exists := txsMap[txid]; !exists {
 			m.mux.Lock()
 			m.removeEntryFromMempool(txid, entry)
+			m.removeTxSpends(txid, entry.inputs)
+			if m.OnTxRemoved != nil {
+				removals = append(removals, m.txRemoval(txid, &entry))
+			}
 			m.mux.Unlock()
 		}
 	}
+	m.mux.Lock()
+	m.pruneReplacedTxs(time.Now())
+	m.mux.Unlock()
+	m.updateFeeStats()
+	for _, ds := range doubleSpends {
+		m.OnDoubleSpend(ds)
+	}
+	for _, r := range removals {
+		m.OnTxRemoved(r)
+	}
 	processDuration = time.Since(processStart)
 	count = len(m.txEntries)
 	return count, nil
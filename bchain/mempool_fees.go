@@ -0,0 +1,227 @@
+package bchain
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// projectedBlockVSize is the maximum block weight in vbytes minus the space reserved for the coinbase transaction
+	projectedBlockVSize = 1000000 - 1000
+	// projectedBlocks is the number of the simulated blocks
+	projectedBlocks = 8
+)
+
+// mempoolFeeHistogramLimits are the lower bounds of the fee rates (in sat/kvB) of the buckets of the fee histogram
+var mempoolFeeHistogramLimits = []int64{
+	0, 1000, 2000, 3000, 4000, 5000, 6000, 8000, 10000, 12000, 15000, 20000, 30000, 40000, 50000, 60000, 70000, 80000, 90000,
+	100000, 125000, 150000, 175000, 200000, 250000, 300000, 350000, 400000, 500000, 600000, 700000, 800000, 900000,
+	1000000, 1200000, 1400000, 1600000, 1800000, 2000000,
+}
+
+// projectedBlockPercentiles are the percentiles of the fee range of a projected block, the same as used by mempool.space
+var projectedBlockPercentiles = []int64{2, 10, 25, 50, 75, 90, 98}
+
+type mempoolFeeEntry struct {
+	feePerKb int64
+	vsize    int64
+	fee      int64
+}
+
+// computeMempoolFeeStats builds the fee histogram and simulates the projected blocks.
+// The blocks are filled by the transactions in the order of their effective fee rate, the dependencies
+// between the mempool transactions are considered only through the effective fee rate.
+func computeMempoolFeeStats(entries []mempoolFeeEntry, now int64) *MempoolFeeStats {
+	// the highest fee rates first, bigger transactions first for equal fee rate to make the order stable
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].feePerKb == entries[j].feePerKb {
+			return entries[i].vsize > entries[j].vsize
+		}
+		return entries[i].feePerKb > entries[j].feePerKb
+	})
+	stats := &MempoolFeeStats{
+		Time:      now,
+		TxCount:   len(entries),
+		Histogram: make([]MempoolFeeHistogramBucket, len(mempoolFeeHistogramLimits)),
+	}
+	for i, l := range mempoolFeeHistogramLimits {
+		stats.Histogram[i].FeePerKb = l
+	}
+	var block []mempoolFeeEntry
+	var blockVSize int64
+	for i := range entries {
+		e := &entries[i]
+		stats.VSize += e.vsize
+		stats.TotalFees += e.fee
+		b := sort.Search(len(mempoolFeeHistogramLimits), func(i int) bool { return mempoolFeeHistogramLimits[i] > e.feePerKb }) - 1
+		h := &stats.Histogram[b]
+		h.TxCount++
+		h.VSize += e.vsize
+		h.TotalFees += e.fee
+		if len(stats.ProjectedBlocks) < projectedBlocks {
+			if blockVSize+e.vsize > projectedBlockVSize && len(block) > 0 {
+				stats.ProjectedBlocks = append(stats.ProjectedBlocks, projectBlock(block, blockVSize, true))
+				block, blockVSize = nil, 0
+			}
+			if len(stats.ProjectedBlocks) < projectedBlocks {
+				block = append(block, *e)
+				blockVSize += e.vsize
+			}
+		}
+	}
+	if len(block) > 0 && len(stats.ProjectedBlocks) < projectedBlocks {
+		stats.ProjectedBlocks = append(stats.ProjectedBlocks, projectBlock(block, blockVSize, false))
+	}
+	return stats
+}
+
+// projectBlock computes the statistics of the transactions of a projected block sorted by fee rate in descending order
+func projectBlock(block []mempoolFeeEntry, vsize int64, full bool) MempoolProjectedBlock {
+	pb := MempoolProjectedBlock{
+		TxCount:         len(block),
+		VSize:           vsize,
+		MaxFeePerKb:     block[0].feePerKb,
+		MinFeePerKb:     block[len(block)-1].feePerKb,
+		FeeRangePerKb:   make([]int64, len(projectedBlockPercentiles)),
+		ReachesMaxVSize: full,
+	}
+	// the percentiles are weighted by vsize, starting from the lowest fee rate
+	p := 0
+	var cumulative int64
+	for i := len(block) - 1; i >= 0; i-- {
+		pb.TotalFees += block[i].fee
+		cumulative += block[i].vsize
+		for p < len(projectedBlockPercentiles) && cumulative*100 >= projectedBlockPercentiles[p]*vsize {
+			pb.FeeRangePerKb[p] = block[i].feePerKb
+			p++
+		}
+	}
+	pb.MedianFeePerKb = pb.FeeRangePerKb[3]
+	return pb
+}
+
+// maxCPFPClusterSize limits the size of the clusters of dependent transactions for which the effective fee rates are computed,
+// the computation is cubic in the size of the cluster, the transactions of larger clusters get their own fee rates
+const maxCPFPClusterSize = 64
+
+// cpfpTx is a mempool transaction with known fee and its dependencies on other such mempool transactions
+type cpfpTx struct {
+	vsize    int64
+	fee      int64
+	parents  []string
+	children []string
+}
+
+// computeEffectiveFeeRates returns the fee rates of the transactions taking into account their unconfirmed
+// ancestors and descendants (CPFP). The dependent transactions are grouped to clusters, in which the packages
+// of a transaction and its ancestors are picked by the highest fee rate the same way as a miner builds a block.
+// Each transaction of a picked package gets the fee rate of the package. The clusters larger than maxCPFPClusterSize are not evaluated.
+func computeEffectiveFeeRates(txs map[string]*cpfpTx) map[string]int64 {
+	rates := make(map[string]int64, len(txs))
+	for txid, t := range txs {
+		if _, found := rates[txid]; found {
+			continue
+		}
+		if len(t.parents) == 0 && len(t.children) == 0 {
+			rates[txid] = t.fee * 1000 / t.vsize
+			continue
+		}
+		cluster := make(map[string]struct{})
+		stack := []string{txid}
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if _, found := cluster[c]; found {
+				continue
+			}
+			cluster[c] = struct{}{}
+			stack = append(stack, txs[c].parents...)
+			stack = append(stack, txs[c].children...)
+		}
+		if len(cluster) > maxCPFPClusterSize {
+			for c := range cluster {
+				rates[c] = txs[c].fee * 1000 / txs[c].vsize
+			}
+			continue
+		}
+		for len(cluster) > 0 {
+			var best []string
+			var bestTxid string
+			bestRate := int64(-1)
+			for c := range cluster {
+				pkg := packageWithAncestors(c, cluster, txs)
+				var fee, vsize int64
+				for _, p := range pkg {
+					fee += txs[p].fee
+					vsize += txs[p].vsize
+				}
+				rate := fee * 1000 / vsize
+				// the txid decides between the packages with equal fee rate to make the result stable
+				if rate > bestRate || (rate == bestRate && c < bestTxid) {
+					best, bestTxid, bestRate = pkg, c, rate
+				}
+			}
+			for _, p := range best {
+				rates[p] = bestRate
+				delete(cluster, p)
+			}
+		}
+	}
+	return rates
+}
+
+// packageWithAncestors returns the transaction and its ancestors which are still in the cluster
+func packageWithAncestors(txid string, cluster map[string]struct{}, txs map[string]*cpfpTx) []string {
+	pkg := []string{txid}
+	seen := map[string]struct{}{txid: {}}
+	for i := 0; i < len(pkg); i++ {
+		for _, p := range txs[pkg[i]].parents {
+			if _, inCluster := cluster[p]; inCluster {
+				if _, found := seen[p]; !found {
+					seen[p] = struct{}{}
+					pkg = append(pkg, p)
+				}
+			}
+		}
+	}
+	return pkg
+}
+
+// updateFeeStats recomputes the effective fee rates and the fee statistics of the mempool, it is called after each resync
+func (m *MempoolBitcoinType) updateFeeStats() {
+	m.mux.Lock()
+	txs := make(map[string]*cpfpTx, len(m.txEntries))
+	for txid, e := range m.txEntries {
+		if e.vsize > 0 {
+			txs[txid] = &cpfpTx{vsize: e.vsize, fee: e.fee}
+		}
+	}
+	for txid, t := range txs {
+		for _, o := range m.txEntries[txid].inputs {
+			if p, found := txs[o.Txid]; found && !containsTxid(t.parents, o.Txid) {
+				t.parents = append(t.parents, o.Txid)
+				p.children = append(p.children, txid)
+			}
+		}
+	}
+	m.mux.Unlock()
+	rates := computeEffectiveFeeRates(txs)
+	entries := make([]mempoolFeeEntry, 0, len(txs))
+	m.mux.Lock()
+	for txid, rate := range rates {
+		if e, found := m.txEntries[txid]; found && e.effectiveFeePerKb != rate {
+			e.effectiveFeePerKb = rate
+			m.txEntries[txid] = e
+		}
+		t := txs[txid]
+		entries = append(entries, mempoolFeeEntry{feePerKb: rate, vsize: t.vsize, fee: t.fee})
+	}
+	m.mux.Unlock()
+	m.feeStats.Store(computeMempoolFeeStats(entries, time.Now().Unix()))
+}
+
+// GetMempoolFeeStats returns the fee histogram and the projected blocks computed at the last resync, nil before the first resync
+func (m *MempoolBitcoinType) GetMempoolFeeStats() (*MempoolFeeStats, error) {
+	stats, _ := m.feeStats.Load().(*MempoolFeeStats)
+	return stats, nil
+}
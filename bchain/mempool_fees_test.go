@@ -0,0 +1,193 @@
+package bchain
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func Test_computeMempoolFeeStats(t *testing.T) {
+	entries := []mempoolFeeEntry{
+		{feePerKb: 500, vsize: 100000, fee: 50},
+		{feePerKb: 20000, vsize: 500000, fee: 10000},
+		{feePerKb: 50000, vsize: 600000, fee: 30000},
+		{feePerKb: 1500, vsize: 200, fee: 300},
+	}
+	got := computeMempoolFeeStats(entries, 1234)
+	if got.Time != 1234 || got.TxCount != 4 || got.VSize != 1200200 || got.TotalFees != 40350 {
+		t.Errorf("computeMempoolFeeStats() totals = %+v", got)
+	}
+	wantBuckets := map[int64]MempoolFeeHistogramBucket{
+		0:     {FeePerKb: 0, TxCount: 1, VSize: 100000, TotalFees: 50},
+		1000:  {FeePerKb: 1000, TxCount: 1, VSize: 200, TotalFees: 300},
+		20000: {FeePerKb: 20000, TxCount: 1, VSize: 500000, TotalFees: 10000},
+		50000: {FeePerKb: 50000, TxCount: 1, VSize: 600000, TotalFees: 30000},
+	}
+	if len(got.Histogram) != len(mempoolFeeHistogramLimits) {
+		t.Fatalf("computeMempoolFeeStats() histogram length = %v", len(got.Histogram))
+	}
+	for _, b := range got.Histogram {
+		want, found := wantBuckets[b.FeePerKb]
+		if !found {
+			want = MempoolFeeHistogramBucket{FeePerKb: b.FeePerKb}
+		}
+		if b != want {
+			t.Errorf("computeMempoolFeeStats() bucket = %+v, want %+v", b, want)
+		}
+	}
+	wantBlocks := []MempoolProjectedBlock{
+		{
+			TxCount:         1,
+			VSize:           600000,
+			TotalFees:       30000,
+			MinFeePerKb:     50000,
+			MedianFeePerKb:  50000,
+			MaxFeePerKb:     50000,
+			FeeRangePerKb:   []int64{50000, 50000, 50000, 50000, 50000, 50000, 50000},
+			ReachesMaxVSize: true,
+		},
+		{
+			TxCount:        3,
+			VSize:          600200,
+			TotalFees:      10350,
+			MinFeePerKb:    500,
+			MedianFeePerKb: 20000,
+			MaxFeePerKb:    20000,
+			FeeRangePerKb:  []int64{500, 500, 20000, 20000, 20000, 20000, 20000},
+		},
+	}
+	if !reflect.DeepEqual(got.ProjectedBlocks, wantBlocks) {
+		t.Errorf("computeMempoolFeeStats() projected blocks = %+v, want %+v", got.ProjectedBlocks, wantBlocks)
+	}
+}
+
+func Test_computeMempoolFeeStats_maxBlocks(t *testing.T) {
+	entries := make([]mempoolFeeEntry, projectedBlocks*2)
+	for i := range entries {
+		entries[i] = mempoolFeeEntry{feePerKb: int64(1000 * (i + 1)), vsize: 600000, fee: int64(600 * (i + 1))}
+	}
+	got := computeMempoolFeeStats(entries, 0)
+	if len(got.ProjectedBlocks) != projectedBlocks {
+		t.Fatalf("computeMempoolFeeStats() projected blocks = %v, want %v", len(got.ProjectedBlocks), projectedBlocks)
+	}
+	if b := got.ProjectedBlocks[projectedBlocks-1]; b.MinFeePerKb != 9000 || !b.ReachesMaxVSize {
+		t.Errorf("computeMempoolFeeStats() last projected block = %+v", b)
+	}
+	if got.TxCount != len(entries) {
+		t.Errorf("computeMempoolFeeStats() TxCount = %v, want %v", got.TxCount, len(entries))
+	}
+}
+
+func Test_computeEffectiveFeeRates(t *testing.T) {
+	txs := map[string]*cpfpTx{
+		// single transaction
+		"single": {vsize: 200, fee: 1000},
+		// low fee parent with a high fee child, mined together
+		"parent1": {vsize: 200, fee: 200, children: []string{"child1"}},
+		"child1":  {vsize: 200, fee: 7800, parents: []string{"parent1"}},
+		// high fee parent with a low fee child, the child is mined by its own fee rate
+		"parent2": {vsize: 100, fee: 5000, children: []string{"child2"}},
+		"child2":  {vsize: 100, fee: 100, parents: []string{"parent2"}},
+		// two parents, only one of them is lifted by the child
+		"parent3": {vsize: 100, fee: 100, children: []string{"child3"}},
+		"parent4": {vsize: 100, fee: 30000, children: []string{"child3"}},
+		"child3":  {vsize: 100, fee: 2900, parents: []string{"parent3", "parent4"}},
+	}
+	want := map[string]int64{
+		"single":  5000,
+		"parent1": 20000,
+		"child1":  20000,
+		"parent2": 50000,
+		"child2":  1000,
+		"parent3": 15000,
+		"parent4": 300000,
+		"child3":  15000,
+	}
+	got := computeEffectiveFeeRates(txs)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computeEffectiveFeeRates() = %v, want %v", got, want)
+	}
+}
+
+func Test_computeEffectiveFeeRates_maxClusterSize(t *testing.T) {
+	// a chain of transactions longer than the limit, the transactions get their own fee rates
+	txs := make(map[string]*cpfpTx)
+	want := make(map[string]int64)
+	for i := 0; i <= maxCPFPClusterSize; i++ {
+		txid := strconv.Itoa(i)
+		tx := &cpfpTx{vsize: 100, fee: int64(100 * (i + 1))}
+		if i > 0 {
+			tx.parents = []string{strconv.Itoa(i - 1)}
+		}
+		if i < maxCPFPClusterSize {
+			tx.children = []string{strconv.Itoa(i + 1)}
+		}
+		txs[txid] = tx
+		want[txid] = int64(1000 * (i + 1))
+	}
+	got := computeEffectiveFeeRates(txs)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computeEffectiveFeeRates() = %v, want %v", got, want)
+	}
+}
+
+func Test_txVSizeFee(t *testing.T) {
+	tests := []struct {
+		name      string
+		tx        Tx
+		vin       []int64
+		wantVSize int64
+		wantFee   int64
+	}{
+		{
+			name:      "vsize",
+			tx:        Tx{VSize: 141, Vout: []Vout{{ValueSat: *big.NewInt(90000)}, {ValueSat: *big.NewInt(5000)}}},
+			vin:       []int64{100000},
+			wantVSize: 141,
+			wantFee:   5000,
+		},
+		{
+			name:      "size from hex of non segwit transaction",
+			tx:        Tx{Hex: "010000000100000000000000000000000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000", Vout: []Vout{{ValueSat: *big.NewInt(1000)}}},
+			vin:       []int64{600, 600},
+			wantVSize: 60,
+			wantFee:   200,
+		},
+		{
+			// weight 3 * 60 + 65 = 245
+			name:      "vsize from hex of segwit transaction",
+			tx:        Tx{Hex: "0100000000010100000000000000000000000000000000000000000000000000000000000000000000000000000000000100000000000000000001010000000000", Vout: []Vout{{ValueSat: *big.NewInt(1000)}}},
+			vin:       []int64{600, 600},
+			wantVSize: 62,
+			wantFee:   200,
+		},
+		{
+			name: "unparseable segwit transaction",
+			tx:   Tx{Hex: "0100000000010100", Vout: []Vout{{ValueSat: *big.NewInt(1000)}}},
+			vin:  []int64{2000},
+		},
+		{
+			name: "negative fee",
+			tx:   Tx{VSize: 141, Vout: []Vout{{ValueSat: *big.NewInt(1000)}}},
+			vin:  []int64{600},
+		},
+		{
+			name: "unknown size",
+			tx:   Tx{Vout: []Vout{{ValueSat: *big.NewInt(1000)}}},
+			vin:  []int64{2000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mtx := MempoolTx{}
+			for _, v := range tt.vin {
+				mtx.Vin = append(mtx.Vin, MempoolVin{ValueSat: *big.NewInt(v)})
+			}
+			gotVSize, gotFee := txVSizeFee(&tt.tx, &mtx)
+			if gotVSize != tt.wantVSize || gotFee != tt.wantFee {
+				t.Errorf("txVSizeFee() = %v, %v, want %v, %v", gotVSize, gotFee, tt.wantVSize, tt.wantFee)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package bchain
+
+import (
+	"time"
+)
+
+// replacedTxRetention is how long the replacement of a removed transaction is remembered
+const replacedTxRetention = 24 * time.Hour
+
+type replacedTx struct {
+	by   string
+	time time.Time
+}
+
+// addTxSpends records the outpoints spent by a new mempool transaction and returns the double spends of the mempool
+// transactions spending the same outpoints, which are replaced by the new transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) addTxSpends(txid string, entry *txEntry) []*MempoolDoubleSpend {
+	var doubleSpends []*MempoolDoubleSpend
+	for _, o := range entry.inputs {
+		if spender, found := m.spenders[o]; found && spender != txid {
+			if _, inMempool := m.txEntries[spender]; inMempool {
+				var ds *MempoolDoubleSpend
+				for _, d := range doubleSpends {
+					if d.ConflictingTxid == spender {
+						ds = d
+						break
+					}
+				}
+				if ds == nil {
+					ds = &MempoolDoubleSpend{Txid: txid, ConflictingTxid: spender}
+					doubleSpends = append(doubleSpends, ds)
+					entry.replaces = append(entry.replaces, spender)
+				}
+				ds.Outpoints = append(ds.Outpoints, o)
+			}
+		}
+		m.spenders[o] = txid
+	}
+	if len(doubleSpends) > 0 {
+		now := time.Now()
+		for _, ds := range doubleSpends {
+			m.replaced[ds.ConflictingTxid] = replacedTx{by: txid, time: now}
+			ds.AddrDescs = uniqueAddrDescs(entry.addrIndexes, m.txEntries[ds.ConflictingTxid].addrIndexes)
+		}
+	}
+	return doubleSpends
+}
+
+// removeTxSpends removes the outpoints spent by a removed mempool transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) removeTxSpends(txid string, inputs []Outpoint) {
+	for _, o := range inputs {
+		if m.spenders[o] == txid {
+			delete(m.spenders, o)
+		}
+	}
+}
+
+// pruneReplacedTxs forgets the replacements older than replacedTxRetention. The caller is responsible for locking!
+func (m *MempoolBitcoinType) pruneReplacedTxs(now time.Time) {
+	for txid, r := range m.replaced {
+		if now.Sub(r.time) > replacedTxRetention {
+			delete(m.replaced, txid)
+		}
+	}
+}
+
+// txRemoval creates the notification about a removed transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) txRemoval(txid string, entry *txEntry) *MempoolTxRemoval {
+	return &MempoolTxRemoval{
+		Txid:       txid,
+		ReplacedBy: m.replaced[txid].by,
+		AddrDescs:  uniqueAddrDescs(entry.addrIndexes),
+		Inputs:     entry.inputs,
+	}
+}
+
+// uniqueAddrDescs returns the distinct address descriptors of the inputs and outputs of transactions
+func uniqueAddrDescs(addrIndexes ...[]addrIndex) []AddressDescriptor {
+	var addrDescs []AddressDescriptor
+	seen := make(map[string]struct{})
+	for _, indexes := range addrIndexes {
+		for _, ai := range indexes {
+			if _, found := seen[ai.addrDesc]; !found {
+				seen[ai.addrDesc] = struct{}{}
+				addrDescs = append(addrDescs, AddressDescriptor(ai.addrDesc))
+			}
+		}
+	}
+	return addrDescs
+}
+
+func containsTxid(txids []string, txid string) bool {
+	for _, t := range txids {
+		if t == txid {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMempoolTxRelations returns the replacements and the effective fee rate of a mempool transaction, nil if the transaction is not in the mempool
+func (m *MempoolBitcoinType) GetMempoolTxRelations(txid string) *MempoolTxRelations {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	e, found := m.txEntries[txid]
+	if !found {
+		return nil
+	}
+	return &MempoolTxRelations{
+		Replaces:          e.replaces,
+		EffectiveFeePerKb: e.effectiveFeePerKb,
+	}
+}
+
+// GetReplacedBy returns the txid of the transaction which replaced the given transaction, empty string if it is not known
+func (m *MempoolBitcoinType) GetReplacedBy(txid string) string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.replaced[txid].by
+}
+
+// GetMempoolPackage returns the transaction with its in-mempool ancestors and descendants, nil if the transaction is not in the mempool
+func (m *MempoolBitcoinType) GetMempoolPackage(txid string) (*MempoolPackage, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	e, found := m.txEntries[txid]
+	if !found {
+		return nil, nil
+	}
+	return &MempoolPackage{
+		Tx:          m.packageTx(txid, &e),
+		Ancestors:   m.packageClosure(txid, m.txParents),
+		Descendants: m.packageClosure(txid, m.txChildren),
+	}, nil
+}
+
+// txParents returns the mempool transactions spent by the transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) txParents(txid string, entry *txEntry) []string {
+	var parents []string
+	for _, o := range entry.inputs {
+		if _, found := m.txEntries[o.Txid]; found && !containsTxid(parents, o.Txid) {
+			parents = append(parents, o.Txid)
+		}
+	}
+	return parents
+}
+
+// txChildren returns the mempool transactions spending the outputs of the transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) txChildren(txid string, entry *txEntry) []string {
+	var children []string
+	for n := int32(0); n < entry.outputs; n++ {
+		if spender, found := m.spenders[Outpoint{txid, n}]; found && !containsTxid(children, spender) {
+			children = append(children, spender)
+		}
+	}
+	return children
+}
+
+// packageClosure walks the relatives of the transaction returned by next in the breadth first order. The caller is responsible for locking!
+func (m *MempoolBitcoinType) packageClosure(txid string, next func(string, *txEntry) []string) []MempoolPackageTx {
+	var txs []MempoolPackageTx
+	seen := map[string]struct{}{txid: {}}
+	queue := []string{txid}
+	for i := 0; i < len(queue); i++ {
+		e := m.txEntries[queue[i]]
+		for _, t := range next(queue[i], &e) {
+			if _, found := seen[t]; !found {
+				seen[t] = struct{}{}
+				queue = append(queue, t)
+				te := m.txEntries[t]
+				txs = append(txs, m.packageTx(t, &te))
+			}
+		}
+	}
+	return txs
+}
+
+// packageTx converts a mempool entry to MempoolPackageTx. The caller is responsible for locking!
+func (m *MempoolBitcoinType) packageTx(txid string, entry *txEntry) MempoolPackageTx {
+	return MempoolPackageTx{
+		Txid:              txid,
+		VSize:             entry.vsize,
+		Fee:               entry.fee,
+		EffectiveFeePerKb: entry.effectiveFeePerKb,
+		Depends:           m.txParents(txid, entry),
+		SpentBy:           m.txChildren(txid, entry),
+	}
+}
+
+// SetOnTxRemoved sets the callback called after a resync for each transaction removed from the mempool
+func (m *MempoolBitcoinType) SetOnTxRemoved(f OnTxRemovedFunc) {
+	m.OnTxRemoved = f
+}
+
+// SetOnDoubleSpend sets the callback called after a resync for each detected double spend
+func (m *MempoolBitcoinType) SetOnDoubleSpend(f OnDoubleSpendFunc) {
+	m.OnDoubleSpend = f
+}
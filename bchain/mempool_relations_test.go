@@ -0,0 +1,127 @@
+package bchain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestMempoolForRelations() *MempoolBitcoinType {
+	return &MempoolBitcoinType{
+		BaseMempool: BaseMempool{
+			txEntries:    make(map[string]txEntry),
+			addrDescToTx: make(map[string][]Outpoint),
+		},
+		spenders: make(map[Outpoint]string),
+		replaced: make(map[string]replacedTx),
+	}
+}
+
+func TestMempoolBitcoinType_replacements(t *testing.T) {
+	m := newTestMempoolForRelations()
+	add := func(txid string, entry txEntry) []*MempoolDoubleSpend {
+		ds := m.addTxSpends(txid, &entry)
+		m.txEntries[txid] = entry
+		return ds
+	}
+	original := txEntry{
+		addrIndexes: []addrIndex{{"addr1", ^int32(0)}, {"addr2", 0}, {"addr1", 1}},
+		inputs:      []Outpoint{{"parent", 0}, {"parent", 1}},
+	}
+	if ds := add("original", original); len(ds) != 0 {
+		t.Fatalf("addTxSpends(original) = %+v", ds)
+	}
+	add("unrelated", txEntry{addrIndexes: []addrIndex{{"addr3", 0}}, inputs: []Outpoint{{"parent", 2}}})
+	if r := m.GetMempoolTxRelations("original"); r == nil || len(r.Replaces) != 0 {
+		t.Fatalf("GetMempoolTxRelations(original) = %+v", r)
+	}
+	replacement := txEntry{
+		addrIndexes: []addrIndex{{"addr1", ^int32(0)}, {"addr4", 0}},
+		inputs:      []Outpoint{{"parent", 1}},
+	}
+	gotDs := add("replacement", replacement)
+	wantDs := []*MempoolDoubleSpend{{
+		Txid:            "replacement",
+		ConflictingTxid: "original",
+		Outpoints:       []Outpoint{{"parent", 1}},
+		AddrDescs:       []AddressDescriptor{AddressDescriptor("addr1"), AddressDescriptor("addr4"), AddressDescriptor("addr2")},
+	}}
+	if !reflect.DeepEqual(gotDs, wantDs) {
+		t.Errorf("addTxSpends(replacement) = %+v, want %+v", gotDs, wantDs)
+	}
+	if r := m.GetMempoolTxRelations("replacement"); r == nil || !reflect.DeepEqual(r.Replaces, []string{"original"}) {
+		t.Fatalf("GetMempoolTxRelations(replacement) = %+v", r)
+	}
+	if got := m.GetReplacedBy("original"); got != "replacement" {
+		t.Errorf("GetReplacedBy(original) = %v, want replacement", got)
+	}
+	if got := m.GetReplacedBy("unrelated"); got != "" {
+		t.Errorf("GetReplacedBy(unrelated) = %v, want empty", got)
+	}
+
+	m.removeEntryFromMempool("original", original)
+	m.removeTxSpends("original", original.inputs)
+	got := m.txRemoval("original", &original)
+	want := &MempoolTxRemoval{
+		Txid:       "original",
+		ReplacedBy: "replacement",
+		AddrDescs:  []AddressDescriptor{AddressDescriptor("addr1"), AddressDescriptor("addr2")},
+		Inputs:     []Outpoint{{"parent", 0}, {"parent", 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("txRemoval() = %+v, want %+v", got, want)
+	}
+	wantSpenders := map[Outpoint]string{{"parent", 1}: "replacement", {"parent", 2}: "unrelated"}
+	if !reflect.DeepEqual(m.spenders, wantSpenders) {
+		t.Errorf("spenders = %v, want %v", m.spenders, wantSpenders)
+	}
+
+	m.pruneReplacedTxs(time.Now().Add(replacedTxRetention - time.Minute))
+	if got := m.GetReplacedBy("original"); got != "replacement" {
+		t.Errorf("GetReplacedBy(original) after prune = %v, want replacement", got)
+	}
+	m.pruneReplacedTxs(time.Now().Add(replacedTxRetention + time.Minute))
+	if got := m.GetReplacedBy("original"); got != "" {
+		t.Errorf("GetReplacedBy(original) after retention = %v, want empty", got)
+	}
+}
+
+func TestMempoolBitcoinType_GetMempoolPackage(t *testing.T) {
+	m := newTestMempoolForRelations()
+	add := func(txid string, entry txEntry) {
+		entry.addrIndexes = []addrIndex{{"addr", 0}}
+		m.addTxSpends(txid, &entry)
+		m.txEntries[txid] = entry
+	}
+	// grandparent -> parent -> child1, child2; the confirmed transaction is not in the mempool
+	add("grandparent", txEntry{vsize: 100, fee: 100, outputs: 1, inputs: []Outpoint{{"confirmed", 0}}})
+	add("parent", txEntry{vsize: 200, fee: 400, outputs: 3, inputs: []Outpoint{{"grandparent", 0}, {"confirmed", 1}}})
+	add("child1", txEntry{vsize: 100, fee: 1000, outputs: 1, inputs: []Outpoint{{"parent", 0}, {"parent", 2}}})
+	add("child2", txEntry{vsize: 100, fee: 500, outputs: 1, inputs: []Outpoint{{"parent", 1}}})
+	add("other", txEntry{vsize: 100, fee: 500, outputs: 1, inputs: []Outpoint{{"confirmed", 2}}})
+
+	if got, err := m.GetMempoolPackage("unknown"); got != nil || err != nil {
+		t.Errorf("GetMempoolPackage(unknown) = %+v, %v, want nil", got, err)
+	}
+	got, err := m.GetMempoolPackage("parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &MempoolPackage{
+		Tx: MempoolPackageTx{Txid: "parent", VSize: 200, Fee: 400, Depends: []string{"grandparent"}, SpentBy: []string{"child1", "child2"}},
+		Ancestors: []MempoolPackageTx{
+			{Txid: "grandparent", VSize: 100, Fee: 100, SpentBy: []string{"parent"}},
+		},
+		Descendants: []MempoolPackageTx{
+			{Txid: "child1", VSize: 100, Fee: 1000, Depends: []string{"parent"}},
+			{Txid: "child2", VSize: 100, Fee: 500, Depends: []string{"parent"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetMempoolPackage(parent) = %+v, want %+v", got, want)
+	}
+	got, _ = m.GetMempoolPackage("child2")
+	if len(got.Ancestors) != 2 || got.Ancestors[0].Txid != "parent" || got.Ancestors[1].Txid != "grandparent" || len(got.Descendants) != 0 {
+		t.Errorf("GetMempoolPackage(child2) = %+v", got)
+	}
+}
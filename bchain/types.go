@@ -35,6 +35,8 @@ var (
 	ErrTxidMissing = errors.New("Txid missing")
 	// ErrTxNotFound is returned if transaction was not found
 	ErrTxNotFound = errors.New("Tx not found")
+	// ErrNotSupportedByMempool is returned if the mempool does not implement the requested optional functionality
+	ErrNotSupportedByMempool = errors.New("Not supported by mempool")
 )
 
 // Outpoint is txid together with output (or input) index
@@ -265,16 +267,32 @@ const (
 	P2SHWPKH
 	P2WPKH
 	P2TR
+	P2SHMULTISIG
+	P2WSHMULTISIG
+	P2SHWSHMULTISIG
 )
 
 // XpubDescriptor contains parsed data from xpub descriptor
 type XpubDescriptor struct {
-	XpubDescriptor string      `ts_doc:"Full descriptor string including xpub and script type."`
-	Xpub           string      `ts_doc:"The xpub part itself extracted from the descriptor."`
-	Type           ScriptType  `ts_doc:"Parsed script type (P2PKH, P2WPKH, etc.)."`
-	Bip            string      `ts_doc:"BIP standard (e.g. BIP44) inferred from the descriptor."`
-	ChangeIndexes  []uint32    `ts_doc:"Indexes designated as change addresses."`
-	ExtKey         interface{} `ts_doc:"Extended key object parsed from xpub (implementation-specific)."`
+	XpubDescriptor string              `ts_doc:"Full descriptor string including xpub and script type."`
+	Xpub           string              `ts_doc:"The xpub part itself extracted from the descriptor."`
+	Type           ScriptType          `ts_doc:"Parsed script type (P2PKH, P2WPKH, etc.)."`
+	Bip            string              `ts_doc:"BIP standard (e.g. BIP44) inferred from the descriptor."`
+	ChangeIndexes  []uint32            `ts_doc:"Indexes designated as change addresses."`
+	ExtKey         interface{}         `ts_doc:"Extended key object parsed from xpub (implementation-specific)."`
+	Threshold      int                 `ts_doc:"Number of signatures required by a multisig descriptor, zero for single key descriptors."`
+	SortedKeys     bool                `ts_doc:"True if the public keys of a multisig descriptor are sorted (sortedmulti)."`
+	Keys           []XpubDescriptorKey `ts_doc:"Keys of all cosigners of a multisig descriptor, empty for single key descriptors."`
+}
+
+// XpubDescriptorKey contains parsed data of one key expression of a multisig descriptor
+type XpubDescriptorKey struct {
+	Xpub          string      `ts_doc:"The xpub of the key expression."`
+	Fingerprint   string      `ts_doc:"Fingerprint of the master key from the key origin, if specified."`
+	OriginPath    string      `ts_doc:"Derivation path from the key origin, if specified (e.g. 48'/0'/0'/2')."`
+	Path          []uint32    `ts_doc:"Unhardened derivation steps between the xpub and the change index, usually empty."`
+	ChangeIndexes []uint32    `ts_doc:"Change indexes of the key, matched by position with the change indexes of the other keys."`
+	ExtKey        interface{} `ts_doc:"Extended key object parsed from xpub (implementation-specific)."`
 }
 
 // MempoolTxidEntries is array of MempoolTxidEntry
@@ -287,6 +305,92 @@ type MempoolTxidFilterEntries struct {
 	UsedZeroedKey bool              `json:"usedZeroedKey,omitempty" ts_doc:"Indicates if a zeroed key was used in filter calculation."`
 }
 
+// MempoolFeeHistogramBucket is a range of fee rates of the mempool fee histogram
+type MempoolFeeHistogramBucket struct {
+	FeePerKb  int64 // lower bound of the fee rates of the bucket
+	TxCount   int
+	VSize     int64
+	TotalFees int64
+}
+
+// MempoolProjectedBlock is a block simulated from the mempool transactions ordered by fee rate
+type MempoolProjectedBlock struct {
+	TxCount         int
+	VSize           int64
+	TotalFees       int64
+	MinFeePerKb     int64
+	MedianFeePerKb  int64
+	MaxFeePerKb     int64
+	FeeRangePerKb   []int64 // 2nd, 10th, 25th, 50th, 75th, 90th and 98th percentile weighted by vsize
+	ReachesMaxVSize bool
+}
+
+// MempoolFeeStats is the fee rate histogram and the projected blocks of the mempool transactions with known fee
+type MempoolFeeStats struct {
+	Time            int64
+	TxCount         int
+	VSize           int64
+	TotalFees       int64
+	Histogram       []MempoolFeeHistogramBucket
+	ProjectedBlocks []MempoolProjectedBlock
+}
+
+// MempoolTxRelations contains the replace-by-fee and child-pays-for-parent relations of a mempool transaction
+type MempoolTxRelations struct {
+	Replaces          []string // txids of the mempool transactions replaced by the transaction
+	EffectiveFeePerKb int64    // fee rate including the unconfirmed ancestors and descendants, 0 if not known
+}
+
+// MempoolTxRemoval describes a transaction removed from the mempool
+type MempoolTxRemoval struct {
+	Txid       string
+	ReplacedBy string // txid of the conflicting transaction, empty if the transaction was confirmed or evicted
+	AddrDescs  []AddressDescriptor
+	Inputs     []Outpoint // outpoints spent by the transaction
+}
+
+// MempoolDoubleSpend describes a new mempool transaction spending outpoints already spent by another mempool transaction
+type MempoolDoubleSpend struct {
+	Txid            string
+	ConflictingTxid string     // the transaction seen first, it is replaced by the new transaction
+	Outpoints       []Outpoint // outpoints spent by both transactions
+	AddrDescs       []AddressDescriptor
+}
+
+// MempoolPackageTx is a transaction of the in-mempool package of a mempool transaction
+type MempoolPackageTx struct {
+	Txid              string
+	VSize             int64 // zero if the fee is not known
+	Fee               int64
+	EffectiveFeePerKb int64
+	Depends           []string // mempool transactions spent by the transaction
+	SpentBy           []string // mempool transactions spending the transaction
+}
+
+// MempoolPackage contains a mempool transaction and its in-mempool ancestors and descendants, the nearest first
+type MempoolPackage struct {
+	Tx          MempoolPackageTx
+	Ancestors   []MempoolPackageTx
+	Descendants []MempoolPackageTx
+}
+
+// DisconnectedBlock is a block removed from the index by a chain reorganization
+type DisconnectedBlock struct {
+	Height uint32   `json:"height"`
+	Hash   string   `json:"hash"`
+	Txids  []string `json:"txids"`
+	// AddrDescs are the address descriptors involved in the transactions, by txid
+	AddrDescs map[string][]AddressDescriptor `json:"-"`
+}
+
+// Reorg describes the blocks disconnected from the index when the chain forked
+type Reorg struct {
+	ForkHeight uint32 `json:"forkHeight"` // height of the last block common to both branches
+	ForkHash   string `json:"forkHash"`
+	// Blocks are the disconnected blocks, from the highest one
+	Blocks []DisconnectedBlock `json:"blocks"`
+}
+
 // ENSResolution represents the result of resolving an ENS name to an Ethereum address.
 type ENSResolution struct {
 	Name    string `json:"name"`
@@ -303,6 +407,15 @@ type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 // OnNewTxFunc is used to send notification about a new transaction/address
 type OnNewTxFunc func(tx *MempoolTx)
 
+// OnTxRemovedFunc is used to send notification about a transaction removed from the mempool
+type OnTxRemovedFunc func(removal *MempoolTxRemoval)
+
+// OnDoubleSpendFunc is used to send notification about a double spend detected in the mempool
+type OnDoubleSpendFunc func(doubleSpend *MempoolDoubleSpend)
+
+// OnReorgFunc is used to send notification about the blocks disconnected from the index by a chain reorganization
+type OnReorgFunc func(reorg *Reorg)
+
 // AddrDescForOutpointFunc returns address descriptor and value for given outpoint or nil if outpoint not found
 type AddrDescForOutpointFunc func(outpoint Outpoint) (AddressDescriptor, *big.Int)
 
@@ -311,6 +424,119 @@ type MempoolBatcher interface {
 	GetRawTransactionsForMempoolBatch(txids []string) (map[string]*Tx, error)
 }
 
+// MempoolFeeStatsProvider is implemented by the mempools which track the fees of their transactions
+type MempoolFeeStatsProvider interface {
+	GetMempoolFeeStats() (*MempoolFeeStats, error)
+}
+
+// MempoolReplacementTracker is implemented by the mempools which track the conflicts and dependencies of their transactions
+type MempoolReplacementTracker interface {
+	GetMempoolTxRelations(txid string) *MempoolTxRelations
+	GetReplacedBy(txid string) string
+	SetOnTxRemoved(f OnTxRemovedFunc)
+	SetOnDoubleSpend(f OnDoubleSpendFunc)
+}
+
+// MempoolPackageProvider is implemented by the mempools which can return the in-mempool ancestors and descendants of their transactions
+type MempoolPackageProvider interface {
+	GetMempoolPackage(txid string) (*MempoolPackage, error)
+}
+
+// FeeEstimate is a fee rate estimated by a provider for the confirmation within the number of blocks
+type FeeEstimate struct {
+	Provider string
+	Blocks   int
+	// FeePerUnit is the fee per kB for the bitcoin type chains and the gas price for the ethereum type chains
+	FeePerUnit big.Int
+}
+
+// FeeEstimatesProvider is implemented by the chains which can return the fee estimates of all their estimators
+type FeeEstimatesProvider interface {
+	GetFeeEstimates(blocks []int) []FeeEstimate
+}
+
+// Codes of the reasons of the rejection of a transaction by the pre-flight validation
+const (
+	TxRejectInvalidTx         = "invalid-tx"
+	TxRejectInvalidSignature  = "invalid-signature"
+	TxRejectAlreadyKnown      = "already-known"
+	TxRejectMissingInput      = "missing-input"
+	TxRejectSpentInput        = "spent-input"
+	TxRejectMempoolConflict   = "mempool-conflict"
+	TxRejectInsufficientFunds = "insufficient-funds"
+	TxRejectFeeTooLow         = "fee-too-low"
+	TxRejectFeeTooHigh        = "fee-too-high"
+	TxRejectDust              = "dust"
+	TxRejectNonFinal          = "non-final"
+	TxRejectNonStandard       = "non-standard"
+	TxRejectNonceTooLow       = "nonce-too-low"
+	TxRejectNonceTooHigh      = "nonce-too-high"
+	TxRejectGasLimitTooLow    = "gas-limit-too-low"
+	TxRejectExecutionReverted = "execution-reverted"
+	TxRejectRejected          = "rejected"
+)
+
+// TxRejection is a reason of the rejection of a transaction
+type TxRejection struct {
+	Code    string
+	Message string
+	// Vin and Vout are the indexes of the input or output the reason relates to, -1 if it relates to the whole transaction
+	Vin  int
+	Vout int
+}
+
+// TxValidationResult is the result of the validation of a transaction by the backend without broadcasting it
+type TxValidationResult struct {
+	Txid string
+	// Rejections is empty if the backend would accept the transaction
+	Rejections []TxRejection
+	// VSize and Fees are returned by the bitcoin type backends, Fees is nil if not known
+	VSize int64
+	Fees  *big.Int
+	// GasUsed is the gas estimated by the ethereum type backends
+	GasUsed uint64
+}
+
+// TransactionValidator is implemented by the chains which can validate a transaction without broadcasting it
+type TransactionValidator interface {
+	// ValidateRawTransaction returns nil result if the backend does not support the validation
+	ValidateRawTransaction(tx string) (*TxValidationResult, error)
+}
+
+// PsbtInput describes an input of a partially signed transaction
+type PsbtInput struct {
+	// Utxo is the spent output taken from the witness or the non-witness utxo, nil if neither is present
+	Utxo              *Vout
+	HasWitnessUtxo    bool
+	HasNonWitnessUtxo bool
+	PartialSigs       int
+	Finalized         bool
+}
+
+// Psbt is a partially signed transaction as defined by BIP-174
+type Psbt struct {
+	// Tx is the unsigned transaction
+	Tx     *Tx
+	Inputs []PsbtInput
+	// VSize is the estimated virtual size of the signed transaction, 0 if it cannot be estimated
+	VSize int64
+}
+
+// PsbtUtxo is the data of the spent output added to an input of a partially signed transaction
+type PsbtUtxo struct {
+	// WitnessUtxo is the spent output, nil if it is not known
+	WitnessUtxo *Vout
+	// Tx is the raw transaction containing the spent output, nil if it is not known
+	Tx []byte
+}
+
+// PsbtParser is implemented by the parsers of the chains supporting the partially signed transactions
+type PsbtParser interface {
+	ParsePsbt(b []byte) (*Psbt, error)
+	// UpdatePsbt adds the utxos missing in the inputs of the psbt and returns the serialized updated psbt
+	UpdatePsbt(b []byte, utxos []PsbtUtxo) ([]byte, error)
+}
+
 // BlockChain defines common interface to block chain daemon
 type BlockChain interface {
 	// life-cycle methods
@@ -351,6 +577,7 @@ type BlockChain interface {
 	GetChainParser() BlockChainParser
 	// EthereumType specific
 	EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error)
+	EthereumTypeGetBalanceAtBlock(addrDesc AddressDescriptor, blockNumber *big.Int) (*big.Int, error)
 	EthereumTypeGetNonce(addrDesc AddressDescriptor) (uint64, error)
 	EthereumTypeEstimateGas(params map[string]interface{}) (uint64, error)
 	EthereumTypeGetEip1559Fees() (*Eip1559Fees, error)
@@ -183,6 +183,30 @@ export interface Vin {
     /** Data for coinbase inputs (when mining). */
     coinbase?: string;
 }
+export interface TxLifecycle {
+    /** Unix timestamp when the transaction was first seen in the mempool. */
+    firstSeen: number;
+    /** Best block height when the transaction was first seen. */
+    firstSeenHeight: number;
+    /** Fee rate of the transaction in satoshi per kvB. */
+    feePerKb?: number;
+    /** Confirmation ETA in blocks predicted when the transaction was first seen. */
+    predictedETABlocks?: number;
+    /** Height of the block in which the transaction was mined. */
+    blockHeight?: number;
+    /** Unix timestamp of the block in which the transaction was mined. */
+    blockTime?: number;
+    /** Seconds from the first seen time to the block time. */
+    confirmationSeconds?: number;
+    /** Number of blocks from the first seen height to the block of the transaction. */
+    confirmationBlocks?: number;
+    /** Reason of the eviction from the mempool, if the transaction was not mined. */
+    eviction?: 'replaced' | 'expired' | 'conflicted';
+    /** Unix timestamp of the eviction from the mempool. */
+    evictionTime?: number;
+    /** Txid of the transaction which replaced the evicted transaction. */
+    replacedBy?: string;
+}
 export interface Tx {
     /** Transaction ID (hash). */
     txid: string;
@@ -220,6 +244,12 @@ export interface Tx {
     hex?: string;
     /** Indicates if this transaction is replace-by-fee (RBF) enabled. */
     rbf?: boolean;
+    /** Txids of the transactions replaced by this unconfirmed transaction (RBF). */
+    replaces?: string[];
+    /** Fee rate of this unconfirmed transaction including its unconfirmed ancestors and descendants (CPFP) in satoshi per kvB. */
+    effectiveFeePerKb?: number;
+    /** Recorded mempool history of the transaction, if it was seen in the mempool. */
+    lifecycle?: TxLifecycle;
     /** Blockchain-specific extended data. */
     coinSpecificData?: any;
     /** Additional normalized chain-specific transaction data. Use payloadType as discriminator for payload. */
@@ -240,6 +270,180 @@ export interface FeeStats {
     averageFeePerKb: number;
     /** Fee distribution deciles (0%..100%) in satoshi or base units per kB. */
     decilesFeePerKb: number[];
+    /** Number of transactions of the block with a recorded mempool first seen time. */
+    firstSeenTxCount?: number;
+    /** Median time from the first seen time to the block time of the transactions with a recorded first seen time. */
+    medianConfirmationSeconds?: number;
+    /** Median number of blocks from the first seen height to the block of the transactions with a recorded first seen time. */
+    medianConfirmationBlocks?: number;
+    /** Number of transactions of the block with a confirmation ETA predicted when they were first seen. */
+    predictedETATxCount?: number;
+    /** Number of transactions of the block mined within their predicted confirmation ETA. */
+    withinETATxCount?: number;
+}
+export interface MempoolProjectedBlock {
+    /** Number of transactions in the projected block. */
+    txCount: number;
+    /** Virtual size of the projected block. */
+    vsize: number;
+    /** Sum of the fees of the projected block in satoshi. */
+    totalFees?: string;
+    /** Lowest fee rate in the projected block in satoshi per kvB. */
+    minFeePerKb: number;
+    /** Median fee rate weighted by vsize in satoshi per kvB. */
+    medianFeePerKb: number;
+    /** Highest fee rate in the projected block in satoshi per kvB. */
+    maxFeePerKb: number;
+    /** 2nd, 10th, 25th, 50th, 75th, 90th and 98th percentile of the fee rate weighted by vsize in satoshi per kvB. */
+    feeRangePerKb: number[];
+    /** True if the projected block is full, false for the last block taking the rest of the mempool. */
+    reachesMaxVSize: boolean;
+}
+export interface MempoolFeeHistogramBucket {
+    /** Lower bound of the fee rate of the bucket in satoshi per kvB. */
+    feePerKb: number;
+    /** Number of transactions in the bucket. */
+    txCount: number;
+    /** Total virtual size of the transactions in the bucket. */
+    vsize: number;
+    /** Sum of the fees of the transactions in the bucket in satoshi. */
+    totalFees?: string;
+}
+export interface MempoolFeeHistogram {
+    /** Unix timestamp of the computation, it is done after each mempool resync. */
+    time: number;
+    /** Number of the mempool transactions with known fee. */
+    txCount: number;
+    /** Total virtual size of the mempool transactions with known fee. */
+    vsize: number;
+    /** Sum of the fees of the mempool transactions in satoshi. */
+    totalFees?: string;
+    /** Fee rate buckets in ascending order of their fee rate. */
+    histogram: MempoolFeeHistogramBucket[];
+    /** Next blocks simulated from the mempool, ordered by the effective fee rate of the transactions including their unconfirmed ancestors and descendants. */
+    projectedBlocks: MempoolProjectedBlock[];
+}
+export interface MempoolPackageTx {
+    /** Transaction ID (hash). */
+    txid: string;
+    /** Virtual size of the transaction, 0 if its fee is not known. */
+    vsize: number;
+    /** Fee of the transaction in satoshi. */
+    fees?: string;
+    /** Fee rate including the unconfirmed ancestors and descendants (CPFP) in satoshi per kvB. */
+    effectiveFeePerKb?: number;
+    /** Txids of the unconfirmed transactions spent by the transaction. */
+    depends?: string[];
+    /** Txids of the unconfirmed transactions spending the transaction. */
+    spentBy?: string[];
+}
+export interface MempoolPackage {
+    /** The requested transaction. */
+    tx: MempoolPackageTx;
+    /** Unconfirmed ancestors of the transaction, the nearest first. */
+    ancestors: MempoolPackageTx[];
+    /** Unconfirmed descendants of the transaction, the nearest first. */
+    descendants: MempoolPackageTx[];
+    /** Number of the transactions of the ancestor package, including the transaction itself. */
+    ancestorCount: number;
+    /** Virtual size of the ancestor package. */
+    ancestorVSize: number;
+    /** Fees of the ancestor package in satoshi. */
+    ancestorFees?: string;
+    /** Fee rate of the ancestor package in satoshi per kvB. */
+    ancestorFeePerKb: number;
+    /** Number of the transactions of the descendant package, including the transaction itself. */
+    descendantCount: number;
+    /** Virtual size of the descendant package. */
+    descendantVSize: number;
+    /** Fees of the descendant package in satoshi. */
+    descendantFees?: string;
+    /** Fee rate of the descendant package in satoshi per kvB. */
+    descendantFeePerKb: number;
+}
+export interface BroadcastTx {
+    /** Transaction ID (hash). */
+    txid: string;
+    /** State of the transaction in the broadcast queue, only pending transactions are rebroadcast. */
+    status: 'pending' | 'confirmed' | 'conflicted' | 'replaced' | 'expired';
+    /** Unix timestamp of the first broadcast. */
+    firstBroadcast: number;
+    /** Unix timestamp of the last broadcast. */
+    lastBroadcast: number;
+    /** Number of broadcasts, including the rebroadcasts. */
+    broadcasts: number;
+    /** True if the transaction is currently in the mempool. */
+    inMempool: boolean;
+    /** Height of the block in which the transaction was mined. */
+    blockHeight?: number;
+    /** Txid of the transaction which replaced it in the mempool. */
+    replacedBy?: string;
+    /** Error returned by the backends in the last broadcast. */
+    lastError?: string;
+}
+export interface TxRejection {
+    /** Code of the reason of the rejection. */
+    code: 'invalid-tx' | 'invalid-signature' | 'already-known' | 'missing-input' | 'spent-input' | 'mempool-conflict' | 'insufficient-funds' | 'fee-too-low' | 'fee-too-high' | 'dust' | 'non-final' | 'non-standard' | 'nonce-too-low' | 'nonce-too-high' | 'gas-limit-too-low' | 'execution-reverted' | 'rejected';
+    /** Human readable description of the reason, for the backend rejections the reason returned by the backend. */
+    message: string;
+    /** Index of the input the reason relates to. */
+    vin?: number;
+    /** Index of the output the reason relates to. */
+    vout?: number;
+}
+export interface TxValidation {
+    /** Transaction ID (hash), empty if the transaction cannot be decoded. */
+    txid?: string;
+    /** True if the transaction would be accepted by the backend. */
+    valid: boolean;
+    /** Reasons of the rejection of the transaction. */
+    rejections?: TxRejection[];
+    /** Virtual size of the transaction (Bitcoin-type coins). */
+    vsize?: number;
+    /** Fee of the transaction in satoshi (Bitcoin-type coins), missing if some inputs are not known. */
+    fees?: string;
+    /** Fee rate of the transaction in satoshi per kvB (Bitcoin-type coins). */
+    feePerKb?: number;
+    /** Gas estimated for the execution of the transaction (Ethereum-type coins). */
+    gasUsed?: number;
+}
+export interface PsbtInput {
+    /** Index of the input. */
+    n: number;
+    /** State of the spent output: confirmed, in the mempool, already spent in a block or not known to the index and the mempool. */
+    status: 'confirmed' | 'unconfirmed' | 'spent' | 'unknown';
+    /** Number of confirmations of the spent output. */
+    confirmations?: number;
+    /** Transaction which already spent the output, only with the extended index. */
+    spentTxId?: string;
+    /** True if the PSBT contains the witness utxo of the input. */
+    witnessUtxo: boolean;
+    /** True if the PSBT contains the non-witness utxo (the whole previous transaction) of the input. */
+    nonWitnessUtxo: boolean;
+    /** True if the witness utxo was added from the index. */
+    addedWitnessUtxo?: boolean;
+    /** True if the non-witness utxo was added from the index. */
+    addedNonWitnessUtxo?: boolean;
+    /** Number of partial signatures of the input. */
+    partialSigs?: number;
+    /** True if the input has the final script sig or witness. */
+    finalized?: boolean;
+}
+export interface PsbtAnalysis {
+    /** Base64 encoded PSBT with the utxo data added from the index. */
+    psbt: string;
+    /** Decoded unsigned transaction. */
+    tx: Tx;
+    /** State of the inputs of the transaction. */
+    inputs: PsbtInput[];
+    /** True if all the inputs are finalized. */
+    complete: boolean;
+    /** Fee of the transaction in satoshi, missing if the values of some inputs are not known or the outputs exceed the inputs. */
+    fees?: string;
+    /** Estimated virtual size of the signed transaction, missing if it cannot be estimated for some input. */
+    vsize?: number;
+    /** Estimated fee rate of the signed transaction in satoshi per kvB. */
+    feePerKb?: number;
 }
 export interface StakingPool {
     /** Staking pool contract address on-chain. */
@@ -278,6 +482,34 @@ export interface ContractInfo {
     /** Block height where contract was destroyed (if any). */
     destructedInBlock?: number;
 }
+export interface Erc4626TokenMetadata {
+    /** Token contract address. */
+    contract: string;
+    /** Human-readable token name. */
+    name?: string;
+    /** Token symbol. */
+    symbol?: string;
+    /** Token decimals. */
+    decimals: number;
+}
+export interface Erc4626Token {
+    /** Metadata of the underlying asset token. */
+    asset?: Erc4626TokenMetadata;
+    /** Metadata of the vault share token. */
+    share?: Erc4626TokenMetadata;
+    /** Total underlying assets managed by the vault. */
+    totalAssets?: string;
+    /** Underlying assets for one whole share unit. */
+    convertToAssets1Share?: string;
+    /** Shares for one whole underlying asset unit. */
+    convertToShares1Asset?: string;
+    /** Previewed shares minted for one whole underlying asset unit. */
+    previewDeposit1Asset?: string;
+    /** Previewed assets redeemed for one whole share unit. */
+    previewRedeem1Share?: string;
+    /** Error message for partial failures while fetching ERC4626 fields. */
+    error?: string;
+}
 export interface Token {
     /** @deprecated: Use standard instead. */
     type: '' | 'XPUBAddress' | 'ERC20' | 'ERC721' | 'ERC1155' | 'BEP20' | 'BEP721' | 'BEP1155';
@@ -308,6 +540,8 @@ export interface Token {
     totalReceived?: string;
     /** Total amount of tokens sent. */
     totalSent?: string;
+    /** ERC4626 vault details when requested and detected. */
+    erc4626?: Erc4626Token;
 }
 export interface Address {
     /** Current page index. */
@@ -316,6 +550,8 @@ export interface Address {
     totalPages?: number;
     /** Number of items returned on this page. */
     itemsOnPage?: number;
+    /** Opaque cursor of the following page of the confirmed history, if there is one. It is stable when new blocks arrive. */
+    nextCursor?: string;
     /** The address string in standard format. */
     address: string;
     /** Current confirmed balance (in satoshi or base units). */
@@ -346,6 +582,14 @@ export interface Address {
     txids?: string[];
     /** Current transaction nonce for Ethereum-like addresses. */
     nonce?: string;
+    /** Height of the first block with a transaction of this address (Ethereum-like addresses). */
+    firstSeenHeight?: number;
+    /** Time of the first block with a transaction of this address (Ethereum-like addresses). */
+    firstSeenTime?: number;
+    /** Height of the last block with a transaction of this address (Ethereum-like addresses). */
+    lastSeenHeight?: number;
+    /** Time of the last block with a transaction of this address (Ethereum-like addresses). */
+    lastSeenTime?: number;
     /** Number of tokens with any historical usage at this address. */
     usedTokens?: number;
     /** List of tokens associated with this address. */
@@ -390,6 +634,68 @@ export interface Utxo {
     lockTime?: number;
     /** Indicates if this UTXO originated from a coinbase transaction. */
     coinbase?: boolean;
+    /** True if the UTXO is reserved by a lock of another token. */
+    locked?: boolean;
+}
+export interface UtxoLock {
+    /** Locked outpoint in the format txid:vout. */
+    outpoint: string;
+    /** Unix time when the lock expires. */
+    expires: number;
+}
+export interface UtxoLocks {
+    /** Lock token issued by Blockbook, the only credential to extend, list and release the locks. */
+    token?: string;
+    /** Current locks of the token. */
+    locks: UtxoLock[];
+    /** Requested outpoints locked by another token, if there are any, none of the requested outpoints was locked. */
+    conflicts?: string[];
+}
+export interface UtxoLockReq {
+    /** Outpoints in the format txid:vout. */
+    outpoints: string[];
+    /** Duration of the lock in seconds, 60 seconds by default, at most one hour. */
+    ttl?: number;
+}
+export interface CoinSelectionOutput {
+    /** Address of the output. */
+    address: string;
+    /** Value of the output in satoshi. */
+    value?: string;
+    /** True for the change output added by the selection. */
+    change?: boolean;
+    /** Derivation path of the change address. */
+    path?: string;
+}
+export interface CoinSelectionReq {
+    /** Target outputs of the transaction. */
+    outputs: CoinSelectionOutput[];
+    /** Fee rate of the transaction in satoshi per kvB. */
+    feePerKb: number;
+    /** Do not select the utxos with value below the dust threshold. */
+    excludeDust?: boolean;
+    /** Outpoints in the format txid:vout which must not be selected. */
+    exclude?: string[];
+    /** Token of the UTXO locks, the UTXOs locked by other tokens are not selected. */
+    lockToken?: string;
+    /** If set, the selected UTXOs are locked for the number of seconds, by lockToken or by a newly issued token. */
+    lockTtl?: number;
+}
+export interface CoinSelection {
+    /** Algorithm which found the selection, branch and bound finds the selections without change. */
+    algorithm: 'bnb' | 'knapsack';
+    /** Selected utxos. */
+    inputs: Utxo[];
+    /** Target outputs followed by the change output, if there is any. */
+    outputs: CoinSelectionOutput[];
+    /** Fee of the transaction in satoshi. */
+    fees?: string;
+    /** Estimated virtual size of the signed transaction. */
+    vsize: number;
+    /** Resulting fee rate of the transaction in satoshi per kvB. */
+    feePerKb: number;
+    /** Token of the locks of the selected UTXOs, if they were locked. */
+    lockToken?: string;
 }
 export interface BalanceHistory {
     /** Unix timestamp for this point in the balance history. */
@@ -421,6 +727,8 @@ export interface Blocks {
     totalPages?: number;
     /** Number of items returned on this page. */
     itemsOnPage?: number;
+    /** Opaque cursor of the following page of the confirmed history, if there is one. It is stable when new blocks arrive. */
+    nextCursor?: string;
     /** List of blocks. */
     blocks: BlockInfo[];
 }
@@ -431,6 +739,8 @@ export interface Block {
     totalPages?: number;
     /** Number of items returned on this page. */
     itemsOnPage?: number;
+    /** Opaque cursor of the following page of the confirmed history, if there is one. It is stable when new blocks arrive. */
+    nextCursor?: string;
     /** Block hash. */
     hash: string;
     /** Hash of the previous block in the chain. */
@@ -590,11 +900,219 @@ export interface AvailableVsCurrencies {
     /** Error message, if any, when fetching the available currencies. */
     error?: string;
 }
+export interface PortfolioAccount {
+    /** Address, XPUB or output descriptor of the account. */
+    descriptor: string;
+    /** Optional user defined label of the account. */
+    label?: string;
+}
+export interface Portfolio {
+    /** Opaque token identifying the portfolio, assigned by the server. */
+    token?: string;
+    /** Optional name of the portfolio. */
+    name?: string;
+    /** Accounts grouped in the portfolio. */
+    accounts: PortfolioAccount[];
+    /** Unix timestamp of the creation of the portfolio. */
+    created?: number;
+    /** Unix timestamp of the last update of the portfolio. */
+    updated?: number;
+}
+export interface PortfolioAccountBalance {
+    /** Address, XPUB or output descriptor of the account. */
+    descriptor: string;
+    /** Optional user defined label of the account. */
+    label?: string;
+    /** Current confirmed balance of the account. */
+    balance?: string;
+    /** Unconfirmed balance of the account. */
+    unconfirmedBalance?: string;
+    /** Number of transactions of the account. */
+    txs: number;
+    /** Value of the account in secondary currency (e.g. fiat). */
+    secondaryValue?: number;
+}
+export interface PortfolioBalance {
+    /** Current page index. */
+    page?: number;
+    /** Total number of pages available. */
+    totalPages?: number;
+    /** Number of items returned on this page. */
+    itemsOnPage?: number;
+    /** Opaque cursor of the following page of the confirmed history, if there is one. It is stable when new blocks arrive. */
+    nextCursor?: string;
+    /** Opaque token identifying the portfolio. */
+    token: string;
+    /** Optional name of the portfolio. */
+    name?: string;
+    /** Sum of confirmed balances of all accounts. */
+    balance?: string;
+    /** Sum of amounts ever received by all accounts. */
+    totalReceived?: string;
+    /** Sum of amounts ever sent by all accounts. */
+    totalSent?: string;
+    /** Sum of unconfirmed balances of all accounts. */
+    unconfirmedBalance?: string;
+    /** Number of unique unconfirmed transactions of the portfolio. */
+    unconfirmedTxs: number;
+    /** Balance summary of the individual accounts. */
+    accounts: PortfolioAccountBalance[];
+    /** Token holdings merged over all accounts. */
+    tokens?: Token[];
+    /** Value of the coin balance in secondary currency (e.g. fiat). */
+    secondaryValue?: number;
+    /** Value of the token holdings in secondary currency. */
+    tokensSecondaryValue?: number;
+    /** Value of the whole portfolio in secondary currency, including tokens. */
+    totalSecondaryValue?: number;
+    /** Merged and deduplicated transaction history (if requested). */
+    transactions?: Tx[];
+    /** Merged and deduplicated transaction IDs (if detailed data is not requested). */
+    txids?: string[];
+    /** Aliases of the addresses in the returned transactions. */
+    addressAliases?: {[key: string]: AddressAlias};
+}
+export interface ExportTokenTransfer {
+    /** Token standard, e.g. ERC20. */
+    standard: string;
+    /** Contract address of the token. */
+    contract: string;
+    /** Token symbol. */
+    symbol?: string;
+    /** Number of decimals of the token. */
+    decimals?: number;
+    /** True if the account received the tokens, false if it sent them. */
+    received: boolean;
+    /** Transferred amount in the token base units. */
+    value?: string;
+    /** Value of the transfer in the requested fiat currency, if known. */
+    fiatValue?: number;
+}
+export interface ExportRow {
+    /** Transaction ID. */
+    txid: string;
+    /** Height of the block containing the transaction. */
+    blockHeight: number;
+    /** Unix timestamp of the block. */
+    blockTime: number;
+    /** Amount received by the account in satoshis. */
+    received?: string;
+    /** Amount sent by the account in satoshis, for Bitcoin-type coins including the fee. */
+    sent?: string;
+    /** Fee paid by the account in satoshis. */
+    fee?: string;
+    /** Token transfers to or from the account. */
+    tokenTransfers?: ExportTokenTransfer[];
+    /** Fiat rate of the coin at the time of the block. */
+    rate?: number;
+    /** Fiat value of the received amount. */
+    receivedFiat?: number;
+    /** Fiat value of the sent amount. */
+    sentFiat?: number;
+    /** Fiat value of the fee. */
+    feeFiat?: number;
+}
+export interface CostBasisTx {
+    /** Transaction ID. */
+    txid: string;
+    /** Unix timestamp of the block containing the transaction. */
+    blockTime: number;
+    /** Net amount acquired by the transaction (in satoshi or base units). */
+    acquired?: string;
+    /** Net amount disposed by the transaction, including the fee paid by the account. */
+    disposed?: string;
+    /** Fiat price of one unit of the asset at the time of the block, 0 if unknown. */
+    rate?: number;
+    /** Fiat value of the acquired or disposed amount (the cost or the proceeds). */
+    value: number;
+    /** Cost basis of the disposed amount computed from the matched lots. */
+    costBasis?: number;
+    /** Realized gain (or loss if negative) of the disposal. */
+    realizedGain?: number;
+}
+export interface CostBasisPeriod {
+    /** Unix timestamp of the beginning of the period. */
+    time: number;
+    /** Number of transactions in the period. */
+    txs: number;
+    /** Amount acquired in the period. */
+    acquired?: string;
+    /** Amount disposed in the period. */
+    disposed?: string;
+    /** Fiat value of the acquisitions in the period. */
+    cost: number;
+    /** Fiat value of the disposals in the period. */
+    proceeds: number;
+    /** Cost basis of the disposals in the period. */
+    costBasis: number;
+    /** Realized gain (or loss if negative) in the period. */
+    realizedGain: number;
+}
+export interface CostBasisAsset {
+    /** Contract address of the token, empty for the base coin. */
+    contract?: string;
+    /** Symbol of the token. */
+    symbol?: string;
+    /** Number of decimals of the amounts. */
+    decimals: number;
+    /** Amount held in the open lots at the end of the history. */
+    held?: string;
+    /** Cost basis of the held amount. */
+    costBasis: number;
+    /** Current fiat price of one unit of the asset, 0 if unknown. */
+    currentRate?: number;
+    /** Current fiat value of the held amount. */
+    currentValue?: number;
+    /** Unrealized gain (or loss if negative) of the held amount at the current price. */
+    unrealizedGain?: number;
+    /** Sum of realized gains in the requested time range. */
+    realizedGain: number;
+    /** Number of transactions without a known historical price, valued at zero. */
+    missingRates?: number;
+    /** Summary per period in the requested time range. */
+    periods?: CostBasisPeriod[];
+    /** Acquisitions and disposals in the requested time range. */
+    transactions?: CostBasisTx[];
+}
+export interface CostBasisReport {
+    /** Address or XPUB of the report. */
+    descriptor: string;
+    /** Fiat currency of the values. */
+    currency: string;
+    /** Lot matching method: fifo, lifo or average. */
+    method: 'fifo' | 'lifo' | 'average';
+    /** Length of the reported periods in seconds. */
+    groupBy: number;
+    /** Report of the base coin followed by the reports of the tokens. */
+    assets: CostBasisAsset[];
+}
+export interface BalanceAtHeight {
+    /** The address or xpub/descriptor. */
+    address: string;
+    /** Block height of the snapshot. */
+    height: number;
+    /** Hash of the block at the snapshot height. */
+    blockHash: string;
+    /** Time of the block at the snapshot height (Unix timestamp). */
+    blockTime: number;
+    /** Number of transactions up to and including the snapshot block. */
+    txs: number;
+    /** Balance at the snapshot height. */
+    balance?: string;
+    /** Total amount received up to the snapshot height (Bitcoin-type coins). */
+    totalReceived?: string;
+    /** Total amount sent up to the snapshot height (Bitcoin-type coins). */
+    totalSent?: string;
+    /** Unspent outputs at the snapshot height, confirmations are counted to the snapshot block (Bitcoin-type coins). */
+    utxos?: Utxo[];
+    /** Token balances (Ethereum-type coins) or addresses of the xpub with their balances at the snapshot height. */
+    tokens?: Token[];
+}
 export interface WsReq {
     /** Unique request identifier. */
     id: string;
     /** Requested method name. */
-    method: 'getAccountInfo' | 'getInfo' | 'getBlockHash'| 'getBlock' | 'getAccountUtxo' | 'getBalanceHistory' | 'getTransaction' | 'getTransactionSpecific' | 'estimateFee' | 'sendTransaction' | 'subscribeNewBlock' | 'unsubscribeNewBlock' | 'subscribeNewTransaction' | 'unsubscribeNewTransaction' | 'subscribeAddresses' | 'unsubscribeAddresses' | 'subscribeFiatRates' | 'unsubscribeFiatRates' | 'ping' | 'getCurrentFiatRates' | 'getFiatRatesForTimestamps' | 'getFiatRatesTickersList' | 'getMempoolFilters';
+    method: 'getAccountInfo' | 'getInfo' | 'getBlockHash'| 'getBlock' | 'getAccountUtxo' | 'getBalanceHistory' | 'getTransaction' | 'getTransactionSpecific' | 'estimateFee' | 'sendTransaction' | 'validateTransaction' | 'analyzePsbt' | 'selectCoins' | 'lockUtxos' | 'unlockUtxos' | 'getUtxoLocks' | 'subscribeNewBlock' | 'unsubscribeNewBlock' | 'subscribeReorg' | 'unsubscribeReorg' | 'subscribeNewTransaction' | 'unsubscribeNewTransaction' | 'subscribeAddresses' | 'unsubscribeAddresses' | 'resumeSession' | 'subscribeTxConfirmations' | 'unsubscribeTxConfirmations' | 'subscribeContractEvents' | 'unsubscribeContractEvents' | 'subscribeFiatRates' | 'unsubscribeFiatRates' | 'ping' | 'getCurrentFiatRates' | 'getFiatRatesForTimestamps' | 'getFiatRatesTickersList' | 'getMempoolFilters' | 'createPortfolio' | 'updatePortfolio' | 'deletePortfolio' | 'getPortfolio';
     /** Parameters for the requested method in raw JSON format. */
     params: any;
 }
@@ -603,6 +1121,8 @@ export interface WsRes {
     id: string;
     /** Payload of the response, structure depends on the request. */
     data: any;
+    /** Sequence number of a notification of a resumable session. */
+    seq?: number;
 }
 export interface WsAccountInfoReq {
     /** Address or XPUB descriptor to query. */
@@ -611,10 +1131,14 @@ export interface WsAccountInfoReq {
     details?: 'basic' | 'tokens' | 'tokenBalances' | 'txids' | 'txslight' | 'txs';
     /** Which tokens to include in the account info. */
     tokens?: 'derived' | 'used' | 'nonzero';
+    /** If true, includes ERC4626 data for detected vault tokens. */
+    includeErc4626?: boolean;
     /** Number of items per page, if paging is used. */
     pageSize?: number;
     /** Requested page index, if paging is used. */
     page?: number;
+    /** Opaque cursor (nextCursor of the previous response) of the requested page, used instead of page. */
+    cursor?: string;
     /** Starting block height for transaction filtering. */
     from?: number;
     /** Ending block height for transaction filtering. */
@@ -626,6 +1150,28 @@ export interface WsAccountInfoReq {
     /** Gap limit for XPUB scanning, if relevant. */
     gap?: number;
 }
+export interface WsPortfolioReq {
+    /** Portfolio token, required for update and delete. */
+    token?: string;
+    /** Optional name of the portfolio. */
+    name?: string;
+    /** Addresses, XPUBs or EVM accounts grouped in the portfolio. */
+    accounts?: PortfolioAccount[];
+}
+export interface WsPortfolioBalanceReq {
+    /** Portfolio token returned by 'createPortfolio'. */
+    token: string;
+    /** Level of detail of the merged transaction history. */
+    details?: 'basic' | 'tokens' | 'tokenBalances' | 'txids' | 'txslight' | 'txs';
+    /** Number of transactions per page. */
+    pageSize?: number;
+    /** Requested page index. */
+    page?: number;
+    /** Currency code to convert values into (e.g. 'USD'). */
+    secondaryCurrency?: string;
+    /** Gap limit for XPUB scanning of the XPUB accounts. */
+    gap?: number;
+}
 export interface WsBackendInfo {
     /** Backend version string. */
     version?: string;
@@ -695,6 +1241,36 @@ export interface WsBlockFiltersBatchReq {
 export interface WsAccountUtxoReq {
     /** Address or XPUB descriptor to retrieve UTXOs for. */
     descriptor: string;
+    /** Token of the UTXO locks, its locked UTXOs are not flagged as locked. */
+    lockToken?: string;
+    /** If true, the UTXOs locked by other tokens are not returned. */
+    excludeLocked?: boolean;
+}
+export interface WsSelectCoinsReq {
+    /** XPUB or output descriptor whose UTXOs are selected. */
+    descriptor: string;
+    /** Target outputs of the transaction. */
+    outputs: CoinSelectionOutput[];
+    /** Fee rate of the transaction in satoshi per kvB. */
+    feePerKb: number;
+    /** Do not select the UTXOs with value below the dust threshold. */
+    excludeDust?: boolean;
+    /** Outpoints in the format txid:vout which must not be selected. */
+    exclude?: string[];
+    /** Gap limit of the xpub derivation. */
+    gap?: number;
+    /** Token of the UTXO locks, the UTXOs locked by other tokens are not selected. */
+    lockToken?: string;
+    /** If set, the selected UTXOs are locked for the number of seconds, by lockToken or by a newly issued token. */
+    lockTtl?: number;
+}
+export interface WsUtxoLockReq {
+    /** Lock token issued by Blockbook, lockUtxos without token issues a new one. */
+    token?: string;
+    /** Outpoints in the format txid:vout, unlockUtxos without outpoints releases all locks of the token. */
+    outpoints?: string[];
+    /** Duration of the lock in seconds, 60 seconds by default, at most one hour. */
+    ttl?: number;
 }
 export interface WsBalanceHistoryReq {
     /** Address or XPUB descriptor to query history for. */
@@ -764,11 +1340,45 @@ export interface WsSendTransactionReq {
     /** Use alternative RPC method to broadcast transaction. */
     disableAlternativeRpc: boolean;
 }
+export interface WsValidateTransactionReq {
+    /** Hex-encoded transaction data to validate. */
+    hex: string;
+}
+export interface WsAnalyzePsbtReq {
+    /** Base64 or hex encoded PSBT (BIP-174). */
+    psbt: string;
+}
 export interface WsSubscribeAddressesReq {
     /** List of addresses to subscribe for updates (e.g., new transactions). */
     addresses: string[];
     /** If true, also publish confirmed transactions for subscribed addresses when new blocks are connected. */
     newBlockTxs?: boolean;
+    /** If true, also notify when an unconfirmed transaction of the subscribed addresses is replaced or evicted from the mempool. */
+    mempoolRemovals?: boolean;
+    /** If true, also notify when a new mempool transaction spends the same outpoints as an unconfirmed transaction of the subscribed addresses. */
+    doubleSpends?: boolean;
+    /** If true, also notify when a confirmed transaction of the subscribed addresses is disconnected by a chain reorganization. */
+    reorgs?: boolean;
+    /** If true, the subscription is kept for a while after the disconnection and can be resumed by resumeSession. */
+    session?: boolean;
+}
+export interface WsResumeSessionReq {
+    /** Identifier of the session returned by subscribeAddresses. */
+    sessionId: string;
+    /** Sequence number of the last notification received by the client, the newer notifications are replayed. */
+    lastSeq: number;
+}
+export interface WsSubscribeTxConfirmationsReq {
+    /** List of transactions to watch. */
+    txids: string[];
+    /** Numbers of confirmations at which a notification is sent (e.g. [1, 3, 6]). */
+    confirmations: number[];
+}
+export interface WsSubscribeContractEventsReq {
+    /** Address of the contract emitting the events. */
+    contract: string;
+    /** Filter of the event topics by position as in eth_getLogs, an empty position matches any topic, otherwise the topic must be one of the listed values. */
+    topics?: string[][];
 }
 export interface WsSubscribeFiatRatesReq {
     /** Fiat currency code (e.g. 'USD'). */
@@ -86,6 +86,14 @@ var (
 	resyncMempoolPeriodMs = flag.Int("resyncmempoolperiod", 60017, "resync mempool period in milliseconds")
 
 	extendedIndex = flag.Bool("extendedindex", false, "if true, create index of input txids and spending transactions")
+
+	txLifecycleDays = flag.Int("txlifecycledays", 0, "number of days the recorded lifecycle of the mempool transactions is kept, 0 disables the recording")
+
+	feeEstimateBacktest = flag.Bool("feeestimatebacktest", false, "if true, compare the fee estimates with the fee rates of the mined blocks")
+
+	rebroadcastPeriod = flag.Int("rebroadcastperiod", 0, "period in seconds of the rebroadcasting of the unconfirmed transactions sent through Blockbook, 0 disables the broadcast queue")
+
+	webhookWorkers = flag.Int("webhookworkers", 0, "number of parallel deliveries of the webhooks registered in the internal server, 0 disables the webhooks")
 )
 
 var (
@@ -95,6 +103,8 @@ var (
 	chanSyncIndexDone             = make(chan struct{})
 	chanSyncMempoolDone           = make(chan struct{})
 	chanStoreInternalStateDone    = make(chan struct{})
+	chanBroadcastQueue            = make(chan struct{}, 1)
+	chanBroadcastQueueDone        = make(chan struct{})
 	chain                         bchain.BlockChain
 	mempool                       bchain.Mempool
 	index                         *db.RocksDB
@@ -103,9 +113,13 @@ var (
 	syncWorker                    *db.SyncWorker
 	internalState                 *common.InternalState
 	fiatRates                     *fiat.FiatRates
+	apiWorker                     *api.Worker
 	callbacksOnNewBlock           []bchain.OnNewBlockFunc
 	callbacksOnNewTxAddr          []bchain.OnNewTxAddrFunc
 	callbacksOnNewTx              []bchain.OnNewTxFunc
+	callbacksOnTxRemoved          []bchain.OnTxRemovedFunc
+	callbacksOnDoubleSpend        []bchain.OnDoubleSpendFunc
+	callbacksOnReorg              []bchain.OnReorgFunc
 	callbacksOnNewFiatRatesTicker []fiat.OnNewFiatRatesTicker
 	chanOsSignal                  chan os.Signal
 )
@@ -226,6 +240,16 @@ func mainWithExitCode() int {
 		internalState.SortedAddressContracts = true
 	}
 
+	// build the address activity index for addresses indexed before it existed
+	if !internalState.AddressActivityIndexed {
+		err = index.BuildAddressActivity(chanOsSignal)
+		if err != nil {
+			glog.Error("buildAddressActivity: ", err)
+			return exitCodeFatal
+		}
+		internalState.AddressActivityIndexed = true
+	}
+
 	index.SetInternalState(internalState)
 	if *fixUtxo {
 		err = index.StoreInternalState(internalState)
@@ -270,6 +294,7 @@ func mainWithExitCode() int {
 		glog.Errorf("NewSyncWorker %v", err)
 		return exitCodeFatal
 	}
+	syncWorker.SetOnReorg(onReorg)
 
 	// set the DbState to open at this moment, after all important workers are initialized
 	internalState.DbState = common.DbStateOpen
@@ -297,6 +322,12 @@ func mainWithExitCode() int {
 		return exitCodeFatal
 	}
 
+	// the worker shared by the background tasks
+	if apiWorker, err = api.NewWorker(index, chain, mempool, txCache, metrics, internalState, fiatRates); err != nil {
+		glog.Error("apiWorker ", err)
+		return exitCodeFatal
+	}
+
 	// report BlockbookAppInfo metric, only log possible error
 	if err = blockbookAppInfoMetric(index, chain, txCache, internalState, metrics); err != nil {
 		glog.Error("blockbookAppInfoMetric ", err)
@@ -340,6 +371,22 @@ func mainWithExitCode() int {
 			glog.Error("initializeMempool ", err)
 			return exitCodeFatal
 		}
+		if t, ok := mempool.(bchain.MempoolReplacementTracker); ok {
+			t.SetOnTxRemoved(onTxRemoved)
+			t.SetOnDoubleSpend(onDoubleSpend)
+		}
+		if *txLifecycleDays > 0 && chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
+			initTxLifecycleTracker()
+		}
+		if *feeEstimateBacktest {
+			initFeeEstimateBacktester()
+		}
+		if *rebroadcastPeriod > 0 && chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
+			initBroadcastQueue()
+		}
+		if *webhookWorkers > 0 {
+			initWebhookDispatcher()
+		}
 		var mempoolCount int
 		if mempoolCount, err = mempool.Resync(); err != nil {
 			glog.Error("resyncMempool ", err)
@@ -357,6 +404,9 @@ func mainWithExitCode() int {
 		callbacksOnNewBlock = append(callbacksOnNewBlock, publicServer.OnNewBlock)
 		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, publicServer.OnNewTxAddr)
 		callbacksOnNewTx = append(callbacksOnNewTx, publicServer.OnNewTx)
+		callbacksOnTxRemoved = append(callbacksOnTxRemoved, publicServer.OnTxRemoved)
+		callbacksOnDoubleSpend = append(callbacksOnDoubleSpend, publicServer.OnDoubleSpend)
+		callbacksOnReorg = append(callbacksOnReorg, publicServer.OnReorg)
 		callbacksOnNewFiatRatesTicker = append(callbacksOnNewFiatRatesTicker, publicServer.OnNewFiatRatesTicker)
 		publicServer.ConnectFullPublicInterface()
 	}
@@ -392,6 +442,10 @@ func mainWithExitCode() int {
 		close(chanSyncMempool)
 		<-chanSyncIndexDone
 		<-chanSyncMempoolDone
+		if internalState.EnableBroadcastQueue {
+			close(chanBroadcastQueue)
+			<-chanBroadcastQueueDone
+		}
 	}
 	<-chanStoreInternalStateDone
 	return exitCodeOK
@@ -540,6 +594,52 @@ func newInternalState(config *common.Config, d *db.RocksDB, enableSubNewTx bool)
 	return is, nil
 }
 
+// initTxLifecycleTracker registers the recording of the lifecycle of the mempool transactions to the callbacks
+func initTxLifecycleTracker() {
+	t := api.NewTxLifecycleTracker(apiWorker, time.Duration(*txLifecycleDays)*24*time.Hour)
+	callbacksOnNewBlock = append(callbacksOnNewBlock, t.OnNewBlock)
+	callbacksOnNewTx = append(callbacksOnNewTx, t.OnNewTx)
+	callbacksOnTxRemoved = append(callbacksOnTxRemoved, t.OnTxRemoved)
+}
+
+// initFeeEstimateBacktester registers the back-testing of the fee estimates to the new block callbacks
+func initFeeEstimateBacktester() {
+	if b := api.NewFeeEstimateBacktester(apiWorker); b != nil {
+		callbacksOnNewBlock = append(callbacksOnNewBlock, b.OnNewBlock)
+	}
+}
+
+// initBroadcastQueue enables the recording of the sent transactions and starts their rebroadcasting
+func initBroadcastQueue() {
+	period := time.Duration(*rebroadcastPeriod) * time.Second
+	q := api.NewBroadcastQueue(apiWorker, period)
+	internalState.EnableBroadcastQueue = true
+	// the statuses of the queued transactions are updated also after each new block
+	callbacksOnNewBlock = append(callbacksOnNewBlock, func(_ *bchain.Block) {
+		select {
+		case chanBroadcastQueue <- struct{}{}:
+		default:
+		}
+	})
+	go broadcastQueueLoop(q, period)
+}
+
+// initWebhookDispatcher registers the delivery of the webhooks to the callbacks
+func initWebhookDispatcher() {
+	d := api.NewWebhookDispatcher(apiWorker, *webhookWorkers)
+	callbacksOnNewBlock = append(callbacksOnNewBlock, d.OnNewBlock)
+	callbacksOnNewTx = append(callbacksOnNewTx, d.OnNewTx)
+	callbacksOnReorg = append(callbacksOnReorg, d.OnReorg)
+}
+
+// broadcastQueueLoop processes the queue every rebroadcast period, but at least once a minute
+func broadcastQueueLoop(q *api.BroadcastQueue, period time.Duration) {
+	defer close(chanBroadcastQueueDone)
+	glog.Info("broadcastQueueLoop starting")
+	common.TickAndDebounce(min(period, time.Minute), debounceResyncMempoolMs*time.Millisecond, chanBroadcastQueue, q.Process)
+	glog.Info("broadcastQueueLoop stopped")
+}
+
 func syncIndexLoop() {
 	defer close(chanSyncIndexDone)
 	glog.Info("syncIndexLoop starting")
@@ -669,6 +769,39 @@ func onNewTx(tx *bchain.MempoolTx) {
 	}
 }
 
+func onTxRemoved(removal *bchain.MempoolTxRemoval) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("onTxRemoved recovered from panic: ", r)
+		}
+	}()
+	for _, c := range callbacksOnTxRemoved {
+		c(removal)
+	}
+}
+
+func onDoubleSpend(doubleSpend *bchain.MempoolDoubleSpend) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("onDoubleSpend recovered from panic: ", r)
+		}
+	}()
+	for _, c := range callbacksOnDoubleSpend {
+		c(doubleSpend)
+	}
+}
+
+func onReorg(reorg *bchain.Reorg) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("onReorg recovered from panic: ", r)
+		}
+	}()
+	for _, c := range callbacksOnReorg {
+		c(reorg)
+	}
+}
+
 func pushSynchronizationHandler(nt bchain.NotificationType) {
 	glog.V(1).Info("MQ: notification ", nt)
 	if common.IsInShutdown() {
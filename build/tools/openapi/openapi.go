@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/trezor/blockbook/server"
+)
+
+// writeJSON writes the document indented, with a trailing newline
+func writeJSON(file string, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err.Error())
+	}
+	if err = os.WriteFile(file, append(b, '\n'), 0644); err != nil {
+		panic(err.Error())
+	}
+}
+
+func main() {
+	b, err := os.ReadFile("configs/environ.json")
+	if err != nil {
+		panic(err.Error())
+	}
+	var environ struct {
+		Version string `json:"version"`
+	}
+	if err = json.Unmarshal(b, &environ); err != nil {
+		panic(err.Error())
+	}
+	writeJSON("blockbook-openapi.json", server.OpenAPIDocument(environ.Version))
+	writeJSON("blockbook-asyncapi.json", server.AsyncAPIDocument(environ.Version))
+}
@@ -28,8 +28,17 @@ func main() {
 	t.Add(bchain.TronChainExtraData{})
 	t.Add(api.Tx{})
 	t.Add(api.FeeStats{})
+	t.Add(api.MempoolFeeHistogram{})
+	t.Add(api.MempoolPackage{})
+	t.Add(api.BroadcastTx{})
+	t.Add(api.TxValidation{})
+	t.Add(api.PsbtAnalysis{})
 	t.Add(api.Address{})
 	t.Add(api.Utxo{})
+	t.Add(api.UtxoLocks{})
+	t.Add(api.UtxoLockReq{})
+	t.Add(api.CoinSelectionReq{})
+	t.Add(api.CoinSelection{})
 	t.Add(api.BalanceHistory{})
 	t.Add(api.Blocks{})
 	t.Add(api.Block{})
@@ -38,11 +47,18 @@ func main() {
 	t.Add(api.FiatTicker{})
 	t.Add(api.FiatTickers{})
 	t.Add(api.AvailableVsCurrencies{})
+	t.Add(api.Portfolio{})
+	t.Add(api.PortfolioBalance{})
+	t.Add(api.ExportRow{})
+	t.Add(api.CostBasisReport{})
+	t.Add(api.BalanceAtHeight{})
 
 	// Websocket specific
 	t.Add(server.WsReq{})
 	t.Add(server.WsRes{})
 	t.Add(server.WsAccountInfoReq{})
+	t.Add(server.WsPortfolioReq{})
+	t.Add(server.WsPortfolioBalanceReq{})
 	t.Add(server.WsInfoRes{})
 	t.Add(server.WsBlockHashReq{})
 	t.Add(server.WsBlockHashRes{})
@@ -50,6 +66,8 @@ func main() {
 	t.Add(server.WsBlockFilterReq{})
 	t.Add(server.WsBlockFiltersBatchReq{})
 	t.Add(server.WsAccountUtxoReq{})
+	t.Add(server.WsSelectCoinsReq{})
+	t.Add(server.WsUtxoLockReq{})
 	t.Add(server.WsBalanceHistoryReq{})
 	t.Add(server.WsTransactionReq{})
 	t.Add(server.WsTransactionSpecificReq{})
@@ -57,7 +75,12 @@ func main() {
 	t.Add(server.WsEstimateFeeRes{})
 	t.Add(server.WsLongTermFeeRateRes{})
 	t.Add(server.WsSendTransactionReq{})
+	t.Add(server.WsValidateTransactionReq{})
+	t.Add(server.WsAnalyzePsbtReq{})
 	t.Add(server.WsSubscribeAddressesReq{})
+	t.Add(server.WsResumeSessionReq{})
+	t.Add(server.WsSubscribeTxConfirmationsReq{})
+	t.Add(server.WsSubscribeContractEventsReq{})
 	t.Add(server.WsSubscribeFiatRatesReq{})
 	t.Add(server.WsCurrentFiatRatesReq{})
 	t.Add(server.WsFiatRatesForTimestampsReq{})
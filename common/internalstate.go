@@ -50,6 +50,17 @@ type BackendInfo struct {
 	Consensus        interface{} `json:"consensus,omitempty" ts_doc:"Additional chain-specific consensus data."`
 }
 
+// FeeEstimateAccuracy contains the results of the back-testing of the recent fee estimates of a provider for a number of blocks
+type FeeEstimateAccuracy struct {
+	Provider        string  `json:"provider" ts_doc:"Source of the fee estimates (e.g. 'conservative', 'mempoolspace')."`
+	Blocks          int     `json:"blocks" ts_doc:"Confirmation target of the estimates in blocks."`
+	Evaluated       int     `json:"evaluated" ts_doc:"Number of evaluated estimates."`
+	Hits            int     `json:"hits" ts_doc:"Number of estimates sufficient for the inclusion in one of the target blocks."`
+	HitRate         float64 `json:"hitRate" ts_doc:"Ratio of the hits to the evaluated estimates."`
+	AvgOverpayment  float64 `json:"avgOverpayment" ts_doc:"Average relative overpayment of the hits over the required fee rate."`
+	AvgUnderpayment float64 `json:"avgUnderpayment" ts_doc:"Average relative underpayment of the misses under the required fee rate."`
+}
+
 // InternalState contains the data of the internal state
 type InternalState struct {
 	mux sync.Mutex `ts_doc:"Mutex for synchronized access to the internal state."`
@@ -89,11 +100,14 @@ type InternalState struct {
 
 	EnableSubNewTx bool `json:"-" ts_doc:"Internal flag controlling subscription to new transactions (not exposed)."`
 
+	EnableBroadcastQueue bool `json:"-" ts_doc:"Internal flag controlling recording of the sent transactions for rebroadcasting (not exposed)."`
+
 	BackendInfo BackendInfo `json:"-" ts_doc:"Information about the connected blockchain backend (not exposed in JSON)."`
 
 	// database migrations
 	UtxoChecked            bool `json:"utxoChecked" ts_doc:"Indicates if UTXO consistency checks have been performed."`
 	SortedAddressContracts bool `json:"sortedAddressContracts" ts_doc:"Indicates if address/contract sorting has been completed."`
+	AddressActivityIndexed bool `json:"addressActivityIndexed" ts_doc:"Indicates if the address activity index has been built for the existing addresses."`
 
 	// golomb filter settings
 	BlockGolombFilterP      uint8  `json:"block_golomb_filter_p" ts_doc:"Parameter P for building Golomb-Rice filters for blocks."`
@@ -103,6 +117,8 @@ type InternalState struct {
 	// allowed number of fetched accounts over websocket
 	WsGetAccountInfoLimit int            `json:"-" ts_doc:"Limit of how many getAccountInfo calls can be made via WS (not exposed)."`
 	WsLimitExceedingIPs   map[string]int `json:"-" ts_doc:"Tracks IP addresses exceeding the WS limit (not exposed)."`
+
+	FeeEstimateAccuracy []FeeEstimateAccuracy `json:"-" ts_doc:"Back-testing results of the fee estimates (not exposed)."`
 }
 
 // StartedSync signals start of synchronization
@@ -380,3 +396,17 @@ func (is *InternalState) ResetWsLimitExceedingIPs() {
 	defer is.mux.Unlock()
 	is.WsLimitExceedingIPs = make(map[string]int)
 }
+
+// SetFeeEstimateAccuracy sets the back-testing results of the fee estimates
+func (is *InternalState) SetFeeEstimateAccuracy(a []FeeEstimateAccuracy) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.FeeEstimateAccuracy = a
+}
+
+// GetFeeEstimateAccuracy returns the back-testing results of the fee estimates
+func (is *InternalState) GetFeeEstimateAccuracy() []FeeEstimateAccuracy {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.FeeEstimateAccuracy
+}
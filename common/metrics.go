@@ -42,7 +42,13 @@ type Metrics struct {
 	IndexDBSize                       prometheus.Gauge
 	ExplorerViews                     *prometheus.CounterVec
 	MempoolSize                       prometheus.Gauge
+	MempoolDoubleSpends               prometheus.Counter
 	EstimatedFee                      *prometheus.GaugeVec
+	FeeEstimateBacktests              *prometheus.CounterVec
+	FeeEstimateDeviation              *prometheus.HistogramVec
+	FeeEstimateAccuracy               *prometheus.GaugeVec
+	WebhookDeliveries                 *prometheus.CounterVec
+	WebhookDroppedEvents              *prometheus.CounterVec
 	AvgBlockPeriod                    prometheus.Gauge
 	SyncBlockStats                    *prometheus.GaugeVec
 	SyncHotnessStats                  *prometheus.GaugeVec
@@ -346,6 +352,13 @@ func GetMetrics(coin string) (*Metrics, error) {
 			ConstLabels: Labels{"coin": coin},
 		},
 	)
+	metrics.MempoolDoubleSpends = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name:        "blockbook_mempool_double_spends",
+			Help:        "Number of mempool transactions spending outpoints of other mempool transactions",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
 	metrics.EstimatedFee = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "blockbook_estimated_fee",
@@ -354,6 +367,47 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"blocks", "conservative"},
 	)
+	metrics.FeeEstimateBacktests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_fee_estimate_backtests",
+			Help:        "Number of fee estimates compared to the fee rates of the mined blocks by provider, number of blocks and result",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"provider", "blocks", "result"},
+	)
+	metrics.FeeEstimateDeviation = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_fee_estimate_deviation",
+			Help:        "Relative difference of the fee estimate and the fee rate required by the mined blocks by provider and number of blocks",
+			Buckets:     []float64{-0.75, -0.5, -0.25, -0.1, 0, 0.1, 0.25, 0.5, 1, 2, 5},
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"provider", "blocks"},
+	)
+	metrics.FeeEstimateAccuracy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_fee_estimate_accuracy",
+			Help:        "Hit rate, average overpayment and underpayment of the recent fee estimates by provider and number of blocks",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"provider", "blocks", "stat"},
+	)
+	metrics.WebhookDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_webhook_deliveries",
+			Help:        "Number of webhook delivery attempts by event and result",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"event", "result"},
+	)
+	metrics.WebhookDroppedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_webhook_dropped_events",
+			Help:        "Number of blocks, mempool transactions and reorgs not processed by webhooks because the events queue was full",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"event"},
+	)
 	metrics.AvgBlockPeriod = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name:        "blockbook_avg_block_period",
@@ -0,0 +1,165 @@
+package db
+
+import (
+	vlq "github.com/bsm/go-vlq"
+	"github.com/juju/errors"
+)
+
+// BroadcastTxStatus is the state of a transaction in the broadcast queue
+type BroadcastTxStatus uint8
+
+const (
+	// BroadcastTxPending - the transaction is not confirmed and is periodically rebroadcast
+	BroadcastTxPending BroadcastTxStatus = iota
+	// BroadcastTxConfirmed - the transaction was mined
+	BroadcastTxConfirmed
+	// BroadcastTxConflicted - an input of the transaction was spent by another mined transaction
+	BroadcastTxConflicted
+	// BroadcastTxReplaced - the transaction was replaced in the mempool by a conflicting transaction
+	BroadcastTxReplaced
+	// BroadcastTxExpired - the transaction was not confirmed within the maximal time of rebroadcasting
+	BroadcastTxExpired
+)
+
+// BroadcastTx is a transaction sent through Blockbook, recorded for the rebroadcasting until it is confirmed
+type BroadcastTx struct {
+	Tx             []byte
+	FirstBroadcast int64
+	LastBroadcast  int64
+	Broadcasts     uint32
+	Status         BroadcastTxStatus
+	// StatusTime is the unix time of the last change of the status
+	StatusTime  int64
+	BlockHeight uint32
+	ReplacedBy  string
+	// LastError is the error returned by the backends in the last broadcast, empty if it succeeded
+	LastError string
+}
+
+// BroadcastTxCallback is called for each transaction in the broadcast queue, the iteration stops if it returns an error
+type BroadcastTxCallback func(txid string, bt *BroadcastTx) error
+
+func (d *RocksDB) packBroadcastTx(bt *BroadcastTx) ([]byte, error) {
+	buf := make([]byte, 0, 8*vlq.MaxLen64+len(bt.Tx)+len(bt.LastError)+d.chainParser.PackedTxidLen())
+	varBuf := make([]byte, vlq.MaxLen64)
+	for _, v := range []uint64{uint64(bt.FirstBroadcast), uint64(bt.LastBroadcast), uint64(bt.Broadcasts), uint64(bt.Status),
+		uint64(bt.StatusTime), uint64(bt.BlockHeight), uint64(len(bt.Tx))} {
+		n := vlq.PutUint(varBuf, v)
+		buf = append(buf, varBuf[:n]...)
+	}
+	buf = append(buf, bt.Tx...)
+	n := vlq.PutUint(varBuf, uint64(len(bt.LastError)))
+	buf = append(buf, varBuf[:n]...)
+	buf = append(buf, bt.LastError...)
+	if bt.ReplacedBy != "" {
+		btxid, err := d.chainParser.PackTxid(bt.ReplacedBy)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, btxid...)
+	}
+	return buf, nil
+}
+
+func (d *RocksDB) unpackBroadcastTx(buf []byte) (*BroadcastTx, error) {
+	var v [7]uint64
+	for i := range v {
+		u, n := vlq.Uint(buf)
+		if n <= 0 {
+			return nil, errors.New("Invalid broadcast tx data")
+		}
+		v[i] = u
+		buf = buf[n:]
+	}
+	if uint64(len(buf)) < v[6] {
+		return nil, errors.New("Invalid broadcast tx data")
+	}
+	bt := &BroadcastTx{
+		FirstBroadcast: int64(v[0]),
+		LastBroadcast:  int64(v[1]),
+		Broadcasts:     uint32(v[2]),
+		Status:         BroadcastTxStatus(v[3]),
+		StatusTime:     int64(v[4]),
+		BlockHeight:    uint32(v[5]),
+		Tx:             append([]byte(nil), buf[:v[6]]...),
+	}
+	buf = buf[v[6]:]
+	l, n := vlq.Uint(buf)
+	if n <= 0 || uint64(len(buf)-n) < l {
+		return nil, errors.New("Invalid broadcast tx data")
+	}
+	bt.LastError = string(buf[n : n+int(l)])
+	buf = buf[n+int(l):]
+	if len(buf) > 0 {
+		txid, err := d.chainParser.UnpackTxid(buf)
+		if err != nil {
+			return nil, err
+		}
+		bt.ReplacedBy = txid
+	}
+	return bt, nil
+}
+
+// GetBroadcastTx returns the transaction from the broadcast queue or nil if it is not there
+func (d *RocksDB) GetBroadcastTx(txid string) (*BroadcastTx, error) {
+	key, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfBroadcastTxs], key)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if data == nil {
+		return nil, nil
+	}
+	bt, err := d.unpackBroadcastTx(data)
+	if err != nil {
+		return nil, errors.Annotatef(err, "broadcast tx %v", txid)
+	}
+	return bt, nil
+}
+
+// StoreBroadcastTx stores (creates or replaces) the transaction in the broadcast queue
+func (d *RocksDB) StoreBroadcastTx(txid string, bt *BroadcastTx) error {
+	key, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return err
+	}
+	val, err := d.packBroadcastTx(bt)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfBroadcastTxs], key, val)
+}
+
+// DeleteBroadcastTx removes the transaction from the broadcast queue
+func (d *RocksDB) DeleteBroadcastTx(txid string) error {
+	key, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return err
+	}
+	return d.db.DeleteCF(d.wo, d.cfh[cfBroadcastTxs], key)
+}
+
+// IterateBroadcastTxs calls fn for each transaction in the broadcast queue
+func (d *RocksDB) IterateBroadcastTxs(fn BroadcastTxCallback) error {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfBroadcastTxs])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		txid, err := d.chainParser.UnpackTxid(it.Key().Data())
+		if err != nil {
+			return err
+		}
+		bt, err := d.unpackBroadcastTx(it.Value().Data())
+		if err != nil {
+			return errors.Annotatef(err, "broadcast tx %v", txid)
+		}
+		if err = fn(txid, bt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
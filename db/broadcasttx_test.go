@@ -0,0 +1,76 @@
+//go:build unittest
+
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksBroadcastTxs(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	bt, err := d.GetBroadcastTx(dbtestdata.TxidB1T1)
+	if err != nil || bt != nil {
+		t.Fatalf("GetBroadcastTx(missing) = %v, %v, want nil, nil", bt, err)
+	}
+
+	pending := &BroadcastTx{
+		Tx:             []byte{1, 2, 3, 4},
+		FirstBroadcast: 1600000000,
+		LastBroadcast:  1600000600,
+		Broadcasts:     2,
+		StatusTime:     1600000000,
+		LastError:      "-26: txn-mempool-conflict",
+	}
+	replaced := &BroadcastTx{
+		Tx:             []byte{5, 6},
+		FirstBroadcast: 1500000000,
+		LastBroadcast:  1500000000,
+		Broadcasts:     1,
+		Status:         BroadcastTxReplaced,
+		StatusTime:     1500000100,
+		ReplacedBy:     dbtestdata.TxidB1T1,
+	}
+	if err := d.StoreBroadcastTx(dbtestdata.TxidB1T1, pending); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.StoreBroadcastTx(dbtestdata.TxidB1T2, replaced); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*BroadcastTx{
+		dbtestdata.TxidB1T1: pending,
+		dbtestdata.TxidB1T2: replaced,
+	}
+	for txid, w := range want {
+		got, err := d.GetBroadcastTx(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("GetBroadcastTx(%v) = %+v, want %+v", txid, got, w)
+		}
+	}
+	got := make(map[string]*BroadcastTx)
+	if err := d.IterateBroadcastTxs(func(txid string, bt *BroadcastTx) error {
+		got[txid] = bt
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IterateBroadcastTxs() = %+v, want %+v", got, want)
+	}
+
+	if err := d.DeleteBroadcastTx(dbtestdata.TxidB1T2); err != nil {
+		t.Fatal(err)
+	}
+	if bt, err := d.GetBroadcastTx(dbtestdata.TxidB1T2); err != nil || bt != nil {
+		t.Errorf("GetBroadcastTx after delete = %v, %v, want nil, nil", bt, err)
+	}
+}
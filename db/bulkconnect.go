@@ -180,6 +180,11 @@ func (b *BulkConnect) parallelStoreBalances(c chan error, all bool) {
 }
 
 func (b *BulkConnect) storeBulkAddresses(wb *grocksdb.WriteBatch) error {
+	if b.chainType == bchain.ChainEthereumType {
+		if err := b.d.storeAddressActivity(wb, b.bulkAddresses); err != nil {
+			return err
+		}
+	}
 	for _, ba := range b.bulkAddresses {
 		if err := b.d.storeAddresses(wb, ba.bi.Height, ba.addresses); err != nil {
 			return err
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+)
+
+// portfolioPrunePeriod is the minimal period between the removals of the expired portfolios when the limit of the portfolios is reached
+const portfolioPrunePeriod = time.Hour
+
+// PortfolioAccount is a single member of a portfolio - an address, xpub/output descriptor or EVM account
+type PortfolioAccount struct {
+	Descriptor string `json:"descriptor"`
+	Label      string `json:"label,omitempty"`
+}
+
+// Portfolio is a server side stored group of watch-only accounts, identified by an opaque token
+type Portfolio struct {
+	Name     string             `json:"name,omitempty"`
+	Accounts []PortfolioAccount `json:"accounts"`
+	Created  int64              `json:"created"`
+	Updated  int64              `json:"updated"`
+	Accessed int64              `json:"accessed,omitempty"`
+}
+
+// LastUse returns the unix time of the last update or read of the portfolio
+func (p *Portfolio) LastUse() int64 {
+	if p.Accessed > p.Updated {
+		return p.Accessed
+	}
+	return p.Updated
+}
+
+// GetPortfolio returns the portfolio stored under the token or nil if it does not exist
+func (d *RocksDB) GetPortfolio(token string) (*Portfolio, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfPortfolios], []byte(token))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if data == nil {
+		return nil, nil
+	}
+	var p Portfolio
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, errors.Annotatef(err, "portfolio %v", token)
+	}
+	return &p, nil
+}
+
+// StorePortfolio stores (creates or replaces) the portfolio under the token
+func (d *RocksDB) StorePortfolio(token string, p *Portfolio) error {
+	if len(token) == 0 {
+		return errors.New("Missing portfolio token")
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfPortfolios], []byte(token), data)
+}
+
+// AddPortfolio stores a new portfolio under the token if fewer than maxPortfolios portfolios are stored,
+// returns false if the limit is reached even after the removal of the portfolios not used since expiredBefore
+func (d *RocksDB) AddPortfolio(token string, p *Portfolio, maxPortfolios int, expiredBefore int64) (bool, error) {
+	d.portfolioMux.Lock()
+	defer d.portfolioMux.Unlock()
+	if d.portfolioCount < 0 || (d.portfolioCount >= maxPortfolios && time.Since(d.lastPortfolioPrune) >= portfolioPrunePeriod) {
+		if _, err := d.prunePortfolios(expiredBefore); err != nil {
+			return false, err
+		}
+	}
+	if d.portfolioCount >= maxPortfolios {
+		return false, nil
+	}
+	if err := d.StorePortfolio(token, p); err != nil {
+		return false, err
+	}
+	d.portfolioCount++
+	return true, nil
+}
+
+// DeletePortfolio removes the portfolio stored under the token
+func (d *RocksDB) DeletePortfolio(token string) error {
+	d.portfolioMux.Lock()
+	defer d.portfolioMux.Unlock()
+	if err := d.db.DeleteCF(d.wo, d.cfh[cfPortfolios], []byte(token)); err != nil {
+		return err
+	}
+	if d.portfolioCount > 0 {
+		d.portfolioCount--
+	}
+	return nil
+}
+
+// PrunePortfolios removes the portfolios not used since the given unix time, returns the number of removed portfolios
+func (d *RocksDB) PrunePortfolios(expiredBefore int64) (int, error) {
+	d.portfolioMux.Lock()
+	defer d.portfolioMux.Unlock()
+	return d.prunePortfolios(expiredBefore)
+}
+
+func (d *RocksDB) prunePortfolios(expiredBefore int64) (int, error) {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfPortfolios])
+	defer it.Close()
+	count, removed := 0, 0
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		var p Portfolio
+		if err := json.Unmarshal(it.Value().Data(), &p); err != nil || p.LastUse() < expiredBefore {
+			wb.DeleteCF(d.cfh[cfPortfolios], it.Key().Data())
+			removed++
+		} else {
+			count++
+		}
+	}
+	if removed > 0 {
+		if err := d.db.Write(d.wo, wb); err != nil {
+			return 0, err
+		}
+	}
+	d.portfolioCount = count
+	d.lastPortfolioPrune = time.Now()
+	return removed, nil
+}
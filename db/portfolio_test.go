@@ -0,0 +1,96 @@
+//go:build unittest
+
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRocksPortfolio(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	p, err := d.GetPortfolio("missing")
+	if err != nil || p != nil {
+		t.Fatalf("GetPortfolio(missing) = %v, %v, want nil, nil", p, err)
+	}
+
+	want := &Portfolio{
+		Name: "savings",
+		Accounts: []PortfolioAccount{
+			{Descriptor: "mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz", Label: "cold"},
+			{Descriptor: "upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q"},
+		},
+		Created: 1600000000,
+		Updated: 1600000001,
+	}
+	if err := d.StorePortfolio("token1", want); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.StorePortfolio("", want); err == nil {
+		t.Fatal("StorePortfolio with empty token: expected error")
+	}
+	got, err := d.GetPortfolio("token1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPortfolio() = %+v, want %+v", got, want)
+	}
+
+	if err := d.DeletePortfolio("token1"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = d.GetPortfolio("token1")
+	if err != nil || got != nil {
+		t.Errorf("GetPortfolio after delete = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestRocksPortfolioLimit(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	p := func(updated, accessed int64) *Portfolio {
+		return &Portfolio{
+			Accounts: []PortfolioAccount{{Descriptor: "mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"}},
+			Created:  updated,
+			Updated:  updated,
+			Accessed: accessed,
+		}
+	}
+	// token1 is expired, token2 was accessed after the expiration limit
+	if err := d.StorePortfolio("token1", p(1000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.StorePortfolio("token2", p(1000, 3000)); err != nil {
+		t.Fatal(err)
+	}
+	added, err := d.AddPortfolio("token3", p(3000, 0), 2, 2000)
+	if err != nil || !added {
+		t.Fatalf("AddPortfolio(token3) = %v, %v, want true, nil", added, err)
+	}
+	if got, _ := d.GetPortfolio("token1"); got != nil {
+		t.Errorf("expired portfolio token1 was not pruned")
+	}
+	added, err = d.AddPortfolio("token4", p(3000, 0), 2, 2000)
+	if err != nil || added {
+		t.Fatalf("AddPortfolio(token4) = %v, %v, want false, nil", added, err)
+	}
+	if err := d.DeletePortfolio("token2"); err != nil {
+		t.Fatal(err)
+	}
+	added, err = d.AddPortfolio("token4", p(3000, 0), 2, 2000)
+	if err != nil || !added {
+		t.Fatalf("AddPortfolio(token4) after delete = %v, %v, want true, nil", added, err)
+	}
+	removed, err := d.PrunePortfolios(4000)
+	if err != nil || removed != 2 {
+		t.Errorf("PrunePortfolios() = %v, %v, want 2, nil", removed, err)
+	}
+}
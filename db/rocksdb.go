@@ -84,6 +84,10 @@ type RocksDB struct {
 	addrContractsCacheBytes int64
 	hotAddrTracker          *addressHotness
 	setBlockTimesWG         sync.WaitGroup
+	portfolioMux            sync.Mutex
+	// portfolioCount is the number of stored portfolios, -1 until they are counted by the first prune
+	portfolioCount     int
+	lastPortfolioPrune time.Time
 }
 
 const (
@@ -93,6 +97,11 @@ const (
 	cfBlockTxs
 	cfTransactions
 	cfFiatRates
+	cfPortfolios
+	cfTxLifecycle
+	cfBroadcastTxs
+	cfWebhooks
+	cfWebhookDeadLetters
 	// BitcoinType
 	cfAddressBalance
 	cfTxAddresses
@@ -109,15 +118,18 @@ const (
 
 	// TODO move to common section
 	cfAddressAliases
+
+	// EthereumType
+	cfAddressActivity
 )
 
 // common columns
 var cfNames []string
-var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "fiatRates"}
+var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "fiatRates", "portfolios", "txLifecycle", "broadcastTxs", "webhooks", "webhookDeadLetters"}
 
 // type specific columns
 var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "blockFilter"}
-var cfNamesEthereumType = []string{"addressContracts", "internalData", "contracts", "functionSignatures", "blockInternalDataErrors", "addressAliases"}
+var cfNamesEthereumType = []string{"addressContracts", "internalData", "contracts", "functionSignatures", "blockInternalDataErrors", "addressAliases", "addressActivity"}
 
 func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*grocksdb.ColumnFamilyHandle, error) {
 	// opts with bloom filter
@@ -125,8 +137,8 @@ func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*gro
 	// opts for addresses without bloom filter
 	// from documentation: if most of your queries are executed using iterators, you shouldn't set bloom filter
 	optsAddresses := createAndSetDBOptions(0, c, openFiles)
-	// default, height, addresses, blockTxids, transactions
-	cfOptions := []*grocksdb.Options{opts, opts, optsAddresses, opts, opts, opts}
+	// default, height, addresses, blockTxids, transactions, fiatRates, portfolios, txLifecycle, broadcastTxs, webhooks, webhookDeadLetters
+	cfOptions := []*grocksdb.Options{opts, opts, optsAddresses, opts, opts, opts, opts, opts, opts, opts, opts}
 	// append type specific options
 	count := len(cfNames) - len(cfOptions)
 	for i := 0; i < count; i++ {
@@ -182,6 +194,7 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 		addrContractsCacheMaxBytes: 0,
 		addrContractsCacheBytes:    0,
 		hotAddrTracker:             nil,
+		portfolioCount:             -1,
 	}
 	if chainType == bchain.ChainEthereumType {
 		r.hotAddrTracker = newAddressHotnessFromParser(parser)
@@ -469,6 +482,9 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		if err := d.storeAndCleanupBlockTxsEthereumType(wb, block, blockTxs); err != nil {
 			return err
 		}
+		if err := d.storeAddressActivity(wb, []bulkAddresses{{bi: BlockInfo{Height: block.Height, Time: block.Time}, addresses: addresses}}); err != nil {
+			return err
+		}
 	} else {
 		return errors.New("Unknown chain type")
 	}
@@ -1489,6 +1505,105 @@ func (d *RocksDB) GetBlockHash(height uint32) (string, error) {
 	return info.Hash, nil
 }
 
+// GetBlockTxids returns the ids of the transactions of the block stored in db,
+// the transactions are kept only for the blocks which can be disconnected in a reorg
+func (d *RocksDB) GetBlockTxids(height uint32) ([]string, error) {
+	var btxIDs [][]byte
+	if d.chainParser.GetChainType() == bchain.ChainEthereumType {
+		bt, err := d.getBlockTxsEthereumType(height)
+		if err != nil {
+			return nil, err
+		}
+		for i := range bt {
+			btxIDs = append(btxIDs, bt[i].btxID)
+		}
+	} else {
+		bt, err := d.getBlockTxs(height)
+		if err != nil {
+			return nil, err
+		}
+		for i := range bt {
+			btxIDs = append(btxIDs, bt[i].btxID)
+		}
+	}
+	txids := make([]string, len(btxIDs))
+	for i, btxID := range btxIDs {
+		txid, err := d.chainParser.UnpackTxid(btxID)
+		if err != nil {
+			return nil, err
+		}
+		txids[i] = txid
+	}
+	return txids, nil
+}
+
+// GetBlockTxAddrDescs returns the address descriptors involved in the transactions of the block, by txid
+func (d *RocksDB) GetBlockTxAddrDescs(height uint32) (map[string][]bchain.AddressDescriptor, error) {
+	r := make(map[string][]bchain.AddressDescriptor)
+	add := func(btxID []byte, ads ...bchain.AddressDescriptor) error {
+		txid, err := d.chainParser.UnpackTxid(btxID)
+		if err != nil {
+			return err
+		}
+		txAds := r[txid]
+		for _, ad := range ads {
+			found := len(ad) == 0
+			for j := 0; j < len(txAds) && !found; j++ {
+				found = bytes.Equal(txAds[j], ad)
+			}
+			if !found {
+				txAds = append(txAds, ad)
+			}
+		}
+		r[txid] = txAds
+		return nil
+	}
+	if d.chainParser.GetChainType() == bchain.ChainEthereumType {
+		bt, err := d.getBlockTxsEthereumType(height)
+		if err != nil {
+			return nil, err
+		}
+		for i := range bt {
+			ads := []bchain.AddressDescriptor{bt[i].from, bt[i].to}
+			for j := range bt[i].contracts {
+				ads = append(ads, bt[i].contracts[j].from, bt[i].contracts[j].to)
+			}
+			if bt[i].internalData != nil {
+				for j := range bt[i].internalData.transfers {
+					ads = append(ads, bt[i].internalData.transfers[j].from, bt[i].internalData.transfers[j].to)
+				}
+			}
+			if err = add(bt[i].btxID, ads...); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	bt, err := d.getBlockTxs(height)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bt {
+		ta, err := d.getTxAddresses(bt[i].btxID)
+		if err != nil {
+			return nil, err
+		}
+		var ads []bchain.AddressDescriptor
+		if ta != nil {
+			for j := range ta.Inputs {
+				ads = append(ads, ta.Inputs[j].AddrDesc)
+			}
+			for j := range ta.Outputs {
+				ads = append(ads, ta.Outputs[j].AddrDesc)
+			}
+		}
+		if err = add(bt[i].btxID, ads...); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
 // GetBlockInfo returns block info stored in db
 func (d *RocksDB) GetBlockInfo(height uint32) (*BlockInfo, error) {
 	key := packUint(height)
@@ -2088,6 +2203,7 @@ func (d *RocksDB) LoadInternalState(config *common.Config) (*common.InternalStat
 			Coin:                    config.CoinName,
 			UtxoChecked:             true,
 			SortedAddressContracts:  true,
+			AddressActivityIndexed:  true,
 			ExtendedIndex:           d.extendedIndex,
 			BlockGolombFilterP:      config.BlockGolombFilterP,
 			BlockFilterScripts:      config.BlockFilterScripts,
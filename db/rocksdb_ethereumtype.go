@@ -3,6 +3,7 @@ package db
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
 	"math/big"
 	"os"
 	"sort"
@@ -1442,7 +1443,7 @@ func (d *RocksDB) disconnectInternalData(btxID []byte, addresses map[string]map[
 	return nil
 }
 
-func (d *RocksDB) disconnectBlockTxsEthereumType(wb *grocksdb.WriteBatch, height uint32, blockTxs []ethBlockTx, contracts map[string]*unpackedAddrContracts) error {
+func (d *RocksDB) disconnectBlockTxsEthereumType(wb *grocksdb.WriteBatch, height uint32, blockTxs []ethBlockTx, contracts map[string]*unpackedAddrContracts, activity map[string]uint32) error {
 	glog.Info("Disconnecting block ", height, " containing ", len(blockTxs), " transactions")
 	addresses := make(map[string]map[string]struct{})
 	for i := range blockTxs {
@@ -1477,9 +1478,10 @@ func (d *RocksDB) disconnectBlockTxsEthereumType(wb *grocksdb.WriteBatch, height
 		wb.DeleteCF(d.cfh[cfTransactions], blockTx.btxID)
 		wb.DeleteCF(d.cfh[cfInternalData], blockTx.btxID)
 	}
-	for a := range addresses {
+	for a, txs := range addresses {
 		key := packAddressKey([]byte(a), height)
 		wb.DeleteCF(d.cfh[cfAddresses], key)
+		activity[a] += uint32(len(txs))
 	}
 	return nil
 }
@@ -1502,8 +1504,9 @@ func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32)
 	wb := grocksdb.NewWriteBatch()
 	defer wb.Destroy()
 	contracts := make(map[string]*unpackedAddrContracts)
+	activity := make(map[string]uint32)
 	for height := higher; height >= lower; height-- {
-		if err := d.disconnectBlockTxsEthereumType(wb, height, blocks[height-lower], contracts); err != nil {
+		if err := d.disconnectBlockTxsEthereumType(wb, height, blocks[height-lower], contracts, activity); err != nil {
 			return err
 		}
 		key := packUint(height)
@@ -1512,6 +1515,9 @@ func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32)
 		wb.DeleteCF(d.cfh[cfBlockInternalDataErrors], key)
 	}
 	d.storeUnpackedAddressContracts(wb, contracts)
+	if err := d.disconnectAddressActivity(wb, lower, activity); err != nil {
+		return err
+	}
 	err := d.WriteBatch(wb)
 	if err == nil {
 		d.is.RemoveLastBlockTimes(int(higher-lower) + 1)
@@ -2008,3 +2014,240 @@ func (d *RocksDB) periodicStoreAddrContractsCache() {
 		d.storeAddrContractsCache()
 	}
 }
+
+// AddrActivity holds the number of transactions of an address and the first and the last block in which it was active
+type AddrActivity struct {
+	Txs         uint32
+	FirstHeight uint32
+	FirstTime   int64
+	LastHeight  uint32
+	LastTime    int64
+}
+
+func packAddrActivity(a *AddrActivity) []byte {
+	buf := make([]byte, 0, 5*vlq.MaxLen64)
+	varBuf := make([]byte, vlq.MaxLen64)
+	l := packVaruint(uint(a.Txs), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(a.FirstHeight), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVarint(int(a.FirstTime), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(a.LastHeight), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVarint(int(a.LastTime), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	return buf
+}
+
+func unpackAddrActivity(buf []byte) (*AddrActivity, error) {
+	var a AddrActivity
+	v, l := unpackVaruint(buf)
+	a.Txs = uint32(v)
+	buf = buf[l:]
+	v, l = unpackVaruint(buf)
+	a.FirstHeight = uint32(v)
+	buf = buf[l:]
+	t, l := unpackVarint(buf)
+	a.FirstTime = int64(t)
+	buf = buf[l:]
+	v, l = unpackVaruint(buf)
+	a.LastHeight = uint32(v)
+	buf = buf[l:]
+	t, l = unpackVarint(buf)
+	a.LastTime = int64(t)
+	if l == 0 {
+		return nil, errors.New("Invalid address activity")
+	}
+	return &a, nil
+}
+
+// GetAddrDescActivity returns the activity of the address or nil if the address was never active
+func (d *RocksDB) GetAddrDescActivity(addrDesc bchain.AddressDescriptor) (*AddrActivity, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressActivity], addrDesc)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return unpackAddrActivity(buf)
+}
+
+// storeAddressActivity merges the addresses of the blocks, ordered by height, to the address activity records
+func (d *RocksDB) storeAddressActivity(wb *grocksdb.WriteBatch, blocks []bulkAddresses) error {
+	activity := make(map[string]*AddrActivity)
+	for i := range blocks {
+		b := &blocks[i]
+		for addrDesc, txi := range b.addresses {
+			a, found := activity[addrDesc]
+			if !found {
+				var err error
+				if a, err = d.GetAddrDescActivity(bchain.AddressDescriptor(addrDesc)); err != nil {
+					return err
+				}
+				if a == nil {
+					a = &AddrActivity{FirstHeight: b.bi.Height, FirstTime: b.bi.Time}
+				}
+				activity[addrDesc] = a
+			}
+			a.Txs += uint32(len(txi))
+			a.LastHeight = b.bi.Height
+			a.LastTime = b.bi.Time
+		}
+	}
+	for addrDesc, a := range activity {
+		wb.PutCF(d.cfh[cfAddressActivity], bchain.AddressDescriptor(addrDesc), packAddrActivity(a))
+	}
+	return nil
+}
+
+// addrDescHeightRange returns the lowest and the highest height at which the address has transactions in the addresses column,
+// considering only heights up to the higher limit
+func (d *RocksDB) addrDescHeightRange(addrDesc bchain.AddressDescriptor, higher uint32) (uint32, uint32, bool, error) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfAddresses])
+	defer it.Close()
+	isAddrKey := func() bool {
+		key := it.Key().Data()
+		return len(key) == len(addrDesc)+packedHeightBytes && bytes.HasPrefix(key, addrDesc)
+	}
+	// the heights are packed in binary complement, the highest height is the first key of the address
+	it.Seek(packAddressKey(addrDesc, higher))
+	if !it.Valid() || !isAddrKey() {
+		return 0, 0, false, nil
+	}
+	_, last, err := unpackAddressKey(it.Key().Data())
+	if err != nil {
+		return 0, 0, false, err
+	}
+	// and the lowest height is the last key of the address
+	stopKey := packAddressKey(addrDesc, 0)
+	it.Seek(stopKey)
+	if !it.Valid() {
+		it.SeekToLast()
+	} else if !bytes.Equal(it.Key().Data(), stopKey) {
+		it.Prev()
+	}
+	if !it.Valid() || !isAddrKey() {
+		return 0, 0, false, errors.Errorf("addrDescHeightRange %v: inconsistent addresses index", addrDesc)
+	}
+	_, first, err := unpackAddressKey(it.Key().Data())
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return first, last, true, nil
+}
+
+func (d *RocksDB) blockTime(height uint32) (int64, error) {
+	bi, err := d.GetBlockInfo(height)
+	if err != nil || bi == nil {
+		return 0, err
+	}
+	return bi.Time, nil
+}
+
+// disconnectAddressActivity updates the activity records of the addresses after the removal of blocks from the lower height,
+// removed contains the number of the removed transactions of each address
+func (d *RocksDB) disconnectAddressActivity(wb *grocksdb.WriteBatch, lower uint32, removed map[string]uint32) error {
+	for addrDesc, txs := range removed {
+		ad := bchain.AddressDescriptor(addrDesc)
+		a, err := d.GetAddrDescActivity(ad)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			continue
+		}
+		if a.FirstHeight >= lower || lower == 0 {
+			wb.DeleteCF(d.cfh[cfAddressActivity], ad)
+			continue
+		}
+		if a.Txs > txs {
+			a.Txs -= txs
+		} else {
+			a.Txs = 0
+		}
+		if a.LastHeight >= lower {
+			_, last, found, err := d.addrDescHeightRange(ad, lower-1)
+			if err != nil {
+				return err
+			}
+			if !found {
+				wb.DeleteCF(d.cfh[cfAddressActivity], ad)
+				continue
+			}
+			a.LastHeight = last
+			if a.LastTime, err = d.blockTime(last); err != nil {
+				return err
+			}
+		}
+		wb.PutCF(d.cfh[cfAddressActivity], ad, packAddrActivity(a))
+	}
+	return nil
+}
+
+// BuildAddressActivity builds the address activity records of the addresses indexed before the address activity column existed
+func (d *RocksDB) BuildAddressActivity(stop chan os.Signal) error {
+	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
+		glog.Info("BuildAddressActivity: applicable only for ethereum type coins")
+		return nil
+	}
+	glog.Info("BuildAddressActivity: starting")
+	// do not use cache
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetFillCache(false)
+	defer ro.Destroy()
+	it := d.db.NewIteratorCF(ro, d.cfh[cfAddressContracts])
+	defer it.Close()
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	var rowCount, storedCount int
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		select {
+		case <-stop:
+			return errors.New("BuildAddressActivity: interrupted")
+		default:
+		}
+		rowCount++
+		addrDesc := append(bchain.AddressDescriptor{}, it.Key().Data()...)
+		buf := it.Value().Data()
+		if len(buf) == 0 {
+			continue
+		}
+		// the total number of transactions is the first item of the packed address contracts
+		txs, _ := unpackVaruint(buf)
+		first, last, found, err := d.addrDescHeightRange(addrDesc, math.MaxUint32)
+		if err != nil {
+			glog.Error("BuildAddressActivity: ", err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		a := AddrActivity{Txs: uint32(txs), FirstHeight: first, LastHeight: last}
+		if a.FirstTime, err = d.blockTime(first); err != nil {
+			return err
+		}
+		if a.LastTime, err = d.blockTime(last); err != nil {
+			return err
+		}
+		wb.PutCF(d.cfh[cfAddressActivity], addrDesc, packAddrActivity(&a))
+		storedCount++
+		if wb.Count() >= refreshIterator {
+			if err := d.WriteBatch(wb); err != nil {
+				return err
+			}
+			wb.Clear()
+		}
+		if rowCount%5000000 == 0 {
+			glog.Infof("BuildAddressActivity: progress - scanned %d rows, stored %d records", rowCount, storedCount)
+		}
+	}
+	if err := d.WriteBatch(wb); err != nil {
+		return err
+	}
+	glog.Infof("BuildAddressActivity: finished - scanned %d rows, stored %d records", rowCount, storedCount)
+	return nil
+}
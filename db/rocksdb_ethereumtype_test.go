@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"os"
 	"reflect"
 	"testing"
 
@@ -575,6 +576,19 @@ func testFourByteSignature(t *testing.T, d *RocksDB) {
 // 5) Disconnect the block 2 using BlockTxs column
 // 6) Reconnect block 2 and check
 // After each step, the content of DB is examined and any difference against expected state is regarded as failure
+func verifyEthereumTypeAddressActivity(t *testing.T, d *RocksDB, want map[string]*AddrActivity) {
+	t.Helper()
+	for address, w := range want {
+		got, err := d.GetAddrDescActivity(addressToAddrDesc(address, d.chainParser))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("GetAddrDescActivity(%s) = %+v, want %+v", address, got, w)
+		}
+	}
+}
+
 func TestRocksDB_Index_EthereumType(t *testing.T) {
 	d := setupRocksDB(t, &testEthereumParser{
 		EthereumParser: ethereumTestnetParser(),
@@ -591,6 +605,10 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 		t.Fatal(err)
 	}
 	verifyAfterEthereumTypeBlock1(t, d, false)
+	verifyEthereumTypeAddressActivity(t, d, map[string]*AddrActivity{
+		dbtestdata.EthAddr3e: {Txs: 2, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 4321000, LastTime: 1534858022},
+		dbtestdata.EthAddr4b: nil,
+	})
 
 	if len(d.is.BlockTimes) != 4321001 {
 		t.Fatal("Expecting is.BlockTimes 4321001, got ", len(d.is.BlockTimes))
@@ -603,6 +621,12 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 	}
 	verifyAfterEthereumTypeBlock2(t, d, true)
 	block2.CoinSpecificData = nil
+	activityAfterBlock2 := map[string]*AddrActivity{
+		dbtestdata.EthAddr3e: {Txs: 3, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 4321001, LastTime: 1534859988},
+		dbtestdata.EthAddr55: {Txs: 5, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 4321001, LastTime: 1534859988},
+		dbtestdata.EthAddr4b: {Txs: 1, FirstHeight: 4321001, FirstTime: 1534859988, LastHeight: 4321001, LastTime: 1534859988},
+	}
+	verifyEthereumTypeAddressActivity(t, d, activityAfterBlock2)
 
 	if len(d.is.BlockTimes) != 4321002 {
 		t.Fatal("Expecting is.BlockTimes 4321002, got ", len(d.is.BlockTimes))
@@ -728,6 +752,11 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 		t.Fatal(err)
 	}
 	verifyAfterEthereumTypeBlock1(t, d, true)
+	verifyEthereumTypeAddressActivity(t, d, map[string]*AddrActivity{
+		dbtestdata.EthAddr3e: {Txs: 2, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 4321000, LastTime: 1534858022},
+		dbtestdata.EthAddr55: {Txs: 2, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 4321000, LastTime: 1534858022},
+		dbtestdata.EthAddr4b: nil,
+	})
 	if err := checkColumn(d, cfTransactions, []keyPair{}); err != nil {
 		{
 			t.Fatal(err)
@@ -743,11 +772,25 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 		t.Fatal(err)
 	}
 	verifyAfterEthereumTypeBlock2(t, d, false)
+	verifyEthereumTypeAddressActivity(t, d, activityAfterBlock2)
 
 	if len(d.is.BlockTimes) != 4321002 {
 		t.Fatal("Expecting is.BlockTimes 4321002, got ", len(d.is.BlockTimes))
 	}
 
+	// rebuild the activity index as for a database created before the index existed
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for address := range activityAfterBlock2 {
+		wb.DeleteCF(d.cfh[cfAddressActivity], addressToAddrDesc(address, d.chainParser))
+	}
+	if err := d.WriteBatch(wb); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.BuildAddressActivity(make(chan os.Signal)); err != nil {
+		t.Fatal(err)
+	}
+	verifyEthereumTypeAddressActivity(t, d, activityAfterBlock2)
 }
 
 func Test_BulkConnect_EthereumType(t *testing.T) {
@@ -794,6 +837,10 @@ func Test_BulkConnect_EthereumType(t *testing.T) {
 	}
 
 	verifyAfterEthereumTypeBlock2(t, d, true)
+	verifyEthereumTypeAddressActivity(t, d, map[string]*AddrActivity{
+		dbtestdata.EthAddr55: {Txs: 5, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 4321001, LastTime: 1534859988},
+		dbtestdata.EthAddr4b: {Txs: 1, FirstHeight: 4321001, FirstTime: 1534859988, LastHeight: 4321001, LastTime: 1534859988},
+	})
 
 	if len(d.is.BlockTimes) != 4321002 {
 		t.Fatal("Expecting is.BlockTimes 4321002, got ", len(d.is.BlockTimes))
@@ -1564,6 +1611,17 @@ func Test_packUnpackBlockTx(t *testing.T) {
 	}
 }
 
+func Test_packUnpackAddrActivity(t *testing.T) {
+	a := &AddrActivity{Txs: 12345, FirstHeight: 4321000, FirstTime: 1534858022, LastHeight: 17000000, LastTime: 1680000000}
+	got, err := unpackAddrActivity(packAddrActivity(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, a) {
+		t.Errorf("unpackAddrActivity() = %+v, want %+v", got, a)
+	}
+}
+
 func Test_packUnpackFourByteSignature(t *testing.T) {
 	tests := []struct {
 		name      string
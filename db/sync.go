@@ -30,6 +30,7 @@ type SyncWorker struct {
 	missingBlockRetry      MissingBlockRetryConfig
 	metrics                *common.Metrics
 	is                     *common.InternalState
+	onReorg                bchain.OnReorgFunc
 }
 
 // MissingBlockRetryConfig controls how long we retry a missing block before re-checking chain state.
@@ -259,6 +260,7 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
 	// find forked blocks, disconnect them and then synchronize again
 	var height uint32
+	var forkHash string
 	hashes := []string{localBestHash}
 	for height = localBestHeight - 1; ; height-- {
 		local, err := w.db.GetBlockHash(height)
@@ -274,16 +276,55 @@ func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, on
 			return err
 		}
 		if local == remote {
+			forkHash = local
 			break
 		}
 		hashes = append(hashes, local)
 	}
+	var reorg *bchain.Reorg
+	if w.onReorg != nil {
+		// the transactions of the blocks must be read before the blocks are disconnected
+		reorg = w.getReorg(height, forkHash, localBestHeight, hashes)
+	}
 	if err := w.DisconnectBlocks(height+1, localBestHeight, hashes); err != nil {
 		return err
 	}
+	if reorg != nil {
+		w.onReorg(reorg)
+	}
 	return w.resyncIndex(onNewBlock, initialSync)
 }
 
+// SetOnReorg sets the callback notified about the blocks disconnected by a chain reorganization
+func (w *SyncWorker) SetOnReorg(onReorg bchain.OnReorgFunc) {
+	w.onReorg = onReorg
+}
+
+// getReorg describes the blocks which are going to be disconnected, hashes are ordered from the best block
+func (w *SyncWorker) getReorg(forkHeight uint32, forkHash string, bestHeight uint32, hashes []string) *bchain.Reorg {
+	reorg := &bchain.Reorg{
+		ForkHeight: forkHeight,
+		ForkHash:   forkHash,
+		Blocks:     make([]bchain.DisconnectedBlock, len(hashes)),
+	}
+	for i, hash := range hashes {
+		b := &reorg.Blocks[i]
+		b.Height = bestHeight - uint32(i)
+		b.Hash = hash
+		txids, err := w.db.GetBlockTxids(b.Height)
+		if err != nil {
+			glog.Error("sync: GetBlockTxids ", b.Height, ": ", err)
+		}
+		b.Txids = txids
+		addrDescs, err := w.db.GetBlockTxAddrDescs(b.Height)
+		if err != nil {
+			glog.Error("sync: GetBlockTxAddrDescs ", b.Height, ": ", err)
+		}
+		b.AddrDescs = addrDescs
+	}
+	return reorg
+}
+
 func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
 	bch := make(chan blockResult, 8)
 	done := make(chan struct{})
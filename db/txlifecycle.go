@@ -0,0 +1,141 @@
+package db
+
+import (
+	vlq "github.com/bsm/go-vlq"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+)
+
+// TxEviction is the reason of the removal of an unconfirmed transaction from the mempool
+type TxEviction uint8
+
+const (
+	// TxNotEvicted - the transaction was not evicted, it is in the mempool or it was mined
+	TxNotEvicted TxEviction = iota
+	// TxEvictionReplaced - the transaction was replaced by a conflicting mempool transaction
+	TxEvictionReplaced
+	// TxEvictionExpired - the transaction was dropped by the backend, for example for its age or low fee
+	TxEvictionExpired
+	// TxEvictionConflicted - an input of the transaction was spent by a transaction mined in a block
+	TxEvictionConflicted
+)
+
+// TxLifecycle is the recorded history of a transaction seen in the mempool
+type TxLifecycle struct {
+	// FirstSeen is the unix time when the transaction was first seen in the mempool
+	FirstSeen       int64
+	FirstSeenHeight uint32
+	// FeePerKb and ETABlocks are the fee rate and the confirmation ETA predicted when the transaction was first seen
+	FeePerKb  int64
+	ETABlocks uint32
+	// BlockHeight and BlockTime are set when the transaction is mined, BlockHeight is 0 otherwise
+	BlockHeight  uint32
+	BlockTime    int64
+	Eviction     TxEviction
+	EvictionTime int64
+	ReplacedBy   string
+}
+
+func (d *RocksDB) packTxLifecycle(l *TxLifecycle) ([]byte, error) {
+	buf := make([]byte, 0, 8*vlq.MaxLen64)
+	varBuf := make([]byte, vlq.MaxLen64)
+	for _, v := range []uint64{uint64(l.FirstSeen), uint64(l.FirstSeenHeight), uint64(l.FeePerKb), uint64(l.ETABlocks),
+		uint64(l.BlockHeight), uint64(l.BlockTime), uint64(l.Eviction), uint64(l.EvictionTime)} {
+		n := vlq.PutUint(varBuf, v)
+		buf = append(buf, varBuf[:n]...)
+	}
+	if l.ReplacedBy != "" {
+		btxid, err := d.chainParser.PackTxid(l.ReplacedBy)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, btxid...)
+	}
+	return buf, nil
+}
+
+func (d *RocksDB) unpackTxLifecycle(buf []byte) (*TxLifecycle, error) {
+	var v [8]uint64
+	for i := range v {
+		u, n := vlq.Uint(buf)
+		if n <= 0 {
+			return nil, errors.New("Invalid tx lifecycle data")
+		}
+		v[i] = u
+		buf = buf[n:]
+	}
+	l := &TxLifecycle{
+		FirstSeen:       int64(v[0]),
+		FirstSeenHeight: uint32(v[1]),
+		FeePerKb:        int64(v[2]),
+		ETABlocks:       uint32(v[3]),
+		BlockHeight:     uint32(v[4]),
+		BlockTime:       int64(v[5]),
+		Eviction:        TxEviction(v[6]),
+		EvictionTime:    int64(v[7]),
+	}
+	if len(buf) > 0 {
+		txid, err := d.chainParser.UnpackTxid(buf)
+		if err != nil {
+			return nil, err
+		}
+		l.ReplacedBy = txid
+	}
+	return l, nil
+}
+
+// GetTxLifecycle returns the recorded lifecycle of the transaction or nil if it was not recorded
+func (d *RocksDB) GetTxLifecycle(txid string) (*TxLifecycle, error) {
+	key, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxLifecycle], key)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if data == nil {
+		return nil, nil
+	}
+	l, err := d.unpackTxLifecycle(data)
+	if err != nil {
+		return nil, errors.Annotatef(err, "tx lifecycle %v", txid)
+	}
+	return l, nil
+}
+
+// StoreTxLifecycle stores (creates or replaces) the lifecycle of the transaction
+func (d *RocksDB) StoreTxLifecycle(txid string, l *TxLifecycle) error {
+	key, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return err
+	}
+	val, err := d.packTxLifecycle(l)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfTxLifecycle], key, val)
+}
+
+// PruneTxLifecycles removes the lifecycles of the transactions first seen before the given unix time, returns the number of removed records
+func (d *RocksDB) PruneTxLifecycles(before int64) (int, error) {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfTxLifecycle])
+	defer it.Close()
+	count := 0
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		// the first seen time is the first packed value of the record
+		firstSeen, n := vlq.Uint(it.Value().Data())
+		if n <= 0 || int64(firstSeen) < before {
+			wb.DeleteCF(d.cfh[cfTxLifecycle], it.Key().Data())
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, d.db.Write(d.wo, wb)
+}
@@ -0,0 +1,73 @@
+//go:build unittest
+
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksTxLifecycle(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	l, err := d.GetTxLifecycle(dbtestdata.TxidB1T1)
+	if err != nil || l != nil {
+		t.Fatalf("GetTxLifecycle(missing) = %v, %v, want nil, nil", l, err)
+	}
+
+	mined := &TxLifecycle{
+		FirstSeen:       1600000000,
+		FirstSeenHeight: 225493,
+		FeePerKb:        12345,
+		ETABlocks:       2,
+		BlockHeight:     225495,
+		BlockTime:       1600001200,
+	}
+	replaced := &TxLifecycle{
+		FirstSeen:       1500000000,
+		FirstSeenHeight: 225490,
+		Eviction:        TxEvictionReplaced,
+		EvictionTime:    1500000100,
+		ReplacedBy:      dbtestdata.TxidB1T1,
+	}
+	if err := d.StoreTxLifecycle(dbtestdata.TxidB1T1, mined); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.StoreTxLifecycle(dbtestdata.TxidB1T2, replaced); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		txid string
+		want *TxLifecycle
+	}{
+		{dbtestdata.TxidB1T1, mined},
+		{dbtestdata.TxidB1T2, replaced},
+	} {
+		got, err := d.GetTxLifecycle(tt.txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetTxLifecycle(%v) = %+v, want %+v", tt.txid, got, tt.want)
+		}
+	}
+
+	count, err := d.PruneTxLifecycles(1550000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("PruneTxLifecycles() = %v, want 1", count)
+	}
+	if got, err := d.GetTxLifecycle(dbtestdata.TxidB1T2); err != nil || got != nil {
+		t.Errorf("GetTxLifecycle after prune = %v, %v, want nil, nil", got, err)
+	}
+	if got, err := d.GetTxLifecycle(dbtestdata.TxidB1T1); err != nil || got == nil {
+		t.Errorf("GetTxLifecycle of a kept record = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/juju/errors"
+)
+
+// Webhook is the registration of an outbound webhook, the events are delivered by POST requests to the URL
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret is the key of the HMAC signature of the delivered payloads
+	Secret string `json:"secret"`
+	// Events are the kinds of the delivered events (address, confirmations, reorg)
+	Events []string `json:"events"`
+	// Addresses are the watched addresses of the address and confirmations events
+	Addresses []string `json:"addresses,omitempty"`
+	// Confirmations are the milestones of the confirmations events
+	Confirmations []uint32 `json:"confirmations,omitempty"`
+	Disabled      bool     `json:"disabled,omitempty"`
+	Created       int64    `json:"created"`
+	Updated       int64    `json:"updated"`
+}
+
+// WebhookDeadLetter is an event which could not be delivered to a webhook within the maximal number of attempts
+type WebhookDeadLetter struct {
+	Webhook     string          `json:"webhook"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"lastError"`
+	Created     int64           `json:"created"`
+	LastAttempt int64           `json:"lastAttempt"`
+}
+
+// WebhookCallback is called for each stored webhook, the iteration stops if it returns an error
+type WebhookCallback func(id string, wh *Webhook) error
+
+// WebhookDeadLetterCallback is called for each stored dead letter, the iteration stops if it returns an error
+type WebhookDeadLetterCallback func(id string, dl *WebhookDeadLetter) error
+
+func (d *RocksDB) getJSON(cf int, key string, v interface{}) (bool, error) {
+	if len(key) == 0 {
+		return false, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cf], []byte(key))
+	if err != nil {
+		return false, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (d *RocksDB) storeJSON(cf int, key string, v interface{}) error {
+	if len(key) == 0 {
+		return errors.New("Missing key")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cf], []byte(key), data)
+}
+
+// GetWebhook returns the webhook stored under the id or nil if it does not exist
+func (d *RocksDB) GetWebhook(id string) (*Webhook, error) {
+	var wh Webhook
+	found, err := d.getJSON(cfWebhooks, id, &wh)
+	if err != nil {
+		return nil, errors.Annotatef(err, "webhook %v", id)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &wh, nil
+}
+
+// StoreWebhook stores (creates or replaces) the webhook under the id
+func (d *RocksDB) StoreWebhook(id string, wh *Webhook) error {
+	return d.storeJSON(cfWebhooks, id, wh)
+}
+
+// DeleteWebhook removes the webhook stored under the id
+func (d *RocksDB) DeleteWebhook(id string) error {
+	return d.db.DeleteCF(d.wo, d.cfh[cfWebhooks], []byte(id))
+}
+
+// IterateWebhooks calls fn for each stored webhook
+func (d *RocksDB) IterateWebhooks(fn WebhookCallback) error {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhooks])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		id := string(it.Key().Data())
+		var wh Webhook
+		if err := json.Unmarshal(it.Value().Data(), &wh); err != nil {
+			return errors.Annotatef(err, "webhook %v", id)
+		}
+		if err := fn(id, &wh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWebhookDeadLetter returns the dead letter stored under the id or nil if it does not exist
+func (d *RocksDB) GetWebhookDeadLetter(id string) (*WebhookDeadLetter, error) {
+	var dl WebhookDeadLetter
+	found, err := d.getJSON(cfWebhookDeadLetters, id, &dl)
+	if err != nil {
+		return nil, errors.Annotatef(err, "webhook dead letter %v", id)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &dl, nil
+}
+
+// StoreWebhookDeadLetter stores (creates or replaces) the dead letter under the id
+func (d *RocksDB) StoreWebhookDeadLetter(id string, dl *WebhookDeadLetter) error {
+	return d.storeJSON(cfWebhookDeadLetters, id, dl)
+}
+
+// DeleteWebhookDeadLetter removes the dead letter stored under the id
+func (d *RocksDB) DeleteWebhookDeadLetter(id string) error {
+	return d.db.DeleteCF(d.wo, d.cfh[cfWebhookDeadLetters], []byte(id))
+}
+
+// IterateWebhookDeadLetters calls fn for each stored dead letter in the order of the ids
+func (d *RocksDB) IterateWebhookDeadLetters(fn WebhookDeadLetterCallback) error {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhookDeadLetters])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		id := string(it.Key().Data())
+		var dl WebhookDeadLetter
+		if err := json.Unmarshal(it.Value().Data(), &dl); err != nil {
+			return errors.Annotatef(err, "webhook dead letter %v", id)
+		}
+		if err := fn(id, &dl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
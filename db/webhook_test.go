@@ -0,0 +1,94 @@
+//go:build unittest
+
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRocksWebhooks(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	wh, err := d.GetWebhook("missing")
+	if err != nil || wh != nil {
+		t.Fatalf("GetWebhook(missing) = %v, %v, want nil, nil", wh, err)
+	}
+
+	want := map[string]*Webhook{
+		"hook1": {
+			URL:           "https://example.com/hook",
+			Secret:        "secret",
+			Events:        []string{"address", "confirmations"},
+			Addresses:     []string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"},
+			Confirmations: []uint32{1, 6},
+			Created:       1600000000,
+			Updated:       1600000001,
+		},
+		"hook2": {
+			URL:     "https://example.com/reorg",
+			Secret:  "secret2",
+			Events:  []string{"reorg"},
+			Created: 1600000002,
+			Updated: 1600000002,
+		},
+	}
+	for id, wh := range want {
+		if err := d.StoreWebhook(id, wh); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.StoreWebhook("", want["hook1"]); err == nil {
+		t.Fatal("StoreWebhook with empty id: expected error")
+	}
+	got := make(map[string]*Webhook)
+	if err := d.IterateWebhooks(func(id string, wh *Webhook) error {
+		got[id] = wh
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IterateWebhooks() = %+v, want %+v", got, want)
+	}
+	if err := d.DeleteWebhook("hook1"); err != nil {
+		t.Fatal(err)
+	}
+	if wh, err = d.GetWebhook("hook1"); err != nil || wh != nil {
+		t.Errorf("GetWebhook after delete = %v, %v, want nil, nil", wh, err)
+	}
+
+	dl := &WebhookDeadLetter{
+		Webhook:     "hook2",
+		Event:       "reorg",
+		Payload:     json.RawMessage(`{"event":"reorg"}`),
+		Attempts:    5,
+		LastError:   "Status 500",
+		Created:     1600000003,
+		LastAttempt: 1600000100,
+	}
+	if err := d.StoreWebhookDeadLetter("dl1", dl); err != nil {
+		t.Fatal(err)
+	}
+	gotDl, err := d.GetWebhookDeadLetter("dl1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotDl, dl) {
+		t.Errorf("GetWebhookDeadLetter() = %+v, want %+v", gotDl, dl)
+	}
+	if err := d.DeleteWebhookDeadLetter("dl1"); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	if err := d.IterateWebhookDeadLetters(func(id string, dl *WebhookDeadLetter) error {
+		n++
+		return nil
+	}); err != nil || n != 0 {
+		t.Errorf("IterateWebhookDeadLetters after delete = %d, %v, want 0, nil", n, err)
+	}
+}
@@ -0,0 +1,501 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/api"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/server/graphql"
+)
+
+const (
+	maxGraphQLBodyBytes  int64 = 64 * 1024
+	maxGraphQLDepth            = 12
+	maxGraphQLComplexity       = 2000
+	// maximum sum of the costs of the fields resolved during the execution, the lists are counted by their actual length
+	maxGraphQLCost = 2000
+	// assumed number of items of lists without paging, e.g. inputs and outputs of a transaction
+	graphQLDefaultListSize = 10
+	// cost of a field which requires a lookup in the db or in the backend
+	graphQLLookupCost = 10
+)
+
+// graphQLError converts an error of the worker to an error returned to the client, details of internal errors are only logged
+func graphQLError(field string, err error) error {
+	if err == api.ErrUnsupportedXpub {
+		return api.NewAPIError("XPUB functionality is not supported", true)
+	}
+	if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+		return apiErr
+	}
+	glog.Error("graphql ", field, " error: ", err)
+	return api.NewAPIError("Internal server error", false)
+}
+
+func intArg(p graphql.ResolveParams, name string, def, min, max int) int {
+	v, ok := p.Args[name].(int64)
+	if !ok {
+		return def
+	}
+	if v < int64(min) {
+		return min
+	}
+	if v > int64(max) {
+		return max
+	}
+	return int(v)
+}
+
+func stringArg(p graphql.ResolveParams, name string) string {
+	s, _ := p.Args[name].(string)
+	return s
+}
+
+func stringListArg(p graphql.ResolveParams, name string) []string {
+	l, _ := p.Args[name].([]interface{})
+	r := make([]string, 0, len(l))
+	for _, s := range l {
+		if s, ok := s.(string); ok {
+			r = append(r, s)
+		}
+	}
+	return r
+}
+
+// pagingArgs returns the paging arguments of the list field, the list field does not have to be selected
+func pagingArgs(f *graphql.CollectedField) (int, int) {
+	page, pageSize := 1, txsOnPage
+	if f != nil {
+		if v, ok := f.Args["page"].(int64); ok && v > 0 && v <= maxPageNumber {
+			page = int(v)
+		}
+		if v, ok := f.Args["pageSize"].(int64); ok && v > 0 {
+			pageSize = int(v)
+			if pageSize > txsInAPI {
+				pageSize = txsInAPI
+			}
+		}
+	}
+	return page, pageSize
+}
+
+func pagingArgDefinitions() []*graphql.ArgumentDefinition {
+	return []*graphql.ArgumentDefinition{
+		{Name: "page", Type: graphql.Int, DefaultValue: int64(1)},
+		{Name: "pageSize", Type: graphql.Int, DefaultValue: int64(txsOnPage)},
+	}
+}
+
+// graphQLVout is an output of a transaction with the txid of the transaction, it allows resolution of the spending transaction
+type graphQLVout struct {
+	*api.Vout
+	txid string
+}
+
+// graphQLRate is one rate of the fiat ticker, the rates are returned as a list as GraphQL does not have maps
+type graphQLRate struct {
+	Currency string  `json:"currency"`
+	Rate     float32 `json:"rate"`
+}
+
+func scalarFields(t graphql.Type, names ...string) []*graphql.FieldDefinition {
+	fields := make([]*graphql.FieldDefinition, len(names))
+	for i, n := range names {
+		fields[i] = &graphql.FieldDefinition{Name: n, Type: t}
+	}
+	return fields
+}
+
+// newGraphQLSchema creates the GraphQL schema of the data served by the worker
+func newGraphQLSchema(w *api.Worker) *graphql.Schema {
+	stringList := &graphql.List{OfType: graphql.String}
+	getTx := func(field, txid string) (interface{}, error) {
+		if txid == "" {
+			return nil, nil
+		}
+		tx, err := w.GetTransaction(txid, false, false)
+		if err != nil {
+			return nil, graphQLError(field, err)
+		}
+		return tx, nil
+	}
+
+	multiTokenValue := &graphql.Object{Name: "MultiTokenValue", Fields: scalarFields(graphql.String, "id", "value")}
+	tokenTransfer := &graphql.Object{Name: "TokenTransfer"}
+	tokenTransfer.AddFields(scalarFields(graphql.String, "type", "standard", "from", "to", "contract", "name", "symbol")...)
+	tokenTransfer.AddFields(scalarFields(graphql.Int, "decimals")...)
+	tokenTransfer.AddFields(scalarFields(graphql.String, "value")...)
+	tokenTransfer.AddFields(&graphql.FieldDefinition{Name: "multiTokenValues", Type: &graphql.List{OfType: multiTokenValue}})
+
+	token := &graphql.Object{Name: "Token"}
+	token.AddFields(scalarFields(graphql.String, "type", "standard", "name", "path", "contract")...)
+	token.AddFields(scalarFields(graphql.Int, "transfers")...)
+	token.AddFields(scalarFields(graphql.String, "symbol")...)
+	token.AddFields(scalarFields(graphql.Int, "decimals")...)
+	token.AddFields(scalarFields(graphql.String, "balance", "totalReceived", "totalSent")...)
+	token.AddFields(&graphql.FieldDefinition{Name: "ids", Type: stringList})
+	token.AddFields(&graphql.FieldDefinition{Name: "multiTokenValues", Type: &graphql.List{OfType: multiTokenValue}})
+
+	ethereumSpecific := &graphql.Object{Name: "EthereumSpecific"}
+	ethereumSpecific.AddFields(scalarFields(graphql.Int, "status")...)
+	ethereumSpecific.AddFields(scalarFields(graphql.String, "error", "createdContract")...)
+	ethereumSpecific.AddFields(scalarFields(graphql.Int, "nonce")...)
+	ethereumSpecific.AddFields(scalarFields(graphql.String, "gasLimit", "gasUsed", "gasPrice", "maxPriorityFeePerGas", "maxFeePerGas", "baseFeePerGas", "data")...)
+
+	tx := &graphql.Object{Name: "Tx", Description: "Transaction"}
+	vin := &graphql.Object{Name: "Vin", Description: "Transaction input"}
+	vin.AddFields(scalarFields(graphql.Int, "n")...)
+	vin.AddFields(scalarFields(graphql.String, "txid")...)
+	vin.AddFields(scalarFields(graphql.Int, "vout", "sequence")...)
+	vin.AddFields(&graphql.FieldDefinition{Name: "addresses", Type: stringList})
+	vin.AddFields(scalarFields(graphql.Boolean, "isAddress")...)
+	vin.AddFields(scalarFields(graphql.String, "value", "hex", "coinbase")...)
+	vin.AddFields(&graphql.FieldDefinition{
+		Name:        "tx",
+		Description: "Transaction with the spent output",
+		Type:        tx,
+		Cost:        graphQLLookupCost,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return getTx("vin.tx", p.Source.(*api.Vin).Txid)
+		},
+	})
+
+	vout := &graphql.Object{Name: "Vout", Description: "Transaction output"}
+	vout.AddFields(scalarFields(graphql.Int, "n")...)
+	vout.AddFields(scalarFields(graphql.String, "value")...)
+	vout.AddFields(scalarFields(graphql.Boolean, "spent")...)
+	vout.AddFields(scalarFields(graphql.String, "spentTxId")...)
+	vout.AddFields(scalarFields(graphql.Int, "spentIndex", "spentHeight")...)
+	vout.AddFields(scalarFields(graphql.String, "hex")...)
+	vout.AddFields(&graphql.FieldDefinition{Name: "addresses", Type: stringList})
+	vout.AddFields(scalarFields(graphql.Boolean, "isAddress")...)
+	vout.AddFields(scalarFields(graphql.String, "type")...)
+	vout.AddFields(&graphql.FieldDefinition{
+		Name:        "spendingTx",
+		Description: "Transaction spending the output, null if the output is unspent",
+		Type:        tx,
+		Cost:        graphQLLookupCost,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			v := p.Source.(*graphQLVout)
+			if !v.Spent {
+				return nil, nil
+			}
+			spendingTxid := v.SpentTxID
+			if spendingTxid == "" {
+				var err error
+				if spendingTxid, err = w.GetSpendingTxid(v.txid, v.N); err != nil {
+					return nil, graphQLError("vout.spendingTx", err)
+				}
+			}
+			return getTx("vout.spendingTx", spendingTxid)
+		},
+	})
+
+	tx.AddFields(scalarFields(graphql.String, "txid")...)
+	tx.AddFields(scalarFields(graphql.Int, "version", "lockTime")...)
+	tx.AddFields(scalarFields(graphql.String, "blockHash")...)
+	tx.AddFields(scalarFields(graphql.Int, "blockHeight", "confirmations", "blockTime", "size", "vsize")...)
+	tx.AddFields(scalarFields(graphql.String, "value", "valueIn", "fees", "hex")...)
+	tx.AddFields(scalarFields(graphql.Boolean, "rbf")...)
+	tx.AddFields(
+		&graphql.FieldDefinition{Name: "vin", Type: &graphql.List{OfType: vin}},
+		&graphql.FieldDefinition{
+			Name: "vout",
+			Type: &graphql.List{OfType: vout},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				t := p.Source.(*api.Tx)
+				vouts := make([]graphQLVout, len(t.Vout))
+				for i := range t.Vout {
+					vouts[i] = graphQLVout{Vout: &t.Vout[i], txid: t.Txid}
+				}
+				return vouts, nil
+			},
+		},
+		&graphql.FieldDefinition{Name: "tokenTransfers", Type: &graphql.List{OfType: tokenTransfer}},
+		&graphql.FieldDefinition{Name: "ethereumSpecific", Type: ethereumSpecific},
+	)
+
+	block := &graphql.Object{Name: "Block"}
+	block.AddFields(scalarFields(graphql.String, "hash", "previousBlockHash", "nextBlockHash")...)
+	block.AddFields(scalarFields(graphql.Int, "height", "confirmations", "size", "time")...)
+	block.AddFields(scalarFields(graphql.String, "version", "merkleRoot", "nonce", "bits", "difficulty")...)
+	block.AddFields(scalarFields(graphql.Int, "txCount", "page", "totalPages", "itemsOnPage")...)
+	block.AddFields(&graphql.FieldDefinition{
+		Name:        "txs",
+		Description: "Transactions of the block, subject to paging",
+		Type:        &graphql.List{OfType: tx},
+		Args:        pagingArgDefinitions(),
+		ListSizeArg: "pageSize",
+	})
+
+	address := &graphql.Object{Name: "Address", Description: "Address or xpub"}
+	address.AddFields(scalarFields(graphql.String, "address", "balance", "totalReceived", "totalSent", "unconfirmedBalance")...)
+	address.AddFields(scalarFields(graphql.Int, "unconfirmedTxs", "txs", "nonTokenTxs")...)
+	address.AddFields(scalarFields(graphql.String, "nonce")...)
+	address.AddFields(scalarFields(graphql.Int, "usedTokens", "firstSeenHeight", "firstSeenTime", "lastSeenHeight", "lastSeenTime", "page", "totalPages", "itemsOnPage")...)
+	address.AddFields(
+		&graphql.FieldDefinition{
+			Name:        "txids",
+			Description: "Txids of the transactions of the address, subject to paging",
+			Type:        stringList,
+			Args:        pagingArgDefinitions(),
+		},
+		&graphql.FieldDefinition{
+			Name:        "transactions",
+			Description: "Transactions of the address, subject to paging",
+			Type:        &graphql.List{OfType: tx},
+			Args:        pagingArgDefinitions(),
+			ListSizeArg: "pageSize",
+		},
+		&graphql.FieldDefinition{Name: "tokens", Type: &graphql.List{OfType: token}},
+	)
+
+	utxo := &graphql.Object{Name: "Utxo"}
+	utxo.AddFields(scalarFields(graphql.String, "txid")...)
+	utxo.AddFields(scalarFields(graphql.Int, "vout")...)
+	utxo.AddFields(scalarFields(graphql.String, "value")...)
+	utxo.AddFields(scalarFields(graphql.Int, "height", "confirmations")...)
+	utxo.AddFields(scalarFields(graphql.String, "address", "path")...)
+	utxo.AddFields(scalarFields(graphql.Int, "lockTime")...)
+	utxo.AddFields(scalarFields(graphql.Boolean, "coinbase")...)
+	utxo.AddFields(&graphql.FieldDefinition{
+		Name:        "tx",
+		Description: "Transaction containing the output",
+		Type:        tx,
+		Cost:        graphQLLookupCost,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return getTx("utxo.tx", p.Source.(*api.Utxo).Txid)
+		},
+	})
+
+	rate := &graphql.Object{Name: "Rate"}
+	rate.AddFields(scalarFields(graphql.String, "currency")...)
+	rate.AddFields(scalarFields(graphql.Float, "rate")...)
+	fiatTicker := &graphql.Object{Name: "FiatTicker"}
+	fiatTicker.AddFields(scalarFields(graphql.Int, "ts")...)
+	fiatTicker.AddFields(&graphql.FieldDefinition{
+		Name: "rates",
+		Type: &graphql.List{OfType: rate},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			t := p.Source.(*api.FiatTicker)
+			rates := make([]graphQLRate, 0, len(t.Rates))
+			for c, r := range t.Rates {
+				rates = append(rates, graphQLRate{Currency: c, Rate: r})
+			}
+			sort.Slice(rates, func(i, j int) bool { return rates[i].Currency < rates[j].Currency })
+			return rates, nil
+		},
+	})
+	fiatTicker.AddFields(scalarFields(graphql.String, "error")...)
+
+	info := &graphql.Object{Name: "Info"}
+	info.AddFields(scalarFields(graphql.String, "coin", "network", "version")...)
+	info.AddFields(scalarFields(graphql.Int, "bestHeight")...)
+	info.AddFields(scalarFields(graphql.String, "lastBlockTime")...)
+	info.AddFields(scalarFields(graphql.Boolean, "inSync", "inSyncMempool")...)
+	info.AddFields(scalarFields(graphql.Int, "mempoolSize", "decimals")...)
+
+	// getAddress returns the address or xpub with the details required by the selected fields
+	getAddress := func(p graphql.ResolveParams, xpub bool) (interface{}, error) {
+		details := api.AccountDetailsBasic
+		paging := p.Field.Selected("transactions")
+		if paging != nil {
+			details = api.AccountDetailsTxHistory
+		} else if paging = p.Field.Selected("txids"); paging != nil {
+			details = api.AccountDetailsTxidHistory
+		} else if p.Selected("tokens") {
+			details = api.AccountDetailsTokenBalances
+		}
+		page, pageSize := pagingArgs(paging)
+		filter := &api.AddressFilter{
+			Vout:           api.AddressFilterVoutOff,
+			Contract:       stringArg(p, "contract"),
+			FromHeight:     uint32(intArg(p, "from", 0, 0, math.MaxUint32)),
+			ToHeight:       uint32(intArg(p, "to", 0, 0, math.MaxUint32)),
+			TokensToReturn: api.TokensToReturnNonzeroBalance,
+		}
+		switch stringArg(p, "tokens") {
+		case "derived":
+			filter.TokensToReturn = api.TokensToReturnDerived
+		case "used":
+			filter.TokensToReturn = api.TokensToReturnUsed
+		}
+		var a *api.Address
+		var err error
+		if xpub {
+			a, err = w.GetXpubAddress(stringArg(p, "xpub"), page, pageSize, details, filter, intArg(p, "gap", 0, 0, maxGapValue), "")
+		} else {
+			a, err = w.GetAddress(stringArg(p, "address"), page, pageSize, details, filter, "")
+		}
+		if err != nil {
+			return nil, graphQLError(p.Field.Name, err)
+		}
+		return a, nil
+	}
+	addressArgs := func(first *graphql.ArgumentDefinition) []*graphql.ArgumentDefinition {
+		return []*graphql.ArgumentDefinition{
+			first,
+			{Name: "from", Description: "Only transactions from this block height", Type: graphql.Int},
+			{Name: "to", Description: "Only transactions up to this block height", Type: graphql.Int},
+			{Name: "contract", Description: "Only transactions of this token contract", Type: graphql.String},
+			{Name: "tokens", Description: "Returned tokens - nonzero, used or derived", Type: graphql.String},
+		}
+	}
+
+	query := &graphql.Object{Name: "Query"}
+	query.AddFields(
+		&graphql.FieldDefinition{
+			Name: "info",
+			Type: info,
+			Cost: 1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				si, err := w.GetSystemInfo(false)
+				if err != nil {
+					return nil, graphQLError("info", err)
+				}
+				return si.Blockbook, nil
+			},
+		},
+		&graphql.FieldDefinition{
+			Name: "block",
+			Type: block,
+			Args: []*graphql.ArgumentDefinition{{Name: "id", Description: "Block height or hash", Type: &graphql.NonNull{OfType: graphql.String}}},
+			Cost: graphQLLookupCost,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page, pageSize := pagingArgs(p.Field.Selected("txs"))
+				b, err := w.GetBlock(stringArg(p, "id"), page, pageSize)
+				if err != nil {
+					return nil, graphQLError("block", err)
+				}
+				return b, nil
+			},
+		},
+		&graphql.FieldDefinition{
+			Name: "transaction",
+			Type: tx,
+			Args: []*graphql.ArgumentDefinition{{Name: "txid", Type: &graphql.NonNull{OfType: graphql.String}}},
+			Cost: graphQLLookupCost,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				// spending data of the outputs may require additional lookups, get them only if they are selected
+				v := p.Field.Selected("vout")
+				spendingTxs := v.Selected("spentTxId") != nil || v.Selected("spentIndex") != nil || v.Selected("spentHeight") != nil
+				t, err := w.GetTransaction(stringArg(p, "txid"), spendingTxs, false)
+				if err != nil {
+					return nil, graphQLError("transaction", err)
+				}
+				return t, nil
+			},
+		},
+		&graphql.FieldDefinition{
+			Name: "address",
+			Type: address,
+			Args: addressArgs(&graphql.ArgumentDefinition{Name: "address", Type: &graphql.NonNull{OfType: graphql.String}}),
+			Cost: graphQLLookupCost,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return getAddress(p, false)
+			},
+		},
+		&graphql.FieldDefinition{
+			Name: "xpub",
+			Type: address,
+			Args: append(addressArgs(&graphql.ArgumentDefinition{Name: "xpub", Type: &graphql.NonNull{OfType: graphql.String}}),
+				&graphql.ArgumentDefinition{Name: "gap", Type: graphql.Int}),
+			Cost: graphQLLookupCost,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return getAddress(p, true)
+			},
+		},
+		&graphql.FieldDefinition{
+			Name: "utxos",
+			Type: &graphql.List{OfType: utxo},
+			Args: []*graphql.ArgumentDefinition{
+				{Name: "descriptor", Description: "Address or xpub", Type: &graphql.NonNull{OfType: graphql.String}},
+				{Name: "confirmed", Description: "Only confirmed outputs", Type: graphql.Boolean, DefaultValue: false},
+				{Name: "gap", Type: graphql.Int},
+			},
+			Cost: graphQLLookupCost,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				desc := stringArg(p, "descriptor")
+				confirmed, _ := p.Args["confirmed"].(bool)
+				utxos, err := w.GetXpubUtxo(desc, confirmed, intArg(p, "gap", 0, 0, maxGapValue), api.UtxoLockFilter{})
+				if err != nil {
+					utxos, err = w.GetAddressUtxo(desc, confirmed, api.UtxoLockFilter{})
+				}
+				if err != nil {
+					return nil, graphQLError("utxos", err)
+				}
+				return utxos, nil
+			},
+		},
+		&graphql.FieldDefinition{
+			Name: "fiatRates",
+			Type: fiatTicker,
+			Args: []*graphql.ArgumentDefinition{
+				{Name: "currencies", Type: stringList},
+				{Name: "timestamp", Description: "Unix timestamp, the current rates if not set", Type: graphql.Int},
+				{Name: "token", Type: graphql.String},
+			},
+			Cost: 1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				currencies := stringListArg(p, "currencies")
+				token := stringArg(p, "token")
+				if ts, ok := p.Args["timestamp"].(int64); ok {
+					tickers, err := w.GetFiatRatesForTimestamps([]int64{ts}, currencies, token)
+					if err != nil {
+						return nil, graphQLError("fiatRates", err)
+					}
+					return &tickers.Tickers[0], nil
+				}
+				ticker, err := w.GetCurrentFiatRates(currencies, token)
+				if err != nil {
+					return nil, graphQLError("fiatRates", err)
+				}
+				return ticker, nil
+			},
+		},
+	)
+	return &graphql.Schema{
+		Query:           query,
+		MaxDepth:        maxGraphQLDepth,
+		MaxComplexity:   maxGraphQLComplexity,
+		DefaultListSize: graphQLDefaultListSize,
+		MaxCost:         maxGraphQLCost,
+	}
+}
+
+// apiGraphQL executes a GraphQL query sent either as POST with JSON body {"query","variables","operationName"}
+// or as GET with the same url parameters. Errors of the query are returned in the GraphQL response.
+func (s *PublicServer) apiGraphQL(r *http.Request, apiVersion int) (interface{}, error) {
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return nil, api.NewAPIError("Parameter 'variables' is not a valid JSON object", true)
+			}
+		}
+	case http.MethodPost:
+		if r.ContentLength > maxGraphQLBodyBytes {
+			return nil, api.NewAPIError("GraphQL request too large", true)
+		}
+		d := json.NewDecoder(io.LimitReader(r.Body, maxGraphQLBodyBytes))
+		if err := d.Decode(&req); err != nil {
+			return nil, api.NewAPIError("Invalid GraphQL request, "+err.Error(), true)
+		}
+	default:
+		return nil, api.NewAPIError("GraphQL request must be sent using GET or POST", true)
+	}
+	if req.Query == "" {
+		return nil, api.NewAPIError("Missing query", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-graphql"}).Inc()
+	return s.graphQLSchema.Execute(&req), nil
+}
@@ -0,0 +1,704 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/golang/glog"
+)
+
+// Request is a GraphQL request as received over http
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// Error is an error of the request or of a resolved field
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Response is the result of the execution of a request
+type Response struct {
+	Data   *OrderedMap `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// OrderedMap is a JSON object which keeps the order of the selected fields
+type OrderedMap struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func newOrderedMap(size int) *OrderedMap {
+	return &OrderedMap{Keys: make([]string, 0, size), Values: make(map[string]interface{}, size)}
+}
+
+// Set sets the value of the key, a new key is appended to the end
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, found := m.Values[key]; !found {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = value
+}
+
+// Get returns the value of the key
+func (m *OrderedMap) Get(key string) interface{} {
+	return m.Values[key]
+}
+
+// MarshalJSON marshals the map with keys in the order of insertion
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.Keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, err := json.Marshal(m.Values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// CollectedField is a field of the query after application of fragments and directives,
+// fields with the same response key are merged together
+type CollectedField struct {
+	ResponseKey string
+	Name        string
+	Args        map[string]interface{}
+	Definition  *FieldDefinition
+	Fields      []*CollectedField
+	Complexity  int
+}
+
+const typenameField = "__typename"
+
+type validator struct {
+	schema    *Schema
+	doc       *Document
+	variables map[string]interface{}
+	defined   map[string]*VariableDefinition
+}
+
+func errorf(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func unwrapType(t Type) Type {
+	for {
+		switch tt := t.(type) {
+		case *NonNull:
+			t = tt.OfType
+		case *List:
+			t = tt.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isListType(t Type) bool {
+	if nn, ok := t.(*NonNull); ok {
+		t = nn.OfType
+	}
+	_, ok := t.(*List)
+	return ok
+}
+
+// coerceValue converts the input value to the type, the value may contain variable references if vars is set
+func (v *validator) coerceValue(value Value, t Type, resolveVars bool) (interface{}, error) {
+	if vr, ok := value.(*Variable); ok {
+		if !resolveVars {
+			return nil, errorf("Variables are not allowed in default values")
+		}
+		if _, found := v.defined[vr.Name]; !found {
+			return nil, errorf("Variable $%s is not defined", vr.Name)
+		}
+		raw, found := v.variables[vr.Name]
+		if !found {
+			if nn, ok := t.(*NonNull); ok {
+				return nil, errorf("Variable $%s of type %s must be provided", vr.Name, nn)
+			}
+			return nil, nil
+		}
+		// the values of the variables are already coerced, they are only checked against the type of the argument
+		return v.coerceValue(raw, t, false)
+	}
+	switch tt := t.(type) {
+	case *NonNull:
+		if value == nil {
+			return nil, errorf("Expected non-null value of type %s", tt)
+		}
+		return v.coerceValue(value, tt.OfType, resolveVars)
+	case *List:
+		if value == nil {
+			return nil, nil
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			// a single value is coerced to a list of one item
+			items = []interface{}{value}
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			c, err := v.coerceValue(item, tt.OfType, resolveVars)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = c
+		}
+		return list, nil
+	case *Scalar:
+		if value == nil {
+			return nil, nil
+		}
+		c, ok := tt.ParseValue(value)
+		if !ok {
+			return nil, errorf("Expected value of type %s, found %s", tt.Name, valueString(value))
+		}
+		return c, nil
+	}
+	return nil, errorf("Unsupported input type %s", t)
+}
+
+func valueString(value Value) string {
+	switch t := value.(type) {
+	case *EnumValue:
+		return t.Name
+	case string:
+		return strconv.Quote(t)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// variableType returns the schema type of a variable declared in the document
+func variableType(def *VariableDefinition) (Type, error) {
+	var parse func(s string) (Type, error)
+	parse = func(s string) (Type, error) {
+		nonNull := false
+		if len(s) > 0 && s[len(s)-1] == '!' {
+			nonNull = true
+			s = s[:len(s)-1]
+		}
+		var t Type
+		if len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']' {
+			inner, err := parse(s[1 : len(s)-1])
+			if err != nil {
+				return nil, err
+			}
+			t = &List{OfType: inner}
+		} else {
+			switch s {
+			case "String":
+				t = String
+			case "Int":
+				t = Int
+			case "Float":
+				t = Float
+			case "Boolean":
+				t = Boolean
+			default:
+				return nil, errorf("Variable $%s has unsupported type %s", def.Name, s)
+			}
+		}
+		if nonNull {
+			t = &NonNull{OfType: t}
+		}
+		return t, nil
+	}
+	t, err := parse(def.Type)
+	if err != nil {
+		return nil, err
+	}
+	if def.NonNull {
+		t = &NonNull{OfType: t}
+	}
+	return t, nil
+}
+
+func (v *validator) coerceVariables(op *Operation, raw map[string]interface{}) error {
+	v.defined = make(map[string]*VariableDefinition, len(op.Variables))
+	v.variables = make(map[string]interface{}, len(op.Variables))
+	for _, def := range op.Variables {
+		if _, found := v.defined[def.Name]; found {
+			return errorf("There can be only one variable named $%s", def.Name)
+		}
+		v.defined[def.Name] = def
+		t, err := variableType(def)
+		if err != nil {
+			return err
+		}
+		value, found := raw[def.Name]
+		if !found {
+			if def.Default == nil {
+				if def.NonNull {
+					return errorf("Variable $%s of type %s! must be provided", def.Name, def.Type)
+				}
+				continue
+			}
+			value = def.Default
+		}
+		c, err := v.coerceValue(value, t, false)
+		if err != nil {
+			return errorf("Variable $%s: %s", def.Name, err.Error())
+		}
+		v.variables[def.Name] = c
+	}
+	return nil
+}
+
+// skipped evaluates the @skip and @include directives
+func (v *validator) skipped(dirs []*Directive) (bool, error) {
+	for _, d := range dirs {
+		if d.Name != "skip" && d.Name != "include" {
+			return false, errorf("Unknown directive @%s", d.Name)
+		}
+		var cond interface{}
+		found := false
+		for _, a := range d.Arguments {
+			if a.Name != "if" {
+				return false, errorf("Unknown argument %q of directive @%s", a.Name, d.Name)
+			}
+			c, err := v.coerceValue(a.Value, &NonNull{OfType: Boolean}, true)
+			if err != nil {
+				return false, errorf("Directive @%s: %s", d.Name, err.Error())
+			}
+			cond, found = c, true
+		}
+		if !found {
+			return false, errorf("Directive @%s requires argument \"if\"", d.Name)
+		}
+		if b := cond.(bool); (d.Name == "skip" && b) || (d.Name == "include" && !b) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// collect groups the selected fields by the response key, resolving fragments and directives
+func (v *validator) collect(obj *Object, selections []Selection, keys *[]string, groups map[string][]*Field) error {
+	for _, s := range selections {
+		skip, err := v.skipped(s.directives())
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		switch sel := s.(type) {
+		case *Field:
+			key := sel.ResponseKey()
+			if _, found := groups[key]; !found {
+				*keys = append(*keys, key)
+			}
+			groups[key] = append(groups[key], sel)
+		case *InlineFragment:
+			if sel.TypeCondition != "" && sel.TypeCondition != obj.Name {
+				return errorf("Fragment on type %s cannot be spread within type %s", sel.TypeCondition, obj.Name)
+			}
+			if err := v.collect(obj, sel.SelectionSet, keys, groups); err != nil {
+				return err
+			}
+		case *FragmentSpread:
+			f, found := v.doc.Fragments[sel.Name]
+			if !found {
+				return errorf("Unknown fragment %q", sel.Name)
+			}
+			if f.TypeCondition != obj.Name {
+				return errorf("Fragment %q on type %s cannot be spread within type %s", f.Name, f.TypeCondition, obj.Name)
+			}
+			if err := v.collect(obj, f.SelectionSet, keys, groups); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) coerceArguments(def *FieldDefinition, field *Field) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(def.Args))
+	for _, a := range field.Arguments {
+		var ad *ArgumentDefinition
+		for _, d := range def.Args {
+			if d.Name == a.Name {
+				ad = d
+				break
+			}
+		}
+		if ad == nil {
+			return nil, errorf("Unknown argument %q on field %q", a.Name, def.Name)
+		}
+		if _, found := args[a.Name]; found {
+			return nil, errorf("There can be only one argument named %q", a.Name)
+		}
+		c, err := v.coerceValue(a.Value, ad.Type, true)
+		if err != nil {
+			return nil, errorf("Argument %q on field %q: %s", a.Name, def.Name, err.Error())
+		}
+		if c != nil {
+			args[a.Name] = c
+		}
+	}
+	for _, ad := range def.Args {
+		if _, found := args[ad.Name]; found {
+			continue
+		}
+		if ad.DefaultValue != nil {
+			args[ad.Name] = ad.DefaultValue
+		} else if _, ok := ad.Type.(*NonNull); ok {
+			return nil, errorf("Field %q argument %q of type %s is required", def.Name, ad.Name, ad.Type)
+		}
+	}
+	return args, nil
+}
+
+// collectFields validates the selection set on the object type and returns the collected fields
+func (v *validator) collectFields(obj *Object, selections []Selection, depth int) ([]*CollectedField, int, error) {
+	if v.schema.MaxDepth > 0 && depth > v.schema.MaxDepth {
+		return nil, 0, errorf("Query is nested too deeply, maximum depth is %d", v.schema.MaxDepth)
+	}
+	var keys []string
+	groups := make(map[string][]*Field)
+	if err := v.collect(obj, selections, &keys, groups); err != nil {
+		return nil, 0, err
+	}
+	fields := make([]*CollectedField, 0, len(keys))
+	complexity := 0
+	for _, key := range keys {
+		group := groups[key]
+		first := group[0]
+		if first.Name == typenameField {
+			fields = append(fields, &CollectedField{ResponseKey: key, Name: first.Name})
+			continue
+		}
+		def := obj.Field(first.Name)
+		if def == nil {
+			return nil, 0, errorf("Cannot query field %q on type %s", first.Name, obj.Name)
+		}
+		args, err := v.coerceArguments(def, first)
+		if err != nil {
+			return nil, 0, err
+		}
+		var sub []Selection
+		for _, f := range group {
+			if f.Name != first.Name {
+				return nil, 0, errorf("Fields %q conflict because %s and %s are different fields", key, first.Name, f.Name)
+			}
+			if f != first {
+				a, err := v.coerceArguments(def, f)
+				if err != nil {
+					return nil, 0, err
+				}
+				if !reflect.DeepEqual(a, args) {
+					return nil, 0, errorf("Fields %q conflict because they have differing arguments", key)
+				}
+			}
+			sub = append(sub, f.SelectionSet...)
+		}
+		cf := &CollectedField{ResponseKey: key, Name: first.Name, Args: args, Definition: def, Complexity: def.Cost}
+		switch t := unwrapType(def.Type).(type) {
+		case *Object:
+			if len(sub) == 0 {
+				return nil, 0, errorf("Field %q of type %s must have a selection of subfields", def.Name, def.Type)
+			}
+			var c int
+			if cf.Fields, c, err = v.collectFields(t, sub, depth+1); err != nil {
+				return nil, 0, err
+			}
+			// each returned object counts one
+			c++
+			if isListType(def.Type) {
+				size := v.schema.DefaultListSize
+				if n, ok := args[def.ListSizeArg].(int64); ok && def.ListSizeArg != "" && n > 0 {
+					size = int(n)
+				}
+				if size < 1 {
+					size = 1
+				}
+				c *= size
+			}
+			cf.Complexity += c
+		default:
+			if len(sub) > 0 {
+				return nil, 0, errorf("Field %q must not have a selection since type %s has no subfields", def.Name, def.Type)
+			}
+		}
+		complexity += cf.Complexity
+		if v.schema.MaxComplexity > 0 && complexity > v.schema.MaxComplexity {
+			return nil, 0, errorf("Query is too complex, maximum complexity is %d", v.schema.MaxComplexity)
+		}
+		fields = append(fields, cf)
+	}
+	return fields, complexity, nil
+}
+
+// checkFragmentCycles returns error if a fragment spreads itself, directly or through other fragments
+func checkFragmentCycles(doc *Document) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(doc.Fragments))
+	var visit func(name string) error
+	var walk func(selections []Selection) error
+	walk = func(selections []Selection) error {
+		for _, s := range selections {
+			switch sel := s.(type) {
+			case *Field:
+				if err := walk(sel.SelectionSet); err != nil {
+					return err
+				}
+			case *InlineFragment:
+				if err := walk(sel.SelectionSet); err != nil {
+					return err
+				}
+			case *FragmentSpread:
+				if err := visit(sel.Name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	visit = func(name string) error {
+		f, found := doc.Fragments[name]
+		if !found || state[name] == done {
+			return nil
+		}
+		if state[name] == visiting {
+			return errorf("Cannot spread fragment %q within itself", name)
+		}
+		state[name] = visiting
+		if err := walk(f.SelectionSet); err != nil {
+			return err
+		}
+		state[name] = done
+		return nil
+	}
+	for name := range doc.Fragments {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepare parses and validates the request, returns the collected root fields and the complexity of the query
+func (s *Schema) Prepare(req *Request) ([]*CollectedField, int, error) {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return nil, 0, err
+	}
+	var op *Operation
+	if req.OperationName == "" {
+		if len(doc.Operations) > 1 {
+			return nil, 0, errorf("Must provide operation name if query contains multiple operations")
+		}
+		op = doc.Operations[0]
+	} else {
+		for _, o := range doc.Operations {
+			if o.Name == req.OperationName {
+				op = o
+				break
+			}
+		}
+		if op == nil {
+			return nil, 0, errorf("Unknown operation named %q", req.OperationName)
+		}
+	}
+	if err := checkFragmentCycles(doc); err != nil {
+		return nil, 0, err
+	}
+	v := &validator{schema: s, doc: doc}
+	if err := v.coerceVariables(op, req.Variables); err != nil {
+		return nil, 0, err
+	}
+	return v.collectFields(s.Query, op.SelectionSet, 1)
+}
+
+// Execute validates and executes the request. Invalid requests are not executed at all,
+// errors of the resolvers are reported with the path of the field and the field is set to null.
+func (s *Schema) Execute(req *Request) *Response {
+	fields, _, err := s.Prepare(req)
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			return &Response{Errors: []*Error{e}}
+		}
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	e := &executor{maxCost: s.MaxCost}
+	data, _ := e.executeFields(s.Query, nil, fields, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+type executor struct {
+	errors   []*Error
+	maxCost  int
+	cost     int
+	exceeded bool
+}
+
+// charge adds the cost of the field to the cost of the execution, returns false if the field must not be resolved
+// because the maximum cost is exceeded. The error is reported only for the first field over the limit.
+func (e *executor) charge(f *CollectedField, path []interface{}) bool {
+	if e.maxCost <= 0 || f.Definition.Cost == 0 {
+		return true
+	}
+	if !e.exceeded {
+		e.cost += f.Definition.Cost
+		if e.cost <= e.maxCost {
+			return true
+		}
+		e.exceeded = true
+		e.addError(fmt.Sprintf("Query exceeded the maximum cost of %d during execution", e.maxCost), path)
+	}
+	return false
+}
+
+func (e *executor) addError(message string, path []interface{}) {
+	e.errors = append(e.errors, &Error{Message: message, Path: append([]interface{}(nil), path...)})
+}
+
+// executeFields resolves the fields of an object, returns false if a non-null field resolved to null
+func (e *executor) executeFields(obj *Object, source interface{}, fields []*CollectedField, path []interface{}) (*OrderedMap, bool) {
+	m := newOrderedMap(len(fields))
+	for _, f := range fields {
+		fieldPath := append(path[:len(path):len(path)], f.ResponseKey)
+		if f.Name == typenameField {
+			m.Set(f.ResponseKey, obj.Name)
+			continue
+		}
+		if !e.charge(f, fieldPath) {
+			c, ok := e.completeValue(f.Definition.Type, nil, f, fieldPath, true)
+			if !ok {
+				return nil, false
+			}
+			m.Set(f.ResponseKey, c)
+			continue
+		}
+		v, err := e.resolve(f, source)
+		if err != nil {
+			e.addError(err.Error(), fieldPath)
+			v = nil
+		}
+		c, ok := e.completeValue(f.Definition.Type, v, f, fieldPath, err != nil)
+		if !ok {
+			return nil, false
+		}
+		m.Set(f.ResponseKey, c)
+	}
+	return m, true
+}
+
+func (e *executor) resolve(f *CollectedField, source interface{}) (v interface{}, err error) {
+	if f.Definition.Resolve == nil {
+		return defaultResolve(source, f.Name), nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("GraphQL resolver of field ", f.Name, " recovered from panic: ", r, ", ", string(debug.Stack()))
+			v, err = nil, errorf("Internal server error")
+		}
+	}()
+	return f.Definition.Resolve(ResolveParams{Source: source, Args: f.Args, Field: f})
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// completeValue converts the resolved value to the output according to the type,
+// returns false if the value is null and the type is non-null
+func (e *executor) completeValue(t Type, v interface{}, f *CollectedField, path []interface{}, reported bool) (interface{}, bool) {
+	if nn, ok := t.(*NonNull); ok {
+		c, ok := e.completeValue(nn.OfType, v, f, path, reported)
+		if !ok {
+			return nil, false
+		}
+		if c == nil {
+			if !reported {
+				e.addError(fmt.Sprintf("Cannot return null for non-nullable field %q", f.Name), path)
+			}
+			return nil, false
+		}
+		return c, true
+	}
+	if isNil(v) {
+		return nil, true
+	}
+	switch tt := t.(type) {
+	case *List:
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.addError(fmt.Sprintf("Field %q expected a list", f.Name), path)
+			return nil, true
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			item := rv.Index(i)
+			// pass addressable items as pointers so that the resolvers do not copy large structs
+			var iv interface{}
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				iv = item.Addr().Interface()
+			} else {
+				iv = item.Interface()
+			}
+			c, ok := e.completeValue(tt.OfType, iv, f, append(path[:len(path):len(path)], i), false)
+			if !ok {
+				return nil, true
+			}
+			list[i] = c
+		}
+		return list, true
+	case *Scalar:
+		c, ok := tt.Serialize(v)
+		if !ok {
+			e.addError(fmt.Sprintf("%s cannot represent value of field %q", tt.Name, f.Name), path)
+			return nil, true
+		}
+		return c, true
+	case *Object:
+		m, ok := e.executeFields(tt, v, f.Fields, path)
+		if !ok {
+			return nil, true
+		}
+		return m, true
+	}
+	return nil, true
+}
@@ -0,0 +1,304 @@
+//go:build unittest
+
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+type testItem struct {
+	ID    string   `json:"id"`
+	Value *big.Int `json:"value,omitempty"`
+	Count uint32   `json:"count"`
+}
+
+type testPaging struct {
+	Page int `json:"page"`
+}
+
+type testList struct {
+	testPaging
+	Items []testItem `json:"items"`
+}
+
+func newTestSchema() *Schema {
+	item := &Object{Name: "Item"}
+	item.AddFields(
+		&FieldDefinition{Name: "id", Type: &NonNull{OfType: String}},
+		&FieldDefinition{Name: "value", Type: String},
+		&FieldDefinition{Name: "count", Type: Int},
+		&FieldDefinition{
+			Name: "failing",
+			Type: String,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return nil, errors.New("failed " + p.Source.(*testItem).ID)
+			},
+		},
+		&FieldDefinition{
+			Name: "required",
+			Type: &NonNull{OfType: String},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return nil, nil
+			},
+		},
+	)
+	item.AddFields(&FieldDefinition{
+		Name: "next",
+		Type: item,
+		Cost: 10,
+		Resolve: func(p ResolveParams) (interface{}, error) {
+			return &testItem{ID: p.Source.(*testItem).ID + "+"}, nil
+		},
+	})
+	list := &Object{Name: "List"}
+	list.AddFields(
+		&FieldDefinition{Name: "page", Type: Int},
+		&FieldDefinition{
+			Name:        "items",
+			Type:        &List{OfType: item},
+			Args:        []*ArgumentDefinition{{Name: "size", Type: Int, DefaultValue: int64(2)}},
+			ListSizeArg: "size",
+		},
+	)
+	query := &Object{Name: "Query"}
+	query.AddFields(
+		&FieldDefinition{
+			Name: "item",
+			Type: item,
+			Args: []*ArgumentDefinition{{Name: "id", Type: &NonNull{OfType: String}}},
+			Cost: 1,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return &testItem{ID: p.Args["id"].(string), Value: big.NewInt(12345678901234), Count: 3}, nil
+			},
+		},
+		&FieldDefinition{
+			Name: "list",
+			Type: list,
+			Args: []*ArgumentDefinition{{Name: "ids", Type: &List{OfType: String}}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				l := &testList{testPaging: testPaging{Page: 1}}
+				for _, id := range p.Args["ids"].([]interface{}) {
+					l.Items = append(l.Items, testItem{ID: id.(string)})
+				}
+				return l, nil
+			},
+		},
+		&FieldDefinition{
+			Name: "panic",
+			Type: String,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				panic("test")
+			},
+		},
+	)
+	return &Schema{Query: query, MaxDepth: 5, MaxComplexity: 100, DefaultListSize: 10, MaxCost: 100}
+}
+
+func execute(t *testing.T, s *Schema, req *Request) string {
+	t.Helper()
+	b, err := json.Marshal(s.Execute(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExecute(t *testing.T) {
+	s := newTestSchema()
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "fields in the order of selection with alias and typename",
+			req:  Request{Query: `{ item(id: "a") { count __typename second: id value } }`},
+			want: `{"data":{"item":{"count":3,"__typename":"Item","second":"a","value":"12345678901234"}}}`,
+		},
+		{
+			name: "nested resolution and embedded struct",
+			req:  Request{Query: `{ list(ids: ["x", "y"]) { page items { id next { id next { id } } } } }`},
+			want: `{"data":{"list":{"page":1,"items":[{"id":"x","next":{"id":"x+","next":{"id":"x++"}}},{"id":"y","next":{"id":"y+","next":{"id":"y++"}}}]}}}`,
+		},
+		{
+			name: "single value coerced to list",
+			req:  Request{Query: `{ list(ids: "x") { items { id } } }`},
+			want: `{"data":{"list":{"items":[{"id":"x"}]}}}`,
+		},
+		{
+			name: "fragments, variables and directives",
+			req: Request{
+				Query: `query Q($id: String!, $skip: Boolean = false) {
+					item(id: $id) { ...F ... on Item { count @skip(if: $skip) } value @include(if: false) }
+				}
+				fragment F on Item { id }`,
+				Variables: map[string]interface{}{"id": "v", "skip": true},
+			},
+			want: `{"data":{"item":{"id":"v"}}}`,
+		},
+		{
+			name: "merged fields",
+			req:  Request{Query: `{ item(id: "a") { id next { id } next { count } } }`},
+			want: `{"data":{"item":{"id":"a","next":{"id":"a+","count":0}}}}`,
+		},
+		{
+			name: "operation name",
+			req:  Request{Query: `query A { item(id: "a") { id } } query B { item(id: "b") { id } }`, OperationName: "B"},
+			want: `{"data":{"item":{"id":"b"}}}`,
+		},
+		{
+			name: "resolver error",
+			req:  Request{Query: `{ list(ids: ["x"]) { items { id failing } } }`},
+			want: `{"data":{"list":{"items":[{"id":"x","failing":null}]}},"errors":[{"message":"failed x","path":["list","items",0,"failing"]}]}`,
+		},
+		{
+			name: "null in non-null field propagates to the parent",
+			req:  Request{Query: `{ item(id: "a") { id required } }`},
+			want: `{"data":{"item":null},"errors":[{"message":"Cannot return null for non-nullable field \"required\"","path":["item","required"]}]}`,
+		},
+		{
+			name: "panic in resolver",
+			req:  Request{Query: `{ panic }`},
+			want: `{"data":{"panic":null},"errors":[{"message":"Internal server error","path":["panic"]}]}`,
+		},
+		{
+			name: "mutation",
+			req:  Request{Query: `mutation { item }`},
+			want: `{"errors":[{"message":"Operation \"mutation\" is not supported, only queries are allowed"}]}`,
+		},
+		{
+			name: "syntax error",
+			req:  Request{Query: "{\n item(id: \"a\") { id }"},
+			want: `{"errors":[{"message":"Syntax error on line 2: unexpected end of document"}]}`,
+		},
+		{
+			name: "unknown field",
+			req:  Request{Query: `{ item(id: "a") { name } }`},
+			want: `{"errors":[{"message":"Cannot query field \"name\" on type Item"}]}`,
+		},
+		{
+			name: "missing argument",
+			req:  Request{Query: `{ item { id } }`},
+			want: `{"errors":[{"message":"Field \"item\" argument \"id\" of type String! is required"}]}`,
+		},
+		{
+			name: "missing variable",
+			req:  Request{Query: `query ($id: String!) { item(id: $id) { id } }`},
+			want: `{"errors":[{"message":"Variable $id of type String! must be provided"}]}`,
+		},
+		{
+			name: "invalid variable",
+			req:  Request{Query: `query ($id: Int) { item(id: $id) { id } }`, Variables: map[string]interface{}{"id": 1.0}},
+			want: `{"errors":[{"message":"Argument \"id\" on field \"item\": Expected value of type String, found 1"}]}`,
+		},
+		{
+			name: "missing selection",
+			req:  Request{Query: `{ item(id: "a") }`},
+			want: `{"errors":[{"message":"Field \"item\" of type Item must have a selection of subfields"}]}`,
+		},
+		{
+			name: "fragment cycle",
+			req:  Request{Query: `{ item(id: "a") { ...F } } fragment F on Item { next { ...F } }`},
+			want: `{"errors":[{"message":"Cannot spread fragment \"F\" within itself"}]}`,
+		},
+		{
+			name: "conflicting fields",
+			req:  Request{Query: `{ item(id: "a") { x: id x: count } }`},
+			want: `{"errors":[{"message":"Fields \"x\" conflict because id and count are different fields"}]}`,
+		},
+		{
+			name: "depth limit",
+			req:  Request{Query: `{ item(id: "a") { next { next { next { next { id } } } } } }`},
+			want: `{"errors":[{"message":"Query is nested too deeply, maximum depth is 5"}]}`,
+		},
+		{
+			name: "complexity limit",
+			req:  Request{Query: `{ list(ids: []) { items(size: 5) { next { next { id } } } } }`},
+			want: `{"errors":[{"message":"Query is too complex, maximum complexity is 100"}]}`,
+		},
+		{
+			// the complexity counts the default 2 items, the execution is charged for each of the 6 items
+			name: "cost limit",
+			req:  Request{Query: `{ list(ids: ["a", "b", "c", "d", "e", "f"]) { items { next { next { id } } } } }`},
+			want: `{"data":{"list":{"items":[{"next":{"next":{"id":"a++"}}},{"next":{"next":{"id":"b++"}}},{"next":{"next":{"id":"c++"}}},{"next":{"next":{"id":"d++"}}},{"next":{"next":{"id":"e++"}}},{"next":null}]}},"errors":[{"message":"Query exceeded the maximum cost of 100 during execution","path":["list","items",5,"next"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, s, &tt.req); got != tt.want {
+				t.Errorf("Execute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareComplexity(t *testing.T) {
+	s := newTestSchema()
+	tests := []struct {
+		query string
+		want  int
+	}{
+		// item costs 1 and returns 1 object
+		{query: `{ item(id: "a") { id } }`, want: 2},
+		// next costs 10 and returns 1 object
+		{query: `{ item(id: "a") { next { id } } }`, want: 13},
+		// the list returns 1 object and the items default to 2 objects
+		{query: `{ list { items { id } } }`, want: 3},
+		{query: `{ list { items(size: 4) { next { id } } } }`, want: 1 + 4*(1+11)},
+		{query: `{ list { items(size: 4) @skip(if: true) { next { id } } } }`, want: 1},
+	}
+	for _, tt := range tests {
+		_, got, err := s.Prepare(&Request{Query: tt.query})
+		if err != nil {
+			t.Fatalf("Prepare(%v) error %v", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Prepare(%v) complexity = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# comment
+		query Q($a: [Int!]! = [1, 2], $b: String) @dir {
+			alias: field(s: "x\"yA", f: -1.5e2, e: ENUM, o: {k: null, l: [true]}, v: $b) {
+				... on T { x }
+				...Frag @include(if: true)
+			}
+		}
+		fragment Frag on T { y }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Operations) != 1 || len(doc.Fragments) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	op := doc.Operations[0]
+	if op.Name != "Q" || len(op.Variables) != 2 || op.Variables[0].Type != "[Int!]" || !op.Variables[0].NonNull {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	f := op.SelectionSet[0].(*Field)
+	if f.ResponseKey() != "alias" || f.Name != "field" || len(f.Arguments) != 5 || len(f.SelectionSet) != 2 {
+		t.Fatalf("unexpected field %+v", f)
+	}
+	if s := f.Arguments[0].Value.(string); s != `x"yA` {
+		t.Errorf("unexpected string %q", s)
+	}
+	if v := f.Arguments[1].Value.(float64); v != -150 {
+		t.Errorf("unexpected float %v", v)
+	}
+	if _, ok := f.SelectionSet[1].(*FragmentSpread); !ok {
+		t.Errorf("unexpected selection %+v", f.SelectionSet[1])
+	}
+
+	for _, q := range []string{`{`, `{ a(b: ) }`, `{ a }}`, `{ a(b: "x) }`, `{ a(b: 1.) }`, `query { }`, `{ .a }`} {
+		if _, err := Parse(q); err == nil || !strings.HasPrefix(err.Error(), "Syntax error") {
+			t.Errorf("Parse(%q) error = %v, want syntax error", q, err)
+		}
+	}
+}
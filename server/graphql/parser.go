@@ -0,0 +1,715 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a parsed GraphQL request document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query operation of the document, mutations and subscriptions are not supported
+type Operation struct {
+	Name         string
+	Variables    []*VariableDefinition
+	SelectionSet []Selection
+}
+
+// VariableDefinition is a declared variable of an operation
+type VariableDefinition struct {
+	Name    string
+	Type    string
+	NonNull bool
+	Default Value
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	SelectionSet  []Selection
+}
+
+// Selection is one of *Field, *FragmentSpread or *InlineFragment
+type Selection interface {
+	directives() []*Directive
+}
+
+// Field is a selected field with its arguments and sub-selection
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []Selection
+	Line         int
+}
+
+// ResponseKey returns the alias of the field or its name
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread is a reference to a named fragment
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment is a selection set with an optional type condition
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+}
+
+func (f *Field) directives() []*Directive          { return f.Directives }
+func (f *FragmentSpread) directives() []*Directive { return f.Directives }
+func (f *InlineFragment) directives() []*Directive { return f.Directives }
+
+// Argument is a named argument of a field or directive
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Directive is a directive applied to a selection, only @include and @skip are supported
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+}
+
+// Value is a literal value or a variable reference in the document
+type Value = interface{}
+
+// Variable is a reference to an operation variable
+type Variable struct {
+	Name string
+}
+
+// EnumValue is an unquoted name used as a value
+type EnumValue struct {
+	Name string
+}
+
+// ObjectField is one field of an object value, the order of the fields is preserved
+type ObjectField struct {
+	Name  string
+	Value Value
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	line  int
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *lexer) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Syntax error on line %d: %s", l.line, fmt.Sprintf(format, a...))
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func (l *lexer) next() (token, error) {
+	// skip ignored tokens - whitespace, commas, comments and the byte order mark
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '\n' {
+			l.line++
+			l.pos++
+		} else if c == ' ' || c == '\t' || c == '\r' || c == ',' {
+			l.pos++
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		} else if strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+			l.pos += len("\ufeff")
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, line: l.line}, nil
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, value: string(c), line: l.line}, nil
+	case c == '.':
+		if strings.HasPrefix(l.src[l.pos:], "...") {
+			l.pos += 3
+			return token{kind: tokenPunctuator, value: "...", line: l.line}, nil
+		}
+		return token{}, l.errorf("unexpected character '.'")
+	case isNameStart(c):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], line: l.line}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return l.number()
+	case c == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf("unexpected character %q", r)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		s := l.pos
+		for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+			l.pos++
+		}
+		return l.pos - s
+	}
+	if digits() == 0 {
+		return token{}, l.errorf("invalid number")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if digits() == 0 {
+			return token{}, l.errorf("invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, l.errorf("invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || l.src[l.pos] == '.') {
+		return token{}, l.errorf("invalid number")
+	}
+	return token{kind: kind, value: l.src[start:l.pos], line: l.line}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		l.pos += 3
+		end := strings.Index(l.src[l.pos:], `"""`)
+		if end < 0 {
+			return token{}, l.errorf("unterminated string")
+		}
+		s := l.src[l.pos : l.pos+end]
+		l.line += strings.Count(s, "\n")
+		l.pos += end + 3
+		return token{kind: tokenString, value: s, line: l.line}, nil
+	}
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokenString, value: sb.String(), line: l.line}, nil
+		case '\n':
+			return token{}, l.errorf("unterminated string")
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf("unterminated string")
+			}
+			e := l.src[l.pos+1]
+			l.pos += 2
+			switch e {
+			case '"', '\\', '/':
+				sb.WriteByte(e)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, l.errorf("invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, l.errorf("invalid unicode escape")
+				}
+				sb.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				return token{}, l.errorf("invalid escape sequence \\%c", e)
+			}
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf("unterminated string")
+}
+
+type parser struct {
+	lex   lexer
+	tok   token
+	depth int
+}
+
+// maxParseDepth limits the nesting of the parsed document to protect the recursive parser
+const maxParseDepth = 64
+
+// Parse parses a GraphQL request document
+func Parse(src string) (*Document, error) {
+	p := &parser{lex: lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.tok.kind != tokenEOF {
+		if p.peek(tokenPunctuator, "{") {
+			ss, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{SelectionSet: ss})
+			continue
+		}
+		if p.tok.kind != tokenName {
+			return nil, p.unexpected()
+		}
+		switch p.tok.value {
+		case "query":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, found := doc.Fragments[f.Name]; found {
+				return nil, fmt.Errorf("There can be only one fragment named %q", f.Name)
+			}
+			doc.Fragments[f.Name] = f
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("Operation %q is not supported, only queries are allowed", p.tok.value)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("The document does not contain any operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return p.lex.errorf("unexpected end of document")
+	}
+	return fmt.Errorf("Syntax error on line %d: unexpected %q", p.tok.line, p.tok.value)
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	if p.tok.kind != kind || (value != "" && p.tok.value != value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	n := p.tok.value
+	return n, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	// skip the keyword query
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	op := &Operation{}
+	var err error
+	if p.tok.kind == tokenName {
+		if op.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		if op.Variables, err = p.variableDefinitions(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*VariableDefinition, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var defs []*VariableDefinition
+	for !p.peek(tokenPunctuator, ")") {
+		if err := p.expect(tokenPunctuator, "$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenPunctuator, ":"); err != nil {
+			return nil, err
+		}
+		d := &VariableDefinition{Name: name}
+		if d.Type, d.NonNull, err = p.typeReference(); err != nil {
+			return nil, err
+		}
+		if p.peek(tokenPunctuator, "=") {
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if d.Default, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		defs = append(defs, d)
+	}
+	return defs, p.advance()
+}
+
+// typeReference parses a type of a variable, the type is returned in its textual form, e.g. [String!]
+func (p *parser) typeReference() (string, bool, error) {
+	var t string
+	if p.peek(tokenPunctuator, "[") {
+		if err := p.advance(); err != nil {
+			return "", false, err
+		}
+		inner, nonNull, err := p.typeReference()
+		if err != nil {
+			return "", false, err
+		}
+		if nonNull {
+			inner += "!"
+		}
+		if err = p.expect(tokenPunctuator, "]"); err != nil {
+			return "", false, err
+		}
+		t = "[" + inner + "]"
+	} else {
+		n, err := p.name()
+		if err != nil {
+			return "", false, err
+		}
+		t = n
+	}
+	if p.peek(tokenPunctuator, "!") {
+		return t, true, p.advance()
+	}
+	return t, false, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, p.lex.errorf("invalid fragment name \"on\"")
+	}
+	if p.tok.kind != tokenName || p.tok.value != "on" {
+		return nil, p.unexpected()
+	}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	f := &Fragment{Name: name}
+	if f.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if f.SelectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect(tokenPunctuator, "{"); err != nil {
+		return nil, err
+	}
+	p.depth++
+	if p.depth > maxParseDepth {
+		return nil, p.lex.errorf("the document is nested too deeply")
+	}
+	var selections []Selection
+	for !p.peek(tokenPunctuator, "}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	p.depth--
+	return selections, p.advance()
+}
+
+func (p *parser) selection() (Selection, error) {
+	var err error
+	if p.peek(tokenPunctuator, "...") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			fs := &FragmentSpread{}
+			if fs.Name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if fs.Directives, err = p.directives(); err != nil {
+				return nil, err
+			}
+			return fs, nil
+		}
+		inf := &InlineFragment{}
+		if p.tok.kind == tokenName {
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if inf.TypeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if inf.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		if inf.SelectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+		return inf, nil
+	}
+	f := &Field{Line: p.tok.line}
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunctuator, ":") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		f.Alias = f.Name
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		if f.Arguments, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+	}
+	if f.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunctuator, "{") {
+		if f.SelectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var args []*Argument
+	for !p.peek(tokenPunctuator, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenPunctuator, ":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &Argument{Name: name, Value: v})
+	}
+	if len(args) == 0 {
+		return nil, p.unexpected()
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var dirs []*Directive
+	for p.peek(tokenPunctuator, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		d := &Directive{}
+		var err error
+		if d.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if p.peek(tokenPunctuator, "(") {
+			if d.Arguments, err = p.arguments(false); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+func (p *parser) value(constant bool) (Value, error) {
+	t := p.tok
+	switch t.kind {
+	case tokenPunctuator:
+		switch t.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return &Variable{Name: name}, nil
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			list := make([]Value, 0)
+			for !p.peek(tokenPunctuator, "]") {
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, p.advance()
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			obj := make([]*ObjectField, 0)
+			for !p.peek(tokenPunctuator, "}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err = p.expect(tokenPunctuator, ":"); err != nil {
+					return nil, err
+				}
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, &ObjectField{Name: name, Value: v})
+			}
+			return obj, p.advance()
+		}
+	case tokenInt:
+		i, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, p.lex.errorf("invalid integer %s", t.value)
+		}
+		return i, p.advance()
+	case tokenFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.lex.errorf("invalid float %s", t.value)
+		}
+		return f, p.advance()
+	case tokenString:
+		return t.value, p.advance()
+	case tokenName:
+		var v Value
+		switch t.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = &EnumValue{Name: t.value}
+		}
+		return v, p.advance()
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,336 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type is one of *Scalar, *Object, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type of the schema
+type Scalar struct {
+	Name string
+	// Serialize converts the resolved value to the output value, returns false if the value cannot be converted
+	Serialize func(v interface{}) (interface{}, bool)
+	// ParseValue converts an input value (from the document or variables) to the argument value
+	ParseValue func(v interface{}) (interface{}, bool)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is a type with named fields, the fields are listed in the order of declaration
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*FieldDefinition
+	fields      map[string]*FieldDefinition
+}
+
+func (o *Object) String() string { return o.Name }
+
+// Field returns the definition of the field with given name or nil
+func (o *Object) Field(name string) *FieldDefinition {
+	if o.fields == nil {
+		o.fields = make(map[string]*FieldDefinition, len(o.Fields))
+		for _, f := range o.Fields {
+			o.fields[f.Name] = f
+		}
+	}
+	return o.fields[name]
+}
+
+// AddFields appends fields to the object, it allows definition of mutually recursive types
+func (o *Object) AddFields(fields ...*FieldDefinition) {
+	o.Fields = append(o.Fields, fields...)
+	o.fields = nil
+}
+
+// List is a list of items of the type OfType
+type List struct {
+	OfType Type
+}
+
+func (l *List) String() string { return "[" + l.OfType.String() + "]" }
+
+// NonNull marks the type OfType as not nullable
+type NonNull struct {
+	OfType Type
+}
+
+func (n *NonNull) String() string { return n.OfType.String() + "!" }
+
+// ResolveFn returns the value of a field
+type ResolveFn func(p ResolveParams) (interface{}, error)
+
+// FieldDefinition is a field of an object type
+type FieldDefinition struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*ArgumentDefinition
+	// Cost is the complexity of resolving the field, fields without Resolve function cost nothing if not specified
+	Cost int
+	// ListSizeArg names the argument holding the number of returned items, it multiplies the complexity of the selection of a list field
+	ListSizeArg string
+	// Resolve returns the value of the field, if not set, the field is read from the source value by its json name
+	Resolve ResolveFn
+}
+
+// ArgumentDefinition is an argument of a field
+type ArgumentDefinition struct {
+	Name         string
+	Description  string
+	Type         Type
+	DefaultValue interface{}
+}
+
+// ResolveParams are passed to the resolve function of a field
+type ResolveParams struct {
+	// Source is the value of the parent object
+	Source interface{}
+	// Args contains the coerced arguments of the field including the default values
+	Args map[string]interface{}
+	// Field is the selection of the field in the document
+	Field *CollectedField
+}
+
+// Selected returns true if the field with given name is selected in the sub-selection of the resolved field
+func (p *ResolveParams) Selected(name string) bool {
+	return p.Field.Selected(name) != nil
+}
+
+// Selected returns the first field with given name from the sub-selection of the field or nil
+func (f *CollectedField) Selected(name string) *CollectedField {
+	if f == nil {
+		return nil
+	}
+	for _, s := range f.Fields {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Schema is the executable schema, it has only the query root type.
+// The complexity of a query is the sum of the costs of the selected fields plus one for each returned object,
+// the complexity of the selection of a list field is multiplied by the expected number of items.
+type Schema struct {
+	Query *Object
+	// MaxDepth is the maximum nesting of the fields in a query, zero means no limit
+	MaxDepth int
+	// MaxComplexity is the maximum complexity of a query, zero means no limit
+	MaxComplexity int
+	// DefaultListSize is the assumed number of items of a list field without the ListSizeArg argument
+	DefaultListSize int
+	// MaxCost is the maximum sum of the costs of the fields resolved during the execution of a query, zero means no limit.
+	// Unlike the complexity, it counts the fields of the actually returned list items.
+	MaxCost int
+}
+
+// String is the built-in String scalar, it serializes also fmt.Stringer, big numbers and time
+var String = &Scalar{
+	Name: "String",
+	Serialize: func(v interface{}) (interface{}, bool) {
+		switch t := v.(type) {
+		case string:
+			return t, true
+		case time.Time:
+			return t.Format(time.RFC3339Nano), true
+		case *big.Int:
+			return t.String(), true
+		case json.Number:
+			return string(t), true
+		case fmt.Stringer:
+			return t.String(), true
+		case bool:
+			return strconv.FormatBool(t), true
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.String:
+			return rv.String(), true
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(rv.Uint(), 10), true
+		}
+		return nil, false
+	},
+	ParseValue: func(v interface{}) (interface{}, bool) {
+		s, ok := v.(string)
+		return s, ok
+	},
+}
+
+// maxSafeInteger is the largest integer exactly representable by a JSON number in javascript
+const maxSafeInteger = 1<<53 - 1
+
+// Int is the built-in Int scalar. Unlike the specification, it is not limited to 32 bits
+// but to the range of integers which javascript clients can represent exactly.
+var Int = &Scalar{
+	Name: "Int",
+	Serialize: func(v interface{}) (interface{}, bool) {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i := rv.Int()
+			return i, i <= maxSafeInteger && i >= -maxSafeInteger
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u := rv.Uint()
+			return int64(u), u <= maxSafeInteger
+		}
+		return nil, false
+	},
+	ParseValue: func(v interface{}) (interface{}, bool) {
+		switch t := v.(type) {
+		case int64:
+			return t, t <= maxSafeInteger && t >= -maxSafeInteger
+		case int:
+			return int64(t), true
+		case float64:
+			if t != math.Trunc(t) || math.Abs(t) > maxSafeInteger {
+				return nil, false
+			}
+			return int64(t), true
+		case json.Number:
+			i, err := t.Int64()
+			return i, err == nil && i <= maxSafeInteger && i >= -maxSafeInteger
+		}
+		return nil, false
+	},
+}
+
+// Float is the built-in Float scalar
+var Float = &Scalar{
+	Name: "Float",
+	Serialize: func(v interface{}) (interface{}, bool) {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Float32:
+			// format via the shortest representation of float32 to avoid artifacts like 0.10000000149011612
+			f, _ := strconv.ParseFloat(strconv.FormatFloat(rv.Float(), 'g', -1, 32), 64)
+			return f, true
+		case reflect.Float64:
+			return rv.Float(), true
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), true
+		}
+		return nil, false
+	},
+	ParseValue: func(v interface{}) (interface{}, bool) {
+		switch t := v.(type) {
+		case float64:
+			return t, true
+		case int64:
+			return float64(t), true
+		case int:
+			return float64(t), true
+		case json.Number:
+			f, err := t.Float64()
+			return f, err == nil
+		}
+		return nil, false
+	},
+}
+
+// Boolean is the built-in Boolean scalar
+var Boolean = &Scalar{
+	Name: "Boolean",
+	Serialize: func(v interface{}) (interface{}, bool) {
+		b, ok := v.(bool)
+		return b, ok
+	},
+	ParseValue: func(v interface{}) (interface{}, bool) {
+		b, ok := v.(bool)
+		return b, ok
+	},
+}
+
+// jsonFieldIndexes caches the paths to the struct fields by their json names
+var jsonFieldIndexes sync.Map
+
+func jsonFields(t reflect.Type) map[string][]int {
+	if m, found := jsonFieldIndexes.Load(t); found {
+		return m.(map[string][]int)
+	}
+	m := make(map[string][]int)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			idx := append(append([]int(nil), index...), i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name := strings.Split(tag, ",")[0]
+			if sf.Anonymous && name == "" {
+				et := sf.Type
+				if et.Kind() == reflect.Ptr {
+					et = et.Elem()
+				}
+				if et.Kind() == reflect.Struct {
+					walk(et, idx)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			// fields of the outer struct shadow the fields of the embedded structs
+			if prev, found := m[name]; !found || len(prev) > len(idx) {
+				m[name] = idx
+			}
+		}
+	}
+	walk(t, nil)
+	jsonFieldIndexes.Store(t, m)
+	return m
+}
+
+// defaultResolve reads the field from a map or from a struct by the json name of the struct field
+func defaultResolve(source interface{}, name string) interface{} {
+	if m, ok := source.(map[string]interface{}); ok {
+		return m[name]
+	}
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	index, found := jsonFields(rv.Type())[name]
+	if !found {
+		return nil
+	}
+	for _, i := range index {
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return nil
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(i)
+	}
+	return rv.Interface()
+}
@@ -74,6 +74,10 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 	serveMux.HandleFunc(path, s.index)
 	serveMux.HandleFunc(path+"admin", s.htmlTemplateHandler(s.adminIndex))
 	serveMux.HandleFunc(path+"admin/ws-limit-exceeding-ips", s.htmlTemplateHandler(s.wsLimitExceedingIPs))
+	serveMux.HandleFunc(path+"admin/fee-estimates", s.htmlTemplateHandler(s.feeEstimates))
+	serveMux.HandleFunc(path+"admin/webhooks/", s.jsonHandler(s.apiWebhooks, 0))
+	serveMux.HandleFunc(path+"admin/webhook-deadletters/", s.jsonHandler(s.apiWebhookDeadLetters, 0))
+	serveMux.HandleFunc(path+"admin/reserves", s.jsonHandler(s.apiReserves, 0))
 	if s.chainParser.GetChainType() == bchain.ChainEthereumType {
 		serveMux.HandleFunc(path+"admin/internal-data-errors", s.htmlTemplateHandler(s.internalDataErrors))
 		serveMux.HandleFunc(path+"admin/contract-info", s.htmlTemplateHandler(s.contractInfoPage))
@@ -126,6 +130,7 @@ const (
 	adminInternalErrorsTpl
 	adminLimitExceedingIPSTpl
 	adminContractInfoTpl
+	adminFeeEstimatesTpl
 
 	internalTplCount
 )
@@ -147,6 +152,7 @@ type InternalTemplateData struct {
 	RefetchingInternalData bool
 	WsGetAccountInfoLimit  int
 	WsLimitExceedingIPs    []WsLimitExceedingIP
+	FeeEstimateAccuracy    []common.FeeEstimateAccuracy
 }
 
 func (s *InternalServer) newTemplateData(r *http.Request) *InternalTemplateData {
@@ -167,7 +173,8 @@ func (s *InternalServer) newTemplateDataWithError(error *api.APIError, r *http.R
 
 func (s *InternalServer) parseTemplates() []*template.Template {
 	templateFuncMap := template.FuncMap{
-		"formatUint32": formatUint32,
+		"formatUint32":  formatUint32,
+		"formatPercent": formatPercent,
 	}
 	createTemplate := func(filenames ...string) *template.Template {
 		if len(filenames) == 0 {
@@ -182,6 +189,7 @@ func (s *InternalServer) parseTemplates() []*template.Template {
 	t[adminInternalErrorsTpl] = createTemplate("./static/internal_templates/block_internal_data_errors.html", "./static/internal_templates/base.html")
 	t[adminLimitExceedingIPSTpl] = createTemplate("./static/internal_templates/ws_limit_exceeding_ips.html", "./static/internal_templates/base.html")
 	t[adminContractInfoTpl] = createTemplate("./static/internal_templates/contract_info.html", "./static/internal_templates/base.html")
+	t[adminFeeEstimatesTpl] = createTemplate("./static/internal_templates/fee_estimates.html", "./static/internal_templates/base.html")
 	return t
 }
 
@@ -224,6 +232,16 @@ func (s *InternalServer) wsLimitExceedingIPs(w http.ResponseWriter, r *http.Requ
 	return adminLimitExceedingIPSTpl, data, nil
 }
 
+func (s *InternalServer) feeEstimates(w http.ResponseWriter, r *http.Request) (tpl, *InternalTemplateData, error) {
+	data := s.newTemplateData(r)
+	data.FeeEstimateAccuracy = s.is.GetFeeEstimateAccuracy()
+	return adminFeeEstimatesTpl, data, nil
+}
+
+func formatPercent(f float64) string {
+	return strconv.FormatFloat(f*100, 'f', 1, 64) + "%"
+}
+
 func (s *InternalServer) contractInfoPage(w http.ResponseWriter, r *http.Request) (tpl, *InternalTemplateData, error) {
 	data := s.newTemplateData(r)
 	return adminContractInfoTpl, data, nil
@@ -272,3 +290,72 @@ func (s *InternalServer) updateContracts(r *http.Request) (interface{}, error) {
 	}
 	return "{\"success\":\"Updated " + strconv.Itoa(len(contractInfos)) + " contracts\"}", nil
 }
+
+const maxWebhookBodyBytes = 1 << 20
+
+// apiWebhooks creates (POST without id), updates (POST or PUT with id), removes (DELETE)
+// or returns (GET, all of them without id) the webhook registrations
+func (s *InternalServer) apiWebhooks(r *http.Request, apiVersion int) (interface{}, error) {
+	var id string
+	i := strings.LastIndex(r.URL.Path, "webhooks/")
+	if i > 0 {
+		id = r.URL.Path[i+9:]
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		var wh api.Webhook
+		d := json.NewDecoder(io.LimitReader(r.Body, maxWebhookBodyBytes))
+		if err := d.Decode(&wh); err != nil {
+			return nil, api.NewAPIError("Invalid webhook, "+err.Error(), true)
+		}
+		if len(id) == 0 {
+			if r.Method == http.MethodPut {
+				return nil, api.NewAPIError("Missing webhook id", true)
+			}
+			return s.api.CreateWebhook(&wh)
+		}
+		return s.api.UpdateWebhook(id, &wh)
+	case http.MethodDelete:
+		return s.api.DeleteWebhook(id)
+	}
+	if len(id) == 0 {
+		return s.api.GetWebhooks()
+	}
+	return s.api.GetWebhook(id)
+}
+
+// apiWebhookDeadLetters returns the undelivered events (GET, optionally of the webhook in the query),
+// removes a dead letter (DELETE) or delivers it again (POST)
+func (s *InternalServer) apiWebhookDeadLetters(r *http.Request, apiVersion int) (interface{}, error) {
+	var id string
+	i := strings.LastIndex(r.URL.Path, "webhook-deadletters/")
+	if i > 0 {
+		id = r.URL.Path[i+20:]
+	}
+	switch r.Method {
+	case http.MethodPost:
+		return s.api.RedeliverWebhookDeadLetter(id)
+	case http.MethodDelete:
+		return s.api.DeleteWebhookDeadLetter(id)
+	}
+	return s.api.GetWebhookDeadLetters(r.URL.Query().Get("webhook"))
+}
+
+const maxReservesBodyBytes int64 = 256 * 1024
+
+// apiReserves returns the proof of reserves of the addresses and xpubs posted in the request body,
+// the report walks the history of all the accounts and is therefore served only by the internal server
+func (s *InternalServer) apiReserves(r *http.Request, apiVersion int) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Reserves request must be sent using POST", true)
+	}
+	if r.ContentLength > maxReservesBodyBytes {
+		return nil, api.NewAPIError("Reserves request too large", true)
+	}
+	var req api.ReservesRequest
+	d := json.NewDecoder(io.LimitReader(r.Body, maxReservesBodyBytes))
+	if err := d.Decode(&req); err != nil {
+		return nil, api.NewAPIError("Invalid reserves request, "+err.Error(), true)
+	}
+	return s.api.GetReserves(&req)
+}
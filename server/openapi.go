@@ -0,0 +1,464 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/trezor/blockbook/api"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/server/graphql"
+	"github.com/trezor/blockbook/server/openapi"
+)
+
+// prefixes of the REST routes, the default prefix api/ returns the v1 format for bitcoin type coins and v2 for the others
+const (
+	routeV1 = 1 << iota
+	routeDefault
+	routeV2
+)
+
+// schemaOneOf is the result of a route or a method returning one of several types
+type schemaOneOf []interface{}
+
+// restRoute describes a route registered in ConnectFullPublicInterface
+type restRoute struct {
+	// path is relative to the version prefix, path parameters are in braces
+	path     string
+	versions int
+	method   string
+	id       string
+	summary  string
+	params   []*openapi.Parameter
+	body     interface{}
+	// textBody marks routes receiving the body as plain text
+	textBody bool
+	result   interface{}
+	// resultV1 is the result in the v1 format, if it differs
+	resultV1 interface{}
+	// csv marks routes returning CSV unless JSON format is requested
+	csv bool
+}
+
+// wsMethod describes a method of the websocket interface
+type wsMethod struct {
+	method       string
+	summary      string
+	params       interface{}
+	result       interface{}
+	notification interface{}
+	// sequenced notifications carry the sequence number of a resumable session
+	sequenced bool
+}
+
+// errorResponse is returned by the REST api with the http status 400 or 500
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func pathParam(name, description string) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "path", Required: true, Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func queryParam(name, typ, description string, enum ...string) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: typ, Enum: enum}}
+}
+
+var addressParams = []*openapi.Parameter{
+	queryParam("page", "integer", "Page of the returned transactions, starting from 1"),
+	queryParam("pageSize", "integer", "Number of transactions per page, at most 1000"),
+	queryParam("from", "integer", "Transactions from this block height"),
+	queryParam("to", "integer", "Transactions up to this block height"),
+	queryParam("details", "string", "Level of detail of the response", "basic", "tokens", "tokenBalances", "txids", "txslight", "txs"),
+	queryParam("tokens", "string", "Which tokens are returned", "derived", "used", "nonzero"),
+	queryParam("filter", "string", "Filter of the transactions: inputs, outputs or the index of the output"),
+	queryParam("contract", "string", "Return only transactions affecting this contract"),
+	queryParam("includeErc4626", "boolean", "Include ERC4626 data of detected vault tokens"),
+	queryParam("cursor", "string", "Opaque cursor (nextCursor of the previous response) of the requested page, used instead of page"),
+	queryParam("secondary", "string", "Currency code of the secondary value of the balances"),
+	queryParam("atHeight", "integer", "Return the balance snapshot at this block height instead of the address details"),
+}
+
+var gapParam = queryParam("gap", "integer", "Gap limit of the xpub derivation")
+
+var tickerParams = []*openapi.Parameter{
+	queryParam("currency", "string", "Return only the rate of this currency"),
+	queryParam("token", "string", "Return the rates of this token instead of the coin"),
+}
+
+var restRoutes = []restRoute{
+	{path: "", versions: routeDefault, method: http.MethodGet, id: "Index", summary: "Status of the blockbook and of the backend", result: api.SystemInfo{}},
+	{path: "block-index/{height}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "BlockIndex", summary: "Hash of the block at the height, of the best block if the height is not a number",
+		params: []*openapi.Parameter{pathParam("height", "Block height")}, result: resBlockIndex{}},
+	{path: "block-filters/", versions: routeDefault | routeV2, method: http.MethodGet, id: "BlockFilters", summary: "Golomb filters of a range of blocks",
+		params: []*openapi.Parameter{
+			queryParam("lastN", "integer", "Filters of the last N blocks"),
+			queryParam("from", "integer", "Filters from this block height"),
+			queryParam("to", "integer", "Filters up to this block height"),
+			queryParam("scriptType", "string", "Script type of the filters, must match the indexed type"),
+		}, result: resBlockFilters{}},
+	{path: "tx-specific/{txid}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "TxSpecific", summary: "Transaction in the format of the backend",
+		params: []*openapi.Parameter{pathParam("txid", "Transaction id")}, result: json.RawMessage{}},
+	{path: "tx/{txid}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "Tx", summary: "Transaction",
+		params: []*openapi.Parameter{pathParam("txid", "Transaction id"), queryParam("spending", "boolean", "Return the ids of the transactions spending the outputs")},
+		result: api.Tx{}, resultV1: api.TxV1{}},
+	{path: "rawtx/{txid}", versions: routeDefault, method: http.MethodGet, id: "RawTx", summary: "Hex encoded transaction",
+		params: []*openapi.Parameter{pathParam("txid", "Transaction id")}, result: ""},
+	{path: "address/{address}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "Address", summary: "Balances and transactions of an address",
+		params: append([]*openapi.Parameter{pathParam("address", "Address")}, addressParams...),
+		result: schemaOneOf{api.Address{}, api.BalanceAtHeight{}}, resultV1: schemaOneOf{api.AddressV1{}, api.BalanceAtHeight{}}},
+	{path: "xpub/{xpub}", versions: routeDefault | routeV2, method: http.MethodGet, id: "Xpub", summary: "Balances and transactions of an xpub or output descriptor",
+		params: append([]*openapi.Parameter{pathParam("xpub", "Xpub or output descriptor")}, append(addressParams, gapParam)...),
+		result: schemaOneOf{api.Address{}, api.BalanceAtHeight{}}, resultV1: schemaOneOf{api.AddressV1{}, api.BalanceAtHeight{}}},
+	{path: "xpub/{xpub}/select", versions: routeV2, method: http.MethodPost, id: "SelectCoins", summary: "Select the confirmed utxos of an xpub to fund the outputs at the fee rate, with change to the next unused change address",
+		params: []*openapi.Parameter{pathParam("xpub", "Xpub or output descriptor"), gapParam}, body: api.CoinSelectionReq{}, result: api.CoinSelection{}},
+	{path: "utxo/{descriptor}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "Utxo", summary: "Unspent outputs of an address or xpub",
+		params: []*openapi.Parameter{
+			pathParam("descriptor", "Address, xpub or output descriptor"),
+			queryParam("confirmed", "boolean", "Return only confirmed outputs"),
+			gapParam,
+			queryParam("lockToken", "string", "Token of the utxo locks, its locked outputs are not flagged as locked"),
+			queryParam("excludeLocked", "boolean", "Do not return the outputs locked by other tokens"),
+		}, result: api.Utxos{}, resultV1: []api.AddressUtxoV1{}},
+	{path: "utxolock/", versions: routeV2, method: http.MethodPost, id: "LockUtxos", summary: "Lock the outpoints under a newly issued lock token, none of them is locked if some are locked by another token",
+		body: api.UtxoLockReq{}, result: api.UtxoLocks{}},
+	{path: "utxolock/{token}", versions: routeV2, method: http.MethodPost, id: "ExtendUtxoLocks", summary: "Lock more outpoints under the token and extend its locks, none of them is locked if some are locked by another token",
+		params: []*openapi.Parameter{pathParam("token", "Lock token")}, body: api.UtxoLockReq{}, result: api.UtxoLocks{}},
+	{path: "utxolock/{token}", versions: routeV2, method: http.MethodGet, id: "UtxoLocks", summary: "Current utxo locks of the token",
+		params: []*openapi.Parameter{pathParam("token", "Lock token")}, result: api.UtxoLocks{}},
+	{path: "utxolock/{token}", versions: routeV2, method: http.MethodDelete, id: "UnlockUtxos", summary: "Release the utxo locks of the token",
+		params: []*openapi.Parameter{
+			pathParam("token", "Lock token"),
+			queryParam("outpoints", "string", "Comma separated outpoints to release, all locks of the token by default"),
+		}, result: api.UtxoLocks{}},
+	{path: "block/{block}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "Block", summary: "Block with a page of its transactions",
+		params: []*openapi.Parameter{pathParam("block", "Block height or hash"), queryParam("page", "integer", "Page of the returned transactions")},
+		result: api.Block{}, resultV1: api.BlockV1{}},
+	{path: "rawblock/{block}", versions: routeDefault | routeV2, method: http.MethodGet, id: "RawBlock", summary: "Hex encoded block",
+		params: []*openapi.Parameter{pathParam("block", "Block height or hash")}, result: api.BlockRaw{}},
+	{path: "sendtx/{hex}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "SendTx", summary: "Broadcast a hex encoded transaction",
+		params: []*openapi.Parameter{pathParam("hex", "Hex encoded transaction")}, result: resultSendTransaction{}},
+	{path: "sendtx/", versions: routeV1 | routeDefault | routeV2, method: http.MethodPost, id: "SendTxPost", summary: "Broadcast a hex encoded transaction sent in the body",
+		body: "", textBody: true, result: resultSendTransaction{}},
+	{path: "validatetx/", versions: routeV2, method: http.MethodPost, id: "ValidateTx", summary: "Check a hex encoded transaction sent in the body without broadcasting it",
+		body: "", textBody: true, result: api.TxValidation{}},
+	{path: "psbt/", versions: routeV2, method: http.MethodPost, id: "AnalyzePsbt", summary: "Analyze a base64 or hex encoded PSBT sent in the body and add the missing utxos of its inputs",
+		body: "", textBody: true, result: api.PsbtAnalysis{}},
+	{path: "estimatefee/{blocks}", versions: routeV1 | routeDefault | routeV2, method: http.MethodGet, id: "EstimateFee", summary: "Fee rate per kilobyte for the confirmation within the number of blocks",
+		params: []*openapi.Parameter{pathParam("blocks", "Number of blocks"), queryParam("conservative", "boolean", "Conservative estimation, true by default")},
+		result: resultEstimateFeeAsString{}},
+	{path: "feestats/{block}", versions: routeV2, method: http.MethodGet, id: "FeeStats", summary: "Statistics of the fees of the transactions in a block",
+		params: []*openapi.Parameter{pathParam("block", "Block height or hash")}, result: api.FeeStats{}},
+	{path: "mempool/histogram", versions: routeV2, method: http.MethodGet, id: "MempoolHistogram", summary: "Fee rate histogram and projected blocks of the mempool",
+		result: api.MempoolFeeHistogram{}},
+	{path: "mempool/package/{txid}", versions: routeV2, method: http.MethodGet, id: "MempoolPackage", summary: "Unconfirmed ancestors and descendants of a mempool transaction",
+		params: []*openapi.Parameter{pathParam("txid", "Transaction id")}, result: api.MempoolPackage{}},
+	{path: "broadcast/{txid}", versions: routeV2, method: http.MethodGet, id: "BroadcastTx", summary: "State of a transaction sent through Blockbook in the broadcast queue",
+		params: []*openapi.Parameter{pathParam("txid", "Transaction id")}, result: api.BroadcastTx{}},
+	{path: "balancehistory/{descriptor}", versions: routeDefault | routeV2, method: http.MethodGet, id: "BalanceHistory", summary: "History of the balance of an address or xpub",
+		params: []*openapi.Parameter{
+			pathParam("descriptor", "Address, xpub or output descriptor"),
+			queryParam("from", "integer", "Unix timestamp of the start of the history"),
+			queryParam("to", "integer", "Unix timestamp of the end of the history"),
+			queryParam("fiatcurrency", "string", "Return the fiat rate of this currency for each item"),
+			queryParam("groupBy", "integer", "Interval of the items in seconds, 3600 by default"),
+			gapParam,
+		}, result: []api.BalanceHistory{}},
+	{path: "portfolio/", versions: routeV2, method: http.MethodPost, id: "CreatePortfolio", summary: "Create a portfolio, the returned token identifies it",
+		body: api.Portfolio{}, result: api.Portfolio{}},
+	{path: "portfolio/{token}", versions: routeV2, method: http.MethodGet, id: "PortfolioBalance", summary: "Aggregated balance and merged history of the accounts of a portfolio",
+		params: []*openapi.Parameter{
+			pathParam("token", "Portfolio token"),
+			queryParam("page", "integer", "Page of the returned transactions"),
+			queryParam("pageSize", "integer", "Number of transactions per page"),
+			queryParam("details", "string", "Level of detail of the merged transaction history", "basic", "tokens", "tokenBalances", "txids", "txslight", "txs"),
+			queryParam("secondary", "string", "Currency code of the secondary value of the balances"),
+			gapParam,
+		}, result: api.PortfolioBalance{}},
+	{path: "portfolio/{token}", versions: routeV2, method: http.MethodPut, id: "UpdatePortfolio", summary: "Replace the accounts of a portfolio",
+		params: []*openapi.Parameter{pathParam("token", "Portfolio token")}, body: api.Portfolio{}, result: api.Portfolio{}},
+	{path: "portfolio/{token}", versions: routeV2, method: http.MethodDelete, id: "DeletePortfolio", summary: "Delete a portfolio",
+		params: []*openapi.Parameter{pathParam("token", "Portfolio token")}, result: api.Portfolio{}},
+	{path: "export/{descriptor}", versions: routeV2, method: http.MethodGet, id: "Export", summary: "Confirmed transaction history of an address or xpub for accounting",
+		params: []*openapi.Parameter{
+			pathParam("descriptor", "Address, xpub or output descriptor"),
+			queryParam("from", "integer", "Unix timestamp of the first exported transaction"),
+			queryParam("to", "integer", "Unix timestamp of the last exported transaction"),
+			queryParam("format", "string", "Format of the export, csv by default", "csv", "json"),
+			queryParam("currency", "string", "Fiat currency of the values"),
+			gapParam,
+		}, result: []api.ExportRow{}, csv: true},
+	{path: "costbasis/{descriptor}", versions: routeV2, method: http.MethodGet, id: "CostBasis", summary: "Realized and unrealized gains of an address or xpub",
+		params: []*openapi.Parameter{
+			pathParam("descriptor", "Address, xpub or output descriptor"),
+			queryParam("from", "integer", "Unix timestamp of the start of the report"),
+			queryParam("to", "integer", "Unix timestamp of the end of the report"),
+			queryParam("groupBy", "integer", "Interval of the periods of the report in seconds"),
+			queryParam("currency", "string", "Fiat currency of the report"),
+			queryParam("method", "string", "Cost basis method", "fifo", "lifo", "average"),
+			gapParam,
+		}, result: api.CostBasisReport{}},
+	{path: "graphql", versions: routeV2, method: http.MethodGet, id: "GraphQL", summary: "GraphQL query passed in the url",
+		params: []*openapi.Parameter{
+			queryParam("query", "string", "GraphQL query"),
+			queryParam("variables", "string", "JSON object with the variables of the query"),
+			queryParam("operationName", "string", "Name of the executed operation"),
+		}, result: graphql.Response{}},
+	{path: "graphql", versions: routeV2, method: http.MethodPost, id: "GraphQLPost", summary: "GraphQL query",
+		body: graphql.Request{}, result: graphql.Response{}},
+	{path: "tickers/", versions: routeV2, method: http.MethodGet, id: "Tickers", summary: "Fiat rates for a block, a timestamp or the latest rates",
+		params: append([]*openapi.Parameter{
+			queryParam("block", "string", "Block height or hash"),
+			queryParam("timestamp", "integer", "Unix timestamp"),
+		}, tickerParams...), result: api.FiatTicker{}},
+	{path: "multi-tickers/", versions: routeV2, method: http.MethodGet, id: "MultiTickers", summary: "Fiat rates for a list of timestamps",
+		params: append([]*openapi.Parameter{
+			queryParam("timestamp", "string", "Comma separated list of Unix timestamps"),
+		}, tickerParams...), result: []api.FiatTicker{}},
+	{path: "tickers-list/", versions: routeV2, method: http.MethodGet, id: "TickersList", summary: "Currencies available at a timestamp",
+		params: []*openapi.Parameter{
+			queryParam("timestamp", "integer", "Unix timestamp"),
+			queryParam("token", "string", "Return the currencies of this token"),
+		}, result: api.AvailableVsCurrencies{}},
+	{path: "openapi.json", versions: routeV2, method: http.MethodGet, id: "OpenAPI", summary: "This OpenAPI specification", result: map[string]interface{}{}},
+	{path: "asyncapi.json", versions: routeV2, method: http.MethodGet, id: "AsyncAPI", summary: "AsyncAPI specification of the websocket interface", result: map[string]interface{}{}},
+}
+
+var wsMethods = []wsMethod{
+	{method: "getAccountInfo", summary: "Balances and transactions of an address or xpub", params: WsAccountInfoReq{}, result: api.Address{}},
+	{method: "createPortfolio", summary: "Create a portfolio", params: WsPortfolioReq{}, result: api.Portfolio{}},
+	{method: "updatePortfolio", summary: "Replace the accounts of a portfolio", params: WsPortfolioReq{}, result: api.Portfolio{}},
+	{method: "deletePortfolio", summary: "Delete a portfolio", params: WsPortfolioReq{}, result: api.Portfolio{}},
+	{method: "getPortfolio", summary: "Aggregated balance of the accounts of a portfolio", params: WsPortfolioBalanceReq{}, result: api.PortfolioBalance{}},
+	{method: "getInfo", summary: "Status of the blockbook and of the backend", result: WsInfoRes{}},
+	{method: "getBlockHash", summary: "Hash of the block at a height", params: WsBlockHashReq{}, result: WsBlockHashRes{}},
+	{method: "getBlock", summary: "Block with a page of its transactions", params: WsBlockReq{}, result: api.Block{}},
+	{method: "getAccountUtxo", summary: "Unspent outputs of an address or xpub", params: WsAccountUtxoReq{}, result: api.Utxos{}},
+	{method: "selectCoins", summary: "Select the confirmed utxos of an xpub to fund the outputs at the fee rate", params: WsSelectCoinsReq{}, result: api.CoinSelection{}},
+	{method: "lockUtxos", summary: "Lock the outpoints under the token or under a newly issued token", params: WsUtxoLockReq{}, result: api.UtxoLocks{}},
+	{method: "unlockUtxos", summary: "Release the utxo locks of the token", params: WsUtxoLockReq{}, result: api.UtxoLocks{}},
+	{method: "getUtxoLocks", summary: "Current utxo locks of the token", params: WsUtxoLockReq{}, result: api.UtxoLocks{}},
+	{method: "getBalanceHistory", summary: "History of the balance of an address or xpub", params: WsBalanceHistoryReq{}, result: []api.BalanceHistory{}},
+	{method: "getTransaction", summary: "Transaction", params: WsTransactionReq{}, result: api.Tx{}},
+	{method: "getTransactionSpecific", summary: "Transaction in the format of the backend", params: WsTransactionSpecificReq{}, result: json.RawMessage{}},
+	{method: "estimateFee", summary: "Fee estimation for the confirmation within the numbers of blocks", params: WsEstimateFeeReq{}, result: []WsEstimateFeeRes{}},
+	{method: "longTermFeeRate", summary: "Long term fee rate", result: WsLongTermFeeRateRes{}},
+	{method: "sendTransaction", summary: "Broadcast a hex encoded transaction", params: WsSendTransactionReq{}, result: resultSendTransaction{}},
+	{method: "validateTransaction", summary: "Check a hex encoded transaction without broadcasting it", params: WsValidateTransactionReq{}, result: api.TxValidation{}},
+	{method: "analyzePsbt", summary: "Analyze a base64 or hex encoded PSBT and add the missing utxos of its inputs", params: WsAnalyzePsbtReq{}, result: api.PsbtAnalysis{}},
+	{method: "getMempoolFilters", summary: "Golomb filters of the mempool transactions", params: WsMempoolFiltersReq{}, result: resMempoolFilters{}},
+	{method: "getBlockFilter", summary: "Golomb filter of a block", params: WsBlockFilterReq{}, result: resBlockFilter{}},
+	{method: "getBlockFiltersBatch", summary: "Golomb filters of the blocks following the best known block", params: WsBlockFiltersBatchReq{}, result: resBlockFiltersBatch{}},
+	{method: "rpcCall", summary: "Call of a contract", params: WsRpcCallReq{}, result: WsRpcCallRes{}},
+	{method: "subscribeNewBlock", summary: "Subscribe to new blocks", result: subscriptionResponse{}, notification: wsNewBlockNotification{}},
+	{method: "unsubscribeNewBlock", summary: "Unsubscribe from new blocks", result: subscriptionResponse{}},
+	{method: "subscribeReorg", summary: "Subscribe to chain reorganizations", result: subscriptionResponse{}, notification: wsReorgNotification{}},
+	{method: "unsubscribeReorg", summary: "Unsubscribe from chain reorganizations", result: subscriptionResponse{}},
+	{method: "subscribeNewTransaction", summary: "Subscribe to new mempool transactions", result: schemaOneOf{subscriptionResponse{}, subscriptionResponseMessage{}}, notification: api.Tx{}},
+	{method: "unsubscribeNewTransaction", summary: "Unsubscribe from new mempool transactions", result: schemaOneOf{subscriptionResponse{}, subscriptionResponseMessage{}}},
+	{method: "subscribeAddresses", summary: "Subscribe to transactions of addresses", params: WsSubscribeAddressesReq{}, result: schemaOneOf{subscriptionResponse{}, subscriptionSessionResponse{}}, notification: schemaOneOf{wsAddressNotification{}, wsAddressRemovalNotification{}, wsAddressDoubleSpendNotification{}}, sequenced: true},
+	{method: "unsubscribeAddresses", summary: "Unsubscribe from transactions of addresses", result: subscriptionResponse{}},
+	{method: "resumeSession", summary: "Resume the address subscription of a session and replay the missed notifications", params: WsResumeSessionReq{}, result: resumeSessionResponse{}},
+	{method: "subscribeTxConfirmations", summary: "Subscribe to the confirmations of transactions", params: WsSubscribeTxConfirmationsReq{}, result: subscriptionResponse{}, notification: wsTxConfirmationsNotification{}},
+	{method: "unsubscribeTxConfirmations", summary: "Unsubscribe from the confirmations of transactions", result: subscriptionResponse{}},
+	{method: "subscribeContractEvents", summary: "Subscribe to the events of a contract", params: WsSubscribeContractEventsReq{}, result: subscriptionResponse{}, notification: wsContractEventNotification{}},
+	{method: "unsubscribeContractEvents", summary: "Unsubscribe from the events of a contract", result: subscriptionResponse{}},
+	{method: "subscribeFiatRates", summary: "Subscribe to new fiat rates", params: WsSubscribeFiatRatesReq{}, result: subscriptionResponse{}, notification: wsFiatRatesNotification{}},
+	{method: "unsubscribeFiatRates", summary: "Unsubscribe from new fiat rates", result: subscriptionResponse{}},
+	{method: "ping", summary: "Keep the connection alive", result: struct{}{}},
+	{method: "getCurrentFiatRates", summary: "Latest fiat rates", params: WsCurrentFiatRatesReq{}, result: api.FiatTicker{}},
+	{method: "getFiatRatesForTimestamps", summary: "Fiat rates for a list of timestamps", params: WsFiatRatesForTimestampsReq{}, result: api.FiatTickers{}},
+	{method: "getFiatRatesTickersList", summary: "Currencies available at a timestamp", params: WsFiatRatesTickersListReq{}, result: api.AvailableVsCurrencies{}},
+}
+
+func newSpecGenerator() *openapi.Generator {
+	g := openapi.NewGenerator()
+	g.Manage(api.Amount{}, &openapi.Schema{Type: "string", Description: "Amount in the base units of the coin or token"})
+	g.Manage(common.JSONNumber(""), &openapi.Schema{Type: "number"})
+	g.Name(graphql.Request{}, "GraphQLRequest")
+	g.Name(graphql.Response{}, "GraphQLResponse")
+	g.Name(graphql.Error{}, "GraphQLError")
+	g.Manage(graphql.OrderedMap{}, &openapi.Schema{Type: "object", Description: "Result of the query in the shape of the selection"})
+	return g
+}
+
+func specSchema(g *openapi.Generator, v interface{}) *openapi.Schema {
+	if o, ok := v.(schemaOneOf); ok {
+		s := &openapi.Schema{}
+		for _, i := range o {
+			s.OneOf = append(s.OneOf, g.Ref(i))
+		}
+		return s
+	}
+	return g.Ref(v)
+}
+
+func (r *restRoute) operation(g *openapi.Generator, prefix string, version int) *openapi.Operation {
+	suffix := map[int]string{routeV1: "V1", routeDefault: "", routeV2: "V2"}[version]
+	op := &openapi.Operation{
+		OperationID: strings.ToLower(r.method[:1]) + strings.ToLower(r.method[1:]) + r.id + suffix,
+		Summary:     r.summary,
+		Tags:        []string{prefix},
+		Parameters:  r.params,
+		Responses:   make(map[string]*openapi.Response),
+	}
+	result := specSchema(g, r.result)
+	if r.resultV1 != nil {
+		switch version {
+		case routeV1:
+			result = specSchema(g, r.resultV1)
+		case routeDefault:
+			op.Description = "The response has the v1 format for bitcoin type coins and the v2 format for the other coins."
+			result = &openapi.Schema{OneOf: []*openapi.Schema{result, specSchema(g, r.resultV1)}}
+		}
+	}
+	content := openapi.JSON(result)
+	if r.csv {
+		content["text/csv"] = &openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+	}
+	op.Responses["200"] = &openapi.Response{Description: "Success", Content: content}
+	op.Responses["400"] = &openapi.Response{Description: "Invalid request", Content: openapi.JSON(g.Ref(errorResponse{}))}
+	op.Responses["500"] = &openapi.Response{Description: "Internal error", Content: openapi.JSON(g.Ref(errorResponse{}))}
+	if r.body != nil {
+		if r.textBody {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}}
+		} else {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(g.Ref(r.body))}
+		}
+	}
+	return op
+}
+
+// OpenAPIDocument returns the OpenAPI 3 specification of the REST api
+func OpenAPIDocument(version string) *openapi.Document {
+	g := newSpecGenerator()
+	doc := &openapi.Document{
+		OpenAPI: "3.0.3",
+		Info: openapi.Info{
+			Title:       "Blockbook REST API",
+			Version:     version,
+			Description: "The api/v2 prefix is stable, the api/ prefix without version can change to a different version at any time.",
+		},
+		Paths: make(map[string]*openapi.PathItem),
+	}
+	prefixes := []struct {
+		version int
+		prefix  string
+	}{{routeV1, "api/v1/"}, {routeDefault, "api/"}, {routeV2, "api/v2/"}}
+	for i := range restRoutes {
+		r := &restRoutes[i]
+		for _, p := range prefixes {
+			if r.versions&p.version == 0 {
+				continue
+			}
+			path := "/" + p.prefix + r.path
+			item := doc.Paths[path]
+			if item == nil {
+				item = &openapi.PathItem{}
+				doc.Paths[path] = item
+			}
+			op := r.operation(g, strings.TrimSuffix(p.prefix, "/"), p.version)
+			switch r.method {
+			case http.MethodGet:
+				item.Get = op
+			case http.MethodPost:
+				item.Post = op
+			case http.MethodPut:
+				item.Put = op
+			case http.MethodDelete:
+				item.Delete = op
+			}
+		}
+	}
+	doc.Components.Schemas = g.Schemas
+	return doc
+}
+
+// AsyncAPIDocument returns the AsyncAPI 2 specification of the websocket interface.
+// Each request is a message with the method name, the response and the notifications
+// of a subscription are sent with the id of the request.
+func AsyncAPIDocument(version string) *openapi.AsyncDocument {
+	g := newSpecGenerator()
+	doc := &openapi.AsyncDocument{
+		AsyncAPI: "2.6.0",
+		Info: openapi.Info{
+			Title:   "Blockbook websocket API",
+			Version: version,
+		},
+		Channels: make(map[string]*openapi.Channel),
+	}
+	messages := make(map[string]*openapi.Message)
+	var requests, responses []*openapi.Message
+	ref := func(name string, m *openapi.Message) *openapi.Message {
+		messages[name] = m
+		return &openapi.Message{Ref: "#/components/messages/" + name}
+	}
+	id := &openapi.Schema{Type: "string", Description: "Identifier of the request"}
+	errorResult := g.Ref(resultError{})
+	for i := range wsMethods {
+		m := &wsMethods[i]
+		params := &openapi.Schema{Type: "object"}
+		if m.params != nil {
+			params = g.Ref(m.params)
+		}
+		requests = append(requests, ref(m.method, &openapi.Message{
+			Name:    m.method,
+			Summary: m.summary,
+			Payload: &openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"id":     id,
+					"method": {Type: "string", Enum: []string{m.method}},
+					"params": params,
+				},
+				Required: []string{"id", "method"},
+			},
+		}))
+		responses = append(responses, ref(m.method+"Result", &openapi.Message{
+			Name:    m.method + "Result",
+			Summary: "Result of " + m.method,
+			Payload: &openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"id":   id,
+					"data": {OneOf: []*openapi.Schema{specSchema(g, m.result), errorResult}},
+				},
+				Required: []string{"id", "data"},
+			},
+		}))
+		if m.notification != nil {
+			payload := &openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"id":   id,
+					"data": specSchema(g, m.notification),
+				},
+				Required: []string{"id", "data"},
+			}
+			if m.sequenced {
+				payload.Properties["seq"] = &openapi.Schema{Type: "integer", Format: "int64", Description: "Sequence number of the notification, present if the subscription has a session"}
+			}
+			responses = append(responses, ref(m.method+"Notification", &openapi.Message{
+				Name:    m.method + "Notification",
+				Summary: "Notification sent with the id of the " + m.method + " request",
+				Payload: payload,
+			}))
+		}
+	}
+	doc.Channels["/websocket"] = &openapi.Channel{
+		Description: "Websocket connection, the requests and responses are JSON messages",
+		Publish:     &openapi.ChannelOperation{OperationID: "request", Summary: "Requests sent by the client", Message: &openapi.Message{OneOf: requests}},
+		Subscribe:   &openapi.ChannelOperation{OperationID: "response", Summary: "Responses and notifications sent by the server", Message: &openapi.Message{OneOf: responses}},
+	}
+	doc.Components.Schemas = g.Schemas
+	doc.Components.Messages = messages
+	return doc
+}
+
+func (s *PublicServer) apiOpenAPI(r *http.Request, apiVersion int) (interface{}, error) {
+	return s.openAPI, nil
+}
+
+func (s *PublicServer) apiAsyncAPI(r *http.Request, apiVersion int) (interface{}, error) {
+	return s.asyncAPI, nil
+}
@@ -0,0 +1,115 @@
+package openapi
+
+// Document is the root of an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info describes the api
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is the location of the api, in AsyncAPI documents with the protocol
+type Server struct {
+	URL         string `json:"url"`
+	Protocol    string `json:"protocol,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of one path
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+// Operation is one http method of a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of an operation
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas referenced from the document
+type Components struct {
+	Schemas  map[string]*Schema  `json:"schemas"`
+	Messages map[string]*Message `json:"messages,omitempty"`
+}
+
+// AsyncDocument is the root of an AsyncAPI 2 document
+type AsyncDocument struct {
+	AsyncAPI   string              `json:"asyncapi"`
+	Info       Info                `json:"info"`
+	Servers    map[string]Server   `json:"servers,omitempty"`
+	Channels   map[string]*Channel `json:"channels"`
+	Components Components          `json:"components"`
+}
+
+// Channel describes the messages sent by the client (publish) and by the server (subscribe)
+type Channel struct {
+	Description string            `json:"description,omitempty"`
+	Publish     *ChannelOperation `json:"publish,omitempty"`
+	Subscribe   *ChannelOperation `json:"subscribe,omitempty"`
+}
+
+// ChannelOperation lists the messages of one direction of a channel
+type ChannelOperation struct {
+	OperationID string   `json:"operationId"`
+	Summary     string   `json:"summary,omitempty"`
+	Message     *Message `json:"message"`
+}
+
+// Message is a message of a channel or a reference to a message component
+type Message struct {
+	Ref         string     `json:"$ref,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Summary     string     `json:"summary,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Payload     *Schema    `json:"payload,omitempty"`
+	OneOf       []*Message `json:"oneOf,omitempty"`
+}
+
+// JSON returns a response or request body in JSON format with the schema
+func JSON(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
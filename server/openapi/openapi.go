@@ -0,0 +1,212 @@
+// Package openapi generates OpenAPI 3 and AsyncAPI 2 documents with JSON schemas derived from go types,
+// in the same way as the typescript definitions are generated from the api types by typescriptify.
+package openapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the JSON schema used by OpenAPI 3.0 and AsyncAPI 2
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+const componentsPrefix = "#/components/schemas/"
+
+// Generator converts go types to schemas, named structs are stored as components and referenced
+type Generator struct {
+	Schemas   map[string]*Schema
+	managed   map[reflect.Type]*Schema
+	names     map[reflect.Type]string
+	usedNames map[string]reflect.Type
+}
+
+// NewGenerator returns a generator with the formats of the common types used in the api
+func NewGenerator() *Generator {
+	g := &Generator{
+		Schemas:   make(map[string]*Schema),
+		managed:   make(map[reflect.Type]*Schema),
+		names:     make(map[reflect.Type]string),
+		usedNames: make(map[string]reflect.Type),
+	}
+	g.Manage(big.Int{}, &Schema{Type: "number", Description: "Arbitrary precision integer", Nullable: true})
+	g.Manage(time.Time{}, &Schema{Type: "string", Format: "date-time"})
+	g.Manage(json.RawMessage{}, &Schema{Description: "Any JSON value"})
+	return g
+}
+
+// Manage sets the schema of the type of the value, the schema is used instead of the reflection of the type
+func (g *Generator) Manage(v interface{}, s *Schema) {
+	g.managed[reflect.TypeOf(v)] = s
+}
+
+// Ref returns the schema of the value, a reference to a component for named structs
+func (g *Generator) Ref(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+// Name sets the name of the component of the type of the value
+func (g *Generator) Name(v interface{}, name string) {
+	t := reflect.TypeOf(v)
+	g.names[t] = name
+	g.usedNames[name] = t
+}
+
+func (g *Generator) componentName(t reflect.Type) string {
+	if n, found := g.names[t]; found {
+		return n
+	}
+	// unexported types are exported with capitalized names
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if prev, found := g.usedNames[name]; found && prev != t {
+		// types with the same name from different packages are prefixed by the package name
+		pkg := t.PkgPath()
+		if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+			pkg = pkg[i+1:]
+		}
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	g.usedNames[name] = t
+	return name
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	if s, found := g.managed[t]; found {
+		c := *s
+		return &c
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := &Schema{Type: "integer"}
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			s.Format = "int64"
+		} else if t.Kind() != reflect.Int && t.Kind() != reflect.Uint {
+			s.Format = "int32"
+		}
+		return s
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := g.componentName(t)
+		if _, found := g.Schemas[name]; !found {
+			// register the name before the reflection of the fields to allow recursive types
+			g.Schemas[name] = &Schema{}
+			*g.Schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: componentsPrefix + name}
+	}
+	return &Schema{}
+}
+
+// tsEnum matches the ts_type tags listing string literals, e.g. 'basic' | 'tokens'
+var tsEnum = regexp.MustCompile(`^\s*'[^']*'(\s*\|\s*'[^']*')*\s*$`)
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *Generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				g.addFields(s, et)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p := g.schema(f.Type)
+		if tsType := f.Tag.Get("ts_type"); tsEnum.MatchString(tsType) && p.Type == "string" {
+			for _, e := range strings.Split(tsType, "|") {
+				p.Enum = append(p.Enum, strings.Trim(strings.TrimSpace(e), "'"))
+			}
+		}
+		omitempty := false
+		for _, o := range parts[1:] {
+			if o == "omitempty" {
+				omitempty = true
+			}
+		}
+		// nil pointers, slices and maps without omitempty are marshalled as null,
+		// the managed types marshal themselves and their schema tells if they can be null
+		k := f.Type.Kind()
+		nullable := !omitempty && (k == reflect.Ptr || k == reflect.Slice || k == reflect.Map) && p.Format != "byte"
+		et := f.Type
+		if k == reflect.Ptr {
+			et = et.Elem()
+		}
+		if m, found := g.managed[et]; found {
+			nullable = !omitempty && m.Nullable
+		}
+		doc := f.Tag.Get("ts_doc")
+		if p.Ref != "" && (doc != "" || nullable) {
+			// siblings of $ref are ignored in OpenAPI 3.0, the reference is wrapped to keep the description
+			p = &Schema{OneOf: []*Schema{p}}
+		}
+		if doc != "" {
+			p.Description = doc
+		}
+		p.Nullable = nullable
+		if !omitempty && k != reflect.Interface {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = p
+	}
+}
@@ -0,0 +1,53 @@
+//go:build unittest
+
+package openapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+type testBase struct {
+	ID string `json:"id" ts_doc:"Identifier."`
+}
+
+type testChild struct {
+	Name string `json:"name"`
+}
+
+type testItem struct {
+	testBase
+	Kind     string            `json:"kind,omitempty" ts_type:"'a' | 'b'"`
+	Value    *big.Int          `json:"value"`
+	Child    *testChild        `json:"child" ts_doc:"Child item."`
+	Children []testChild       `json:"children,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Raw      []byte            `json:"raw"`
+	Ignored  int               `json:"-"`
+	private  int
+}
+
+func TestGenerator(t *testing.T) {
+	g := NewGenerator()
+	if ref := g.Ref(&testItem{}); ref.Ref != "#/components/schemas/TestItem" {
+		t.Fatalf("Ref() = %+v", ref)
+	}
+	b, err := json.Marshal(g.Schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"TestChild":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]},` +
+		`"TestItem":{"type":"object","properties":{` +
+		`"child":{"description":"Child item.","oneOf":[{"$ref":"#/components/schemas/TestChild"}],"nullable":true},` +
+		`"children":{"type":"array","items":{"$ref":"#/components/schemas/TestChild"}},` +
+		`"id":{"type":"string","description":"Identifier."},` +
+		`"kind":{"type":"string","enum":["a","b"]},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"},"nullable":true},` +
+		`"raw":{"type":"string","format":"byte"},` +
+		`"value":{"type":"number","description":"Arbitrary precision integer","nullable":true}},` +
+		`"required":["id","value","child","labels","raw"]}}`
+	if string(b) != want {
+		t.Errorf("Schemas = %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,158 @@
+//go:build unittest
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/trezor/blockbook/server/openapi"
+)
+
+// collectRefs returns all $ref values of the JSON document
+func collectRefs(v interface{}, refs map[string]struct{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, i := range t {
+			if s, ok := i.(string); ok && k == "$ref" {
+				refs[s] = struct{}{}
+			} else {
+				collectRefs(i, refs)
+			}
+		}
+	case []interface{}:
+		for _, i := range t {
+			collectRefs(i, refs)
+		}
+	}
+}
+
+// checkRefs checks that all references of the document point to existing components
+func checkRefs(t *testing.T, doc interface{}) {
+	t.Helper()
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d map[string]interface{}
+	if err = json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	refs := make(map[string]struct{})
+	collectRefs(d, refs)
+	if len(refs) == 0 {
+		t.Fatal("no references in the document")
+	}
+	for ref := range refs {
+		var v interface{} = d
+		for _, p := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				v = nil
+				break
+			}
+			v = m[p]
+		}
+		if v == nil {
+			t.Errorf("unresolved reference %v", ref)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := OpenAPIDocument("test")
+	checkRefs(t, doc)
+
+	// every api route registered in public.go must be described, the tests run in the root of the repository
+	src, err := os.ReadFile("server/public.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := regexp.MustCompile(`serveMux\.HandleFunc\(path\+"(api/[^"]*)"`).FindAllStringSubmatch(string(src), -1)
+	if len(routes) < 40 {
+		t.Fatalf("found only %d routes in public.go", len(routes))
+	}
+	for _, r := range routes {
+		pattern := "/" + r[1]
+		found := false
+		for p := range doc.Paths {
+			if p == pattern || (strings.HasSuffix(pattern, "/") && strings.HasPrefix(p, pattern) && (pattern != "/api/" || p == "/api/")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("route %v is not in the OpenAPI document", pattern)
+		}
+	}
+
+	// operation ids are used as method names by the client generators and must be unique
+	ids := make(map[string]string)
+	for p, item := range doc.Paths {
+		for _, op := range []*openapi.Operation{item.Get, item.Post, item.Put, item.Delete} {
+			if op == nil {
+				continue
+			}
+			if prev, found := ids[op.OperationID]; found {
+				t.Errorf("duplicate operationId %v in %v and %v", op.OperationID, prev, p)
+			}
+			ids[op.OperationID] = p
+		}
+	}
+}
+
+func TestAsyncAPIDocument(t *testing.T) {
+	doc := AsyncAPIDocument("test")
+	checkRefs(t, doc)
+	for method := range requestHandlers {
+		if _, found := doc.Components.Messages[method]; !found {
+			t.Errorf("websocket method %v is not in the AsyncAPI document", method)
+		}
+		if _, found := doc.Components.Messages[method+"Result"]; !found {
+			t.Errorf("result of websocket method %v is not in the AsyncAPI document", method)
+		}
+	}
+	for _, m := range wsMethods {
+		if _, found := requestHandlers[m.method]; !found {
+			t.Errorf("AsyncAPI document describes unknown websocket method %v", m.method)
+		}
+	}
+}
+
+func Test_HTTPOpenAPI_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		url  string
+		key  string
+		want string
+	}{
+		{url: "/api/v2/openapi.json", key: "openapi", want: "3.0.3"},
+		{url: "/api/v2/asyncapi.json", key: "asyncapi", want: "2.6.0"},
+	} {
+		resp, err := http.DefaultClient.Do(newGetRequest(ts.URL + tt.url))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&doc)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || doc[tt.key] != tt.want {
+			t.Errorf("%v: StatusCode = %v, %v = %v, want %v", tt.url, resp.StatusCode, tt.key, doc[tt.key], tt.want)
+		}
+	}
+}
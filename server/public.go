@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html"
@@ -26,6 +27,8 @@ import (
 	"github.com/trezor/blockbook/common"
 	"github.com/trezor/blockbook/db"
 	"github.com/trezor/blockbook/fiat"
+	"github.com/trezor/blockbook/server/graphql"
+	"github.com/trezor/blockbook/server/openapi"
 )
 
 const txsOnPage = 25
@@ -35,6 +38,10 @@ const txsInAPI = 1000
 const maxPageNumber = 1000000
 const maxGapValue = 10000
 const maxSendTxBodyBytes int64 = 8 * 1024 * 1024
+const maxPortfolioBodyBytes int64 = 256 * 1024
+const maxCoinSelectionBodyBytes int64 = 256 * 1024
+const xpubSelectCoinsSuffix = "/select"
+const maxUtxoLockBodyBytes int64 = 256 * 1024
 
 const secondaryCoinCookieName = "secondary_coin"
 const templatesDir = "./static/templates"
@@ -75,6 +82,10 @@ type PublicServer struct {
 	fiatRates           *fiat.FiatRates
 	useSatsAmountFormat bool
 	isFullInterface     bool
+	graphQLSchema       *graphql.Schema
+	utxoLocker          *api.UtxoLocker
+	openAPI             *openapi.Document
+	asyncAPI            *openapi.AsyncDocument
 }
 
 // NewPublicServer creates new public server http interface to blockbook and returns its handle
@@ -125,6 +136,10 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 		fiatRates:           fiatRates,
 		useSatsAmountFormat: chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType && chain.GetChainParser().AmountDecimals() == 8,
 	}
+	s.graphQLSchema = newGraphQLSchema(api)
+	version := common.GetVersionInfo().Version
+	s.openAPI = OpenAPIDocument(version)
+	s.asyncAPI = AsyncAPIDocument(version)
 	s.htmlTemplates.newTemplateData = s.newTemplateData
 	s.htmlTemplates.newTemplateDataWithError = s.newTemplateDataWithError
 	s.htmlTemplates.parseTemplates = s.parseTemplates
@@ -156,6 +171,12 @@ func (s *PublicServer) Run() error {
 func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux := s.https.Handler.(*http.ServeMux)
 	_, path := splitBinding(s.binding)
+	if s.chainParser.GetChainType() == bchain.ChainBitcoinType {
+		// the utxo locks are shared by the REST and the websocket interfaces
+		s.utxoLocker = api.NewUtxoLocker()
+		s.api.SetUtxoLocker(s.utxoLocker)
+		s.websocket.api.SetUtxoLocker(s.utxoLocker)
+	}
 	// support for test pages
 	serveMux.Handle(path+"test-socketio.html", http.FileServer(http.Dir("./static/")))
 	serveMux.Handle(path+"test-websocket.html", http.FileServer(http.Dir("./static/")))
@@ -222,9 +243,21 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/v2/block/", s.jsonHandler(s.apiBlock, apiV2))
 	serveMux.HandleFunc(path+"api/v2/rawblock/", s.jsonHandler(s.apiBlockRaw, apiDefault))
 	serveMux.HandleFunc(path+"api/v2/sendtx/", s.jsonHandler(s.apiSendTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/broadcast/", s.jsonHandler(s.apiBroadcastTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/validatetx/", s.jsonHandler(s.apiValidateTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/psbt/", s.jsonHandler(s.apiAnalyzePsbt, apiV2))
 	serveMux.HandleFunc(path+"api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
 	serveMux.HandleFunc(path+"api/v2/feestats/", s.jsonHandler(s.apiFeeStats, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/histogram", s.jsonHandler(s.apiMempoolHistogram, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/package/", s.jsonHandler(s.apiMempoolPackage, apiV2))
 	serveMux.HandleFunc(path+"api/v2/balancehistory/", s.jsonHandler(s.apiBalanceHistory, apiDefault))
+	serveMux.HandleFunc(path+"api/v2/portfolio/", s.jsonHandler(s.apiPortfolio, apiV2))
+	serveMux.HandleFunc(path+"api/v2/utxolock/", s.jsonHandler(s.apiUtxoLock, apiV2))
+	serveMux.HandleFunc(path+"api/v2/export/", s.apiExport)
+	serveMux.HandleFunc(path+"api/v2/costbasis/", s.jsonHandler(s.apiCostBasis, apiV2))
+	serveMux.HandleFunc(path+"api/v2/graphql", s.jsonHandler(s.apiGraphQL, apiV2))
+	serveMux.HandleFunc(path+"api/v2/openapi.json", s.jsonHandler(s.apiOpenAPI, apiV2))
+	serveMux.HandleFunc(path+"api/v2/asyncapi.json", s.jsonHandler(s.apiAsyncAPI, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tickers/", s.jsonHandler(s.apiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/multi-tickers/", s.jsonHandler(s.apiMultiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tickers-list/", s.jsonHandler(s.apiAvailableVsCurrencies, apiV2))
@@ -256,6 +289,10 @@ func (s *PublicServer) Shutdown(ctx context.Context) error {
 func (s *PublicServer) OnNewBlock(block *bchain.Block) {
 	s.socketio.OnNewBlockHash(block.Hash)
 	s.websocket.OnNewBlock(block)
+	if s.utxoLocker != nil {
+		// the utxo locks are released when the locked outputs are spent
+		s.utxoLocker.OnNewBlock(block)
+	}
 }
 
 // OnNewFiatRatesTicker notifies users subscribed to bitcoind/fiatrates about new ticker
@@ -271,6 +308,25 @@ func (s *PublicServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor)
 // OnNewTx notifies users subscribed to notification about new tx
 func (s *PublicServer) OnNewTx(tx *bchain.MempoolTx) {
 	s.websocket.OnNewTx(tx)
+	if s.utxoLocker != nil {
+		s.utxoLocker.OnNewTx(tx)
+	}
+}
+
+// OnTxRemoved notifies users subscribed to notification about replaced and evicted mempool transactions
+func (s *PublicServer) OnTxRemoved(removal *bchain.MempoolTxRemoval) {
+	s.websocket.OnTxRemoved(removal)
+}
+
+// OnDoubleSpend notifies users subscribed to notification about double spends in the mempool
+func (s *PublicServer) OnDoubleSpend(doubleSpend *bchain.MempoolDoubleSpend) {
+	s.websocket.OnDoubleSpend(doubleSpend)
+}
+
+// OnReorg notifies users subscribed to notification about the transactions disconnected by a chain reorganization
+func (s *PublicServer) OnReorg(reorg *bchain.Reorg) {
+	s.socketio.OnReorg(reorg)
+	s.websocket.OnReorg(reorg)
 }
 
 func (s *PublicServer) txRedirect(w http.ResponseWriter, r *http.Request) {
@@ -948,6 +1004,7 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 		ToHeight:       uint32(to),
 		Contract:       contract,
 		IncludeErc4626: includeErc4626,
+		Cursor:         r.URL.Query().Get("cursor"),
 	}, filterParam, gap
 }
 
@@ -1147,7 +1204,7 @@ func (s *PublicServer) explorerSendTx(w http.ResponseWriter, r *http.Request) (t
 		}
 		hex := r.FormValue("hex")
 		if len(hex) > 0 {
-			res, err := s.chain.SendRawTransaction(hex, false)
+			res, err := s.api.SendRawTransaction(hex, false)
 			if err != nil {
 				data.SendTxHex = hex
 				data.Error = &api.APIError{Text: err.Error(), Public: true}
@@ -1241,10 +1298,11 @@ func (s *PublicServer) apiIndex(r *http.Request, apiVersion int) (interface{}, e
 	return s.api.GetSystemInfo(false)
 }
 
+type resBlockIndex struct {
+	BlockHash string `json:"blockHash"`
+}
+
 func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface{}, error) {
-	type resBlockIndex struct {
-		BlockHash string `json:"blockHash"`
-	}
 	var err error
 	var hash string
 	height := -1
@@ -1267,19 +1325,19 @@ func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface
 	}, nil
 }
 
-func (s *PublicServer) apiBlockFilters(r *http.Request, apiVersion int) (interface{}, error) {
-	// Define return type
-	type blockFilterResult struct {
-		BlockHash string `json:"blockHash"`
-		Filter    string `json:"filter"`
-	}
-	type resBlockFilters struct {
-		ParamP       uint8                     `json:"P"`
-		ParamM       uint64                    `json:"M"`
-		ZeroedKey    bool                      `json:"zeroedKey"`
-		BlockFilters map[int]blockFilterResult `json:"blockFilters"`
-	}
+type blockFilterResult struct {
+	BlockHash string `json:"blockHash"`
+	Filter    string `json:"filter"`
+}
+
+type resBlockFilters struct {
+	ParamP       uint8                     `json:"P"`
+	ParamM       uint64                    `json:"M"`
+	ZeroedKey    bool                      `json:"zeroedKey"`
+	BlockFilters map[int]blockFilterResult `json:"blockFilters"`
+}
 
+func (s *PublicServer) apiBlockFilters(r *http.Request, apiVersion int) (interface{}, error) {
 	lastN := validateIntParam(r.URL.Query().Get("lastN"), 0, 0, 10000)
 	from := validateIntParam(r.URL.Query().Get("from"), 0, 0, 10000000000)
 	to := validateIntParam(r.URL.Query().Get("to"), 0, 0, 10000000000)
@@ -1418,6 +1476,12 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address"}).Inc()
+	if atHeight, found, err := parseAtHeightParam(r); found || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return s.api.GetAddressAtHeight(addressParam, atHeight)
+	}
 	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
 	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
 	address, err = s.api.GetAddress(addressParam, page, pageSize, details, filter, secondaryCoin)
@@ -1427,6 +1491,19 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 	return address, err
 }
 
+// parseAtHeightParam returns the block height of the requested balance snapshot, if the parameter atHeight is present
+func parseAtHeightParam(r *http.Request) (uint32, bool, error) {
+	v := r.URL.Query().Get("atHeight")
+	if v == "" {
+		return 0, false, nil
+	}
+	h, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, true, api.NewAPIError("Parameter 'atHeight' is not a valid block height", true)
+	}
+	return uint32(h), true, nil
+}
+
 func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, error) {
 	var xpub string
 	i := strings.LastIndex(r.URL.Path, "xpub/")
@@ -1436,10 +1513,23 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 	if len(xpub) == 0 {
 		return nil, api.NewAPIError("Missing xpub", true)
 	}
+	if apiVersion == apiV2 && strings.HasSuffix(xpub, xpubSelectCoinsSuffix) {
+		return s.apiXpubSelectCoins(r, strings.TrimSuffix(xpub, xpubSelectCoinsSuffix))
+	}
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub"}).Inc()
 	page, pageSize, details, filter, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	if atHeight, found, err := parseAtHeightParam(r); found || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		balance, err := s.api.GetXpubAtHeight(xpub, atHeight, gap)
+		if err == api.ErrUnsupportedXpub {
+			err = api.NewAPIError("XPUB functionality is not supported", true)
+		}
+		return balance, err
+	}
 	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
 	address, err = s.api.GetXpubAddress(xpub, page, pageSize, details, filter, gap, secondaryCoin)
 	if err == nil && apiVersion == apiV1 {
@@ -1451,6 +1541,105 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 	return address, err
 }
 
+// apiXpubSelectCoins selects the utxos of the xpub for the outputs and the fee rate sent in the body of the POST request
+func (s *PublicServer) apiXpubSelectCoins(r *http.Request, xpub string) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-select"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Only POST method is supported", true)
+	}
+	var req api.CoinSelectionReq
+	d := json.NewDecoder(io.LimitReader(r.Body, maxCoinSelectionBodyBytes))
+	if err := d.Decode(&req); err != nil {
+		return nil, api.NewAPIError("Invalid coin selection request, "+err.Error(), true)
+	}
+	gap := validateIntParam(r.URL.Query().Get("gap"), 0, 0, maxGapValue)
+	cs, err := s.api.SelectCoins(xpub, &req, gap, getIP(r))
+	if err == api.ErrUnsupportedXpub {
+		err = api.NewAPIError("XPUB functionality is not supported", true)
+	}
+	return cs, err
+}
+
+func readPortfolioFromBody(body io.Reader) (*api.Portfolio, error) {
+	var p api.Portfolio
+	d := json.NewDecoder(io.LimitReader(body, maxPortfolioBodyBytes))
+	if err := d.Decode(&p); err != nil {
+		return nil, api.NewAPIError("Invalid portfolio, "+err.Error(), true)
+	}
+	return &p, nil
+}
+
+// apiPortfolio creates (POST without token), updates (PUT or POST with token), deletes (DELETE)
+// or returns the aggregated balance (GET) of a portfolio
+func (s *PublicServer) apiPortfolio(r *http.Request, apiVersion int) (interface{}, error) {
+	var token string
+	i := strings.LastIndex(r.URL.Path, "portfolio/")
+	if i > 0 {
+		token = r.URL.Path[i+10:]
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		if r.ContentLength > maxPortfolioBodyBytes {
+			return nil, api.NewAPIError("Portfolio too large", true)
+		}
+		p, err := readPortfolioFromBody(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(token) == 0 {
+			if r.Method == http.MethodPut {
+				return nil, api.NewAPIError("Missing portfolio token", true)
+			}
+			s.metrics.ExplorerViews.With(common.Labels{"action": "api-portfolio-create"}).Inc()
+			return s.api.CreatePortfolio(p)
+		}
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-portfolio-update"}).Inc()
+		return s.api.UpdatePortfolio(token, p)
+	case http.MethodDelete:
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-portfolio-delete"}).Inc()
+		return s.api.DeletePortfolio(token)
+	}
+	if len(token) == 0 {
+		return nil, api.NewAPIError("Missing portfolio token", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-portfolio"}).Inc()
+	page, pageSize, details, _, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
+	return s.api.GetPortfolioBalance(token, page, pageSize, details, secondaryCoin, gap)
+}
+
+// apiUtxoLock locks the outpoints under a newly issued token (POST without token) or under the token (POST),
+// releases the locks of the token (DELETE) or returns the current locks of the token (GET)
+func (s *PublicServer) apiUtxoLock(r *http.Request, apiVersion int) (interface{}, error) {
+	var token string
+	i := strings.LastIndex(r.URL.Path, "utxolock/")
+	if i > 0 {
+		token = r.URL.Path[i+9:]
+	}
+	switch r.Method {
+	case http.MethodPost:
+		if r.ContentLength > maxUtxoLockBodyBytes {
+			return nil, api.NewAPIError("UTXO lock request too large", true)
+		}
+		var req api.UtxoLockReq
+		d := json.NewDecoder(io.LimitReader(r.Body, maxUtxoLockBodyBytes))
+		if err := d.Decode(&req); err != nil {
+			return nil, api.NewAPIError("Invalid UTXO lock request, "+err.Error(), true)
+		}
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-utxolock-lock"}).Inc()
+		return s.api.LockUtxos(token, req.Outpoints, req.TTL, getIP(r))
+	case http.MethodDelete:
+		var outpoints []string
+		if o := r.URL.Query().Get("outpoints"); len(o) > 0 {
+			outpoints = strings.Split(o, ",")
+		}
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-utxolock-unlock"}).Inc()
+		return s.api.UnlockUtxos(token, outpoints)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-utxolock"}).Inc()
+	return s.api.GetUtxoLocks(token)
+}
+
 func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, error) {
 	var utxo []api.Utxo
 	var err error
@@ -1465,11 +1654,18 @@ func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, er
 			}
 		}
 		gap := validateIntParam(r.URL.Query().Get("gap"), 0, 0, maxGapValue)
-		utxo, err = s.api.GetXpubUtxo(desc, onlyConfirmed, gap)
+		lf := api.UtxoLockFilter{Token: r.URL.Query().Get("lockToken")}
+		if e := r.URL.Query().Get("excludeLocked"); len(e) > 0 {
+			lf.ExcludeLocked, err = strconv.ParseBool(e)
+			if err != nil {
+				return nil, api.NewAPIError("Parameter 'excludeLocked' cannot be converted to boolean", true)
+			}
+		}
+		utxo, err = s.api.GetXpubUtxo(desc, onlyConfirmed, gap, lf)
 		if err == nil {
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-utxo"}).Inc()
 		} else {
-			utxo, err = s.api.GetAddressUtxo(desc, onlyConfirmed)
+			utxo, err = s.api.GetAddressUtxo(desc, onlyConfirmed, lf)
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-utxo"}).Inc()
 		}
 		if err == nil && apiVersion == apiV1 {
@@ -1520,6 +1716,159 @@ func (s *PublicServer) apiBalanceHistory(r *http.Request, apiVersion int) (inter
 	return history, err
 }
 
+func (s *PublicServer) apiCostBasis(r *http.Request, apiVersion int) (interface{}, error) {
+	var descriptor string
+	if i := strings.LastIndex(r.URL.Path, "costbasis/"); i > 0 {
+		descriptor = r.URL.Path[i+10:]
+	}
+	if len(descriptor) == 0 {
+		return nil, api.NewAPIError("Missing address or xpub", true)
+	}
+	fromTimestamp, err := parseTimestampParam(r, "from")
+	if err != nil {
+		return nil, err
+	}
+	toTimestamp, err := parseTimestampParam(r, "to")
+	if err != nil {
+		return nil, err
+	}
+	groupBy, err := strconv.ParseUint(r.URL.Query().Get("groupBy"), 10, 32)
+	if err != nil {
+		groupBy = 0
+	}
+	gap := validateIntParam(r.URL.Query().Get("gap"), 0, 0, maxGapValue)
+	report, err := s.api.GetCostBasis(descriptor, fromTimestamp, toTimestamp, r.URL.Query().Get("currency"), r.URL.Query().Get("method"), uint32(groupBy), gap)
+	if err == nil {
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-costbasis"}).Inc()
+	}
+	return report, err
+}
+
+func parseTimestampParam(r *http.Request, name string) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	t, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || t < 0 {
+		return 0, api.NewAPIError("Parameter '"+name+"' is not a valid Unix timestamp", true)
+	}
+	return t, nil
+}
+
+// apiExport streams the whole confirmed transaction history of an address or xpub as CSV or JSON.
+// It does not use jsonHandler, the rows are written to the response as they are produced.
+func (s *PublicServer) apiExport(w http.ResponseWriter, r *http.Request) {
+	var descriptor string
+	if i := strings.LastIndex(r.URL.Path, "export/"); i > 0 {
+		descriptor = r.URL.Path[i+7:]
+	}
+	writeError := func(err error) {
+		status := http.StatusInternalServerError
+		text := "Internal server error"
+		if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+			status = http.StatusBadRequest
+			text = apiErr.Error()
+		} else {
+			glog.Error("apiExport ", descriptor, " error: ", err)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(struct {
+			Text string `json:"error"`
+		}{text})
+	}
+	if len(descriptor) == 0 {
+		writeError(api.NewAPIError("Missing address or xpub", true))
+		return
+	}
+	fromTimestamp, err := parseTimestampParam(r, "from")
+	if err != nil {
+		writeError(err)
+		return
+	}
+	toTimestamp, err := parseTimestampParam(r, "to")
+	if err != nil {
+		writeError(err)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "csv"
+	} else if format != "csv" && format != "json" {
+		writeError(api.NewAPIError("Unsupported format "+format, true))
+		return
+	}
+	currency := strings.ToLower(r.URL.Query().Get("currency"))
+	gap := validateIntParam(r.URL.Query().Get("gap"), 0, 0, maxGapValue)
+	decimals := s.chainParser.AmountDecimals()
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-export"}).Inc()
+
+	var (
+		started bool
+		rows    int
+		csvw    *csv.Writer
+		enc     *json.Encoder
+	)
+	// the headers are sent with the first row so that parameter errors can still be reported as JSON
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Security-Policy", getContentSecurityPolicy())
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="export.csv"`)
+			csvw = csv.NewWriter(w)
+			return csvw.Write(api.ExportCSVHeader)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="export.json"`)
+		enc = json.NewEncoder(w)
+		_, err := io.WriteString(w, "[")
+		return err
+	}
+	emit := func(row *api.ExportRow) error {
+		// stop walking the history if the client went away
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		rows++
+		if csvw != nil {
+			return csvw.Write(row.CSVRecord(decimals))
+		}
+		if rows > 1 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		return enc.Encode(row)
+	}
+	err = s.api.ExportTransactions(descriptor, fromTimestamp, toTimestamp, currency, gap, emit)
+	if err != nil {
+		if !started {
+			writeError(err)
+			return
+		}
+		// the response is partially sent, leave it unterminated so that the client detects the failure
+		glog.Error("apiExport ", descriptor, " failed after ", rows, " rows: ", err)
+		return
+	}
+	if !started {
+		if err = start(); err != nil {
+			return
+		}
+	}
+	if csvw != nil {
+		csvw.Flush()
+	} else {
+		io.WriteString(w, "]\n")
+	}
+}
+
 func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, error) {
 	var block *api.Block
 	var err error
@@ -1554,6 +1903,31 @@ func (s *PublicServer) apiFeeStats(r *http.Request, apiVersion int) (interface{}
 	return feeStats, err
 }
 
+func (s *PublicServer) apiMempoolHistogram(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-histogram"}).Inc()
+	return s.api.GetMempoolFeeHistogram()
+}
+
+func (s *PublicServer) apiMempoolPackage(r *http.Request, apiVersion int) (interface{}, error) {
+	var mempoolPackage *api.MempoolPackage
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-package"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		mempoolPackage, err = s.api.GetMempoolPackage(r.URL.Path[i+1:])
+	}
+	return mempoolPackage, err
+}
+
+func (s *PublicServer) apiBroadcastTx(r *http.Request, apiVersion int) (interface{}, error) {
+	var broadcastTx *api.BroadcastTx
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-broadcast"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		broadcastTx, err = s.api.GetBroadcastTx(r.URL.Path[i+1:])
+	}
+	return broadcastTx, err
+}
+
 type resultSendTransaction struct {
 	Result string `json:"result"`
 }
@@ -1589,7 +1963,7 @@ func (s *PublicServer) apiSendTx(r *http.Request, apiVersion int) (interface{},
 		}
 	}
 	if len(hex) > 0 {
-		res.Result, err = s.chain.SendRawTransaction(hex, false)
+		res.Result, err = s.api.SendRawTransaction(hex, false)
 		if err != nil {
 			return nil, api.NewAPIError(err.Error(), true)
 		}
@@ -1598,6 +1972,45 @@ func (s *PublicServer) apiSendTx(r *http.Request, apiVersion int) (interface{},
 	return nil, api.NewAPIError("Missing tx blob", true)
 }
 
+// apiValidateTx checks the transaction sent in the body of the POST request without broadcasting it
+func (s *PublicServer) apiValidateTx(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-validatetx"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Only POST method is supported", true)
+	}
+	if r.ContentLength > maxSendTxBodyBytes {
+		return nil, api.NewAPIError("Tx blob too large", true)
+	}
+	hex, err := readSendTxHexFromBody(r.Body, maxSendTxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(hex) == 0 {
+		return nil, api.NewAPIError("Missing tx blob", true)
+	}
+	return s.api.ValidateTransaction(hex)
+}
+
+// apiAnalyzePsbt analyzes the base64 or hex encoded PSBT sent in the body of the POST request
+func (s *PublicServer) apiAnalyzePsbt(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-psbt"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Only POST method is supported", true)
+	}
+	if r.ContentLength > maxSendTxBodyBytes {
+		return nil, api.NewAPIError("PSBT too large", true)
+	}
+	var psbt strings.Builder
+	n, err := io.Copy(&psbt, io.LimitReader(r.Body, maxSendTxBodyBytes+1))
+	if err != nil || n == 0 {
+		return nil, api.NewAPIError("Missing PSBT", true)
+	}
+	if n > maxSendTxBodyBytes {
+		return nil, api.NewAPIError("PSBT too large", true)
+	}
+	return s.api.AnalyzePsbt(psbt.String())
+}
+
 // apiAvailableVsCurrencies returns a list of available versus currencies
 func (s *PublicServer) apiAvailableVsCurrencies(r *http.Request, apiVersion int) (interface{}, error) {
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tickers-list"}).Inc()
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -23,6 +24,7 @@ import (
 	"github.com/martinboehm/btcutil/chaincfg"
 	gosocketio "github.com/martinboehm/golang-socketio"
 	"github.com/martinboehm/golang-socketio/transport"
+	"github.com/trezor/blockbook/api"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/bchain/coins/btc"
 	"github.com/trezor/blockbook/common"
@@ -606,6 +608,33 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"txCount":3,"totalFeesSat":"1284","averageFeePerKb":1398,"decilesFeePerKb":[155,155,155,155,1679,1679,1679,2361,2361,2361,2361]}`,
 			},
 		},
+		{
+			name:        "apiMempoolHistogram before mempool resync",
+			r:           newGetRequest(ts.URL + "/api/v2/mempool/histogram"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Mempool fee histogram is not available yet"}`,
+			},
+		},
+		{
+			name:        "apiMempoolPackage unknown tx",
+			r:           newGetRequest(ts.URL + "/api/v2/mempool/package/" + dbtestdata.TxidB1T1),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Transaction '` + dbtestdata.TxidB1T1 + `' not found in mempool"}`,
+			},
+		},
+		{
+			name:        "apiBroadcastTx unknown tx",
+			r:           newGetRequest(ts.URL + "/api/v2/broadcast/" + dbtestdata.TxidB1T1),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Transaction '` + dbtestdata.TxidB1T1 + `' not found in the broadcast queue"}`,
+			},
+		},
 		{
 			name:        "apiFiatRates all currencies",
 			r:           newGetRequest(ts.URL + "/api/v2/tickers"),
@@ -1038,6 +1067,105 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Tx blob too large"}`,
 			},
 		},
+		{
+			name:        "apiValidateTx GET",
+			r:           newGetRequest(ts.URL + "/api/v2/validatetx/123456"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Only POST method is supported"}`,
+			},
+		},
+		{
+			name:        "apiValidateTx POST invalid hex",
+			r:           newPostRequest(ts.URL+"/api/v2/validatetx/", "zz"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"valid":false,"rejections":[{"code":"invalid-tx","message":"encoding/hex: invalid byte: U+007A 'z'"}]}`,
+			},
+		},
+		{
+			name:        "apiAnalyzePsbt GET",
+			r:           newGetRequest(ts.URL + "/api/v2/psbt/"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Only POST method is supported"}`,
+			},
+		},
+		{
+			name:        "apiAnalyzePsbt POST invalid base64",
+			r:           newPostRequest(ts.URL+"/api/v2/psbt/", "zz"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid PSBT: illegal base64 data at input byte 0"}`,
+			},
+		},
+		{
+			name:        "apiXpubSelectCoins GET",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "/select"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Only POST method is supported"}`,
+			},
+		},
+		{
+			name:        "apiXpubSelectCoins POST without outputs",
+			r:           newPostRequest(ts.URL+"/api/v2/xpub/"+dbtestdata.Xpub+"/select", `{"outputs":[],"feePerKb":1000}`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing outputs"}`,
+			},
+		},
+		{
+			name:        "apiUtxoLock GET without token",
+			r:           newGetRequest(ts.URL + "/api/v2/utxolock/"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing lock token"}`,
+			},
+		},
+		{
+			name:        "apiUtxoLock POST invalid outpoint",
+			r:           newPostRequest(ts.URL+"/api/v2/utxolock/", `{"outpoints":["`+dbtestdata.TxidB1T1+`"]}`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid outpoint ` + dbtestdata.TxidB1T1 + `"}`,
+			},
+		},
+		{
+			name:        "apiUtxoLock POST spent outpoint",
+			r:           newPostRequest(ts.URL+"/api/v2/utxolock/", `{"outpoints":["`+dbtestdata.TxidB1T1+`:1"]}`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Outpoint ` + dbtestdata.TxidB1T1 + `:1 is spent"}`,
+			},
+		},
+		{
+			name:        "apiUtxoLock POST unknown outpoint",
+			r:           newPostRequest(ts.URL+"/api/v2/utxolock/", `{"outpoints":["`+dbtestdata.TxidB1T1+`:9"]}`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Outpoint ` + dbtestdata.TxidB1T1 + `:9 not found"}`,
+			},
+		},
+		{
+			name:        "apiUtxoLock GET unknown token",
+			r:           newGetRequest(ts.URL + "/api/v2/utxolock/0123456789abcdef0123456789abcdef01234567"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unknown lock token"}`,
+			},
+		},
 		{
 			name:        "apiEstimateFee",
 			r:           newGetRequest(ts.URL + "/api/estimatefee/123?conservative=false"),
@@ -1806,6 +1934,86 @@ var websocketTestsBitcoinType = []websocketTest{
 		},
 		want: `{"id":"44","data":{"error":{"message":"not supported"}}}`,
 	},
+	{
+		name: "websocket createPortfolio invalid account",
+		req: websocketReq{
+			Method: "createPortfolio",
+			Params: WsPortfolioReq{
+				Accounts: []api.PortfolioAccount{{Descriptor: "invalid"}},
+			},
+		},
+		want: `{"id":"45","data":{"error":{"message":"Invalid portfolio account invalid"}}}`,
+	},
+	{
+		name: "websocket getPortfolio not found",
+		req: websocketReq{
+			Method: "getPortfolio",
+			Params: WsPortfolioBalanceReq{
+				Token: "00112233445566778899aabbccddeeff00112233",
+			},
+		},
+		want: `{"id":"46","data":{"error":{"message":"Portfolio not found"}}}`,
+	},
+	{
+		name: "websocket subscribeTxConfirmations",
+		req: websocketReq{
+			Method: "subscribeTxConfirmations",
+			Params: WsSubscribeTxConfirmationsReq{
+				Txids:         []string{dbtestdata.TxidB1T1},
+				Confirmations: []uint32{6, 3},
+			},
+		},
+		want: `{"id":"47","data":{"subscribed":true}}`,
+	},
+	{
+		name: "websocket subscribeTxConfirmations missing confirmations",
+		req: websocketReq{
+			Method: "subscribeTxConfirmations",
+			Params: WsSubscribeTxConfirmationsReq{
+				Txids: []string{dbtestdata.TxidB1T1},
+			},
+		},
+		want: `{"id":"48","data":{"error":{"message":"Missing confirmations"}}}`,
+	},
+	{
+		name: "websocket unsubscribeTxConfirmations",
+		req: websocketReq{
+			Method: "unsubscribeTxConfirmations",
+		},
+		want: `{"id":"49","data":{"subscribed":false}}`,
+	},
+	{
+		name: "websocket resumeSession unknown session",
+		req: websocketReq{
+			Method: "resumeSession",
+			Params: WsResumeSessionReq{
+				SessionID: "0123456789abcdef0123456789abcdef",
+				LastSeq:   1,
+			},
+		},
+		want: `{"id":"50","data":{"error":{"message":"Unknown or expired session"}}}`,
+	},
+	{
+		name: "websocket subscribeContractEvents not supported",
+		req: websocketReq{
+			Method: "subscribeContractEvents",
+			Params: WsSubscribeContractEventsReq{
+				Contract: "0x4af4114f73d1c1c903ac9e0361b379d1291808a2",
+			},
+		},
+		want: `{"id":"51","data":{"error":{"message":"Contract events are not supported"}}}`,
+	},
+	{
+		name: "websocket subscribeTxConfirmations invalid txid",
+		req: websocketReq{
+			Method: "subscribeTxConfirmations",
+			Params: WsSubscribeTxConfirmationsReq{
+				Txids:         []string{dbtestdata.TxidB1T1, "abcd"},
+				Confirmations: []uint32{1},
+			},
+		},
+		want: `{"id":"52","data":{"error":{"message":"Invalid txid abcd"}}}`,
+	},
 }
 
 func runWebsocketTests(t *testing.T, ts *httptest.Server, tests []websocketTest) {
@@ -2360,3 +2568,523 @@ func Test_validateIntParam(t *testing.T) {
 		})
 	}
 }
+
+func Test_HTTPPortfolio_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	doJSON := func(method, u, body string, statusCode int, out interface{}) {
+		t.Helper()
+		r, err := http.NewRequest(method, u, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != statusCode {
+			t.Fatalf("%s %s: StatusCode = %v, want %v, body = %s", method, u, resp.StatusCode, statusCode, string(b))
+		}
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatalf("failed to decode JSON body %q: %v", string(b), err)
+		}
+	}
+	type accountResponse struct {
+		Balance string   `json:"balance"`
+		Txids   []string `json:"txids"`
+	}
+	type portfolioResponse struct {
+		Token    string `json:"token"`
+		Name     string `json:"name"`
+		Balance  string `json:"balance"`
+		Accounts []struct {
+			Descriptor string `json:"descriptor"`
+			Label      string `json:"label"`
+			Balance    string `json:"balance"`
+		} `json:"accounts"`
+		Txids []string `json:"txids"`
+	}
+
+	var created portfolioResponse
+	doJSON("POST", ts.URL+"/api/v2/portfolio/", `{"name":"test","accounts":[{"descriptor":"`+dbtestdata.Addr2+`","label":"a2"},{"descriptor":"`+dbtestdata.Xpub+`"}]}`, http.StatusOK, &created)
+	if len(created.Token) == 0 || created.Name != "test" || len(created.Accounts) != 2 {
+		t.Fatalf("unexpected created portfolio %+v", created)
+	}
+	var apiErr apiErrorResponse
+	doJSON("POST", ts.URL+"/api/v2/portfolio/", `{"accounts":[{"descriptor":"`+dbtestdata.Addr2+`"},{"descriptor":"`+dbtestdata.Addr2+`"}]}`, http.StatusBadRequest, &apiErr)
+	if !strings.Contains(apiErr.Error, "Duplicate portfolio account") {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+	doJSON("POST", ts.URL+"/api/v2/portfolio/", `{"accounts":[{"descriptor":"invalid"}]}`, http.StatusBadRequest, &apiErr)
+	if !strings.Contains(apiErr.Error, "Invalid portfolio account") {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+
+	// the aggregated view must match the individual accounts
+	var addr, xpub accountResponse
+	mustGetJSON(t, ts.URL+"/api/v2/address/"+dbtestdata.Addr2+"?details=txids", http.StatusOK, &addr)
+	mustGetJSON(t, ts.URL+"/api/v2/xpub/"+dbtestdata.Xpub+"?details=txids", http.StatusOK, &xpub)
+	var pb portfolioResponse
+	mustGetJSON(t, ts.URL+"/api/v2/portfolio/"+created.Token+"?details=txids", http.StatusOK, &pb)
+	a, _ := strconv.ParseInt(addr.Balance, 10, 64)
+	x, _ := strconv.ParseInt(xpub.Balance, 10, 64)
+	if pb.Balance != strconv.FormatInt(a+x, 10) {
+		t.Errorf("portfolio balance = %v, want %v", pb.Balance, a+x)
+	}
+	if len(pb.Accounts) != 2 || pb.Accounts[0].Balance != addr.Balance || pb.Accounts[0].Label != "a2" || pb.Accounts[1].Balance != xpub.Balance {
+		t.Errorf("unexpected portfolio accounts %+v", pb.Accounts)
+	}
+	unique := make(map[string]struct{})
+	for _, txid := range append(addr.Txids, xpub.Txids...) {
+		unique[txid] = struct{}{}
+	}
+	if len(unique) == 0 || len(pb.Txids) != len(unique) {
+		t.Errorf("portfolio txids = %v, want %d unique txids", pb.Txids, len(unique))
+	}
+	for _, txid := range pb.Txids {
+		if _, found := unique[txid]; !found {
+			t.Errorf("unexpected portfolio txid %v", txid)
+		}
+		delete(unique, txid)
+	}
+
+	var updated portfolioResponse
+	doJSON("PUT", ts.URL+"/api/v2/portfolio/"+created.Token, `{"name":"renamed","accounts":[{"descriptor":"`+dbtestdata.Addr2+`"}]}`, http.StatusOK, &updated)
+	if updated.Token != created.Token || updated.Name != "renamed" || len(updated.Accounts) != 1 {
+		t.Fatalf("unexpected updated portfolio %+v", updated)
+	}
+	mustGetJSON(t, ts.URL+"/api/v2/portfolio/"+created.Token+"?details=txids", http.StatusOK, &pb)
+	if pb.Balance != addr.Balance || !reflect.DeepEqual(pb.Txids, addr.Txids) {
+		t.Errorf("updated portfolio = %+v, want balance %v, txids %v", pb, addr.Balance, addr.Txids)
+	}
+
+	doJSON("DELETE", ts.URL+"/api/v2/portfolio/"+created.Token, "", http.StatusOK, &updated)
+	mustGetJSON(t, ts.URL+"/api/v2/portfolio/"+created.Token, http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Portfolio not found" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+	mustGetJSON(t, ts.URL+"/api/v2/portfolio/invalid", http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Invalid portfolio token" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+}
+
+func Test_HTTPExport_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	get := func(u string, statusCode int) (string, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != statusCode {
+			t.Fatalf("%s: StatusCode = %v, want %v, body = %s", u, resp.StatusCode, statusCode, string(b))
+		}
+		return resp.Header.Get("Content-Type"), string(b)
+	}
+
+	contentType, body := get("/api/v2/export/"+dbtestdata.Addr2+"?currency=usd", http.StatusOK)
+	if contentType != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %v", contentType)
+	}
+	want := "txid,blockHeight,blockTime,received,sent,fee,rate,receivedFiat,sentFiat,feeFiat,tokenTransfers\n" +
+		dbtestdata.TxidB2T1 + ",225494,2018-03-21T01:27:58Z,0,0.00012345,0.00000346,2002,0,0.24714689999999997,0.00692692,\n" +
+		dbtestdata.TxidB1T1 + ",225493,2018-03-20T03:03:46Z,0.0002469,0,0,2001,0.49404689999999996,0,0,\n"
+	if body != want {
+		t.Errorf("CSV export = %q, want %q", body, want)
+	}
+
+	// from filter, unknown currency leaves the fiat columns empty
+	_, body = get("/api/v2/export/"+dbtestdata.Addr2+"?from=1521590000&currency=xyz", http.StatusOK)
+	want = "txid,blockHeight,blockTime,received,sent,fee,rate,receivedFiat,sentFiat,feeFiat,tokenTransfers\n" +
+		dbtestdata.TxidB2T1 + ",225494,2018-03-21T01:27:58Z,0,0.00012345,0.00000346,,,,,\n"
+	if body != want {
+		t.Errorf("CSV export = %q, want %q", body, want)
+	}
+
+	contentType, body = get("/api/v2/export/"+dbtestdata.Xpub+"?format=json", http.StatusOK)
+	if contentType != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %v", contentType)
+	}
+	var rows []api.ExportRow
+	if err := json.Unmarshal([]byte(body), &rows); err != nil {
+		t.Fatalf("failed to decode JSON body %q: %v", body, err)
+	}
+	if len(rows) != 2 || rows[0].Txid != dbtestdata.TxidB2T2 || rows[1].Txid != dbtestdata.TxidB1T2 ||
+		rows[0].FeeSat.String() != "62" || rows[1].ReceivedSat.String() != "1" || rows[0].Rate != 0 {
+		t.Errorf("unexpected JSON export %s", body)
+	}
+
+	_, body = get("/api/v2/export/"+dbtestdata.Addr2+"?format=xml", http.StatusBadRequest)
+	if body != "{\"error\":\"Unsupported format xml\"}\n" {
+		t.Errorf("unexpected error %q", body)
+	}
+	_, body = get("/api/v2/export/"+dbtestdata.Addr2+"?from=abc", http.StatusBadRequest)
+	if body != "{\"error\":\"Parameter 'from' is not a valid Unix timestamp\"}\n" {
+		t.Errorf("unexpected error %q", body)
+	}
+}
+
+func Test_HTTPCostBasis_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	var report api.CostBasisReport
+	mustGetJSON(t, ts.URL+"/api/v2/costbasis/"+dbtestdata.Addr2+"?currency=usd", http.StatusOK, &report)
+	if report.Method != api.CostBasisFIFO || len(report.Assets) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	coin := &report.Assets[0]
+	// received 24690 @ 2001, sent 12345 @ 2002
+	if coin.HeldSat.String() != "12345" || len(coin.Transactions) != 2 || len(coin.Periods) != 2 ||
+		coin.Transactions[0].Txid != dbtestdata.TxidB1T1 || coin.Transactions[0].AcquiredSat.String() != "24690" ||
+		coin.Transactions[1].Txid != dbtestdata.TxidB2T1 || coin.Transactions[1].DisposedSat.String() != "12345" ||
+		math.Abs(coin.Transactions[1].CostBasis-0.00012345*2001) > 1e-9 || math.Abs(coin.RealizedGain-0.00012345) > 1e-9 {
+		t.Errorf("unexpected coin report %+v", coin)
+	}
+
+	// the lots are built from the whole history, only the gains in the range are reported
+	mustGetJSON(t, ts.URL+"/api/v2/costbasis/"+dbtestdata.Addr2+"?currency=usd&method=average&from=1521590000", http.StatusOK, &report)
+	coin = &report.Assets[0]
+	if report.Method != api.CostBasisAverage || len(coin.Transactions) != 1 || coin.HeldSat.String() != "12345" || math.Abs(coin.RealizedGain-0.00012345) > 1e-9 {
+		t.Errorf("unexpected coin report %+v", coin)
+	}
+
+	var apiErr apiErrorResponse
+	mustGetJSON(t, ts.URL+"/api/v2/costbasis/"+dbtestdata.Addr2+"?currency=usd&method=hifo", http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Unsupported cost basis method hifo" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+	mustGetJSON(t, ts.URL+"/api/v2/costbasis/"+dbtestdata.Addr2, http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Missing parameter 'currency'" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+}
+
+func Test_HTTPReserves_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	// the reserves are served only by the internal server
+	is, err := NewInternalServer("localhost:12346", "", s.db, chain, s.mempool, s.txCache, metrics, s.is, s.fiatRates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(is.https.Handler)
+	defer ts.Close()
+
+	post := func(method, body string, statusCode int, out interface{}) {
+		t.Helper()
+		r, err := http.NewRequest(method, ts.URL+"/admin/reserves", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != statusCode {
+			t.Fatalf("StatusCode = %v, want %v, body = %s", resp.StatusCode, statusCode, string(b))
+		}
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatalf("failed to decode JSON body %q: %v", string(b), err)
+		}
+	}
+
+	blockHash := "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"
+	// Addr2 is listed twice, its output must be counted only once
+	var report api.ReservesReport
+	post(http.MethodPost, `{"descriptors":["`+dbtestdata.Addr2+`","`+dbtestdata.Xpub+`","`+dbtestdata.Addr2+`"],"blockHash":"`+blockHash+`"}`, http.StatusOK, &report)
+	if report.Height != 225494 || report.BlockTime != 1521595678 || len(report.Accounts) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.BalanceSat.String() != "118641987845" || report.MerkleSumSat.String() != report.BalanceSat.String() {
+		t.Errorf("balance %v, merkle sum %v, want 118641987845", report.BalanceSat, report.MerkleSumSat)
+	}
+	if len(report.Utxos) != 2 || report.Utxos[0].Txid != dbtestdata.TxidB1T1 || report.Utxos[0].Address != dbtestdata.Addr2 ||
+		report.Utxos[1].Txid != dbtestdata.TxidB2T2 || report.Utxos[1].Path != "m/49'/1'/33'/1/3" {
+		t.Fatalf("unexpected utxos %+v", report.Utxos)
+	}
+	if len(report.MerkleRoot) != 64 || len(report.Utxos[0].LeafHash) != 64 || report.MerkleRoot == report.Utxos[0].LeafHash {
+		t.Errorf("unexpected merkle root %v, leaf %v", report.MerkleRoot, report.Utxos[0].LeafHash)
+	}
+	// the tree does not depend on the order of the descriptors
+	var reordered api.ReservesReport
+	post(http.MethodPost, `{"descriptors":["`+dbtestdata.Xpub+`","`+dbtestdata.Addr2+`"],"blockHash":"`+blockHash+`"}`, http.StatusOK, &reordered)
+	if reordered.MerkleRoot != report.MerkleRoot {
+		t.Errorf("merkle root %v differs from %v", reordered.MerkleRoot, report.MerkleRoot)
+	}
+
+	var apiErr apiErrorResponse
+	post(http.MethodPost, `{"descriptors":["`+dbtestdata.Addr2+`"],"blockHash":"0000000000000000000000000000000000000000000000000000000000000000"}`, http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Block not found" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+	post(http.MethodPost, `{"blockHash":"`+blockHash+`"}`, http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Missing descriptors" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+	post(http.MethodGet, "", http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Reserves request must be sent using POST" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+}
+
+func Test_HTTPGraphQL_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	do := func(r *http.Request, statusCode int) string {
+		t.Helper()
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != statusCode {
+			t.Fatalf("StatusCode = %v, want %v, body = %s", resp.StatusCode, statusCode, string(b))
+		}
+		return strings.TrimSpace(string(b))
+	}
+	get := func(query string) string {
+		t.Helper()
+		return do(newGetRequest(ts.URL+"/api/v2/graphql?query="+url.QueryEscape(query)), http.StatusOK)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "transaction with previous and spending transactions",
+			query: `{ transaction(txid: "` + dbtestdata.TxidB2T1 + `") { txid vin { n value tx { txid blockHeight } } vout { n spent spendingTx { txid } } } }`,
+			want:  `{"data":{"transaction":{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"n":0,"value":"1234567890123","tx":{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","blockHeight":225493}},{"n":1,"value":"12345","tx":{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","blockHeight":225493}}],"vout":[{"n":0,"spent":true,"spendingTx":{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"}},{"n":1,"spent":false,"spendingTx":null},{"n":2,"spent":false,"spendingTx":null}]}}}`,
+		},
+		{
+			name:  "block with paged transactions",
+			query: `{ block(id: "225494") { height txCount totalPages txs(pageSize: 2) { txid } } }`,
+			want:  `{"data":{"block":{"height":225494,"txCount":4,"totalPages":2,"txs":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"},{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"}]}}}`,
+		},
+		{
+			name:  "address and xpub",
+			query: `{ address(address: "` + dbtestdata.Addr2 + `") { balance txids } xpub(xpub: "` + dbtestdata.Xpub + `") { balance tokens { path balance } } }`,
+			want:  `{"data":{"address":{"balance":"12345","txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"]},"xpub":{"balance":"118641975500","tokens":[{"path":"m/49'/1'/33'/1/3","balance":"118641975500"}]}}}`,
+		},
+		{
+			name:  "utxos, info and fiat rates",
+			query: `{ utxos(descriptor: "` + dbtestdata.Addr2 + `") { txid vout value } info { bestHeight } fiatRates(currencies: ["usd"]) { ts rates { currency rate } } }`,
+			want:  `{"data":{"utxos":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":2,"value":"12345"}],"info":{"bestHeight":225494},"fiatRates":{"ts":1574380800,"rates":[{"currency":"usd","rate":7914.5}]}}}`,
+		},
+		{
+			name:  "error of a field",
+			query: `{ transaction(txid: "abcd") { txid } }`,
+			want:  `{"data":{"transaction":null},"errors":[{"message":"Transaction 'abcd' not found","path":["transaction"]}]}`,
+		},
+		{
+			name:  "complexity limit",
+			query: `{ block(id: "225494") { txs(pageSize: 100) { vin { tx { vin { tx { txid } } } } } } }`,
+			want:  `{"errors":[{"message":"Query is too complex, maximum complexity is 2000"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := get(tt.query); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	r, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v2/graphql", strings.NewReader(`{"query":"query ($a: String!) { address(address: $a) { balance } }","variables":{"a":"`+dbtestdata.Addr1+`"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := do(r, http.StatusOK), `{"data":{"address":{"balance":"100000000"}}}`; got != want {
+		t.Errorf("POST got %v, want %v", got, want)
+	}
+	r, err = http.NewRequest(http.MethodPut, ts.URL+"/api/v2/graphql", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := do(r, http.StatusBadRequest), `{"error":"GraphQL request must be sent using GET or POST"}`; got != want {
+		t.Errorf("PUT got %v, want %v", got, want)
+	}
+	if got, want := do(newGetRequest(ts.URL+"/api/v2/graphql"), http.StatusBadRequest), `{"error":"Missing query"}`; got != want {
+		t.Errorf("missing query got %v, want %v", got, want)
+	}
+}
+
+func Test_HTTPBalanceAtHeight_BitcoinType(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		want   string
+	}{
+		{
+			name:   "address before spend",
+			url:    "/api/v2/address/" + dbtestdata.Addr2 + "?atHeight=225493",
+			status: http.StatusOK,
+			want:   `{"address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz","height":225493,"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockTime":1521515026,"txs":1,"balance":"24690","totalReceived":"24690","totalSent":"0","utxos":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":2,"value":"12345","height":225493,"confirmations":1},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"value":"12345","height":225493,"confirmations":1}]}`,
+		},
+		{
+			name:   "address after spend",
+			url:    "/api/v2/address/" + dbtestdata.Addr2 + "?atHeight=225494",
+			status: http.StatusOK,
+			want:   `{"address":"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz","height":225494,"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockTime":1521595678,"txs":2,"balance":"12345","totalReceived":"24690","totalSent":"12345","utxos":[{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":2,"value":"12345","height":225493,"confirmations":2}]}`,
+		},
+		{
+			name:   "xpub",
+			url:    "/api/v2/xpub/" + dbtestdata.Xpub + "?atHeight=225493",
+			status: http.StatusOK,
+			want:   `{"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","height":225493,"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockTime":1521515026,"txs":1,"balance":"1","totalReceived":"1","totalSent":"0","utxos":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"value":"1","height":225493,"confirmations":1,"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0"}],"tokens":[{"type":"XPUBAddress","standard":"XPUBAddress","name":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0","transfers":1,"decimals":8,"balance":"1","totalReceived":"1","totalSent":"0"}]}`,
+		},
+		{
+			name:   "height above best block",
+			url:    "/api/v2/address/" + dbtestdata.Addr2 + "?atHeight=225495",
+			status: http.StatusBadRequest,
+			want:   `{"error":"Height 225495 is above the best block"}`,
+		},
+		{
+			name:   "invalid height",
+			url:    "/api/v2/xpub/" + dbtestdata.Xpub + "?atHeight=-1",
+			status: http.StatusBadRequest,
+			want:   `{"error":"Parameter 'atHeight' is not a valid block height"}`,
+		},
+	}
+	// with the extended index, the spent outputs are resolved from the index, otherwise from the spending transactions
+	for _, extendedIndex := range []bool{false, true} {
+		parser, chain := setupChain(t)
+		s, dbpath := setupPublicHTTPServer(parser, chain, t, extendedIndex)
+		s.ConnectFullPublicInterface()
+		ts := httptest.NewServer(s.https.Handler)
+		for _, tt := range tests {
+			t.Run(tt.name+" extendedIndex="+strconv.FormatBool(extendedIndex), func(t *testing.T) {
+				resp, err := http.Get(ts.URL + tt.url)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				b, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != tt.status {
+					t.Errorf("StatusCode = %v, want %v", resp.StatusCode, tt.status)
+				}
+				if got := strings.TrimSpace(string(b)); got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+		ts.Close()
+		closeAndDestroyPublicServer(t, s, dbpath)
+	}
+}
+
+func Test_HTTPCursorPaging_BitcoinType(t *testing.T) {
+	parser, chain := setupChain(t)
+
+	s, dbpath := setupPublicHTTPServer(parser, chain, t, false)
+	defer closeAndDestroyPublicServer(t, s, dbpath)
+	s.ConnectFullPublicInterface()
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	type pageResponse struct {
+		Page       int      `json:"page"`
+		NextCursor string   `json:"nextCursor"`
+		Txids      []string `json:"txids"`
+	}
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{
+			name: "address",
+			url:  ts.URL + "/api/v2/address/" + dbtestdata.Addr2 + "?details=txids&pageSize=1",
+			want: []string{dbtestdata.TxidB2T1, dbtestdata.TxidB1T1},
+		},
+		{
+			name: "xpub",
+			url:  ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=txids&pageSize=1",
+			want: []string{dbtestdata.TxidB2T2, dbtestdata.TxidB1T2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			cursor := ""
+			for i := 0; i < len(tt.want)+1; i++ {
+				u := tt.url
+				if cursor != "" {
+					u += "&cursor=" + cursor
+				}
+				var p pageResponse
+				mustGetJSON(t, u, http.StatusOK, &p)
+				got = append(got, p.Txids...)
+				if p.NextCursor == "" {
+					break
+				}
+				if cursor != "" && p.Page != 0 {
+					t.Errorf("cursor page returned page number %d", p.Page)
+				}
+				cursor = p.NextCursor
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("txids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var apiErr apiErrorResponse
+	mustGetJSON(t, ts.URL+"/api/v2/address/"+dbtestdata.Addr2+"?details=txids&cursor=invalid", http.StatusBadRequest, &apiErr)
+	if apiErr.Error != "Invalid cursor" {
+		t.Errorf("unexpected error %q", apiErr.Error)
+	}
+}
@@ -674,8 +674,9 @@ func (s *SocketIoServer) getMempoolEntry(txid string) (res resultGetMempoolEntry
 	return
 }
 
-// onSubscribe expects two event subscriptions based on the req parameter (including the doublequotes):
+// onSubscribe expects three event subscriptions based on the req parameter (including the doublequotes):
 // "bitcoind/hashblock"
+// "bitcoind/reorg"
 // "bitcoind/addresstxid",["2MzTmvPJLZaLzD9XdN3jMtQA5NexC3rAPww","2NAZRJKr63tSdcTxTN3WaE9ZNDyXy6PgGuv"]
 func (s *SocketIoServer) onSubscribe(c *gosocketio.Channel, req []byte) interface{} {
 	defer func() {
@@ -721,8 +722,8 @@ func (s *SocketIoServer) onSubscribe(c *gosocketio.Channel, req []byte) interfac
 		}
 	} else {
 		sc = r[1 : len(r)-1]
-		if sc != "bitcoind/hashblock" {
-			onError(c.Id(), sc, "invalid data", "expecting bitcoind/hashblock, req: "+r)
+		if sc != "bitcoind/hashblock" && sc != "bitcoind/reorg" {
+			onError(c.Id(), sc, "invalid data", "expecting bitcoind/hashblock or bitcoind/reorg, req: "+r)
 			return nil
 		}
 		c.Join(sc)
@@ -741,6 +742,27 @@ func (s *SocketIoServer) OnNewBlockHash(hash string) {
 	go s.onNewBlockHashAsync(hash)
 }
 
+// OnReorg notifies users subscribed to bitcoind/reorg about the blocks disconnected by a chain reorganization
+// and users subscribed to bitcoind/addresstxid about the disconnected transactions of the addresses
+// by the bitcoind/addresstxidreorged event
+func (s *SocketIoServer) OnReorg(reorg *bchain.Reorg) {
+	c := s.server.BroadcastTo("bitcoind/reorg", "bitcoind/reorg", reorg)
+	glog.Info("broadcasting reorg to fork height ", reorg.ForkHeight, " to ", c, " channels")
+	for i := range reorg.Blocks {
+		b := &reorg.Blocks[i]
+		for _, txid := range b.Txids {
+			for _, desc := range b.AddrDescs[txid] {
+				addr, searchable, err := s.chainParser.GetAddressesFromAddrDesc(desc)
+				if err != nil || !searchable || len(addr) != 1 {
+					continue
+				}
+				data := map[string]interface{}{"address": addr[0], "txid": txid, "blockHash": b.Hash}
+				s.server.BroadcastTo("bitcoind/addresstxid-"+string(desc), "bitcoind/addresstxidreorged", data)
+			}
+		}
+	}
+}
+
 // OnNewTxAddr notifies users subscribed to bitcoind/addresstxid about new block
 func (s *SocketIoServer) OnNewTxAddr(txid string, desc bchain.AddressDescriptor) {
 	addr, searchable, err := s.chainParser.GetAddressesFromAddrDesc(desc)
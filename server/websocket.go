@@ -1,12 +1,15 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
 	"net/http"
 	"net/url"
 	"os"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +21,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/trezor/blockbook/api"
 	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
 	"github.com/trezor/blockbook/common"
 	"github.com/trezor/blockbook/db"
 	"github.com/trezor/blockbook/fiat"
@@ -49,7 +53,10 @@ type websocketChannel struct {
 	alive                        bool
 	aliveLock                    sync.Mutex
 	closeReason                  string
-	addrDescs                    []string // subscribed address descriptors as strings
+	addrDescs                    []string          // subscribed address descriptors as strings
+	txids                        []string          // txids subscribed by subscribeTxConfirmations
+	session                      *websocketSession // session of the address subscriptions, guarded by addressSubscriptionsLock
+	contract                     string            // contract address descriptor subscribed by subscribeContractEvents
 	getAddressInfoDescriptorsMux sync.Mutex
 	getAddressInfoDescriptors    map[string]struct{}
 }
@@ -59,6 +66,16 @@ type addressDetails struct {
 	// publishNewBlockTxs enables notifications for confirmed transactions
 	// detected while processing newly connected blocks.
 	publishNewBlockTxs bool
+	// publishMempoolRemovals enables notifications about unconfirmed transactions
+	// replaced or evicted from the mempool.
+	publishMempoolRemovals bool
+	// publishDoubleSpends enables notifications about the conflicting mempool transactions.
+	publishDoubleSpends bool
+	// publishReorgs enables notifications about the transactions disconnected
+	// by a chain reorganization.
+	publishReorgs bool
+	// session numbers and buffers the notifications of a resumable subscription.
+	session *websocketSession
 }
 
 // WebsocketServer is a handle to websocket server
@@ -75,6 +92,8 @@ type WebsocketServer struct {
 	block0hash                      string
 	newBlockSubscriptions           map[*websocketChannel]string
 	newBlockSubscriptionsLock       sync.Mutex
+	reorgSubscriptions              map[*websocketChannel]string
+	reorgSubscriptionsLock          sync.Mutex
 	newTransactionEnabled           bool
 	newTransactionSubscriptions     map[*websocketChannel]string
 	newTransactionSubscriptionsLock sync.Mutex
@@ -83,11 +102,17 @@ type WebsocketServer struct {
 	// newBlockTxsSubscriptionCount is a fast-path guard for OnNewBlock.
 	// It tracks how many address subscriptions requested newBlockTxs=true.
 	newBlockTxsSubscriptionCount int
-	fiatRatesSubscriptions       map[string]map[*websocketChannel]string
-	fiatRatesTokenSubscriptions  map[*websocketChannel][]string
-	fiatRatesSubscriptionsLock   sync.Mutex
-	allowedOrigins               map[string]struct{}
-	allowedRpcCallTo             map[string]struct{}
+	// sessions are the resumable address subscriptions, guarded by addressSubscriptionsLock.
+	sessions                         map[string]*websocketSession
+	fiatRatesSubscriptions           map[string]map[*websocketChannel]string
+	fiatRatesTokenSubscriptions      map[*websocketChannel][]string
+	fiatRatesSubscriptionsLock       sync.Mutex
+	txConfirmationsSubscriptions     map[string]*txConfirmationsSubscription
+	txConfirmationsSubscriptionsLock sync.Mutex
+	contractEventsSubscriptions      map[string]map[*websocketChannel]*contractEventsDetails
+	contractEventsSubscriptionsLock  sync.Mutex
+	allowedOrigins                   map[string]struct{}
+	allowedRpcCallTo                 map[string]struct{}
 }
 
 // NewWebsocketServer creates new websocket interface to blockbook and returns its handle
@@ -101,21 +126,25 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 		return nil, err
 	}
 	s := &WebsocketServer{
-		db:                          db,
-		txCache:                     txCache,
-		chain:                       chain,
-		chainParser:                 chain.GetChainParser(),
-		mempool:                     mempool,
-		metrics:                     metrics,
-		is:                          is,
-		api:                         api,
-		block0hash:                  b0,
-		newBlockSubscriptions:       make(map[*websocketChannel]string),
-		newTransactionEnabled:       is.EnableSubNewTx,
-		newTransactionSubscriptions: make(map[*websocketChannel]string),
-		addressSubscriptions:        make(map[string]map[*websocketChannel]*addressDetails),
-		fiatRatesSubscriptions:      make(map[string]map[*websocketChannel]string),
-		fiatRatesTokenSubscriptions: make(map[*websocketChannel][]string),
+		db:                           db,
+		txCache:                      txCache,
+		chain:                        chain,
+		chainParser:                  chain.GetChainParser(),
+		mempool:                      mempool,
+		metrics:                      metrics,
+		is:                           is,
+		api:                          api,
+		block0hash:                   b0,
+		newBlockSubscriptions:        make(map[*websocketChannel]string),
+		reorgSubscriptions:           make(map[*websocketChannel]string),
+		newTransactionEnabled:        is.EnableSubNewTx,
+		newTransactionSubscriptions:  make(map[*websocketChannel]string),
+		addressSubscriptions:         make(map[string]map[*websocketChannel]*addressDetails),
+		sessions:                     make(map[string]*websocketSession),
+		fiatRatesSubscriptions:       make(map[string]map[*websocketChannel]string),
+		fiatRatesTokenSubscriptions:  make(map[*websocketChannel][]string),
+		txConfirmationsSubscriptions: make(map[string]*txConfirmationsSubscription),
+		contractEventsSubscriptions:  make(map[string]map[*websocketChannel]*contractEventsDetails),
 	}
 	s.upgrader = &websocket.Upgrader{
 		ReadBufferSize:    1024 * 32,
@@ -354,9 +383,14 @@ func (s *WebsocketServer) onConnect(c *websocketChannel) {
 
 func (s *WebsocketServer) onDisconnect(c *websocketChannel) {
 	s.unsubscribeNewBlock(c)
+	s.unsubscribeReorg(c)
 	s.unsubscribeNewTransaction(c)
-	s.unsubscribeAddresses(c)
+	if !s.suspendSession(c) {
+		s.unsubscribeAddresses(c)
+	}
 	s.unsubscribeFiatRates(c)
+	s.unsubscribeTxConfirmations(c)
+	s.unsubscribeContractEvents(c)
 	glog.Info("Client disconnected ", c.id, ", ", c.ip)
 	s.metrics.WebsocketClients.Dec()
 }
@@ -382,6 +416,38 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		}
 		return
 	},
+	"createPortfolio": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsPortfolioReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.CreatePortfolio(&api.Portfolio{Name: r.Name, Accounts: r.Accounts})
+		}
+		return
+	},
+	"updatePortfolio": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsPortfolioReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.UpdatePortfolio(r.Token, &api.Portfolio{Name: r.Name, Accounts: r.Accounts})
+		}
+		return
+	},
+	"deletePortfolio": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsPortfolioReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.DeletePortfolio(r.Token)
+		}
+		return
+	},
+	"getPortfolio": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsPortfolioBalanceReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.getPortfolio(&r)
+		}
+		return
+	},
 	"getInfo": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		return s.getInfo()
 	},
@@ -411,7 +477,46 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		r := WsAccountUtxoReq{}
 		err = json.Unmarshal(req.Params, &r)
 		if err == nil {
-			rv, err = s.getAccountUtxo(r.Descriptor)
+			rv, err = s.getAccountUtxo(r.Descriptor, api.UtxoLockFilter{Token: r.LockToken, ExcludeLocked: r.ExcludeLocked})
+		}
+		return
+	},
+	"selectCoins": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsSelectCoinsReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.SelectCoins(r.Descriptor, &api.CoinSelectionReq{
+				Outputs:     r.Outputs,
+				FeePerKb:    r.FeePerKb,
+				ExcludeDust: r.ExcludeDust,
+				Exclude:     r.Exclude,
+				LockToken:   r.LockToken,
+				LockTTL:     r.LockTTL,
+			}, r.Gap, c.ip)
+		}
+		return
+	},
+	"lockUtxos": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsUtxoLockReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.LockUtxos(r.Token, r.Outpoints, r.TTL, c.ip)
+		}
+		return
+	},
+	"unlockUtxos": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsUtxoLockReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.UnlockUtxos(r.Token, r.Outpoints)
+		}
+		return
+	},
+	"getUtxoLocks": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsUtxoLockReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.GetUtxoLocks(r.Token)
 		}
 		return
 	},
@@ -466,6 +571,24 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		return
 	},
 
+	"validateTransaction": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsValidateTransactionReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.ValidateTransaction(r.Hex)
+		}
+		return
+	},
+
+	"analyzePsbt": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsAnalyzePsbtReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.AnalyzePsbt(r.Psbt)
+		}
+		return
+	},
+
 	"getMempoolFilters": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		r := WsMempoolFiltersReq{}
 		err = json.Unmarshal(req.Params, &r)
@@ -504,6 +627,12 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 	"unsubscribeNewBlock": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		return s.unsubscribeNewBlock(c)
 	},
+	"subscribeReorg": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		return s.subscribeReorg(c, req)
+	},
+	"unsubscribeReorg": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		return s.unsubscribeReorg(c)
+	},
 	"subscribeNewTransaction": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		return s.subscribeNewTransaction(c, req)
 	},
@@ -511,15 +640,42 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		return s.unsubscribeNewTransaction(c)
 	},
 	"subscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
-		ad, nbtxs, err := s.unmarshalAddresses(req.Params)
+		ad, r, err := s.unmarshalAddresses(req.Params)
 		if err == nil {
-			rv, err = s.subscribeAddresses(c, ad, nbtxs, req)
+			rv, err = s.subscribeAddresses(c, ad, r, req)
 		}
 		return
 	},
 	"unsubscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		return s.unsubscribeAddresses(c)
 	},
+	"resumeSession": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		var r WsResumeSessionReq
+		if err = json.Unmarshal(req.Params, &r); err != nil {
+			return nil, api.NewAPIError("Invalid resumeSession params", true)
+		}
+		return s.resumeSession(c, &r)
+	},
+	"subscribeTxConfirmations": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		var r WsSubscribeTxConfirmationsReq
+		if err = json.Unmarshal(req.Params, &r); err != nil {
+			return nil, api.NewAPIError("Invalid subscribeTxConfirmations params", true)
+		}
+		return s.subscribeTxConfirmations(c, &r, req)
+	},
+	"unsubscribeTxConfirmations": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		return s.unsubscribeTxConfirmations(c)
+	},
+	"subscribeContractEvents": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		var r WsSubscribeContractEventsReq
+		if err = json.Unmarshal(req.Params, &r); err != nil {
+			return nil, api.NewAPIError("Invalid subscribeContractEvents params", true)
+		}
+		return s.subscribeContractEvents(c, &r, req)
+	},
+	"unsubscribeContractEvents": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		return s.unsubscribeContractEvents(c)
+	},
 	"subscribeFiatRates": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		var r WsSubscribeFiatRatesReq
 		err = json.Unmarshal(req.Params, &r)
@@ -625,22 +781,24 @@ func unmarshalGetAccountInfoRequest(params []byte) (*WsAccountInfoReq, error) {
 	return &r, nil
 }
 
-func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Address, err error) {
-	var opt api.AccountDetails
-	switch req.Details {
+func parseWsAccountDetails(details string) api.AccountDetails {
+	switch details {
 	case "tokens":
-		opt = api.AccountDetailsTokens
+		return api.AccountDetailsTokens
 	case "tokenBalances":
-		opt = api.AccountDetailsTokenBalances
+		return api.AccountDetailsTokenBalances
 	case "txids":
-		opt = api.AccountDetailsTxidHistory
+		return api.AccountDetailsTxidHistory
 	case "txslight":
-		opt = api.AccountDetailsTxHistoryLight
+		return api.AccountDetailsTxHistoryLight
 	case "txs":
-		opt = api.AccountDetailsTxHistory
-	default:
-		opt = api.AccountDetailsBasic
+		return api.AccountDetailsTxHistory
 	}
+	return api.AccountDetailsBasic
+}
+
+func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Address, err error) {
+	opt := parseWsAccountDetails(req.Details)
 	var tokensToReturn api.TokensToReturn
 	switch req.Tokens {
 	case "used":
@@ -657,6 +815,7 @@ func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Addres
 		Vout:           api.AddressFilterVoutOff,
 		TokensToReturn: tokensToReturn,
 		IncludeErc4626: req.IncludeErc4626,
+		Cursor:         req.Cursor,
 	}
 	if req.PageSize == 0 {
 		req.PageSize = txsOnPage
@@ -668,10 +827,17 @@ func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Addres
 	return a, nil
 }
 
-func (s *WebsocketServer) getAccountUtxo(descriptor string) (api.Utxos, error) {
-	utxo, err := s.api.GetXpubUtxo(descriptor, false, 0)
+func (s *WebsocketServer) getPortfolio(req *WsPortfolioBalanceReq) (*api.PortfolioBalance, error) {
+	if req.PageSize == 0 {
+		req.PageSize = txsOnPage
+	}
+	return s.api.GetPortfolioBalance(req.Token, req.Page, req.PageSize, parseWsAccountDetails(req.Details), strings.ToLower(req.SecondaryCurrency), req.Gap)
+}
+
+func (s *WebsocketServer) getAccountUtxo(descriptor string, lf api.UtxoLockFilter) (api.Utxos, error) {
+	utxo, err := s.api.GetXpubUtxo(descriptor, false, 0, lf)
 	if err != nil {
-		return s.api.GetAddressUtxo(descriptor, false)
+		return s.api.GetAddressUtxo(descriptor, false, lf)
 	}
 	return utxo, nil
 }
@@ -845,7 +1011,7 @@ func (s *WebsocketServer) longTermFeeRate() (res interface{}, err error) {
 }
 
 func (s *WebsocketServer) sendTransaction(tx string, disableAlternativeRPC bool) (res resultSendTransaction, err error) {
-	txid, err := s.chain.SendRawTransaction(tx, disableAlternativeRPC)
+	txid, err := s.api.SendRawTransaction(tx, disableAlternativeRPC)
 	if err != nil {
 		return res, err
 	}
@@ -853,13 +1019,14 @@ func (s *WebsocketServer) sendTransaction(tx string, disableAlternativeRPC bool)
 	return
 }
 
+type resMempoolFilters struct {
+	ParamP    uint8             `json:"P"`
+	ParamM    uint64            `json:"M"`
+	ZeroedKey bool              `json:"zeroedKey"`
+	Entries   map[string]string `json:"entries"`
+}
+
 func (s *WebsocketServer) getMempoolFilters(r *WsMempoolFiltersReq) (res interface{}, err error) {
-	type resMempoolFilters struct {
-		ParamP    uint8             `json:"P"`
-		ParamM    uint64            `json:"M"`
-		ZeroedKey bool              `json:"zeroedKey"`
-		Entries   map[string]string `json:"entries"`
-	}
 	filterEntries, err := s.mempool.GetTxidFilterEntries(r.ScriptType, r.FromTimestamp)
 	if err != nil {
 		return nil, err
@@ -872,13 +1039,14 @@ func (s *WebsocketServer) getMempoolFilters(r *WsMempoolFiltersReq) (res interfa
 	}, nil
 }
 
+type resBlockFilter struct {
+	ParamP      uint8  `json:"P"`
+	ParamM      uint64 `json:"M"`
+	ZeroedKey   bool   `json:"zeroedKey"`
+	BlockFilter string `json:"blockFilter"`
+}
+
 func (s *WebsocketServer) getBlockFilter(r *WsBlockFilterReq) (res interface{}, err error) {
-	type resBlockFilter struct {
-		ParamP      uint8  `json:"P"`
-		ParamM      uint64 `json:"M"`
-		ZeroedKey   bool   `json:"zeroedKey"`
-		BlockFilter string `json:"blockFilter"`
-	}
 	if s.is.BlockFilterScripts != r.ScriptType {
 		return nil, errors.Errorf("Unsupported script type %s", r.ScriptType)
 	}
@@ -894,13 +1062,14 @@ func (s *WebsocketServer) getBlockFilter(r *WsBlockFilterReq) (res interface{},
 	}, nil
 }
 
+type resBlockFiltersBatch struct {
+	ParamP            uint8    `json:"P"`
+	ParamM            uint64   `json:"M"`
+	ZeroedKey         bool     `json:"zeroedKey"`
+	BlockFiltersBatch []string `json:"blockFiltersBatch"`
+}
+
 func (s *WebsocketServer) getBlockFiltersBatch(r *WsBlockFiltersBatchReq) (res interface{}, err error) {
-	type resBlockFiltersBatch struct {
-		ParamP            uint8    `json:"P"`
-		ParamM            uint64   `json:"M"`
-		ZeroedKey         bool     `json:"zeroedKey"`
-		BlockFiltersBatch []string `json:"blockFiltersBatch"`
-	}
 	if s.is.BlockFilterScripts != r.ScriptType {
 		return nil, errors.Errorf("Unsupported script type %s", r.ScriptType)
 	}
@@ -954,6 +1123,22 @@ func (s *WebsocketServer) unsubscribeNewBlock(c *websocketChannel) (res interfac
 	return &subscriptionResponse{false}, nil
 }
 
+func (s *WebsocketServer) subscribeReorg(c *websocketChannel, req *WsReq) (res interface{}, err error) {
+	s.reorgSubscriptionsLock.Lock()
+	defer s.reorgSubscriptionsLock.Unlock()
+	s.reorgSubscriptions[c] = req.ID
+	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeReorg"}).Set(float64(len(s.reorgSubscriptions)))
+	return &subscriptionResponse{true}, nil
+}
+
+func (s *WebsocketServer) unsubscribeReorg(c *websocketChannel) (res interface{}, err error) {
+	s.reorgSubscriptionsLock.Lock()
+	defer s.reorgSubscriptionsLock.Unlock()
+	delete(s.reorgSubscriptions, c)
+	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeReorg"}).Set(float64(len(s.reorgSubscriptions)))
+	return &subscriptionResponse{false}, nil
+}
+
 func (s *WebsocketServer) subscribeNewTransaction(c *websocketChannel, req *WsReq) (res interface{}, err error) {
 	s.newTransactionSubscriptionsLock.Lock()
 	defer s.newTransactionSubscriptionsLock.Unlock()
@@ -976,21 +1161,21 @@ func (s *WebsocketServer) unsubscribeNewTransaction(c *websocketChannel) (res in
 	return &subscriptionResponse{false}, nil
 }
 
-func (s *WebsocketServer) unmarshalAddresses(params []byte) ([]string, bool, error) {
+func (s *WebsocketServer) unmarshalAddresses(params []byte) ([]string, *WsSubscribeAddressesReq, error) {
 	r := WsSubscribeAddressesReq{}
 	err := json.Unmarshal(params, &r)
 	if err != nil {
-		return nil, false, api.NewAPIError("Invalid subscribeAddresses params", true)
+		return nil, nil, api.NewAPIError("Invalid subscribeAddresses params", true)
 	}
 	rv := make([]string, len(r.Addresses))
 	for i, a := range r.Addresses {
 		ad, err := s.chainParser.GetAddrDescFromAddress(a)
 		if err != nil {
-			return nil, false, api.NewAPIError("Invalid address "+strconv.Quote(a)+", "+err.Error(), true)
+			return nil, nil, api.NewAPIError("Invalid address "+strconv.Quote(a)+", "+err.Error(), true)
 		}
 		rv[i] = string(ad)
 	}
-	return rv, r.NewBlockTxs, nil
+	return rv, &r, nil
 }
 
 // doUnsubscribeAddresses removes all address subscriptions for a channel.
@@ -1013,16 +1198,43 @@ func (s *WebsocketServer) doUnsubscribeAddresses(c *websocketChannel) {
 		}
 	}
 	c.addrDescs = nil
+	if c.session != nil {
+		if c.session.expiry != nil {
+			c.session.expiry.Stop()
+		}
+		delete(s.sessions, c.session.id)
+		c.session = nil
+	}
 }
 
 // subscribeAddresses replaces previous address subscriptions for the channel.
 // If newBlockTxs is enabled, the channel receives both mempool notifications and
 // confirmed notifications detected from newly connected blocks.
-func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []string, newBlockTxs bool, req *WsReq) (res interface{}, err error) {
+// If mempoolRemovals is enabled, the channel is also notified about the replaced
+// and evicted mempool transactions, if doubleSpends is enabled about the conflicting
+// mempool transactions, if reorgs is enabled about the transactions disconnected by
+// a chain reorganization. If session is enabled, the subscription can be resumed
+// after the disconnection of the channel by resumeSession.
+func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []string, r *WsSubscribeAddressesReq, req *WsReq) (res interface{}, err error) {
+	var sessionID string
+	if r.Session {
+		if sessionID, err = newSessionID(); err != nil {
+			return nil, err
+		}
+	}
 	s.addressSubscriptionsLock.Lock()
 	defer s.addressSubscriptionsLock.Unlock()
+	if r.Session && c.session == nil && len(s.sessions) >= maxWebsocketSessions {
+		return nil, api.NewAPIError("Too many sessions", true)
+	}
 	// unsubscribe all previous subscriptions
 	s.doUnsubscribeAddresses(c)
+	var session *websocketSession
+	if r.Session {
+		session = &websocketSession{id: sessionID, channel: c}
+		s.sessions[sessionID] = session
+		c.session = session
+	}
 	for _, ads := range addrDesc {
 		as, ok := s.addressSubscriptions[ads]
 		if !ok {
@@ -1030,16 +1242,23 @@ func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []str
 			s.addressSubscriptions[ads] = as
 		}
 		as[c] = &addressDetails{
-			requestID:          req.ID,
-			publishNewBlockTxs: newBlockTxs,
-		}
-		if newBlockTxs {
+			requestID:              req.ID,
+			publishNewBlockTxs:     r.NewBlockTxs,
+			publishMempoolRemovals: r.MempoolRemovals,
+			publishDoubleSpends:    r.DoubleSpends,
+			publishReorgs:          r.Reorgs,
+			session:                session,
+		}
+		if r.NewBlockTxs {
 			s.newBlockTxsSubscriptionCount++
 		}
 	}
 	c.addrDescs = addrDesc
 	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeAddresses"}).Set(float64(len(s.addressSubscriptions)))
 	s.metrics.WebsocketNewBlockTxsSubscriptions.Set(float64(s.newBlockTxsSubscriptionCount))
+	if session != nil {
+		return &subscriptionSessionResponse{Subscribed: true, SessionID: sessionID}, nil
+	}
 	return &subscriptionResponse{true}, nil
 }
 
@@ -1053,6 +1272,131 @@ func (s *WebsocketServer) unsubscribeAddresses(c *websocketChannel) (res interfa
 	return &subscriptionResponse{false}, nil
 }
 
+const websocketSessionTTL = 5 * time.Minute
+const maxWebsocketSessionEvents = 250
+const maxWebsocketSessions = 10000
+const websocketSessionIDBytes = 16
+
+// websocketSession keeps the address subscriptions of a channel for websocketSessionTTL
+// after its disconnection and buffers the last notifications, so that a client
+// reconnected by resumeSession receives the notifications it missed.
+// The sessions are guarded by addressSubscriptionsLock.
+type websocketSession struct {
+	id      string
+	channel *websocketChannel // channel owning the address subscriptions
+	seq     uint64            // sequence number of the last notification
+	events  []*WsRes          // last notifications, at most maxWebsocketSessionEvents
+	expiry  *time.Timer       // set while the channel is disconnected
+}
+
+type subscriptionSessionResponse struct {
+	Subscribed bool   `json:"subscribed"`
+	SessionID  string `json:"sessionId" ts_doc:"Identifier of the session, it is the only credential needed to resume the subscription."`
+}
+
+type resumeSessionResponse struct {
+	Resumed  bool   `json:"resumed"`
+	Seq      uint64 `json:"seq" ts_doc:"Sequence number of the last notification of the session."`
+	Replayed int    `json:"replayed" ts_doc:"Number of the replayed notifications."`
+	Gap      bool   `json:"gap,omitempty" ts_doc:"If true, some of the notifications after lastSeq are no longer buffered, the state of the addresses must be reloaded."`
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, websocketSessionIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sendAddressNotification sends a notification to an address subscriber, the notifications
+// of a session are numbered and buffered for the replay.
+// addressSubscriptionsLock must be held by the caller.
+func (s *WebsocketServer) sendAddressNotification(c *websocketChannel, details *addressDetails, data interface{}) {
+	res := &WsRes{ID: details.requestID, Data: data}
+	if session := details.session; session != nil {
+		session.seq++
+		res.Seq = session.seq
+		if len(session.events) == maxWebsocketSessionEvents {
+			copy(session.events, session.events[1:])
+			session.events = session.events[:len(session.events)-1]
+		}
+		session.events = append(session.events, res)
+	}
+	// if the channel is disconnected, the notification is only buffered
+	c.DataOut(res)
+}
+
+// suspendSession keeps the address subscriptions of the disconnected channel with a session
+// until the session is resumed or expires. It returns false if the channel has no session.
+func (s *WebsocketServer) suspendSession(c *websocketChannel) bool {
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	session := c.session
+	if session == nil {
+		return false
+	}
+	var expiry *time.Timer
+	expiry = time.AfterFunc(websocketSessionTTL, func() {
+		s.addressSubscriptionsLock.Lock()
+		defer s.addressSubscriptionsLock.Unlock()
+		// the session could have been resumed while the timer was firing
+		if session.expiry == expiry {
+			glog.Info("Session of client ", session.channel.id, " expired")
+			s.doUnsubscribeAddresses(session.channel)
+			s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeAddresses"}).Set(float64(len(s.addressSubscriptions)))
+			s.metrics.WebsocketNewBlockTxsSubscriptions.Set(float64(s.newBlockTxsSubscriptionCount))
+		}
+	})
+	session.expiry = expiry
+	return true
+}
+
+// resumeSession moves the address subscriptions of the session to the channel and replays
+// the buffered notifications newer than lastSeq, the live notifications follow them.
+// The previous address subscriptions of the channel are replaced.
+func (s *WebsocketServer) resumeSession(c *websocketChannel, r *WsResumeSessionReq) (res interface{}, err error) {
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	session, found := s.sessions[r.SessionID]
+	if !found {
+		return nil, api.NewAPIError("Unknown or expired session", true)
+	}
+	if old := session.channel; old != c {
+		s.doUnsubscribeAddresses(c)
+		// the old channel may be still connected, if the client noticed the disconnection first
+		for _, ads := range old.addrDescs {
+			if sa, e := s.addressSubscriptions[ads]; e {
+				if details, e := sa[old]; e {
+					sa[c] = details
+					delete(sa, old)
+				}
+			}
+		}
+		c.addrDescs = old.addrDescs
+		old.addrDescs = nil
+		old.session = nil
+		c.session = session
+		session.channel = c
+	}
+	if session.expiry != nil {
+		session.expiry.Stop()
+		session.expiry = nil
+	}
+	rv := &resumeSessionResponse{Resumed: true, Seq: session.seq}
+	if len(session.events) > 0 && session.events[0].Seq > r.LastSeq+1 {
+		rv.Gap = true
+	}
+	for _, e := range session.events {
+		if e.Seq > r.LastSeq {
+			c.DataOut(e)
+			rv.Replayed++
+		}
+	}
+	glog.Info("Client ", c.id, " resumed session, replayed ", rv.Replayed, " notifications")
+	return rv, nil
+}
+
 // doUnsubscribeFiatRates fiat rates without fiatRatesSubscriptionsLock - can be called only from subscribeFiatRates and unsubscribeFiatRates
 func (s *WebsocketServer) doUnsubscribeFiatRates(c *websocketChannel) {
 	for fr, sa := range s.fiatRatesSubscriptions {
@@ -1102,13 +1446,411 @@ func (s *WebsocketServer) unsubscribeFiatRates(c *websocketChannel) (res interfa
 	return &subscriptionResponse{false}, nil
 }
 
+const maxTxConfirmationsTxids = 1000
+const maxTxConfirmations = 1000
+
+// txConfirmationsSubscription contains the block of a subscribed transaction and the subscribed channels
+type txConfirmationsSubscription struct {
+	// blockHeight is zero if the transaction is not mined
+	blockHeight uint32
+	blockHash   string
+	channels    map[*websocketChannel]*txConfirmationsDetails
+}
+
+type txConfirmationsDetails struct {
+	requestID string
+	// confirmations are the requested numbers of confirmations in ascending order
+	confirmations []uint32
+	// last is the last notified number of confirmations
+	last uint32
+}
+
+// wsTxConfirmationsNotification is sent to the subscribers of a transaction when it reaches a requested number of confirmations
+// or when the block containing it is disconnected by a chain reorganization
+type wsTxConfirmationsNotification struct {
+	Txid          string `json:"txid"`
+	Confirmations uint32 `json:"confirmations" ts_doc:"Reached number of confirmations, zero if the transaction was reorged out."`
+	BlockHeight   uint32 `json:"blockHeight" ts_doc:"Height of the block containing the transaction."`
+	BlockHash     string `json:"blockHash" ts_doc:"Hash of the block containing the transaction."`
+	Reorged       bool   `json:"reorged,omitempty" ts_doc:"If true, the block containing the transaction was disconnected by a chain reorganization."`
+}
+
+// doUnsubscribeTxConfirmations removes the tx confirmations subscriptions of the channel.
+// txConfirmationsSubscriptionsLock must be held by the caller.
+func (s *WebsocketServer) doUnsubscribeTxConfirmations(c *websocketChannel) {
+	for _, txid := range c.txids {
+		if ts, found := s.txConfirmationsSubscriptions[txid]; found {
+			delete(ts.channels, c)
+			if len(ts.channels) == 0 {
+				delete(s.txConfirmationsSubscriptions, txid)
+			}
+		}
+	}
+	c.txids = nil
+}
+
+// getTxBlock returns the height and hash of the block containing the transaction, found only in the index without the use of the backend,
+// zero height if the transaction is not mined or not indexed
+func (s *WebsocketServer) getTxBlock(txid string) (uint32, string) {
+	var height uint32
+	if s.chainParser.GetChainType() == bchain.ChainBitcoinType {
+		ta, err := s.db.GetTxAddresses(txid)
+		if err != nil || ta == nil {
+			return 0, ""
+		}
+		height = ta.Height
+	} else {
+		tx, h, err := s.db.GetTx(txid)
+		if err != nil || tx == nil {
+			return 0, ""
+		}
+		height = h
+	}
+	if height == 0 {
+		return 0, ""
+	}
+	hash, err := s.db.GetBlockHash(height)
+	if err != nil || hash == "" {
+		return 0, ""
+	}
+	return height, hash
+}
+
+// subscribeTxConfirmations replaces previous tx confirmations subscriptions of the channel.
+// The numbers of confirmations already reached at the time of the subscription are notified by a single notification.
+func (s *WebsocketServer) subscribeTxConfirmations(c *websocketChannel, r *WsSubscribeTxConfirmationsReq, req *WsReq) (res interface{}, err error) {
+	if len(r.Txids) == 0 {
+		return nil, api.NewAPIError("Missing txids", true)
+	}
+	if len(r.Txids) > maxTxConfirmationsTxids {
+		return nil, api.NewAPIError("More than "+strconv.Itoa(maxTxConfirmationsTxids)+" txids", true)
+	}
+	if len(r.Confirmations) == 0 {
+		return nil, api.NewAPIError("Missing confirmations", true)
+	}
+	confirmations := make([]uint32, 0, len(r.Confirmations))
+	for _, n := range r.Confirmations {
+		if n == 0 || n > maxTxConfirmations {
+			return nil, api.NewAPIError("Confirmations must be between 1 and "+strconv.Itoa(maxTxConfirmations), true)
+		}
+		confirmations = append(confirmations, n)
+	}
+	sort.Slice(confirmations, func(i, j int) bool { return confirmations[i] < confirmations[j] })
+	for _, txid := range r.Txids {
+		if b, err := s.chainParser.PackTxid(txid); err != nil || len(b) != s.chainParser.PackedTxidLen() {
+			return nil, api.NewAPIError("Invalid txid "+txid, true)
+		}
+	}
+	// find the blocks of the transactions before the lock is taken
+	blocks := make(map[string]*txConfirmationsSubscription, len(r.Txids))
+	for _, txid := range r.Txids {
+		if _, found := blocks[txid]; !found {
+			height, hash := s.getTxBlock(txid)
+			blocks[txid] = &txConfirmationsSubscription{blockHeight: height, blockHash: hash}
+		}
+	}
+	bestHeight, _, err := s.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	s.txConfirmationsSubscriptionsLock.Lock()
+	defer s.txConfirmationsSubscriptionsLock.Unlock()
+	s.doUnsubscribeTxConfirmations(c)
+	for txid, b := range blocks {
+		ts, found := s.txConfirmationsSubscriptions[txid]
+		if !found {
+			ts = b
+			ts.channels = make(map[*websocketChannel]*txConfirmationsDetails)
+			s.txConfirmationsSubscriptions[txid] = ts
+		}
+		details := &txConfirmationsDetails{requestID: req.ID, confirmations: confirmations}
+		ts.channels[c] = details
+		c.txids = append(c.txids, txid)
+		if ts.blockHeight > 0 && ts.blockHeight <= bestHeight {
+			// notify only the highest of the already reached numbers of confirmations
+			n := bestHeight - ts.blockHeight + 1
+			for _, m := range confirmations {
+				if m <= n {
+					details.last = m
+				}
+			}
+			if details.last > 0 {
+				s.sendTxConfirmations(c, details, txid, ts, details.last)
+			}
+		}
+	}
+	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeTxConfirmations"}).Set(float64(len(s.txConfirmationsSubscriptions)))
+	return &subscriptionResponse{true}, nil
+}
+
+// unsubscribeTxConfirmations unsubscribes all tx confirmations subscriptions of the channel
+func (s *WebsocketServer) unsubscribeTxConfirmations(c *websocketChannel) (res interface{}, err error) {
+	s.txConfirmationsSubscriptionsLock.Lock()
+	defer s.txConfirmationsSubscriptionsLock.Unlock()
+	s.doUnsubscribeTxConfirmations(c)
+	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeTxConfirmations"}).Set(float64(len(s.txConfirmationsSubscriptions)))
+	return &subscriptionResponse{false}, nil
+}
+
+func (s *WebsocketServer) sendTxConfirmations(c *websocketChannel, details *txConfirmationsDetails, txid string, ts *txConfirmationsSubscription, confirmations uint32) {
+	c.DataOut(&WsRes{
+		ID: details.requestID,
+		Data: &wsTxConfirmationsNotification{
+			Txid:          txid,
+			Confirmations: confirmations,
+			BlockHeight:   ts.blockHeight,
+			BlockHash:     ts.blockHash,
+		},
+	})
+}
+
+// setTxConfirmationsBlock records the block of the subscribed transactions mined in the new block
+func (s *WebsocketServer) setTxConfirmationsBlock(block *bchain.Block) {
+	s.txConfirmationsSubscriptionsLock.Lock()
+	defer s.txConfirmationsSubscriptionsLock.Unlock()
+	if len(s.txConfirmationsSubscriptions) == 0 {
+		return
+	}
+	for i := range block.Txs {
+		if ts, found := s.txConfirmationsSubscriptions[block.Txs[i].Txid]; found {
+			ts.blockHeight = block.Height
+			ts.blockHash = block.Hash
+		}
+	}
+}
+
+// publishTxConfirmations notifies the subscribers of the transactions which reached a requested number of confirmations at the height
+func (s *WebsocketServer) publishTxConfirmations(height uint32) {
+	s.txConfirmationsSubscriptionsLock.Lock()
+	defer s.txConfirmationsSubscriptionsLock.Unlock()
+	count := 0
+	for txid, ts := range s.txConfirmationsSubscriptions {
+		if ts.blockHeight == 0 || ts.blockHeight > height {
+			continue
+		}
+		n := height - ts.blockHeight + 1
+		for c, details := range ts.channels {
+			for _, m := range details.confirmations {
+				if m > details.last && m <= n {
+					s.sendTxConfirmations(c, details, txid, ts, m)
+					details.last = m
+					count++
+				}
+			}
+		}
+	}
+	if count > 0 {
+		glog.Info("broadcasting ", count, " tx confirmations at height ", height)
+	}
+}
+
+// publishTxConfirmationsReorg notifies the subscribers of the transactions in the blocks disconnected by the reorg,
+// the confirmations are notified again when the transactions are mined in the new chain
+func (s *WebsocketServer) publishTxConfirmationsReorg(reorg *bchain.Reorg) {
+	s.txConfirmationsSubscriptionsLock.Lock()
+	defer s.txConfirmationsSubscriptionsLock.Unlock()
+	for txid, ts := range s.txConfirmationsSubscriptions {
+		if ts.blockHeight <= reorg.ForkHeight {
+			continue
+		}
+		for c, details := range ts.channels {
+			c.DataOut(&WsRes{
+				ID: details.requestID,
+				Data: &wsTxConfirmationsNotification{
+					Txid:        txid,
+					BlockHeight: ts.blockHeight,
+					BlockHash:   ts.blockHash,
+					Reorged:     true,
+				},
+			})
+			details.last = 0
+		}
+		glog.Info("broadcasting reorged out tx ", txid, " to ", len(ts.channels), " channels")
+		ts.blockHeight = 0
+		ts.blockHash = ""
+	}
+}
+
+const maxContractEventTopics = 4
+const maxContractEventTopicValues = 1000
+
+type contractEventsDetails struct {
+	requestID string
+	contract  string     // address of the contract in the notifications
+	topics    [][]string // lower case topics by position, an empty position matches any topic
+}
+
+// wsContractEventNotification is sent to the subscribers of the events of a contract emitted by a new transaction
+type wsContractEventNotification struct {
+	Contract    string   `json:"contract"`
+	Txid        string   `json:"txid"`
+	Topics      []string `json:"topics" ts_doc:"Topics of the event, the first one is the signature of the event."`
+	Data        string   `json:"data" ts_doc:"Unindexed data of the event in hex form."`
+	BlockHeight uint32   `json:"blockHeight,omitempty" ts_doc:"Height of the block containing the transaction, missing for a mempool transaction."`
+}
+
+func (d *contractEventsDetails) matches(topics []string) bool {
+	for i, values := range d.topics {
+		if len(values) == 0 {
+			continue
+		}
+		if i >= len(topics) {
+			return false
+		}
+		topic := strings.ToLower(topics[i])
+		found := false
+		for _, v := range values {
+			if v == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidTopic(topic string) bool {
+	if len(topic) != 66 || topic[:2] != "0x" {
+		return false
+	}
+	_, err := hex.DecodeString(topic[2:])
+	return err == nil
+}
+
+// doUnsubscribeContractEvents removes the contract events subscription of the channel.
+// contractEventsSubscriptionsLock must be held by the caller.
+func (s *WebsocketServer) doUnsubscribeContractEvents(c *websocketChannel) {
+	if cs, found := s.contractEventsSubscriptions[c.contract]; found {
+		delete(cs, c)
+		if len(cs) == 0 {
+			delete(s.contractEventsSubscriptions, c.contract)
+		}
+	}
+	c.contract = ""
+}
+
+// subscribeContractEvents replaces the previous contract events subscription of the channel.
+// The events are matched against the topics in the same way as by eth_getLogs.
+func (s *WebsocketServer) subscribeContractEvents(c *websocketChannel, r *WsSubscribeContractEventsReq, req *WsReq) (res interface{}, err error) {
+	if s.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return nil, api.NewAPIError("Contract events are not supported", true)
+	}
+	if r.Contract == "" {
+		return nil, api.NewAPIError("Missing contract", true)
+	}
+	ad, err := s.chainParser.GetAddrDescFromAddress(r.Contract)
+	if err != nil {
+		return nil, api.NewAPIError("Invalid contract "+strconv.Quote(r.Contract)+", "+err.Error(), true)
+	}
+	contract := r.Contract
+	if a, _, err := s.chainParser.GetAddressesFromAddrDesc(ad); err == nil && len(a) == 1 {
+		contract = a[0]
+	}
+	if len(r.Topics) > maxContractEventTopics {
+		return nil, api.NewAPIError("More than "+strconv.Itoa(maxContractEventTopics)+" topics", true)
+	}
+	topics := make([][]string, len(r.Topics))
+	n := 0
+	for i, values := range r.Topics {
+		for _, v := range values {
+			v = strings.ToLower(v)
+			if !isValidTopic(v) {
+				return nil, api.NewAPIError("Invalid topic "+strconv.Quote(v), true)
+			}
+			topics[i] = append(topics[i], v)
+		}
+		n += len(values)
+	}
+	if n > maxContractEventTopicValues {
+		return nil, api.NewAPIError("More than "+strconv.Itoa(maxContractEventTopicValues)+" topics", true)
+	}
+	s.contractEventsSubscriptionsLock.Lock()
+	defer s.contractEventsSubscriptionsLock.Unlock()
+	s.doUnsubscribeContractEvents(c)
+	cs, found := s.contractEventsSubscriptions[string(ad)]
+	if !found {
+		cs = make(map[*websocketChannel]*contractEventsDetails)
+		s.contractEventsSubscriptions[string(ad)] = cs
+	}
+	cs[c] = &contractEventsDetails{requestID: req.ID, contract: contract, topics: topics}
+	c.contract = string(ad)
+	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeContractEvents"}).Set(float64(len(s.contractEventsSubscriptions)))
+	return &subscriptionResponse{true}, nil
+}
+
+// unsubscribeContractEvents unsubscribes the contract events subscription of the channel
+func (s *WebsocketServer) unsubscribeContractEvents(c *websocketChannel) (res interface{}, err error) {
+	s.contractEventsSubscriptionsLock.Lock()
+	defer s.contractEventsSubscriptionsLock.Unlock()
+	s.doUnsubscribeContractEvents(c)
+	s.metrics.WebsocketSubscribes.With(common.Labels{"method": "subscribeContractEvents"}).Set(float64(len(s.contractEventsSubscriptions)))
+	return &subscriptionResponse{false}, nil
+}
+
+func (s *WebsocketServer) hasContractEventsSubscriptions() bool {
+	if s.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return false
+	}
+	s.contractEventsSubscriptionsLock.Lock()
+	defer s.contractEventsSubscriptionsLock.Unlock()
+	return len(s.contractEventsSubscriptions) > 0
+}
+
+// publishContractEvents notifies the subscribers of the contracts which emitted the logs of the transaction
+func (s *WebsocketServer) publishContractEvents(txid string, logs []*bchain.RpcLog, blockHeight uint32) {
+	s.contractEventsSubscriptionsLock.Lock()
+	defer s.contractEventsSubscriptionsLock.Unlock()
+	for _, l := range logs {
+		ad, err := s.chainParser.GetAddrDescFromAddress(l.Address)
+		if err != nil {
+			continue
+		}
+		for c, details := range s.contractEventsSubscriptions[string(ad)] {
+			if details.matches(l.Topics) {
+				c.DataOut(&WsRes{
+					ID: details.requestID,
+					Data: &wsContractEventNotification{
+						Contract:    details.contract,
+						Txid:        txid,
+						Topics:      l.Topics,
+						Data:        l.Data,
+						BlockHeight: blockHeight,
+					},
+				})
+			}
+		}
+	}
+}
+
+// publishNewBlockContractEvents notifies the subscribers of the contract events emitted by the transactions of the block
+func (s *WebsocketServer) publishNewBlockContractEvents(block *bchain.Block) {
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		var logs []*bchain.RpcLog
+		if csd, ok := tx.CoinSpecificData.(bchain.EthereumSpecificData); ok && csd.Receipt != nil {
+			logs = csd.Receipt.Logs
+		} else if transfers, err := s.chainParser.EthereumTypeGetTokenTransfersFromTx(tx); err == nil {
+			logs = eth.GetTransferLogsFromTokenTransfers(transfers)
+		}
+		if len(logs) > 0 {
+			s.publishContractEvents(tx.Txid, logs, block.Height)
+		}
+	}
+}
+
+// wsNewBlockNotification is sent to the subscribers of new blocks
+type wsNewBlockNotification struct {
+	Height uint32 `json:"height"`
+	Hash   string `json:"hash"`
+}
+
 func (s *WebsocketServer) onNewBlockAsync(hash string, height uint32) {
 	s.newBlockSubscriptionsLock.Lock()
 	defer s.newBlockSubscriptionsLock.Unlock()
-	data := struct {
-		Height uint32 `json:"height"`
-		Hash   string `json:"hash"`
-	}{
+	data := wsNewBlockNotification{
 		Height: height,
 		Hash:   hash,
 	}
@@ -1119,6 +1861,7 @@ func (s *WebsocketServer) onNewBlockAsync(hash string, height uint32) {
 		})
 	}
 	glog.Info("broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+	s.publishTxConfirmations(height)
 }
 
 // setConfirmedBlockTxMetadata normalizes parsed block transactions.
@@ -1268,9 +2011,13 @@ func (s *WebsocketServer) publishNewBlockTxsByAddr(block *bchain.Block) {
 
 // OnNewBlock is a callback that broadcasts info about new block to subscribed clients
 func (s *WebsocketServer) OnNewBlock(block *bchain.Block) {
+	s.setTxConfirmationsBlock(block)
 	s.addressSubscriptionsLock.Lock()
 	defer s.addressSubscriptionsLock.Unlock()
 	go s.onNewBlockAsync(block.Hash, block.Height)
+	if s.hasContractEventsSubscriptions() {
+		go s.publishNewBlockContractEvents(block)
+	}
 	if s.newBlockTxsSubscriptionCount > 0 {
 		// Skip per-tx address matching when nobody opted into newBlockTxs.
 		go s.publishNewBlockTxsByAddr(block)
@@ -1289,6 +2036,12 @@ func (s *WebsocketServer) sendOnNewTx(tx *api.Tx) {
 	glog.Info("broadcasting new tx ", tx.Txid, " to ", len(s.newTransactionSubscriptions), " channels")
 }
 
+// wsAddressNotification is sent to the subscribers of an address involved in a new transaction
+type wsAddressNotification struct {
+	Address string  `json:"address"`
+	Tx      *api.Tx `json:"tx"`
+}
+
 func (s *WebsocketServer) sendOnNewTxAddr(stringAddressDescriptor string, tx *api.Tx, newBlockTx bool) {
 	addrDesc := bchain.AddressDescriptor(stringAddressDescriptor)
 	addr, _, err := s.chainParser.GetAddressesFromAddrDesc(addrDesc)
@@ -1297,10 +2050,7 @@ func (s *WebsocketServer) sendOnNewTxAddr(stringAddressDescriptor string, tx *ap
 		return
 	}
 	if len(addr) == 1 {
-		data := struct {
-			Address string  `json:"address"`
-			Tx      *api.Tx `json:"tx"`
-		}{
+		data := wsAddressNotification{
 			Address: addr[0],
 			Tx:      tx,
 		}
@@ -1321,10 +2071,7 @@ func (s *WebsocketServer) sendOnNewTxAddr(stringAddressDescriptor string, tx *ap
 				if s.metrics != nil {
 					s.metrics.WebsocketAddrNotifications.With(common.Labels{"source": source}).Inc()
 				}
-				c.DataOut(&WsRes{
-					ID:   details.requestID,
-					Data: &data,
-				})
+				s.sendAddressNotification(c, details, &data)
 			}
 			glog.Info("broadcasting new tx ", tx.Txid, ", addr ", addr[0], " to ", len(as), " channels")
 		}
@@ -1394,20 +2141,233 @@ func (s *WebsocketServer) onNewTxAsync(tx *bchain.MempoolTx, subscribed map[stri
 	}
 }
 
-// OnNewTx is a callback that broadcasts info about a tx affecting subscribed address
+// OnNewTx is a callback that broadcasts info about a tx affecting subscribed address or emitting subscribed contract events
 func (s *WebsocketServer) OnNewTx(tx *bchain.MempoolTx) {
+	if len(tx.TokenTransfers) > 0 && s.hasContractEventsSubscriptions() {
+		// the logs of a mempool transaction are not known, the events are derived from its token transfers
+		s.publishContractEvents(tx.Txid, eth.GetTransferLogsFromTokenTransfers(tx.TokenTransfers), 0)
+	}
 	subscribed := s.getNewTxSubscriptions(tx.Vin, tx.Vout, tx.TokenTransfers, nil)
 	if len(s.newTransactionSubscriptions) > 0 || len(subscribed) > 0 {
 		go s.onNewTxAsync(tx, subscribed)
 	}
 }
 
+// wsAddressRemovalNotification is sent to the subscribers of an address involved in a transaction removed from the mempool
+type wsAddressRemovalNotification struct {
+	Address     string `json:"address"`
+	Txid        string `json:"txid"`
+	Removal     string `json:"removal" ts_doc:"Reason of the removal, replaced, evicted or reorged."`
+	ReplacedBy  string `json:"replacedBy,omitempty" ts_doc:"Txid of the transaction which replaced the removed transaction."`
+	BlockHeight uint32 `json:"blockHeight,omitempty" ts_doc:"Height of the disconnected block which contained the reorged transaction."`
+	BlockHash   string `json:"blockHash,omitempty" ts_doc:"Hash of the disconnected block which contained the reorged transaction."`
+}
+
+func (s *WebsocketServer) onTxRemovedAsync(removal *bchain.MempoolTxRemoval, subscribed []string) {
+	reason := "replaced"
+	if removal.ReplacedBy == "" {
+		// the transaction was either confirmed or evicted, the confirmations are notified by the new block
+		_, err := s.chain.GetTransaction(removal.Txid)
+		if err == nil {
+			return
+		}
+		if err != bchain.ErrTxNotFound {
+			glog.Error("GetTransaction error ", err, " for removed tx ", removal.Txid)
+			return
+		}
+		reason = "evicted"
+	}
+	for _, sad := range subscribed {
+		addr, _, err := s.chainParser.GetAddressesFromAddrDesc(bchain.AddressDescriptor(sad))
+		if err != nil || len(addr) != 1 {
+			continue
+		}
+		data := wsAddressRemovalNotification{
+			Address:    addr[0],
+			Txid:       removal.Txid,
+			Removal:    reason,
+			ReplacedBy: removal.ReplacedBy,
+		}
+		s.addressSubscriptionsLock.Lock()
+		for c, details := range s.addressSubscriptions[sad] {
+			if details.publishMempoolRemovals {
+				if s.metrics != nil {
+					s.metrics.WebsocketAddrNotifications.With(common.Labels{"source": "mempool_removal"}).Inc()
+				}
+				s.sendAddressNotification(c, details, &data)
+			}
+		}
+		s.addressSubscriptionsLock.Unlock()
+	}
+	glog.Info("broadcasting ", reason, " tx ", removal.Txid, " to ", len(subscribed), " addresses")
+}
+
+// OnTxRemoved is a callback that notifies the subscribers of the addresses of a transaction replaced or evicted from the mempool
+func (s *WebsocketServer) OnTxRemoved(removal *bchain.MempoolTxRemoval) {
+	subscribed := s.getOptionalSubscriptions(removal.AddrDescs, func(d *addressDetails) bool { return d.publishMempoolRemovals })
+	if len(subscribed) > 0 {
+		go s.onTxRemovedAsync(removal, subscribed)
+	}
+}
+
+// getOptionalSubscriptions returns the address descriptors with a subscription which enabled the notification
+func (s *WebsocketServer) getOptionalSubscriptions(addrDescs []bchain.AddressDescriptor, enabled func(*addressDetails) bool) []string {
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	var subscribed []string
+	for _, ad := range addrDescs {
+		for _, details := range s.addressSubscriptions[string(ad)] {
+			if enabled(details) {
+				subscribed = append(subscribed, string(ad))
+				break
+			}
+		}
+	}
+	return subscribed
+}
+
+// wsOutpoint is an outpoint spent by a transaction
+type wsOutpoint struct {
+	Txid string `json:"txid"`
+	Vout int32  `json:"vout"`
+}
+
+// wsAddressDoubleSpendNotification is sent to the subscribers of an address involved in one of two mempool transactions spending the same outpoints
+type wsAddressDoubleSpendNotification struct {
+	Address         string       `json:"address"`
+	Txid            string       `json:"txid" ts_doc:"The new transaction, it replaced the conflicting transaction in the mempool."`
+	ConflictingTxid string       `json:"conflictingTxid" ts_doc:"The transaction seen first."`
+	Outpoints       []wsOutpoint `json:"outpoints" ts_doc:"Outpoints spent by both transactions."`
+}
+
+// OnDoubleSpend is a callback that notifies the subscribers of the addresses of two conflicting mempool transactions
+func (s *WebsocketServer) OnDoubleSpend(doubleSpend *bchain.MempoolDoubleSpend) {
+	if s.metrics != nil {
+		s.metrics.MempoolDoubleSpends.Inc()
+	}
+	subscribed := s.getOptionalSubscriptions(doubleSpend.AddrDescs, func(d *addressDetails) bool { return d.publishDoubleSpends })
+	if len(subscribed) == 0 {
+		return
+	}
+	outpoints := make([]wsOutpoint, len(doubleSpend.Outpoints))
+	for i, o := range doubleSpend.Outpoints {
+		outpoints[i] = wsOutpoint{Txid: o.Txid, Vout: o.Vout}
+	}
+	for _, sad := range subscribed {
+		addr, _, err := s.chainParser.GetAddressesFromAddrDesc(bchain.AddressDescriptor(sad))
+		if err != nil || len(addr) != 1 {
+			continue
+		}
+		data := wsAddressDoubleSpendNotification{
+			Address:         addr[0],
+			Txid:            doubleSpend.Txid,
+			ConflictingTxid: doubleSpend.ConflictingTxid,
+			Outpoints:       outpoints,
+		}
+		s.addressSubscriptionsLock.Lock()
+		for c, details := range s.addressSubscriptions[sad] {
+			if details.publishDoubleSpends {
+				if s.metrics != nil {
+					s.metrics.WebsocketAddrNotifications.With(common.Labels{"source": "double_spend"}).Inc()
+				}
+				s.sendAddressNotification(c, details, &data)
+			}
+		}
+		s.addressSubscriptionsLock.Unlock()
+	}
+	glog.Info("broadcasting double spend of ", doubleSpend.ConflictingTxid, " by ", doubleSpend.Txid, " to ", len(subscribed), " addresses")
+}
+
+// wsDisconnectedBlock is a block disconnected by a chain reorganization
+type wsDisconnectedBlock struct {
+	Height uint32   `json:"height"`
+	Hash   string   `json:"hash"`
+	Txids  []string `json:"txids" ts_doc:"Transactions of the block, they are unconfirmed again or removed."`
+}
+
+// wsReorgNotification is sent to the subscribers of reorgs before the blocks of the new chain
+type wsReorgNotification struct {
+	ForkHeight   uint32                `json:"forkHeight" ts_doc:"Height of the last block common to both branches."`
+	ForkHash     string                `json:"forkHash" ts_doc:"Hash of the last block common to both branches."`
+	Disconnected []wsDisconnectedBlock `json:"disconnected" ts_doc:"Disconnected blocks, from the highest one."`
+}
+
+func (s *WebsocketServer) publishReorg(reorg *bchain.Reorg) {
+	data := wsReorgNotification{
+		ForkHeight:   reorg.ForkHeight,
+		ForkHash:     reorg.ForkHash,
+		Disconnected: make([]wsDisconnectedBlock, len(reorg.Blocks)),
+	}
+	for i := range reorg.Blocks {
+		b := &reorg.Blocks[i]
+		data.Disconnected[i] = wsDisconnectedBlock{Height: b.Height, Hash: b.Hash, Txids: b.Txids}
+	}
+	s.reorgSubscriptionsLock.Lock()
+	defer s.reorgSubscriptionsLock.Unlock()
+	for c, id := range s.reorgSubscriptions {
+		c.DataOut(&WsRes{
+			ID:   id,
+			Data: &data,
+		})
+	}
+	glog.Info("broadcasting reorg to fork height ", reorg.ForkHeight, " to ", len(s.reorgSubscriptions), " channels")
+}
+
+// publishAddressesReorg notifies the address subscribers about their transactions in the blocks disconnected by the reorg
+func (s *WebsocketServer) publishAddressesReorg(reorg *bchain.Reorg) {
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	for i := range reorg.Blocks {
+		b := &reorg.Blocks[i]
+		for _, txid := range b.Txids {
+			for _, ad := range b.AddrDescs[txid] {
+				as, ok := s.addressSubscriptions[string(ad)]
+				if !ok {
+					continue
+				}
+				addr, _, err := s.chainParser.GetAddressesFromAddrDesc(ad)
+				if err != nil || len(addr) != 1 {
+					continue
+				}
+				data := wsAddressRemovalNotification{
+					Address:     addr[0],
+					Txid:        txid,
+					Removal:     "reorged",
+					BlockHeight: b.Height,
+					BlockHash:   b.Hash,
+				}
+				for c, details := range as {
+					if details.publishReorgs {
+						if s.metrics != nil {
+							s.metrics.WebsocketAddrNotifications.With(common.Labels{"source": "reorg"}).Inc()
+						}
+						s.sendAddressNotification(c, details, &data)
+					}
+				}
+			}
+		}
+	}
+}
+
+// OnReorg is a callback that notifies the subscribers of the transactions in the blocks disconnected by a chain reorganization
+func (s *WebsocketServer) OnReorg(reorg *bchain.Reorg) {
+	// synchronously, the notifications must precede the notifications about the blocks of the new chain
+	// and the transactions must be reset before they are found in the blocks of the new chain
+	s.publishReorg(reorg)
+	s.publishAddressesReorg(reorg)
+	s.publishTxConfirmationsReorg(reorg)
+}
+
+// wsFiatRatesNotification is sent to the subscribers of fiat rates, tokenRates only to those subscribed to tokens
+type wsFiatRatesNotification struct {
+	Rates      map[string]float32 `json:"rates"`
+	TokenRates map[string]float32 `json:"tokenRates,omitempty"`
+}
+
 func (s *WebsocketServer) broadcastTicker(currency string, rates map[string]float32, ticker *common.CurrencyRatesTicker) {
 	as, ok := s.fiatRatesSubscriptions[currency]
 	if ok && len(as) > 0 {
-		data := struct {
-			Rates interface{} `json:"rates"`
-		}{
+		data := wsFiatRatesNotification{
 			Rates: rates,
 		}
 		for c, id := range as {
@@ -1416,10 +2376,7 @@ func (s *WebsocketServer) broadcastTicker(currency string, rates map[string]floa
 				tokens = s.fiatRatesTokenSubscriptions[c]
 			}
 			if len(tokens) > 0 {
-				dataWithTokens := struct {
-					Rates      interface{}        `json:"rates"`
-					TokenRates map[string]float32 `json:"tokenRates,omitempty"`
-				}{
+				dataWithTokens := wsFiatRatesNotification{
 					Rates:      rates,
 					TokenRates: map[string]float32{},
 				}
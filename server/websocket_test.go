@@ -6,6 +6,7 @@ package server
 import (
 	"errors"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -202,6 +203,23 @@ func TestUnmarshalAddressesReturnsPublicAPIError(t *testing.T) {
 	}
 }
 
+func TestUnmarshalAddressesOptions(t *testing.T) {
+	s := &WebsocketServer{
+		chainParser: eth.NewEthereumParser(0, false),
+	}
+
+	ad, r, err := s.unmarshalAddresses([]byte(`{"addresses":["0x5dc6f5bf0a6c1a4e5f3a3f5d2a6b1aa2dd7c5a3e"],"mempoolRemovals":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ad) != 1 {
+		t.Fatalf("unexpected address descriptors %v", ad)
+	}
+	if !r.MempoolRemovals || r.NewBlockTxs {
+		t.Fatalf("unexpected options %+v", r)
+	}
+}
+
 func TestSetConfirmedBlockTxMetadataLeavesConfirmedTxUnchanged(t *testing.T) {
 	tx := bchain.Tx{
 		Confirmations: 3,
@@ -377,3 +395,185 @@ func TestPopulateBitcoinVinAddrDescsEnablesSenderOnlyMatching(t *testing.T) {
 		t.Fatal("sender subscription did not match after vin descriptor resolution")
 	}
 }
+
+func TestResumeSessionReplaysMissedNotifications(t *testing.T) {
+	session := &websocketSession{id: "s1"}
+	old := &websocketChannel{out: make(chan *WsRes, outChannelSize), alive: true, addrDescs: []string{"ad1"}, session: session}
+	session.channel = old
+	details := &addressDetails{requestID: "1", session: session}
+	s := &WebsocketServer{
+		addressSubscriptions: map[string]map[*websocketChannel]*addressDetails{"ad1": {old: details}},
+		sessions:             map[string]*websocketSession{"s1": session},
+	}
+	s.sendAddressNotification(old, details, "n1")
+	if !s.suspendSession(old) {
+		t.Fatal("suspendSession() = false, want true")
+	}
+	old.alive = false
+	s.sendAddressNotification(old, details, "n2")
+	s.sendAddressNotification(old, details, "n3")
+	if len(old.out) != 1 {
+		t.Fatalf("disconnected channel received %d messages, want 1", len(old.out))
+	}
+
+	c := &websocketChannel{out: make(chan *WsRes, outChannelSize), alive: true}
+	if _, err := s.resumeSession(c, &WsResumeSessionReq{SessionID: "unknown"}); err == nil {
+		t.Fatal("resumeSession() of unknown session succeeded")
+	}
+	res, err := s.resumeSession(c, &WsResumeSessionReq{SessionID: "s1", LastSeq: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&resumeSessionResponse{Resumed: true, Seq: 3, Replayed: 2}); !reflect.DeepEqual(res, want) {
+		t.Errorf("resumeSession() = %+v, want %+v", res, want)
+	}
+	for _, want := range []string{"n2", "n3"} {
+		if m := <-c.out; m.ID != "1" || m.Data != want {
+			t.Errorf("replayed %+v, want %v", m, want)
+		}
+	}
+	if session.expiry != nil || c.session != session || old.session != nil {
+		t.Error("session not moved to the resumed channel")
+	}
+	if _, ok := s.addressSubscriptions["ad1"][c]; !ok || len(s.addressSubscriptions["ad1"]) != 1 {
+		t.Errorf("address subscriptions %+v not moved to the resumed channel", s.addressSubscriptions["ad1"])
+	}
+
+	// the oldest notifications are dropped from the buffer, the gap is reported
+	for i := 0; i < maxWebsocketSessionEvents; i++ {
+		s.sendAddressNotification(c, details, i)
+	}
+	c2 := &websocketChannel{out: make(chan *WsRes, outChannelSize), alive: true}
+	res, err = s.resumeSession(c2, &WsResumeSessionReq{SessionID: "s1", LastSeq: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&resumeSessionResponse{Resumed: true, Seq: 3 + maxWebsocketSessionEvents, Replayed: maxWebsocketSessionEvents, Gap: true}); !reflect.DeepEqual(res, want) {
+		t.Errorf("resumeSession() = %+v, want %+v", res, want)
+	}
+}
+
+func TestOnReorgNotifiesSubscribers(t *testing.T) {
+	parser, _ := setupChain(t)
+	addrDesc, err := parser.GetAddrDescFromAddress(dbtestdata.Addr1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newChannel := func() *websocketChannel {
+		return &websocketChannel{out: make(chan *WsRes, 2), alive: true}
+	}
+	newBlock, reorg, withReorgs, withoutReorgs := newChannel(), newChannel(), newChannel(), newChannel()
+	s := &WebsocketServer{
+		chainParser:           parser,
+		newBlockSubscriptions: map[*websocketChannel]string{newBlock: "block"},
+		reorgSubscriptions:    map[*websocketChannel]string{reorg: "reorg"},
+		addressSubscriptions: map[string]map[*websocketChannel]*addressDetails{
+			string(addrDesc): {
+				withReorgs:    {requestID: "with-reorgs", publishReorgs: true},
+				withoutReorgs: {requestID: "without-reorgs"},
+			},
+		},
+		txConfirmationsSubscriptions: make(map[string]*txConfirmationsSubscription),
+	}
+
+	s.OnReorg(&bchain.Reorg{
+		ForkHeight: 100,
+		ForkHash:   "fork",
+		Blocks: []bchain.DisconnectedBlock{{
+			Height:    101,
+			Hash:      "disconnected",
+			Txids:     []string{"tx1", "tx2"},
+			AddrDescs: map[string][]bchain.AddressDescriptor{"tx2": {addrDesc}},
+		}},
+	})
+
+	want := &wsReorgNotification{
+		ForkHeight:   100,
+		ForkHash:     "fork",
+		Disconnected: []wsDisconnectedBlock{{Height: 101, Hash: "disconnected", Txids: []string{"tx1", "tx2"}}},
+	}
+	if len(reorg.out) != 1 {
+		t.Fatalf("reorg subscriber received %d messages, want 1", len(reorg.out))
+	}
+	if m := <-reorg.out; m.ID != "reorg" || !reflect.DeepEqual(m.Data, want) {
+		t.Errorf("reorg notification %+v, want %+v", m.Data, want)
+	}
+	// the new block subscribers expect only the new block payload
+	if len(newBlock.out) != 0 {
+		t.Fatalf("new block subscriber received %d messages, want 0", len(newBlock.out))
+	}
+	if len(withoutReorgs.out) != 0 {
+		t.Fatalf("address subscriber without reorgs received %d messages, want 0", len(withoutReorgs.out))
+	}
+	if len(withReorgs.out) != 1 {
+		t.Fatalf("address subscriber with reorgs received %d messages, want 1", len(withReorgs.out))
+	}
+	wantRemoval := &wsAddressRemovalNotification{Address: dbtestdata.Addr1, Txid: "tx2", Removal: "reorged", BlockHeight: 101, BlockHash: "disconnected"}
+	if m := <-withReorgs.out; m.ID != "with-reorgs" || !reflect.DeepEqual(m.Data, wantRemoval) {
+		t.Errorf("address notification %+v, want %+v", m.Data, wantRemoval)
+	}
+}
+
+func TestPublishContractEvents(t *testing.T) {
+	const (
+		contract = "0x4af4114F73d1c1C903aC9E0361b379D1291808A2"
+		transfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+		holder   = "0x000000000000000000000000555ee11fbddc0e49a9bab358a8941ad95ffdb48f"
+		other    = "0x00000000000000000000000020cd153de35d469ba46127a0c8f18626b59a256a"
+	)
+	parser := eth.NewEthereumParser(0, false)
+	ad, err := parser.GetAddrDescFromAddress(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := &websocketChannel{out: make(chan *WsRes, 2), alive: true}
+	toHolder := &websocketChannel{out: make(chan *WsRes, 2), alive: true}
+	s := &WebsocketServer{
+		chainParser: parser,
+		contractEventsSubscriptions: map[string]map[*websocketChannel]*contractEventsDetails{
+			string(ad): {
+				all:      {requestID: "all", contract: contract},
+				toHolder: {requestID: "to-holder", contract: contract, topics: [][]string{{transfer}, nil, {holder}}},
+			},
+		},
+	}
+
+	s.publishContractEvents("tx1", []*bchain.RpcLog{
+		{Address: strings.ToLower(contract), Topics: []string{transfer, holder, other}, Data: "0x01"},
+		{Address: contract, Topics: []string{transfer, other, "0x" + strings.ToUpper(holder[2:])}, Data: "0x02"},
+		{Address: "0x20cd153de35d469ba46127a0c8f18626b59a256a", Topics: []string{transfer, other, holder}, Data: "0x03"},
+	}, 10)
+
+	if len(all.out) != 2 {
+		t.Fatalf("subscriber without topics received %d messages, want 2", len(all.out))
+	}
+	if len(toHolder.out) != 1 {
+		t.Fatalf("subscriber of transfers to holder received %d messages, want 1", len(toHolder.out))
+	}
+	want := &wsContractEventNotification{Contract: contract, Txid: "tx1", Topics: []string{transfer, other, "0x" + strings.ToUpper(holder[2:])}, Data: "0x02", BlockHeight: 10}
+	if m := <-toHolder.out; m.ID != "to-holder" || !reflect.DeepEqual(m.Data, want) {
+		t.Errorf("contract event %+v, want %+v", m.Data, want)
+	}
+}
+
+func TestSubscribeContractEventsErrors(t *testing.T) {
+	s := &WebsocketServer{chainParser: eth.NewEthereumParser(0, false)}
+	tooManyTopics := make([][]string, maxContractEventTopics+1)
+	tests := []struct {
+		name    string
+		req     WsSubscribeContractEventsReq
+		wantErr string
+	}{
+		{"missing contract", WsSubscribeContractEventsReq{}, "Missing contract"},
+		{"too many topics", WsSubscribeContractEventsReq{Contract: "0x4af4114f73d1c1c903ac9e0361b379d1291808a2", Topics: tooManyTopics}, "More than 4 topics"},
+		{"invalid topic", WsSubscribeContractEventsReq{Contract: "0x4af4114f73d1c1c903ac9e0361b379d1291808a2", Topics: [][]string{{"0x1234"}}}, `Invalid topic "0x1234"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.subscribeContractEvents(&websocketChannel{}, &tt.req, &WsReq{ID: "1"})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("subscribeContractEvents() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -9,7 +9,7 @@ import (
 // WsReq represents a generic WebSocket request with an ID, method, and raw parameters.
 type WsReq struct {
 	ID     string          `json:"id" ts_doc:"Unique request identifier."`
-	Method string          `json:"method" ts_type:"'getAccountInfo' | 'getInfo' | 'getBlockHash'| 'getBlock' | 'getAccountUtxo' | 'getBalanceHistory' | 'getTransaction' | 'getTransactionSpecific' | 'estimateFee' | 'sendTransaction' | 'subscribeNewBlock' | 'unsubscribeNewBlock' | 'subscribeNewTransaction' | 'unsubscribeNewTransaction' | 'subscribeAddresses' | 'unsubscribeAddresses' | 'subscribeFiatRates' | 'unsubscribeFiatRates' | 'ping' | 'getCurrentFiatRates' | 'getFiatRatesForTimestamps' | 'getFiatRatesTickersList' | 'getMempoolFilters'" ts_doc:"Requested method name."`
+	Method string          `json:"method" ts_type:"'getAccountInfo' | 'getInfo' | 'getBlockHash'| 'getBlock' | 'getAccountUtxo' | 'getBalanceHistory' | 'getTransaction' | 'getTransactionSpecific' | 'estimateFee' | 'sendTransaction' | 'validateTransaction' | 'analyzePsbt' | 'selectCoins' | 'lockUtxos' | 'unlockUtxos' | 'getUtxoLocks' | 'subscribeNewBlock' | 'unsubscribeNewBlock' | 'subscribeReorg' | 'unsubscribeReorg' | 'subscribeNewTransaction' | 'unsubscribeNewTransaction' | 'subscribeAddresses' | 'unsubscribeAddresses' | 'resumeSession' | 'subscribeTxConfirmations' | 'unsubscribeTxConfirmations' | 'subscribeContractEvents' | 'unsubscribeContractEvents' | 'subscribeFiatRates' | 'unsubscribeFiatRates' | 'ping' | 'getCurrentFiatRates' | 'getFiatRatesForTimestamps' | 'getFiatRatesTickersList' | 'getMempoolFilters' | 'createPortfolio' | 'updatePortfolio' | 'deletePortfolio' | 'getPortfolio'" ts_doc:"Requested method name."`
 	Params json.RawMessage `json:"params" ts_type:"any" ts_doc:"Parameters for the requested method in raw JSON format."`
 }
 
@@ -17,6 +17,7 @@ type WsReq struct {
 type WsRes struct {
 	ID   string      `json:"id" ts_doc:"Corresponding request identifier."`
 	Data interface{} `json:"data" ts_doc:"Payload of the response, structure depends on the request."`
+	Seq  uint64      `json:"seq,omitempty" ts_doc:"Sequence number of a notification of a resumable session."`
 }
 
 // WsAccountInfoReq carries parameters for the 'getAccountInfo' method.
@@ -27,6 +28,7 @@ type WsAccountInfoReq struct {
 	IncludeErc4626    bool   `json:"includeErc4626,omitempty" ts_doc:"If true, includes ERC4626 data for detected vault tokens."`
 	PageSize          int    `json:"pageSize,omitempty" ts_doc:"Number of items per page, if paging is used."`
 	Page              int    `json:"page,omitempty" ts_doc:"Requested page index, if paging is used."`
+	Cursor            string `json:"cursor,omitempty" ts_doc:"Opaque cursor (nextCursor of the previous response) of the requested page, used instead of page."`
 	FromHeight        int    `json:"from,omitempty" ts_doc:"Starting block height for transaction filtering."`
 	ToHeight          int    `json:"to,omitempty" ts_doc:"Ending block height for transaction filtering."`
 	ContractFilter    string `json:"contractFilter,omitempty" ts_doc:"Filter by specific contract address (for token data)."`
@@ -34,6 +36,23 @@ type WsAccountInfoReq struct {
 	Gap               int    `json:"gap,omitempty" ts_doc:"Gap limit for XPUB scanning, if relevant."`
 }
 
+// WsPortfolioReq carries parameters for the 'createPortfolio', 'updatePortfolio' and 'deletePortfolio' methods.
+type WsPortfolioReq struct {
+	Token    string                 `json:"token,omitempty" ts_doc:"Portfolio token, required for update and delete."`
+	Name     string                 `json:"name,omitempty" ts_doc:"Optional name of the portfolio."`
+	Accounts []api.PortfolioAccount `json:"accounts,omitempty" ts_doc:"Addresses, XPUBs or EVM accounts grouped in the portfolio."`
+}
+
+// WsPortfolioBalanceReq carries parameters for the 'getPortfolio' method.
+type WsPortfolioBalanceReq struct {
+	Token             string `json:"token" ts_doc:"Portfolio token returned by 'createPortfolio'."`
+	Details           string `json:"details,omitempty" ts_type:"'basic' | 'tokens' | 'tokenBalances' | 'txids' | 'txslight' | 'txs'" ts_doc:"Level of detail of the merged transaction history."`
+	PageSize          int    `json:"pageSize,omitempty" ts_doc:"Number of transactions per page."`
+	Page              int    `json:"page,omitempty" ts_doc:"Requested page index."`
+	SecondaryCurrency string `json:"secondaryCurrency,omitempty" ts_doc:"Currency code to convert values into (e.g. 'USD')."`
+	Gap               int    `json:"gap,omitempty" ts_doc:"Gap limit for XPUB scanning of the XPUB accounts."`
+}
+
 // WsBackendInfo holds extended info about the connected backend node.
 type WsBackendInfo struct {
 	Version          string      `json:"version,omitempty" ts_doc:"Backend version string."`
@@ -75,7 +94,28 @@ type WsBlockReq struct {
 
 // WsAccountUtxoReq is used to request unspent transaction outputs (UTXOs) for a given xpub/address.
 type WsAccountUtxoReq struct {
-	Descriptor string `json:"descriptor" ts_doc:"Address or XPUB descriptor to retrieve UTXOs for."`
+	Descriptor    string `json:"descriptor" ts_doc:"Address or XPUB descriptor to retrieve UTXOs for."`
+	LockToken     string `json:"lockToken,omitempty" ts_doc:"Token of the UTXO locks, its locked UTXOs are not flagged as locked."`
+	ExcludeLocked bool   `json:"excludeLocked,omitempty" ts_doc:"If true, the UTXOs locked by other tokens are not returned."`
+}
+
+// WsSelectCoinsReq carries parameters for the 'selectCoins' method.
+type WsSelectCoinsReq struct {
+	Descriptor  string                    `json:"descriptor" ts_doc:"XPUB or output descriptor whose UTXOs are selected."`
+	Outputs     []api.CoinSelectionOutput `json:"outputs" ts_doc:"Target outputs of the transaction."`
+	FeePerKb    int64                     `json:"feePerKb" ts_doc:"Fee rate of the transaction in satoshi per kvB."`
+	ExcludeDust bool                      `json:"excludeDust,omitempty" ts_doc:"Do not select the UTXOs with value below the dust threshold."`
+	Exclude     []string                  `json:"exclude,omitempty" ts_doc:"Outpoints in the format txid:vout which must not be selected."`
+	Gap         int                       `json:"gap,omitempty" ts_doc:"Gap limit of the xpub derivation."`
+	LockToken   string                    `json:"lockToken,omitempty" ts_doc:"Token of the UTXO locks, the UTXOs locked by other tokens are not selected."`
+	LockTTL     int64                     `json:"lockTtl,omitempty" ts_doc:"If set, the selected UTXOs are locked for the number of seconds, by lockToken or by a newly issued token."`
+}
+
+// WsUtxoLockReq carries parameters for the 'lockUtxos', 'unlockUtxos' and 'getUtxoLocks' methods.
+type WsUtxoLockReq struct {
+	Token     string   `json:"token,omitempty" ts_doc:"Lock token issued by Blockbook, lockUtxos without token issues a new one."`
+	Outpoints []string `json:"outpoints,omitempty" ts_doc:"Outpoints in the format txid:vout, unlockUtxos without outpoints releases all locks of the token."`
+	TTL       int64    `json:"ttl,omitempty" ts_doc:"Duration of the lock in seconds, 60 seconds by default, at most one hour."`
 }
 
 // WsBalanceHistoryReq is used to retrieve a historical balance chart or intervals for an account.
@@ -146,10 +186,42 @@ type WsSendTransactionReq struct {
 	DisableAlternativeRPC bool   `json:"disableAlternativeRpc" ts_doc:"Use alternative RPC method to broadcast transaction."`
 }
 
+// WsValidateTransactionReq is used to validate a transaction without broadcasting it.
+type WsValidateTransactionReq struct {
+	Hex string `json:"hex" ts_doc:"Hex-encoded transaction data to validate."`
+}
+
+// WsAnalyzePsbtReq is used to analyze a partially signed transaction.
+type WsAnalyzePsbtReq struct {
+	Psbt string `json:"psbt" ts_doc:"Base64 or hex encoded PSBT (BIP-174)."`
+}
+
 // WsSubscribeAddressesReq is used to subscribe to updates on a list of addresses.
 type WsSubscribeAddressesReq struct {
-	Addresses   []string `json:"addresses" ts_doc:"List of addresses to subscribe for updates (e.g., new transactions)."`
-	NewBlockTxs bool     `json:"newBlockTxs,omitempty" ts_doc:"If true, also publish confirmed transactions for subscribed addresses when new blocks are connected."`
+	Addresses       []string `json:"addresses" ts_doc:"List of addresses to subscribe for updates (e.g., new transactions)."`
+	NewBlockTxs     bool     `json:"newBlockTxs,omitempty" ts_doc:"If true, also publish confirmed transactions for subscribed addresses when new blocks are connected."`
+	MempoolRemovals bool     `json:"mempoolRemovals,omitempty" ts_doc:"If true, also notify when an unconfirmed transaction of the subscribed addresses is replaced or evicted from the mempool."`
+	DoubleSpends    bool     `json:"doubleSpends,omitempty" ts_doc:"If true, also notify when a new mempool transaction spends the same outpoints as an unconfirmed transaction of the subscribed addresses."`
+	Reorgs          bool     `json:"reorgs,omitempty" ts_doc:"If true, also notify when a confirmed transaction of the subscribed addresses is disconnected by a chain reorganization."`
+	Session         bool     `json:"session,omitempty" ts_doc:"If true, the subscription is kept for a while after the disconnection and can be resumed by resumeSession."`
+}
+
+// WsResumeSessionReq is used to resume the address subscription of a session after a reconnection.
+type WsResumeSessionReq struct {
+	SessionID string `json:"sessionId" ts_doc:"Identifier of the session returned by subscribeAddresses."`
+	LastSeq   uint64 `json:"lastSeq" ts_doc:"Sequence number of the last notification received by the client, the newer notifications are replayed."`
+}
+
+// WsSubscribeTxConfirmationsReq is used to subscribe to the confirmations of a list of transactions.
+type WsSubscribeTxConfirmationsReq struct {
+	Txids         []string `json:"txids" ts_doc:"List of transactions to watch."`
+	Confirmations []uint32 `json:"confirmations" ts_doc:"Numbers of confirmations at which a notification is sent (e.g. [1, 3, 6])."`
+}
+
+// WsSubscribeContractEventsReq is used to subscribe to the events of a contract on Ethereum type coins.
+type WsSubscribeContractEventsReq struct {
+	Contract string     `json:"contract" ts_doc:"Address of the contract emitting the events."`
+	Topics   [][]string `json:"topics,omitempty" ts_doc:"Filter of the event topics by position as in eth_getLogs, an empty position matches any topic, otherwise the topic must be one of the listed values."`
 }
 
 // WsSubscribeFiatRatesReq subscribes to updates of fiat rates for a specific currency or set of tokens.